@@ -0,0 +1,182 @@
+// Command docmind-import uploads a folder of Markdown files, or a ZIP archive
+// of one or of a Confluence or Notion export, to a DocMind server and prints
+// the import report.
+//
+//	docmind-import -server http://localhost:8080 -token $TOKEN -dry-run ./docs
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+)
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "DocMind server URL")
+	token := flag.String("token", os.Getenv("DOCMIND_TOKEN"), "access token, defaults to $DOCMIND_TOKEN")
+	format := flag.String("format", "", "format of the export: markdown, confluence or notion; detected when empty")
+	dryRun := flag.Bool("dry-run", false, "report what would be imported without changing anything")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <folder or .zip>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *token == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := upload(*server, *token, flag.Arg(0), *format, *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import failed:", err)
+		os.Exit(1)
+	}
+
+	printReport(report)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func upload(server, token, source, format string, dryRun bool) (*models.ImportReport, error) {
+	archive, err := readArchive(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	name := filepath.Base(strings.TrimSuffix(source, string(filepath.Separator)))
+	if !strings.EqualFold(filepath.Ext(name), ".zip") {
+		name += ".zip"
+	}
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(archive); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"dry_run": {strconv.FormatBool(dryRun)}}
+	if format != "" {
+		query.Set("format", format)
+	}
+	endpoint := strings.TrimSuffix(server, "/") + "/api/v1/documents/import?" + query.Encode()
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	var report models.ImportReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &report, nil
+}
+
+// readArchive returns the ZIP archive at source, or zips the folder at source
+func readArchive(source string) ([]byte, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return os.ReadFile(source)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	err = fs.WalkDir(os.DirFS(source), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(filepath.Join(source, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		f, err := w.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func printReport(report *models.ImportReport) {
+	for _, doc := range report.Documents {
+		fmt.Printf("%-9s %s", doc.Action, doc.Path)
+		if doc.Title != "" {
+			fmt.Printf(" (%s)", doc.Title)
+		}
+		fmt.Println()
+		for _, warning := range doc.Warnings {
+			fmt.Printf("          warning: %s\n", warning)
+		}
+		if doc.Error != "" {
+			fmt.Printf("          error: %s\n", doc.Error)
+		}
+	}
+
+	if report.DryRun {
+		fmt.Print("dry run: ")
+	}
+	fmt.Printf("%s export: %d created, %d updated, %d unchanged, %d failed, %d images and %d attachments uploaded\n",
+		report.Format, report.Created, report.Updated, report.Unchanged, report.Failed, report.Images, report.Attachments)
+}
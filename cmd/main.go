@@ -1,78 +1,53 @@
 package main
 
 import (
-	"github.com/Zhaoyikaiii/docmind/internal/api/controllers"
-	"github.com/Zhaoyikaiii/docmind/internal/api/middleware"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/api/handlers"
+	"github.com/Zhaoyikaiii/docmind/internal/app"
+	"github.com/Zhaoyikaiii/docmind/internal/storage"
+	"github.com/Zhaoyikaiii/docmind/internal/storage/operators"
 	"github.com/Zhaoyikaiii/docmind/pkg/config"
 	"github.com/Zhaoyikaiii/docmind/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"github.com/Zhaoyikaiii/docmind/internal/service"
-	"github.com/Zhaoyikaiii/docmind/internal/interfaces"
-	"github.com/Zhaoyikaiii/docmind/internal/storage"
-	"github.com/Zhaoyikaiii/docmind/internal/storage/operators"
-	"fmt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
-func setupRouter() *gin.Engine {
+func setupRouter(a *app.App) *gin.Engine {
 	r := gin.New()
-	middleware.ApplyMiddleware(r)
 
 	// Public routes
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
 
-	// Auth routes
-	authController := &controllers.AuthController{}
-	auth := r.Group("/auth")
-	{
-		auth.POST("/login", authController.Login)
-		auth.POST("/refresh", authController.RefreshToken)
-	}
-
-	// Protected routes
-	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware())
-	{
-		api.GET("/protected", func(c *gin.Context) {
-			userID, _ := c.Get("userID")
-			username, _ := c.Get("username")
-			c.JSON(200, gin.H{
-				"message":  "You have access to protected resource",
-				"user_id":  userID,
-				"username": username,
-			})
-		})
-	}
-
+	a.SetupRoutes(r)
 	return r
 }
 
-func setupFileOperator(cfg *config.Config) (interfaces.FileOperator, error) {
-	switch cfg.Storage.Type {
+func setupFileOperator() (storage.FileOperator, error) {
+	switch storageType := config.GetString("storage.type"); storageType {
 	case "local":
+		allowed := make(map[string]bool)
+		for _, ext := range viper.GetStringSlice("storage.local.allowed_types") {
+			allowed[strings.ToLower(ext)] = true
+		}
 		return storage.NewFileOperator(storage.Local, operators.LocalConfig{
-			UploadDir:    cfg.Storage.LocalPath,
-			MaxFileSize:  cfg.Storage.MaxFileSize,
-			AllowedTypes: cfg.Storage.AllowedTypes,
-		})
-
-	case "oss":
-		return storage.NewFileOperator(storage.OSS, operators.OSSConfig{
-			Endpoint:        cfg.OSS.Endpoint,
-			AccessKeyID:     cfg.OSS.AccessKeyID,
-			AccessKeySecret: cfg.OSS.AccessKeySecret,
-				BucketName:      cfg.OSS.BucketName,
-				BasePath:        cfg.OSS.BasePath,
-				MaxFileSize:     cfg.OSS.MaxFileSize,
-				AllowedTypes:    cfg.OSS.AllowedTypes,
+			UploadDir:    config.GetString("storage.local.path"),
+			MaxFileSize:  config.GetInt64("storage.local.max_file_size"),
+			AllowedTypes: allowed,
 		})
 
 	// ... 其他存储类型的配置
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
 	}
-
-	return nil, fmt.Errorf("unsupported storage type: %s", cfg.Storage.Type)
 }
 
 func main() {
@@ -81,12 +56,37 @@ func main() {
 	utils.InitLogger()
 	defer utils.Logger.Sync()
 
-	r := setupRouter()
+	db, err := gorm.Open(postgres.Open(os.ExpandEnv(config.GetString("database.dsn"))), &gorm.Config{})
+	if err != nil {
+		utils.Logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+
+	fileOperator, err := setupFileOperator()
+	if err != nil {
+		utils.Logger.Fatal("Failed to set up file storage", zap.Error(err))
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		utils.Logger.Fatal("Failed to load settings", zap.Error(err))
+	}
+
+	a, err := app.New(context.Background(), db, fileOperator, handlers.UploadConfig{
+		UploadDir: config.GetString("storage.local.path"),
+		MaxSize:   config.GetInt64("storage.local.max_file_size"),
+	}, settings)
+	if err != nil {
+		utils.Logger.Fatal("Failed to build services", zap.Error(err))
+	}
+	// 退出前处理完排队的任务并写入索引
+	defer a.Close()
+
+	r := setupRouter(a)
 
 	port := config.GetString("server.port")
 	utils.Logger.Info("Starting server on port " + port)
 
 	if err := r.Run(":" + port); err != nil {
-		utils.Logger.Fatal("Failed to start server", zap.Error(err))
+		utils.Logger.Error("Failed to start server", zap.Error(err))
 	}
 }
@@ -0,0 +1,24 @@
+package ai
+
+import "context"
+
+// Chat message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a chat conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatModel generates replies with a large language model
+type ChatModel interface {
+	// Stream generates a reply to messages. onToken is called with each piece
+	// of text as it is produced; returning an error from it aborts generation.
+	// The complete reply is returned at the end.
+	Stream(ctx context.Context, messages []Message, onToken func(token string) error) (string, error)
+}
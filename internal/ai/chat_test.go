@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIChatModelStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+
+		var req chatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		assert.Equal(t, "llama3", req.Model)
+		assert.Equal(t, []Message{{Role: RoleUser, Content: "hi"}}, req.Messages)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", token)
+		}
+		fmt.Fprint(w, ": keep-alive\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	model, err := NewOpenAIChatModel(OpenAIConfig{BaseURL: server.URL + "/v1", Model: "llama3"})
+	require.NoError(t, err)
+
+	var tokens []string
+	reply, err := model.Stream(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world", reply)
+	assert.Equal(t, []string{"Hello", ", ", "world"}, tokens)
+}
+
+func TestFakeChatModel(t *testing.T) {
+	prompt := "Sources:\n\n[1] Runbook (document 4, version 2)\nPromote the replica. Then switch.\n\n" +
+		"[2] 手册 (document 5, version 1)\n先提升从库。再切换。\n\nQuestion: how?"
+
+	var streamed strings.Builder
+	reply, err := NewFakeChatModel("").Stream(context.Background(), []Message{
+		{Role: RoleSystem, Content: "system"},
+		{Role: RoleUser, Content: prompt},
+	}, func(token string) error {
+		streamed.WriteString(token)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Promote the replica [1]. 先提升从库 [2].", reply)
+	assert.Equal(t, reply, streamed.String())
+
+	reply, err = NewFakeChatModel("Fixed reply").Stream(context.Background(), nil, func(string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "Fixed reply", reply)
+}
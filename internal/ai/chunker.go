@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Default chunk sizes in characters
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+// Chunk is a passage of a document. Start and End are character (rune)
+// offsets into the source text.
+type Chunk struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Chunker splits text into overlapping passages of about Size characters.
+// Passages end at a paragraph break, sentence end or space when one falls in
+// the last part of the window, so that they rarely cut words or sentences.
+type Chunker struct {
+	Size    int
+	Overlap int
+}
+
+func NewChunker(size, overlap int) *Chunker {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = min(DefaultChunkOverlap, size/2)
+	}
+	return &Chunker{Size: size, Overlap: overlap}
+}
+
+func (c *Chunker) Split(text string) []Chunk {
+	runes := []rune(text)
+	var chunks []Chunk
+
+	for start := 0; start < len(runes); {
+		end := min(start+c.Size, len(runes))
+		if end < len(runes) {
+			// 只在窗口后 30% 内寻找断点，避免切出过短的段落
+			end = breakPoint(runes, start+c.Size*7/10, end)
+		}
+
+		if passage := strings.TrimSpace(string(runes[start:end])); passage != "" {
+			chunks = append(chunks, Chunk{Index: len(chunks), Text: passage, Start: start, End: end})
+		}
+		if end == len(runes) {
+			break
+		}
+
+		next := max(end-c.Overlap, start+1)
+		// 重叠部分从完整的词开始
+		if !unicode.IsSpace(runes[next-1]) {
+			for i := next; i < end && i-next < c.Overlap; i++ {
+				if unicode.IsSpace(runes[i]) {
+					next = i + 1
+					break
+				}
+			}
+		}
+		start = next
+	}
+
+	return chunks
+}
+
+// breakPoint returns the best place in runes[from:to] to end a passage:
+// after a paragraph break, else after a sentence end, else after a space,
+// else to itself.
+func breakPoint(runes []rune, from, to int) int {
+	for i := to - 1; i > from; i-- {
+		if runes[i] == '\n' && runes[i-1] == '\n' {
+			return i + 1
+		}
+	}
+	for i := to - 1; i >= from; i-- {
+		switch runes[i] {
+		case '.', '!', '?', '。', '！', '？', '\n':
+			if runes[i] == '\n' || i+1 >= len(runes) || unicode.IsSpace(runes[i+1]) || runes[i] > unicode.MaxASCII {
+				return i + 1
+			}
+		}
+	}
+	for i := to - 1; i >= from; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return to
+}
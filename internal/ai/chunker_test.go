@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkerSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunker  *Chunker
+		text     string
+		expected []string
+	}{
+		{
+			name:     "short text is one chunk",
+			chunker:  NewChunker(100, 20),
+			text:     "  A single short paragraph.  ",
+			expected: []string{"A single short paragraph."},
+		},
+		{
+			name:     "empty text",
+			chunker:  NewChunker(100, 20),
+			text:     " \n ",
+			expected: nil,
+		},
+		{
+			name:    "breaks at sentence ends with overlap",
+			chunker: NewChunker(34, 15),
+			text:    "The first sentence is here. The second one follows. A third ends it.",
+			expected: []string{
+				"The first sentence is here.",
+				"is here. The second one follows.",
+				"one follows. A third ends it.",
+			},
+		},
+		{
+			name:     "prefers paragraph breaks",
+			chunker:  NewChunker(40, 0),
+			text:     "Intro line one. Still intro.\n\nNext part of the text.",
+			expected: []string{"Intro line one. Still intro.", "Next part of the text."},
+		},
+		{
+			name:     "cjk text without spaces",
+			chunker:  NewChunker(10, 2),
+			text:     "数据库故障切换。先提升从库，再切换流量。",
+			expected: []string{"数据库故障切换。", "换。先提升从库，再切", "再切换流量。"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var texts []string
+			for _, chunk := range tt.chunker.Split(tt.text) {
+				texts = append(texts, chunk.Text)
+			}
+			assert.Equal(t, tt.expected, texts)
+		})
+	}
+}
+
+func TestChunkOffsets(t *testing.T) {
+	text := strings.Repeat("word ", 100)
+	chunks := NewChunker(50, 10).Split(text)
+	runes := []rune(text)
+
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.Equal(t, strings.TrimSpace(string(runes[chunk.Start:chunk.End])), chunk.Text)
+		if i > 0 {
+			assert.Less(t, chunk.Start, chunks[i-1].End, "chunks should overlap")
+		}
+	}
+	assert.Equal(t, len(runes), chunks[len(chunks)-1].End)
+}
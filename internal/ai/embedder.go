@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+)
+
+// Embedder turns texts into vectors whose dot product measures semantic
+// similarity. Implementations return unit-length vectors of Dimensions()
+// elements, one per input text and in the same order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Dimensions() int
+}
+
+// DefaultHashDimensions is the vector size of the hashing embedder when none
+// is configured
+const DefaultHashDimensions = 256
+
+// hashEmbedder projects analyzed terms into a fixed number of buckets
+// (feature hashing). It has no notion of meaning beyond shared words but is
+// deterministic and needs no model, which makes it suitable for tests and
+// offline setups.
+type hashEmbedder struct {
+	dimensions int
+	analyzers  *search.Analyzers
+}
+
+func NewHashEmbedder(dimensions int) Embedder {
+	if dimensions <= 0 {
+		dimensions = DefaultHashDimensions
+	}
+	return &hashEmbedder{
+		dimensions: dimensions,
+		analyzers:  search.NewAnalyzers(nil),
+	}
+}
+
+func (e *hashEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+func (e *hashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, e.dimensions)
+		analyzer := e.analyzers.For(search.DetectLanguage(text))
+		for _, token := range analyzer.Analyze(text) {
+			h := fnv.New64a()
+			h.Write([]byte(token.Term))
+			sum := h.Sum64()
+
+			// 用哈希的最高位决定符号，减少桶冲突带来的偏差
+			if sum>>63 == 0 {
+				vector[sum%uint64(e.dimensions)]++
+			} else {
+				vector[sum%uint64(e.dimensions)]--
+			}
+		}
+		vectors[i] = Normalize(vector)
+	}
+	return vectors, nil
+}
+
+// Normalize scales v to unit length in place and returns it. The zero vector
+// is returned unchanged.
+func Normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= norm
+	}
+	return v
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestHashEmbedder(t *testing.T) {
+	embedder := NewHashEmbedder(64)
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"Database failover runbook",
+		"How to fail over the databases",
+		"Quarterly marketing budget",
+		"",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 4)
+
+	for _, v := range vectors[:3] {
+		assert.Len(t, v, 64)
+		assert.InDelta(t, 1, dot(v, v), 1e-5)
+	}
+	assert.Greater(t, dot(vectors[0], vectors[1]), dot(vectors[0], vectors[2]))
+	assert.Zero(t, dot(vectors[3], vectors[3]))
+
+	again, err := embedder.Embed(context.Background(), []string{"Database failover runbook"})
+	require.NoError(t, err)
+	assert.Equal(t, vectors[0], again[0])
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var requests []embeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req embeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		// 倒序返回，验证按 index 归位
+		var resp embeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			item := struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{float32(len(req.Input[i])), 0}}
+			resp.Data = append(resp.Data, item)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	embedder, err := NewOpenAIEmbedder(OpenAIConfig{
+		BaseURL:    server.URL + "/v1/",
+		APIKey:     "secret",
+		Model:      "nomic-embed-text",
+		Dimensions: 2,
+		BatchSize:  2,
+	})
+	require.NoError(t, err)
+
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {1, 0}, {1, 0}}, vectors)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, []string{"a", "bb"}, requests[0].Input)
+	assert.Equal(t, []string{"ccc"}, requests[1].Input)
+	assert.Zero(t, requests[0].Dimensions)
+}
+
+func TestOpenAIEmbedderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail/embeddings" {
+			http.Error(w, "model not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{{"index": 0, "embedding": []float32{1, 0, 0}}},
+		})
+	}))
+	defer server.Close()
+
+	failing, err := NewOpenAIEmbedder(OpenAIConfig{BaseURL: server.URL + "/fail", Model: "m", Dimensions: 2})
+	require.NoError(t, err)
+	_, err = failing.Embed(context.Background(), []string{"x"})
+	assert.ErrorContains(t, err, "model not found")
+
+	wrongSize, err := NewOpenAIEmbedder(OpenAIConfig{BaseURL: server.URL, Model: "m", Dimensions: 2})
+	require.NoError(t, err)
+	_, err = wrongSize.Embed(context.Background(), []string{"x"})
+	assert.ErrorContains(t, err, "expected 2")
+
+	_, err = NewOpenAIEmbedder(OpenAIConfig{BaseURL: server.URL, Dimensions: 2})
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// sourcePattern matches the source headers of a RAG prompt, e.g. "[2] Title"
+var sourcePattern = regexp.MustCompile(`(?m)^\[(\d+)\][^\n]*\n([^\n]+)`)
+
+// fakeChatModel answers without a language model so that the question
+// answering pipeline can run offline and in tests. When Reply is empty it
+// quotes the first sentence of up to two sources of the prompt and cites
+// them; otherwise it returns Reply. Replies are streamed word by word.
+type fakeChatModel struct {
+	reply string
+}
+
+func NewFakeChatModel(reply string) ChatModel {
+	return &fakeChatModel{reply: reply}
+}
+
+func (m *fakeChatModel) Stream(ctx context.Context, messages []Message, onToken func(token string) error) (string, error) {
+	reply := m.reply
+	if reply == "" {
+		reply = m.quoteSources(messages)
+	}
+
+	// 按词切分，保留空白，模拟逐 token 输出
+	for _, token := range strings.SplitAfter(reply, " ") {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := onToken(token); err != nil {
+			return "", err
+		}
+	}
+	return reply, nil
+}
+
+func (m *fakeChatModel) quoteSources(messages []Message) string {
+	var prompt string
+	for _, msg := range messages {
+		if msg.Role == RoleUser {
+			prompt = msg.Content
+		}
+	}
+
+	var sentences []string
+	for _, match := range sourcePattern.FindAllStringSubmatch(prompt, 2) {
+		sentence := strings.TrimSpace(match[2])
+		if end := strings.IndexAny(sentence, ".!?。！？"); end >= 0 {
+			sentence = sentence[:end]
+		}
+		sentences = append(sentences, sentence+" ["+match[1]+"].")
+	}
+	if len(sentences) == 0 {
+		return "I don't know."
+	}
+	return strings.Join(sentences, " ")
+}
@@ -0,0 +1,143 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultEmbeddingBatchSize is the number of texts sent per embeddings request
+const DefaultEmbeddingBatchSize = 64
+
+// OpenAIConfig configures a client for an OpenAI-compatible API. BaseURL may
+// point at any server implementing the same endpoints, such as a local
+// Ollama instance ("http://localhost:11434/v1").
+type OpenAIConfig struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	Dimensions int // expected vector size; also requested from models that support shortening
+	BatchSize  int
+	Timeout    time.Duration
+}
+
+type openAIEmbedder struct {
+	cfg    OpenAIConfig
+	client *http.Client
+}
+
+func NewOpenAIEmbedder(cfg OpenAIConfig) (Embedder, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("embedding base url is required")
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("embedding model is required")
+	}
+	if cfg.Dimensions <= 0 {
+		return nil, fmt.Errorf("embedding dimensions must be positive")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultEmbeddingBatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+
+	return &openAIEmbedder{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (e *openAIEmbedder) Dimensions() int {
+	return e.cfg.Dimensions
+}
+
+type embeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += e.cfg.BatchSize {
+		end := min(start+e.cfg.BatchSize, len(texts))
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	req := embeddingRequest{Model: e.cfg.Model, Input: texts}
+	// 只有 OpenAI 官方的 text-embedding-3 系列支持指定维度
+	if strings.HasPrefix(e.cfg.Model, "text-embedding-3") {
+		req.Dimensions = e.cfg.Dimensions
+	}
+
+	var resp embeddingResponse
+	if err := postJSON(ctx, e.client, e.cfg.BaseURL+"/embeddings", e.cfg.APIKey, req, &resp); err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors for %d inputs", len(resp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has invalid index %d", item.Index)
+		}
+		if len(item.Embedding) != e.cfg.Dimensions {
+			return nil, fmt.Errorf("embedding has %d dimensions, expected %d", len(item.Embedding), e.cfg.Dimensions)
+		}
+		vectors[item.Index] = Normalize(item.Embedding)
+	}
+	return vectors, nil
+}
+
+// postJSON sends body to url and decodes the JSON response into out
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
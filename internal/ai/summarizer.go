@@ -0,0 +1,260 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+)
+
+// Summary sources
+const (
+	SummarySourceModel      = "model"
+	SummarySourceExtractive = "extractive"
+)
+
+const (
+	DefaultSummarySentences = 3
+	DefaultKeyPoints        = 5
+	// maxSummaryInput caps the characters of a document sent to a model
+	maxSummaryInput = 12000
+	maxKeyPointLen  = 200
+)
+
+// Summary is a short abstract of a document with its key points
+type Summary struct {
+	Text      string   `json:"summary"`
+	KeyPoints []string `json:"key_points"`
+	Source    string   `json:"-"`
+}
+
+// Summarizer condenses a document into a summary and key points
+type Summarizer interface {
+	Summarize(ctx context.Context, title, content string) (*Summary, error)
+}
+
+// extractiveSummarizer picks the most representative sentences of a
+// document, scoring each by the frequency of its terms in the whole text. It
+// needs no model, so summaries are available in any setup.
+type extractiveSummarizer struct {
+	sentences int
+	keyPoints int
+	analyzers *search.Analyzers
+}
+
+func NewExtractiveSummarizer(sentences, keyPoints int) Summarizer {
+	if sentences <= 0 {
+		sentences = DefaultSummarySentences
+	}
+	if keyPoints <= 0 {
+		keyPoints = DefaultKeyPoints
+	}
+	return &extractiveSummarizer{
+		sentences: sentences,
+		keyPoints: keyPoints,
+		analyzers: search.NewAnalyzers(nil),
+	}
+}
+
+type scoredSentence struct {
+	index int
+	text  string
+	score float64
+}
+
+func (s *extractiveSummarizer) Summarize(ctx context.Context, title, content string) (*Summary, error) {
+	sentences := splitSentences(plainText(content))
+	summary := &Summary{KeyPoints: []string{}, Source: SummarySourceExtractive}
+	if len(sentences) == 0 {
+		return summary, nil
+	}
+
+	analyzer := s.analyzers.For(search.DetectLanguage(content))
+	terms := make([][]string, len(sentences))
+	freq := make(map[string]int)
+	for i, sentence := range sentences {
+		for _, token := range analyzer.Analyze(sentence) {
+			if search.IsStopWord(token.Term) {
+				continue
+			}
+			terms[i] = append(terms[i], token.Term)
+			freq[token.Term]++
+		}
+	}
+	// 标题中的词权重加倍
+	titleTerms := make(map[string]bool)
+	for _, token := range analyzer.Analyze(title) {
+		titleTerms[token.Term] = true
+	}
+
+	scored := make([]scoredSentence, 0, len(sentences))
+	for i, sentence := range sentences {
+		if len(terms[i]) == 0 {
+			continue
+		}
+		var score float64
+		for _, term := range terms[i] {
+			weight := float64(freq[term])
+			if titleTerms[term] {
+				weight *= 2
+			}
+			score += weight
+		}
+		// 按长度归一，避免偏向长句；开头的句子通常概括全文
+		score /= math.Sqrt(float64(len(terms[i])))
+		if i == 0 {
+			score *= 1.5
+		}
+		scored = append(scored, scoredSentence{index: i, text: sentence, score: score})
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+
+	summary.Text = strings.Join(inDocumentOrder(scored[:min(s.sentences, len(scored))]), " ")
+	for _, sentence := range inDocumentOrder(scored[:min(s.keyPoints, len(scored))]) {
+		summary.KeyPoints = append(summary.KeyPoints, truncateRunes(sentence, maxKeyPointLen))
+	}
+	return summary, nil
+}
+
+func inDocumentOrder(sentences []scoredSentence) []string {
+	ordered := append([]scoredSentence(nil), sentences...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].index < ordered[j].index })
+	texts := make([]string, 0, len(ordered))
+	for _, s := range ordered {
+		texts = append(texts, s.text)
+	}
+	return texts
+}
+
+const summarySystemPrompt = `You summarize documents. Reply with a JSON object only, without code fences:
+{"summary": "<two to four sentences>", "key_points": ["<key point>", ...]}
+Give at most %d key points. Write in the language of the document.`
+
+// modelSummarizer asks a chat model for the summary and falls back to
+// another summarizer when the model fails or replies in an unexpected format
+type modelSummarizer struct {
+	model     ChatModel
+	fallback  Summarizer
+	keyPoints int
+}
+
+func NewModelSummarizer(model ChatModel, fallback Summarizer, keyPoints int) Summarizer {
+	if keyPoints <= 0 {
+		keyPoints = DefaultKeyPoints
+	}
+	return &modelSummarizer{model: model, fallback: fallback, keyPoints: keyPoints}
+}
+
+func (s *modelSummarizer) Summarize(ctx context.Context, title, content string) (*Summary, error) {
+	summary, err := s.summarize(ctx, title, content)
+	if err == nil || s.fallback == nil {
+		return summary, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return s.fallback.Summarize(ctx, title, content)
+}
+
+func (s *modelSummarizer) summarize(ctx context.Context, title, content string) (*Summary, error) {
+	messages := []Message{
+		{Role: RoleSystem, Content: fmt.Sprintf(summarySystemPrompt, s.keyPoints)},
+		{Role: RoleUser, Content: "# " + title + "\n\n" + truncateRunes(content, maxSummaryInput)},
+	}
+	reply, err := s.model.Stream(ctx, messages, func(string) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+
+	// 模型有时会包上代码块或附加说明，只取 JSON 对象部分
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("summary reply is not JSON")
+	}
+	var summary Summary
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse summary reply: %w", err)
+	}
+	summary.Text = strings.TrimSpace(summary.Text)
+	if summary.Text == "" {
+		return nil, fmt.Errorf("summary reply is empty")
+	}
+
+	points := make([]string, 0, len(summary.KeyPoints))
+	for _, point := range summary.KeyPoints {
+		if point = strings.TrimSpace(point); point != "" && len(points) < s.keyPoints {
+			points = append(points, point)
+		}
+	}
+	summary.KeyPoints = points
+	summary.Source = SummarySourceModel
+	return &summary, nil
+}
+
+var (
+	codeFencePattern = regexp.MustCompile("(?s)```.*?```")
+	linkPattern      = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	headingPattern   = regexp.MustCompile(`(?m)^#{1,6}\s.*$`)
+	listPattern      = regexp.MustCompile(`(?m)^\s*(?:[-*+>]|\d+[.)])\s+`)
+	emphasisReplacer = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// plainText strips the Markdown syntax that would otherwise end up in
+// extracted sentences. Code blocks and headings are dropped, and list items
+// become paragraphs of their own.
+func plainText(markdown string) string {
+	text := codeFencePattern.ReplaceAllString(markdown, "\n")
+	text = headingPattern.ReplaceAllString(text, "\n")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = listPattern.ReplaceAllString(text, "\n")
+	return emphasisReplacer.Replace(text)
+}
+
+// splitSentences splits text at sentence punctuation and line breaks
+func splitSentences(text string) []string {
+	var sentences []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.Join(strings.Fields(b.String()), " "); s != "" {
+			sentences = append(sentences, s)
+		}
+		b.Reset()
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '\n' {
+			// 单个换行通常只是折行，空行才是段落边界
+			if i+1 < len(runes) && runes[i+1] == '\n' {
+				flush()
+			} else {
+				b.WriteRune(' ')
+			}
+			continue
+		}
+		b.WriteRune(r)
+		switch r {
+		case '。', '！', '？':
+			flush()
+		case '.', '!', '?':
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				flush()
+			}
+		}
+	}
+	flush()
+	return sentences
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
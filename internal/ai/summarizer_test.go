@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const runbook = `# Database failover
+
+The primary database replicates to a standby replica in another zone.
+When the primary fails, promote the replica and point the application to the replica.
+
+## Steps
+
+1. Check the **replication lag** of the replica.
+2. Promote the replica with ` + "`pg_ctl promote`" + `.
+3. Update the [DNS record](https://dns.example.com) of the database.
+
+` + "```sh\npg_ctl promote -D /var/lib/postgres\n```" + `
+
+Lunch is served at noon.`
+
+func TestExtractiveSummarizer(t *testing.T) {
+	summary, err := NewExtractiveSummarizer(2, 3).Summarize(context.Background(), "Database failover", runbook)
+	require.NoError(t, err)
+
+	assert.Equal(t, SummarySourceExtractive, summary.Source)
+	assert.Contains(t, summary.Text, "promote the replica")
+	assert.NotContains(t, summary.Text, "Lunch")
+	assert.Len(t, summary.KeyPoints, 3)
+	for _, point := range summary.KeyPoints {
+		assert.NotContains(t, point, "**")
+		assert.NotContains(t, point, "](")
+		assert.NotContains(t, point, "/var/lib")
+	}
+
+	empty, err := NewExtractiveSummarizer(0, 0).Summarize(context.Background(), "Empty", "  ")
+	require.NoError(t, err)
+	assert.Empty(t, empty.Text)
+	assert.Empty(t, empty.KeyPoints)
+}
+
+func TestSplitSentences(t *testing.T) {
+	assert.Equal(t,
+		[]string{"First line continues here.", "Version 1.2 is out!", "新版本已发布。", "请升级", "Heading"},
+		splitSentences("First line\ncontinues here. Version 1.2 is out! 新版本已发布。请升级\n\nHeading"))
+}
+
+type failingChatModel struct{}
+
+func (failingChatModel) Stream(ctx context.Context, messages []Message, onToken func(string) error) (string, error) {
+	return "", errors.New("model unavailable")
+}
+
+func TestModelSummarizer(t *testing.T) {
+	ctx := context.Background()
+	fallback := NewExtractiveSummarizer(2, 3)
+
+	reply := "Here you go:\n```json\n{\"summary\": \"How to fail over the database.\", \"key_points\": [\"Check lag\", \" \", \"Promote\", \"Update DNS\"]}\n```"
+	summary, err := NewModelSummarizer(NewFakeChatModel(reply), fallback, 2).Summarize(ctx, "Database failover", runbook)
+	require.NoError(t, err)
+	assert.Equal(t, SummarySourceModel, summary.Source)
+	assert.Equal(t, "How to fail over the database.", summary.Text)
+	assert.Equal(t, []string{"Check lag", "Promote"}, summary.KeyPoints)
+
+	summary, err = NewModelSummarizer(NewFakeChatModel("I cannot help with that."), fallback, 2).Summarize(ctx, "Database failover", runbook)
+	require.NoError(t, err)
+	assert.Equal(t, SummarySourceExtractive, summary.Source)
+
+	summary, err = NewModelSummarizer(failingChatModel{}, fallback, 2).Summarize(ctx, "Database failover", runbook)
+	require.NoError(t, err)
+	assert.Equal(t, SummarySourceExtractive, summary.Source)
+
+	_, err = NewModelSummarizer(failingChatModel{}, nil, 2).Summarize(ctx, "Database failover", runbook)
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+// Package anchor keeps text ranges attached to the text they were selected
+// on. A range is described by its offsets and by a fingerprint of the
+// selected text and a little context around it, which is used to find the
+// range again after the document has been edited.
+package anchor
+
+import (
+	"errors"
+	"slices"
+)
+
+const (
+	// contextLength is the number of characters kept before and after a
+	// range to tell repeated occurrences of its text apart
+	contextLength = 32
+	// maxFuzzyQuote is the longest text matched approximately as a whole;
+	// longer texts are located by their beginning and end
+	maxFuzzyQuote = 256
+	// edgeLength is the length of the beginning and end of long texts
+	edgeLength = 64
+	// maxFuzzyCells bounds the work of one approximate search
+	maxFuzzyCells = 1 << 24
+)
+
+var ErrInvalidRange = errors.New("invalid text range")
+
+// Selector describes the range [Start, End) of a text. Offsets count
+// characters (Unicode code points), not bytes. Quote is the selected text,
+// Prefix and Suffix the text right before and after it.
+type Selector struct {
+	Start  int
+	End    int
+	Quote  string
+	Prefix string
+	Suffix string
+}
+
+// Select returns the selector of the range [start, end) of text
+func Select(text string, start, end int) (Selector, error) {
+	return selectRunes([]rune(text), start, end)
+}
+
+func selectRunes(text []rune, start, end int) (Selector, error) {
+	if start < 0 || end <= start || end > len(text) {
+		return Selector{}, ErrInvalidRange
+	}
+	return Selector{
+		Start:  start,
+		End:    end,
+		Quote:  string(text[start:end]),
+		Prefix: string(text[max(0, start-contextLength):start]),
+		Suffix: string(text[end:min(len(text), end+contextLength)]),
+	}, nil
+}
+
+// Locate finds the range of sel in text, which may have changed since sel
+// was selected. The quoted text at its old offsets is kept; otherwise the
+// occurrence of the quote whose surroundings best match the context of sel
+// wins, where the occurrence may differ from the quote in up to a quarter of
+// its characters. ok is false when the text is gone.
+func Locate(text string, sel Selector) (located Selector, ok bool) {
+	runes := []rune(text)
+	quote := []rune(sel.Quote)
+	if len(quote) == 0 {
+		return Selector{}, false
+	}
+
+	start, end, ok := locate(runes, quote, sel)
+	if !ok {
+		return Selector{}, false
+	}
+	located, err := selectRunes(runes, start, end)
+	return located, err == nil
+}
+
+func locate(text, quote []rune, sel Selector) (start, end int, ok bool) {
+	if sel.Start >= 0 && sel.Start+len(quote) <= len(text) && slices.Equal(text[sel.Start:sel.Start+len(quote)], quote) {
+		return sel.Start, sel.Start + len(quote), true
+	}
+
+	if len(quote) <= maxFuzzyQuote && len(text)*len(quote) <= maxFuzzyCells {
+		return fuzzyMatch(text, quote, sel)
+	}
+	if start, ok := bestOccurrence(text, quote, sel); ok {
+		return start, start + len(quote), true
+	}
+	if len(quote) <= maxFuzzyQuote {
+		return 0, 0, false
+	}
+
+	// 长文本按首尾两段分别定位，中间的修改不影响锚点
+	head := Selector{Start: sel.Start, Quote: string(quote[:edgeLength]), Prefix: sel.Prefix}
+	tail := Selector{Start: sel.End - edgeLength, Quote: string(quote[len(quote)-edgeLength:]), Suffix: sel.Suffix}
+	headStart, _, ok := locate(text, quote[:edgeLength], head)
+	if !ok {
+		return 0, 0, false
+	}
+	_, tailEnd, ok := locate(text, quote[len(quote)-edgeLength:], tail)
+	if !ok || tailEnd <= headStart {
+		return 0, 0, false
+	}
+	if length := tailEnd - headStart; length < len(quote)*3/4 || length > len(quote)*5/4 {
+		return 0, 0, false
+	}
+	return headStart, tailEnd, true
+}
+
+// bestOccurrence returns the exact occurrence of quote with the best
+// context score
+func bestOccurrence(text, quote []rune, sel Selector) (int, bool) {
+	var best candidate
+	prefix, suffix := []rune(sel.Prefix), []rune(sel.Suffix)
+	for i := 0; i+len(quote) <= len(text); i++ {
+		if slices.Equal(text[i:i+len(quote)], quote) {
+			best = best.better(newCandidate(text, prefix, suffix, i, i+len(quote), 0, sel.Start))
+		}
+	}
+	return best.start, best.found
+}
+
+// fuzzyMatch finds the substrings of text within a quarter of the quote's
+// length in edits of quote (Sellers' algorithm) and returns the one with the
+// best context score
+func fuzzyMatch(text, quote []rune, sel Selector) (start, end int, ok bool) {
+	m := len(quote)
+	maxEdits := m / 4
+
+	// dist[i] is the edit distance between quote[:i] and the best substring
+	// of text ending at the current position, starting at from[i]
+	dist := make([]int, m+1)
+	from := make([]int, m+1)
+	prevDist := make([]int, m+1)
+	prevFrom := make([]int, m+1)
+	for i := range prevDist {
+		prevDist[i] = i
+	}
+
+	var best candidate
+	prefix, suffix := []rune(sel.Prefix), []rune(sel.Suffix)
+	for j := 1; j <= len(text); j++ {
+		dist[0], from[0] = 0, j
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if quote[i-1] == text[j-1] {
+				cost = 0
+			}
+			dist[i], from[i] = prevDist[i-1]+cost, prevFrom[i-1]
+			if prevDist[i]+1 < dist[i] {
+				dist[i], from[i] = prevDist[i]+1, prevFrom[i]
+			}
+			if dist[i-1]+1 < dist[i] {
+				dist[i], from[i] = dist[i-1]+1, from[i-1]
+			}
+		}
+		if dist[m] <= maxEdits && from[m] < j {
+			best = best.better(newCandidate(text, prefix, suffix, from[m], j, dist[m], sel.Start))
+		}
+		dist, prevDist = prevDist, dist
+		from, prevFrom = prevFrom, from
+	}
+	return best.start, best.end, best.found
+}
+
+// candidate is a possible new position of a range. Its score counts the
+// characters of context that still surround it, less the edits needed to
+// turn it into the quote.
+type candidate struct {
+	start, end int
+	score      int
+	distance   int // from the old position
+	found      bool
+}
+
+func newCandidate(text, prefix, suffix []rune, start, end, edits, old int) candidate {
+	return candidate{
+		start:    start,
+		end:      end,
+		score:    commonSuffix(text[:start], prefix) + commonPrefix(text[end:], suffix) - edits,
+		distance: abs(start - old),
+		found:    true,
+	}
+}
+
+// better returns the better of c and o: the one with the higher score, or
+// the one closer to the old position
+func (c candidate) better(o candidate) candidate {
+	if !c.found || o.score > c.score || (o.score == c.score && o.distance < c.distance) {
+		return o
+	}
+	return c
+}
+
+// commonSuffix returns the length of the common end of a and b
+func commonSuffix(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// commonPrefix returns the length of the common start of a and b
+func commonPrefix(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package anchor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	sel, err := Select("先检查复制延迟, then promote the replica.", 0, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "先检查复制延迟", sel.Quote)
+	assert.Empty(t, sel.Prefix)
+	assert.Equal(t, ", then promote the replica.", sel.Suffix)
+
+	for _, r := range [][2]int{{-1, 2}, {3, 3}, {5, 2}, {0, 100}} {
+		_, err := Select("short text", r[0], r[1])
+		assert.ErrorIs(t, err, ErrInvalidRange, "%v", r)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	original := "## Failover\n\nCheck replication lag. Promote the replica. Update DNS.\n\n## Rollback\n\nPromote the replica. Restore the primary.\n"
+	start := strings.Index(original, "Promote the replica. Restore")
+	sel, err := Select(original, start, start+len("Promote the replica."))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		text    string
+		quote   string // expected text at the located range
+		orphan  bool
+		checkAt string // the located range starts right before this text
+	}{
+		{
+			name:    "Unchanged",
+			text:    original,
+			quote:   "Promote the replica.",
+			checkAt: "Promote the replica. Restore",
+		},
+		{
+			name:    "Text inserted before",
+			text:    "# Runbook\n\n" + original,
+			quote:   "Promote the replica.",
+			checkAt: "Promote the replica. Restore",
+		},
+		{
+			name:    "Repeated text picks the matching context",
+			text:    strings.Replace(original, "## Failover", "## Failover steps for the primary database", 1),
+			quote:   "Promote the replica.",
+			checkAt: "Promote the replica. Restore",
+		},
+		{
+			name:    "Quoted text edited",
+			text:    strings.Replace(original, "Promote the replica. Restore", "Promote the new replica. Restore", 1),
+			quote:   "Promote the new replica.",
+			checkAt: "Promote the new replica. Restore",
+		},
+		{
+			name:   "Quoted text removed",
+			text:   "## Failover\n\nUpdate DNS.\n\n## Rollback\n\nRestore the primary.\n",
+			orphan: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			located, ok := Locate(tt.text, sel)
+			if tt.orphan {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.quote, located.Quote)
+			runes := []rune(tt.text)
+			assert.Equal(t, tt.quote, string(runes[located.Start:located.End]))
+			assert.True(t, strings.HasPrefix(string(runes[located.Start:]), tt.checkAt))
+		})
+	}
+}
+
+func TestLocateLongQuote(t *testing.T) {
+	paragraph := strings.Repeat("Escalate to the database team when replication lag exceeds one minute. ", 6)
+	text := "Intro.\n\n" + paragraph + "\n\nOutro."
+	start := len([]rune("Intro.\n\n"))
+	sel, err := Select(text, start, start+len([]rune(paragraph)))
+	require.NoError(t, err)
+
+	edited := strings.Replace(text, "exceeds one minute. Escalate", "exceeds two minutes. Escalate", 2)
+	located, ok := Locate("Changelog added.\n\n"+edited, sel)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(located.Quote, "Escalate to the database team"))
+	assert.True(t, strings.HasSuffix(located.Quote, "exceeds one minute. "))
+	assert.Contains(t, located.Quote, "two minutes")
+
+	_, ok = Locate("Intro.\n\nOutro.", sel)
+	assert.False(t, ok)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AskController struct {
+	askService service.AskService
+}
+
+func NewAskController(askService service.AskService) *AskController {
+	return &AskController{
+		askService: askService,
+	}
+}
+
+type askRequest struct {
+	Question string `json:"question" binding:"required"`
+	// Stream sends the answer as server-sent events; also enabled by
+	// "Accept: text/event-stream"
+	Stream bool `json:"stream"`
+}
+
+// Ask answers a question from the documents the user can read, citing the
+// documents used. Streaming responses emit "token" events with pieces of
+// the answer followed by a "done" event with the answer and its citations,
+// or an "error" event.
+func (ac *AskController) Ask(c *gin.Context) {
+	var req askRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+
+	if !wantsStream(c, req.Stream) {
+		answer, err := ac.askService.Ask(c.Request.Context(), userID, req.Question, func(string) error { return nil })
+		if err != nil {
+			respondAskError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, answer)
+		return
+	}
+
+	streamGeneration(c, func(onToken func(string) error) (any, error) {
+		return ac.askService.Ask(c.Request.Context(), userID, req.Question, onToken)
+	}, respondAskError)
+}
+
+func respondAskError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyQuestion), errors.Is(err, service.ErrQuestionTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate answer"})
+	}
+}
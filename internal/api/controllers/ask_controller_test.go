@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAskService 模拟问答服务，按 tokens 依次回调
+type MockAskService struct {
+	mock.Mock
+}
+
+func (m *MockAskService) Ask(ctx context.Context, userID uint, question string, onToken func(token string) error) (*models.Answer, error) {
+	args := m.Called(ctx, userID, question)
+	for _, token := range args.Get(0).([]string) {
+		if err := onToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if args.Get(1) == nil {
+		return nil, args.Error(2)
+	}
+	return args.Get(1).(*models.Answer), args.Error(2)
+}
+
+func setupAskTest() (*gin.Engine, *MockAskService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockAskService)
+	controller := NewAskController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/ask", controller.Ask)
+
+	return r, mockService
+}
+
+func TestAsk(t *testing.T) {
+	r, mockService := setupAskTest()
+
+	answer := &models.Answer{
+		Question:  "How do I fail over?",
+		Answer:    "Promote the replica [1].",
+		Citations: []models.Citation{{Index: 1, DocumentID: 4, Version: 3, Title: "Oncall runbook"}},
+	}
+
+	tests := []struct {
+		name         string
+		body         map[string]interface{}
+		accept       string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name: "JSON answer",
+			body: map[string]interface{}{"question": "How do I fail over?"},
+			setupMock: func() {
+				mockService.On("Ask", mock.Anything, uint(1), "How do I fail over?").
+					Return([]string{"Promote ", "the replica [1]."}, answer, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"version":3`, `"document_id":4`},
+		},
+		{
+			name: "Streamed answer",
+			body: map[string]interface{}{"question": "How do I fail over?", "stream": true},
+			setupMock: func() {
+				mockService.On("Ask", mock.Anything, uint(1), "How do I fail over?").
+					Return([]string{"Promote ", "the replica [1]."}, answer, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{
+				"event:token\ndata:{\"text\":\"Promote \"}\n\n",
+				"event:token\ndata:{\"text\":\"the replica [1].\"}\n\n",
+				"event:done\ndata:{\"question\":\"How do I fail over?\"",
+			},
+		},
+		{
+			name:   "Stream requested by Accept header",
+			body:   map[string]interface{}{"question": "How do I fail over?"},
+			accept: "text/event-stream",
+			setupMock: func() {
+				mockService.On("Ask", mock.Anything, uint(1), "How do I fail over?").
+					Return([]string{"Promote"}, answer, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{"event:token", "event:done"},
+		},
+		{
+			name: "Error before streaming",
+			body: map[string]interface{}{"question": "x", "stream": true},
+			setupMock: func() {
+				mockService.On("Ask", mock.Anything, uint(1), "x").
+					Return([]string{}, nil, service.ErrQuestionTooLong).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: []string{service.ErrQuestionTooLong.Error()},
+		},
+		{
+			name: "Error while streaming",
+			body: map[string]interface{}{"question": "How do I fail over?", "stream": true},
+			setupMock: func() {
+				mockService.On("Ask", mock.Anything, uint(1), "How do I fail over?").
+					Return([]string{"Promote"}, nil, fmt.Errorf("model unavailable")).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{"event:token", "event:error"},
+		},
+		{
+			name:         "Missing question",
+			body:         map[string]interface{}{},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			body, _ := json.Marshal(tt.body)
+			req, _ := http.NewRequest(http.MethodPost, "/ask", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ChatController struct {
+	chatService service.ChatService
+}
+
+func NewChatController(chatService service.ChatService) *ChatController {
+	return &ChatController{
+		chatService: chatService,
+	}
+}
+
+type createChatSessionRequest struct {
+	ScopeType string `json:"scope_type" binding:"required"`
+	ScopeID   uint   `json:"scope_id" binding:"required"`
+	Title     string `json:"title"`
+}
+
+type chatMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+	// Stream sends the answer as server-sent events; also enabled by
+	// "Accept: text/event-stream"
+	Stream bool `json:"stream"`
+}
+
+type chatFeedbackRequest struct {
+	Feedback string `json:"feedback" binding:"required"` // up, down, none
+}
+
+var feedbackValues = map[string]int{
+	"up":   models.FeedbackUp,
+	"down": models.FeedbackDown,
+	"none": models.FeedbackNone,
+}
+
+// CreateSession starts a conversation scoped to a document, a folder or a tag
+func (cc *ChatController) CreateSession(c *gin.Context) {
+	var req createChatSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	session, err := cc.chatService.CreateSession(c.Request.Context(), c.GetUint("userID"), req.ScopeType, req.ScopeID, req.Title)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// ListSessions lists the user's conversations, most recently used first
+func (cc *ChatController) ListSessions(c *gin.Context) {
+	page, pageSize := 1, 20
+
+	if p := c.Query("page"); p != "" {
+		if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		if size, err := strconv.Atoi(ps); err == nil && size > 0 {
+			pageSize = min(size, 100)
+		}
+	}
+
+	sessions, total, err := cc.chatService.ListSessions(c.Request.Context(), c.GetUint("userID"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":  sessions,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetSession returns a conversation with its messages
+func (cc *ChatController) GetSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	session, err := cc.chatService.GetSession(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (cc *ChatController) DeleteSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := cc.chatService.DeleteSession(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chat session deleted successfully"})
+}
+
+// SendMessage answers a message within a conversation. Streaming responses
+// use the same events as Ask, with the stored answer message in "done".
+func (cc *ChatController) SendMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var req chatMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+
+	if !wantsStream(c, req.Stream) {
+		msg, err := cc.chatService.SendMessage(c.Request.Context(), userID, uint(id), req.Content, func(string) error { return nil })
+		if err != nil {
+			respondChatError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, msg)
+		return
+	}
+
+	streamGeneration(c, func(onToken func(string) error) (any, error) {
+		return cc.chatService.SendMessage(c.Request.Context(), userID, uint(id), req.Content, onToken)
+	}, respondChatError)
+}
+
+// SetFeedback rates an answer with "up", "down" or "none"
+func (cc *ChatController) SetFeedback(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req chatFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	feedback, ok := feedbackValues[req.Feedback]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Feedback must be up, down or none"})
+		return
+	}
+
+	msg, err := cc.chatService.SetFeedback(c.Request.Context(), c.GetUint("userID"), uint(id), feedback)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, msg)
+}
+
+func respondChatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrChatSessionNotFound), errors.Is(err, service.ErrChatMessageNotFound),
+		errors.Is(err, service.ErrChatScopeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidChatScope), errors.Is(err, service.ErrEmptyMessage),
+		errors.Is(err, service.ErrMessageTooLong), errors.Is(err, service.ErrInvalidFeedback):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process chat request"})
+	}
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockChatService 模拟对话服务，SendMessage 按 tokens 依次回调
+type MockChatService struct {
+	mock.Mock
+}
+
+func (m *MockChatService) HandleDocumentEvent(ctx context.Context, event service.DocumentEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockChatService) CreateSession(ctx context.Context, userID uint, scopeType string, scopeID uint, title string) (*models.ChatSession, error) {
+	args := m.Called(ctx, userID, scopeType, scopeID, title)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatSession), args.Error(1)
+}
+
+func (m *MockChatService) ListSessions(ctx context.Context, userID uint, page, pageSize int) ([]models.ChatSession, int64, error) {
+	args := m.Called(ctx, userID, page, pageSize)
+	return args.Get(0).([]models.ChatSession), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockChatService) GetSession(ctx context.Context, userID, sessionID uint) (*models.ChatSession, error) {
+	args := m.Called(ctx, userID, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatSession), args.Error(1)
+}
+
+func (m *MockChatService) DeleteSession(ctx context.Context, userID, sessionID uint) error {
+	return m.Called(ctx, userID, sessionID).Error(0)
+}
+
+func (m *MockChatService) SendMessage(ctx context.Context, userID, sessionID uint, content string, onToken func(token string) error) (*models.ChatMessage, error) {
+	args := m.Called(ctx, userID, sessionID, content)
+	for _, token := range args.Get(0).([]string) {
+		if err := onToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if args.Get(1) == nil {
+		return nil, args.Error(2)
+	}
+	return args.Get(1).(*models.ChatMessage), args.Error(2)
+}
+
+func (m *MockChatService) SetFeedback(ctx context.Context, userID, messageID uint, feedback int) (*models.ChatMessage, error) {
+	args := m.Called(ctx, userID, messageID, feedback)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatMessage), args.Error(1)
+}
+
+func setupChatTest() (*gin.Engine, *MockChatService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockChatService)
+	controller := NewChatController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/chat/sessions", controller.CreateSession)
+	r.GET("/chat/sessions", controller.ListSessions)
+	r.GET("/chat/sessions/:id", controller.GetSession)
+	r.DELETE("/chat/sessions/:id", controller.DeleteSession)
+	r.POST("/chat/sessions/:id/messages", controller.SendMessage)
+	r.PUT("/chat/messages/:id/feedback", controller.SetFeedback)
+
+	return r, mockService
+}
+
+func TestChatController(t *testing.T) {
+	r, mockService := setupChatTest()
+
+	session := &models.ChatSession{ID: 3, UserID: 1, ScopeType: models.ChatScopeFolder, ScopeID: 7}
+	reply := &models.ChatMessage{
+		ID:        12,
+		SessionID: 3,
+		Role:      "assistant",
+		Content:   "Promote the replica [1].",
+		Citations: []models.Citation{{Index: 1, DocumentID: 8, Version: 2, Title: "Oncall runbook"}},
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         map[string]interface{}
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Create session",
+			method: http.MethodPost,
+			url:    "/chat/sessions",
+			body:   map[string]interface{}{"scope_type": "folder", "scope_id": 7},
+			setupMock: func() {
+				mockService.On("CreateSession", mock.Anything, uint(1), "folder", uint(7), "").
+					Return(session, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"scope_type":"folder"`, `"scope_id":7`},
+		},
+		{
+			name:   "Create session with unknown scope",
+			method: http.MethodPost,
+			url:    "/chat/sessions",
+			body:   map[string]interface{}{"scope_type": "space", "scope_id": 7},
+			setupMock: func() {
+				mockService.On("CreateSession", mock.Anything, uint(1), "space", uint(7), "").
+					Return(nil, service.ErrInvalidChatScope).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Create session on unreadable document",
+			method: http.MethodPost,
+			url:    "/chat/sessions",
+			body:   map[string]interface{}{"scope_type": "document", "scope_id": 9},
+			setupMock: func() {
+				mockService.On("CreateSession", mock.Anything, uint(1), "document", uint(9), "").
+					Return(nil, service.ErrChatScopeNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "List sessions",
+			method: http.MethodGet,
+			url:    "/chat/sessions?page=2&page_size=5",
+			setupMock: func() {
+				mockService.On("ListSessions", mock.Anything, uint(1), 2, 5).
+					Return([]models.ChatSession{*session}, int64(6), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"total":6`, `"page":2`},
+		},
+		{
+			name:   "Get session of another user",
+			method: http.MethodGet,
+			url:    "/chat/sessions/4",
+			setupMock: func() {
+				mockService.On("GetSession", mock.Anything, uint(1), uint(4)).
+					Return(nil, service.ErrChatSessionNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "Delete session",
+			method: http.MethodDelete,
+			url:    "/chat/sessions/3",
+			setupMock: func() {
+				mockService.On("DeleteSession", mock.Anything, uint(1), uint(3)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Send message",
+			method: http.MethodPost,
+			url:    "/chat/sessions/3/messages",
+			body:   map[string]interface{}{"content": "How do I fail over?"},
+			setupMock: func() {
+				mockService.On("SendMessage", mock.Anything, uint(1), uint(3), "How do I fail over?").
+					Return([]string{"Promote ", "the replica [1]."}, reply, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"role":"assistant"`, `"document_id":8`},
+		},
+		{
+			name:   "Stream message",
+			method: http.MethodPost,
+			url:    "/chat/sessions/3/messages",
+			body:   map[string]interface{}{"content": "How do I fail over?", "stream": true},
+			setupMock: func() {
+				mockService.On("SendMessage", mock.Anything, uint(1), uint(3), "How do I fail over?").
+					Return([]string{"Promote"}, reply, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{"event:token\ndata:{\"text\":\"Promote\"}\n\n", "event:done\ndata:{\"id\":12"},
+		},
+		{
+			name:         "Send empty message",
+			method:       http.MethodPost,
+			url:          "/chat/sessions/3/messages",
+			body:         map[string]interface{}{},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Thumbs down",
+			method: http.MethodPut,
+			url:    "/chat/messages/12/feedback",
+			body:   map[string]interface{}{"feedback": "down"},
+			setupMock: func() {
+				rated := *reply
+				rated.Feedback = models.FeedbackDown
+				mockService.On("SetFeedback", mock.Anything, uint(1), uint(12), models.FeedbackDown).
+					Return(&rated, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"feedback":-1`},
+		},
+		{
+			name:         "Invalid feedback",
+			method:       http.MethodPut,
+			url:          "/chat/messages/12/feedback",
+			body:         map[string]interface{}{"feedback": "meh"},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Feedback on a question",
+			method: http.MethodPut,
+			url:    "/chat/messages/11/feedback",
+			body:   map[string]interface{}{"feedback": "up"},
+			setupMock: func() {
+				mockService.On("SetFeedback", mock.Anything, uint(1), uint(11), models.FeedbackUp).
+					Return(nil, service.ErrInvalidFeedback).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			var body []byte
+			if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
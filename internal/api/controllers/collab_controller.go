@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ot"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	collabWriteWait    = 10 * time.Second
+	collabPongWait     = 60 * time.Second
+	collabPingInterval = collabPongWait * 9 / 10
+	// collabMaxMessage bounds the size of one message from a client
+	collabMaxMessage = 1 << 20
+)
+
+type CollabController struct {
+	collabService service.CollabService
+	upgrader      websocket.Upgrader
+}
+
+func NewCollabController(collabService service.CollabService) *CollabController {
+	return &CollabController{
+		collabService: collabService,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// 认证使用显式传递的令牌而非 cookie，与 CORS 配置一致允许任意来源
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// collabRequest is a message from a client: an "operation" made on
+// revision, or a "cursor" move
+type collabRequest struct {
+	Type      string                `json:"type"`
+	Revision  int                   `json:"revision"`
+	Operation ot.Operation          `json:"operation"`
+	Cursor    *service.CollabCursor `json:"cursor"`
+}
+
+// collabReply answers a request of the client that failed
+type collabReply struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// EditDocument connects a WebSocket to the real-time editing session of a
+// document. The client receives an "init" message with the content and its
+// revision, then the operations of the other clients and presence updates;
+// it sends its operations and cursor moves, each operation acknowledged
+// with an "ack" message.
+func (cc *CollabController) EditDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "WebSocket upgrade required"})
+		return
+	}
+
+	client, err := cc.collabService.Join(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		respondCollabError(c, err)
+		return
+	}
+	defer client.Leave()
+
+	conn, err := cc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already answered the request
+		return
+	}
+	defer conn.Close()
+
+	replies := make(chan collabReply, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readCollabRequests(conn, client, replies)
+	}()
+
+	ping := time.NewTicker(collabPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case msg, ok := <-client.Messages():
+			if !ok {
+				// 客户端被移出会话（文档被删除或接收过慢）
+				conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session closed"))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			err = conn.WriteJSON(msg)
+		case reply := <-replies:
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			err = conn.WriteJSON(reply)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(collabWriteWait))
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readCollabRequests passes the requests read from conn to client until the
+// connection fails, queueing a reply for each request that fails
+func readCollabRequests(conn *websocket.Conn, client *service.CollabClient, replies chan<- collabReply) {
+	conn.SetReadLimit(collabMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				utils.Logger.Debug("Collaboration connection closed", zap.Uint64("client_id", client.ID), zap.Error(err))
+			}
+			return
+		}
+
+		var req collabRequest
+		if err = json.Unmarshal(data, &req); err != nil {
+			err = errors.New("invalid message")
+		} else {
+			err = handleCollabRequest(client, req)
+		}
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, service.ErrCollabClosed) {
+			return
+		}
+		select {
+		case replies <- collabReply{Type: "error", Error: err.Error()}:
+		default:
+		}
+	}
+}
+
+func handleCollabRequest(client *service.CollabClient, req collabRequest) error {
+	switch req.Type {
+	case "operation":
+		return client.Submit(req.Revision, req.Operation)
+	case "cursor":
+		if req.Cursor == nil {
+			return errors.New("cursor is required")
+		}
+		return client.MoveCursor(*req.Cursor)
+	default:
+		return errors.New("unknown message type")
+	}
+}
+
+func respondCollabError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join editing session"})
+	}
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCollabService 模拟协同编辑服务
+type MockCollabService struct {
+	mock.Mock
+}
+
+func (m *MockCollabService) Join(ctx context.Context, userID, documentID uint) (*service.CollabClient, error) {
+	args := m.Called(ctx, userID, documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CollabClient), args.Error(1)
+}
+
+func (m *MockCollabService) HandleDocumentEvent(ctx context.Context, event service.DocumentEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func setupCollabTest() (*gin.Engine, *MockCollabService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockCollabService)
+	controller := NewCollabController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/documents/:id/collab", controller.EditDocument)
+
+	return r, mockService
+}
+
+func TestCollabController(t *testing.T) {
+	r, mockService := setupCollabTest()
+
+	tests := []struct {
+		name         string
+		url          string
+		upgrade      bool
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:         "Plain HTTP request",
+			url:          "/documents/7/collab",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: []string{"WebSocket upgrade required"},
+		},
+		{
+			name:         "Invalid document ID",
+			url:          "/documents/abc/collab",
+			upgrade:      true,
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Unreadable document",
+			url:     "/documents/8/collab",
+			upgrade: true,
+			setupMock: func() {
+				mockService.On("Join", mock.Anything, uint(1), uint(8)).Return(nil, service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.upgrade {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CommentController struct {
+	commentService service.CommentService
+}
+
+func NewCommentController(commentService service.CommentService) *CommentController {
+	return &CommentController{
+		commentService: commentService,
+	}
+}
+
+type addCommentRequest struct {
+	Content  string                `json:"content" binding:"required"`
+	ParentID *uint                 `json:"parent_id"` // replies to a thread
+	Version  int                   `json:"version"`   // version commented on, the current one when omitted
+	Anchor   *models.CommentAnchor `json:"anchor"`    // range of the version's content a thread is about
+}
+
+type editCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// AddComment starts a thread on a document or replies to one
+func (cc *CommentController) AddComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req addCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	comment, err := cc.commentService.AddComment(c.Request.Context(), service.AddCommentRequest{
+		UserID:     c.GetUint("userID"),
+		DocumentID: uint(id),
+		ParentID:   req.ParentID,
+		Version:    req.Version,
+		Content:    req.Content,
+		Anchor:     req.Anchor,
+	})
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// ListComments lists the threads of a document with their replies.
+// "unresolved=true" leaves out resolved threads.
+func (cc *CommentController) ListComments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	page, pageSize := 1, 20
+
+	if p := c.Query("page"); p != "" {
+		if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		if size, err := strconv.Atoi(ps); err == nil && size > 0 {
+			pageSize = min(size, 100)
+		}
+	}
+
+	unresolved, _ := strconv.ParseBool(c.Query("unresolved"))
+
+	threads, total, err := cc.commentService.ListComments(c.Request.Context(), c.GetUint("userID"), uint(id), unresolved, page, pageSize)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments":  threads,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (cc *CommentController) EditComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var req editCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	comment, err := cc.commentService.EditComment(c.Request.Context(), c.GetUint("userID"), uint(id), req.Content)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	if err := cc.commentService.DeleteComment(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// ResolveThread resolves the thread of a comment
+func (cc *CommentController) ResolveThread(c *gin.Context) {
+	cc.setResolved(c, true)
+}
+
+// ReopenThread marks the thread of a comment as unresolved again
+func (cc *CommentController) ReopenThread(c *gin.Context) {
+	cc.setResolved(c, false)
+}
+
+func (cc *CommentController) setResolved(c *gin.Context, resolved bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	thread, err := cc.commentService.ResolveThread(c.Request.Context(), c.GetUint("userID"), uint(id), resolved)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func respondCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound), errors.Is(err, service.ErrDocumentNotFound),
+		errors.Is(err, service.ErrCommentVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotCommentAuthor), errors.Is(err, service.ErrCommentNotDeletable),
+		errors.Is(err, service.ErrThreadNotResolvable):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmptyComment), errors.Is(err, service.ErrCommentTooLong),
+		errors.Is(err, service.ErrInvalidAnchor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process comment request"})
+	}
+}
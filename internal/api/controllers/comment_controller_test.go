@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCommentService 模拟评论服务
+type MockCommentService struct {
+	mock.Mock
+}
+
+func (m *MockCommentService) AddComment(ctx context.Context, req service.AddCommentRequest) (*models.Comment, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) ListComments(ctx context.Context, userID, documentID uint, unresolvedOnly bool, page, pageSize int) ([]models.Comment, int64, error) {
+	args := m.Called(ctx, userID, documentID, unresolvedOnly, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]models.Comment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCommentService) EditComment(ctx context.Context, userID, commentID uint, content string) (*models.Comment, error) {
+	args := m.Called(ctx, userID, commentID, content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) DeleteComment(ctx context.Context, userID, commentID uint) error {
+	return m.Called(ctx, userID, commentID).Error(0)
+}
+
+func (m *MockCommentService) ResolveThread(ctx context.Context, userID, commentID uint, resolved bool) (*models.Comment, error) {
+	args := m.Called(ctx, userID, commentID, resolved)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) HandleDocumentEvent(ctx context.Context, event service.DocumentEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockCommentService) Close() {
+	m.Called()
+}
+
+func setupCommentTest() (*gin.Engine, *MockCommentService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockCommentService)
+	controller := NewCommentController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/documents/:id/comments", controller.AddComment)
+	r.GET("/documents/:id/comments", controller.ListComments)
+	r.PUT("/comments/:id", controller.EditComment)
+	r.DELETE("/comments/:id", controller.DeleteComment)
+	r.POST("/comments/:id/resolve", controller.ResolveThread)
+	r.POST("/comments/:id/reopen", controller.ReopenThread)
+
+	return r, mockService
+}
+
+func TestCommentController(t *testing.T) {
+	r, mockService := setupCommentTest()
+
+	threadID := uint(4)
+	thread := &models.Comment{ID: 4, DocumentID: 7, Version: 3, AuthorID: 1, Content: "@bob check lag first?", Mentions: []uint{2}}
+	reply := &models.Comment{ID: 5, DocumentID: 7, Version: 3, ParentID: &threadID, AuthorID: 1, Content: "Done"}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         map[string]interface{}
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Start thread",
+			method: http.MethodPost,
+			url:    "/documents/7/comments",
+			body:   map[string]interface{}{"content": "@bob check lag first?"},
+			setupMock: func() {
+				mockService.On("AddComment", mock.Anything, service.AddCommentRequest{UserID: 1, DocumentID: 7, Content: "@bob check lag first?"}).
+					Return(thread, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"version":3`, `"mentions":[2]`},
+		},
+		{
+			name:   "Reply on an older version",
+			method: http.MethodPost,
+			url:    "/documents/7/comments",
+			body:   map[string]interface{}{"content": "Done", "parent_id": 4, "version": 2},
+			setupMock: func() {
+				mockService.On("AddComment", mock.Anything, service.AddCommentRequest{UserID: 1, DocumentID: 7, ParentID: &threadID, Version: 2, Content: "Done"}).
+					Return(reply, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"parent_id":4`},
+		},
+		{
+			name:   "Start anchored thread",
+			method: http.MethodPost,
+			url:    "/documents/7/comments",
+			body:   map[string]interface{}{"content": "Which replica?", "anchor": map[string]interface{}{"start": 0, "end": 20, "quote": "Promote the replica."}},
+			setupMock: func() {
+				anchored := *thread
+				anchored.Anchor = &models.CommentAnchor{Start: 0, End: 20, Quote: "Promote the replica.", Suffix: " Update DNS."}
+				anchored.AnchorStatus = models.AnchorStatusAnchored
+				mockService.On("AddComment", mock.Anything, service.AddCommentRequest{
+					UserID: 1, DocumentID: 7, Content: "Which replica?",
+					Anchor: &models.CommentAnchor{Start: 0, End: 20, Quote: "Promote the replica."},
+				}).Return(&anchored, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"anchor":{"start":0,"end":20`, `"anchor_status":"anchored"`},
+		},
+		{
+			name:   "Anchor outside the content",
+			method: http.MethodPost,
+			url:    "/documents/7/comments",
+			body:   map[string]interface{}{"content": "Hm", "anchor": map[string]interface{}{"start": 5, "end": 500}},
+			setupMock: func() {
+				mockService.On("AddComment", mock.Anything, service.AddCommentRequest{
+					UserID: 1, DocumentID: 7, Content: "Hm", Anchor: &models.CommentAnchor{Start: 5, End: 500},
+				}).Return(nil, service.ErrInvalidAnchor).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Comment on unreadable document",
+			method: http.MethodPost,
+			url:    "/documents/8/comments",
+			body:   map[string]interface{}{"content": "Hello"},
+			setupMock: func() {
+				mockService.On("AddComment", mock.Anything, service.AddCommentRequest{UserID: 1, DocumentID: 8, Content: "Hello"}).
+					Return(nil, service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Empty comment",
+			method:       http.MethodPost,
+			url:          "/documents/7/comments",
+			body:         map[string]interface{}{},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "List unresolved threads",
+			method: http.MethodGet,
+			url:    "/documents/7/comments?unresolved=true&page=2&page_size=10",
+			setupMock: func() {
+				withReply := *thread
+				withReply.Replies = []models.Comment{*reply}
+				mockService.On("ListComments", mock.Anything, uint(1), uint(7), true, 2, 10).
+					Return([]models.Comment{withReply}, int64(11), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"total":11`, `"replies":[{"id":5`},
+		},
+		{
+			name:   "Edit comment of another user",
+			method: http.MethodPut,
+			url:    "/comments/6",
+			body:   map[string]interface{}{"content": "Changed"},
+			setupMock: func() {
+				mockService.On("EditComment", mock.Anything, uint(1), uint(6), "Changed").
+					Return(nil, service.ErrNotCommentAuthor).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "Delete comment",
+			method: http.MethodDelete,
+			url:    "/comments/5",
+			setupMock: func() {
+				mockService.On("DeleteComment", mock.Anything, uint(1), uint(5)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Resolve thread",
+			method: http.MethodPost,
+			url:    "/comments/5/resolve",
+			setupMock: func() {
+				resolved := *thread
+				resolved.ResolvedByID = &resolved.AuthorID
+				mockService.On("ResolveThread", mock.Anything, uint(1), uint(5), true).Return(&resolved, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"resolved_by_id":1`},
+		},
+		{
+			name:   "Reopen missing thread",
+			method: http.MethodPost,
+			url:    "/comments/9/reopen",
+			setupMock: func() {
+				mockService.On("ResolveThread", mock.Anything, uint(1), uint(9), false).Return(nil, service.ErrCommentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			var body []byte
+			if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
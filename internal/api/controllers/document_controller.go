@@ -1,23 +1,39 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Zhaoyikaiii/docmind/internal/models"
 	"github.com/Zhaoyikaiii/docmind/internal/repository"
 	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
 )
 
 type DocumentController struct {
-	docService service.DocumentService
+	docService        service.DocumentService
+	suggestionService service.TagSuggestionService
+	renderService     service.RenderService
+	homeService       service.HomeService
 }
 
-func NewDocumentController(docService service.DocumentService) *DocumentController {
+// NewDocumentController creates the controller. suggestionService may be
+// nil when tag suggestions are disabled, homeService when views of documents
+// aren't recorded.
+func NewDocumentController(docService service.DocumentService, suggestionService service.TagSuggestionService, renderService service.RenderService, homeService service.HomeService) *DocumentController {
 	return &DocumentController{
-		docService: docService,
+		docService:        docService,
+		suggestionService: suggestionService,
+		renderService:     renderService,
+		homeService:       homeService,
 	}
 }
 
@@ -36,6 +52,8 @@ func (dc *DocumentController) CreateDocument(c *gin.Context) {
 	if err := dc.docService.CreateDocument(c.Request.Context(), &doc); err != nil {
 		// 根据错误类型返回不同的状态码和消息
 		switch {
+		case errors.Is(err, service.ErrWorkspaceForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		case strings.Contains(err.Error(), "already exists"):
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Document with this title already exists",
@@ -64,15 +82,39 @@ func (dc *DocumentController) UpdateDocument(c *gin.Context) {
 	}
 
 	var doc models.Document
-	if err := c.ShouldBindJSON(&doc); err != nil {
+	if err := c.ShouldBindBodyWith(&doc, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 请求没有给出 workspace_id 时文档留在原来的工作区
+	var fields map[string]json.RawMessage
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err == nil {
+		if _, ok := fields["workspace_id"]; !ok {
+			current, err := dc.docService.GetDocument(c.Request.Context(), c.GetUint("userID"), uint(id))
+			if errors.Is(err, service.ErrDocumentNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get document"})
+				return
+			}
+			doc.WorkspaceID = current.WorkspaceID
+		}
+	}
+
 	doc.ID = uint(id)
-	doc.CreatorID = c.GetUint("userID")
 
-	if err := dc.docService.UpdateDocument(c.Request.Context(), &doc); err != nil {
+	if err := dc.docService.UpdateDocument(c.Request.Context(), &doc, c.GetUint("userID")); err != nil {
+		if errors.Is(err, service.ErrDocumentLocked) {
+			c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrDocumentEditForbidden) || errors.Is(err, service.ErrWorkspaceForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -105,15 +147,77 @@ func (dc *DocumentController) GetDocument(c *gin.Context) {
 		return
 	}
 
-	doc, err := dc.docService.GetDocument(c.Request.Context(), uint(id))
+	doc, err := dc.docService.GetDocument(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if errors.Is(err, service.ErrDocumentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get document"})
+		return
+	}
+
+	dc.recordView(c, doc.ID)
+	c.JSON(http.StatusOK, doc)
+}
+
+// GetDocumentByPath retrieves a document by its slug path, such as
+// /documents/by-path/handbook/oncall. An optional workspace_id narrows the
+// lookup to a workspace. Outdated paths of renamed or moved documents are
+// redirected permanently to their current path.
+func (dc *DocumentController) GetDocumentByPath(c *gin.Context) {
+	var workspaceID *uint
+	if raw := c.Query("workspace_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+			return
+		}
+		value := uint(id)
+		workspaceID = &value
+	}
+
+	doc, redirect, err := dc.docService.GetDocumentByPath(c.Request.Context(), c.GetUint("userID"), c.Param("path"), workspaceID)
+	if errors.Is(err, service.ErrDocumentNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get document"})
+		return
+	}
+
+	if redirect != "" {
+		// 路由前缀取自请求本身，跳转地址与请求的挂载位置一致
+		base := strings.TrimSuffix(c.Request.URL.Path, c.Param("path"))
+		segments := strings.Split(redirect, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		location := base + "/" + strings.Join(segments, "/")
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
 
+	dc.recordView(c, doc.ID)
 	c.JSON(http.StatusOK, doc)
 }
 
+// recordView adds the document to the recently viewed documents of the
+// current user
+func (dc *DocumentController) recordView(c *gin.Context, documentID uint) {
+	if dc.homeService == nil {
+		return
+	}
+	// 记录失败不影响读取文档
+	if err := dc.homeService.RecordView(c.Request.Context(), c.GetUint("userID"), documentID); err != nil {
+		utils.Logger.Warn("Failed to record document view", zap.Uint("document_id", documentID), zap.Error(err))
+	}
+}
+
 // ListDocuments retrieves a list of documents
 func (dc *DocumentController) ListDocuments(c *gin.Context) {
 	params := repository.DocumentListParams{
@@ -155,20 +259,54 @@ func (dc *DocumentController) ListDocuments(c *gin.Context) {
 		params.Tags = tags
 	}
 
+	// 以下过滤条件对应返回的 facets，便于前端逐级筛选
+	if contentType := c.Query("content_type"); contentType != "" {
+		params.ContentType = contentType
+	}
+
+	now := time.Now()
+	var ok bool
+	if params.CreatedAfter, params.CreatedBefore, ok = dateBucketFilter(c, "created", now); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created date bucket"})
+		return
+	}
+	if params.UpdatedAfter, params.UpdatedBefore, ok = dateBucketFilter(c, "updated", now); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid updated date bucket"})
+		return
+	}
+
 	docs, total, err := dc.docService.ListDocuments(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	facets, err := dc.docService.GetDocumentFacets(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"documents": docs,
 		"total":     total,
+		"facets":    facets,
 		"page":      params.Page,
 		"page_size": params.PageSize,
 	})
 }
 
+// dateBucketFilter returns the time range of the date bucket in query
+// parameter name, such as created=past_week; ok is false when the bucket is
+// unknown
+func dateBucketFilter(c *gin.Context, name string, now time.Time) (after, before *time.Time, ok bool) {
+	bucket := c.Query(name)
+	if bucket == "" {
+		return nil, nil, true
+	}
+	return repository.DateBucketRange(bucket, now)
+}
+
 // CreateVersion creates a new version of a document
 func (dc *DocumentController) CreateVersion(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -212,8 +350,10 @@ func (dc *DocumentController) ManageTags(c *gin.Context) {
 	}
 
 	var req struct {
-		AddTags    []uint `json:"add_tags"`
-		RemoveTags []uint `json:"remove_tags"`
+		AddTags        []uint   `json:"add_tags"`
+		RemoveTags     []uint   `json:"remove_tags"`
+		AddTagNames    []string `json:"add_tag_names"`
+		RemoveTagNames []string `json:"remove_tag_names"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -221,10 +361,96 @@ func (dc *DocumentController) ManageTags(c *gin.Context) {
 		return
 	}
 
-	if err := dc.docService.ManageTags(c.Request.Context(), uint(id), req.AddTags, req.RemoveTags); err != nil {
+	userID := c.GetUint("userID")
+	if len(req.AddTags) > 0 || len(req.RemoveTags) > 0 {
+		if err := dc.docService.ManageTags(c.Request.Context(), userID, uint(id), req.AddTags, req.RemoveTags); err != nil {
+			respondManageTagsError(c, err)
+			return
+		}
+	}
+
+	// Tags given by name are created on demand
+	if len(req.AddTagNames) > 0 || len(req.RemoveTagNames) > 0 {
+		if err := dc.docService.ManageTagsByName(c.Request.Context(), userID, uint(id), req.AddTagNames, req.RemoveTagNames); err != nil {
+			respondManageTagsError(c, err)
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func respondManageTagsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTagName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+	case errors.Is(err, service.ErrDocumentEditForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetTagSuggestions returns tags suggested from the document's content,
+// existing tags first
+func (dc *DocumentController) GetTagSuggestions(c *gin.Context) {
+	if dc.suggestionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Tag suggestions are not enabled"})
 		return
 	}
 
-	c.Status(http.StatusOK)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	suggestions, err := dc.suggestionService.Suggest(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// RenderDocument returns the sanitized HTML and table of contents of the
+// document's current content, or of a saved version with ?version=
+func (dc *DocumentController) RenderDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var version int
+	if v := c.Query("version"); v != "" {
+		if version, err = strconv.Atoi(v); err != nil || version <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+			return
+		}
+	}
+
+	rendered, err := dc.renderService.RenderDocument(c.Request.Context(), c.GetUint("userID"), uint(id), version)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDocumentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		case errors.Is(err, service.ErrVersionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render document"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, rendered)
 }
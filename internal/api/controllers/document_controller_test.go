@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Zhaoyikaiii/docmind/internal/models"
 	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,8 +28,8 @@ func (m *MockDocumentService) CreateDocument(ctx context.Context, doc *models.Do
 	return args.Error(0)
 }
 
-func (m *MockDocumentService) UpdateDocument(ctx context.Context, doc *models.Document) error {
-	args := m.Called(ctx, doc)
+func (m *MockDocumentService) UpdateDocument(ctx context.Context, doc *models.Document, userID uint) error {
+	args := m.Called(ctx, doc, userID)
 	return args.Error(0)
 }
 
@@ -36,19 +38,39 @@ func (m *MockDocumentService) DeleteDocument(ctx context.Context, id uint, userI
 	return args.Error(0)
 }
 
-func (m *MockDocumentService) GetDocument(ctx context.Context, id uint) (*models.Document, error) {
-	args := m.Called(ctx, id)
+func (m *MockDocumentService) GetDocument(ctx context.Context, userID, id uint) (*models.Document, error) {
+	args := m.Called(ctx, userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Document), args.Error(1)
 }
 
+func (m *MockDocumentService) GetDocumentByPath(ctx context.Context, userID uint, path string, workspaceID *uint) (*models.Document, string, error) {
+	args := m.Called(ctx, userID, path, workspaceID)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).(*models.Document), args.String(1), args.Error(2)
+}
+
 func (m *MockDocumentService) ListDocuments(ctx context.Context, params repository.DocumentListParams) ([]models.Document, int64, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).([]models.Document), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockDocumentService) Subscribe(listener service.DocumentListener) {
+	m.Called(listener)
+}
+
+func (m *MockDocumentService) GetDocumentFacets(ctx context.Context, params repository.DocumentListParams) (*models.Facets, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Facets), args.Error(1)
+}
+
 func (m *MockDocumentService) CreateVersion(ctx context.Context, docID uint, userID uint) error {
 	args := m.Called(ctx, docID, userID)
 	return args.Error(0)
@@ -59,15 +81,20 @@ func (m *MockDocumentService) GetVersions(ctx context.Context, docID uint) ([]mo
 	return args.Get(0).([]models.DocumentVersion), args.Error(1)
 }
 
-func (m *MockDocumentService) ManageTags(ctx context.Context, docID uint, addTags []uint, removeTags []uint) error {
-	args := m.Called(ctx, docID, addTags, removeTags)
+func (m *MockDocumentService) ManageTags(ctx context.Context, userID, docID uint, addTags []uint, removeTags []uint) error {
+	args := m.Called(ctx, userID, docID, addTags, removeTags)
+	return args.Error(0)
+}
+
+func (m *MockDocumentService) ManageTagsByName(ctx context.Context, userID, docID uint, addTags []string, removeTags []string) error {
+	args := m.Called(ctx, userID, docID, addTags, removeTags)
 	return args.Error(0)
 }
 
 func setupTest() (*gin.Engine, *MockDocumentService) {
 	gin.SetMode(gin.TestMode)
 	mockService := new(MockDocumentService)
-	controller := NewDocumentController(mockService)
+	controller := NewDocumentController(mockService, nil, nil, nil)
 
 	r := gin.New()
 	r.Use(func(c *gin.Context) {
@@ -81,6 +108,7 @@ func setupTest() (*gin.Engine, *MockDocumentService) {
 		docs.PUT("/:id", controller.UpdateDocument)
 		docs.DELETE("/:id", controller.DeleteDocument)
 		docs.GET("/:id", controller.GetDocument)
+		docs.GET("/by-path/*path", controller.GetDocumentByPath)
 		docs.GET("", controller.ListDocuments)
 		docs.POST("/:id/versions", controller.CreateVersion)
 		docs.GET("/:id/versions", controller.GetVersions)
@@ -126,6 +154,19 @@ func TestCreateDocument(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			description:  "当尝试创建标题重复的文档时应该返回400状态码",
 		},
+		{
+			name: "Error_WorkspaceOfOthers",
+			document: models.Document{
+				Title:       "Planted Document",
+				WorkspaceID: func() *uint { id := uint(9); return &id }(),
+			},
+			setupMock: func() {
+				mockService.On("CreateDocument", mock.Anything, mock.AnythingOfType("*models.Document")).
+					Return(service.ErrWorkspaceForbidden)
+			},
+			expectedCode: http.StatusForbidden,
+			description:  "不是工作区成员时不能在其中创建文档",
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,7 +205,7 @@ func TestGetDocument(t *testing.T) {
 			name:       "Valid document retrieval",
 			documentID: "1",
 			setupMock: func() {
-				mockService.On("GetDocument", mock.Anything, uint(1)).
+				mockService.On("GetDocument", mock.Anything, uint(1), uint(1)).
 					Return(&models.Document{ID: 1, Title: "Test"}, nil)
 			},
 			expectedCode: http.StatusOK,
@@ -173,11 +214,20 @@ func TestGetDocument(t *testing.T) {
 			name:       "Document not found",
 			documentID: "999",
 			setupMock: func() {
-				mockService.On("GetDocument", mock.Anything, uint(999)).
-					Return(nil, fmt.Errorf("not found"))
+				mockService.On("GetDocument", mock.Anything, uint(1), uint(999)).
+					Return(nil, service.ErrDocumentNotFound)
 			},
 			expectedCode: http.StatusNotFound,
 		},
+		{
+			name:       "Service error",
+			documentID: "7",
+			setupMock: func() {
+				mockService.On("GetDocument", mock.Anything, uint(1), uint(7)).
+					Return(nil, fmt.Errorf("db down"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
 		{
 			name:         "Invalid document ID",
 			documentID:   "invalid",
@@ -201,6 +251,157 @@ func TestGetDocument(t *testing.T) {
 	}
 }
 
+func TestGetDocumentByPath(t *testing.T) {
+	r, mockService := setupTest()
+	workspaceID := uint(3)
+
+	tests := []struct {
+		name             string
+		url              string
+		setupMock        func()
+		expectedCode     int
+		expectedLocation string
+	}{
+		{
+			name: "Current path",
+			url:  "/documents/by-path/handbook/oncall",
+			setupMock: func() {
+				mockService.On("GetDocumentByPath", mock.Anything, uint(1), "/handbook/oncall", (*uint)(nil)).
+					Return(&models.Document{ID: 1, Slug: "oncall", SlugPath: "handbook/oncall"}, "", nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Former path in a workspace",
+			url:  "/documents/by-path/handbook/on-call?workspace_id=3",
+			setupMock: func() {
+				mockService.On("GetDocumentByPath", mock.Anything, uint(1), "/handbook/on-call", &workspaceID).
+					Return(nil, "手册/oncall runbook", nil).Once()
+			},
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "/documents/by-path/%E6%89%8B%E5%86%8C/oncall%20runbook?workspace_id=3",
+		},
+		{
+			name: "Unknown path",
+			url:  "/documents/by-path/nowhere",
+			setupMock: func() {
+				mockService.On("GetDocumentByPath", mock.Anything, uint(1), "/nowhere", (*uint)(nil)).
+					Return(nil, "", service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Invalid workspace ID",
+			url:          "/documents/by-path/handbook?workspace_id=x",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateDocument(t *testing.T) {
+	r, mockService := setupTest()
+
+	platform := uint(5)
+	tests := []struct {
+		name         string
+		documentID   string
+		body         string
+		setupMock    func()
+		expectedCode int
+	}{
+		{
+			name:       "Update document",
+			documentID: "1",
+			body:       `{"title":"Runbook","content":"Promote the replica."}`,
+			setupMock: func() {
+				mockService.On("GetDocument", mock.Anything, uint(1), uint(1)).Return(&models.Document{ID: 1, WorkspaceID: &platform}, nil).Once()
+				mockService.On("UpdateDocument", mock.Anything, mock.MatchedBy(func(doc *models.Document) bool {
+					return doc.ID == 1 && doc.WorkspaceID != nil && *doc.WorkspaceID == platform
+				}), uint(1)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:       "Move document out of its workspace",
+			documentID: "1",
+			body:       `{"title":"Runbook","workspace_id":null}`,
+			setupMock: func() {
+				mockService.On("UpdateDocument", mock.Anything, mock.MatchedBy(func(doc *models.Document) bool {
+					return doc.ID == 1 && doc.WorkspaceID == nil
+				}), uint(1)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:       "Document checked out by another user",
+			documentID: "2",
+			body:       `{"title":"Runbook","workspace_id":5}`,
+			setupMock: func() {
+				mockService.On("UpdateDocument", mock.Anything, mock.Anything, uint(1)).Return(service.ErrDocumentLocked).Once()
+			},
+			expectedCode: http.StatusLocked,
+		},
+		{
+			name:       "Document of someone else",
+			documentID: "3",
+			body:       `{"title":"Runbook","workspace_id":5}`,
+			setupMock: func() {
+				mockService.On("UpdateDocument", mock.Anything, mock.Anything, uint(1)).Return(service.ErrDocumentEditForbidden).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:       "Move document into a workspace of others",
+			documentID: "4",
+			body:       `{"title":"Runbook","workspace_id":9}`,
+			setupMock: func() {
+				mockService.On("UpdateDocument", mock.Anything, mock.Anything, uint(1)).Return(service.ErrWorkspaceForbidden).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:       "Missing document",
+			documentID: "6",
+			body:       `{"title":"Runbook"}`,
+			setupMock: func() {
+				mockService.On("GetDocument", mock.Anything, uint(1), uint(6)).Return(nil, service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodPut, "/documents/"+tt.documentID, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestListDocuments(t *testing.T) {
 	r, mockService := setupTest()
 
@@ -215,7 +416,9 @@ func TestListDocuments(t *testing.T) {
 			query: "?page=1&page_size=10",
 			setupMock: func() {
 				mockService.On("ListDocuments", mock.Anything, mock.AnythingOfType("repository.DocumentListParams")).
-					Return([]models.Document{{ID: 1}}, int64(1), nil)
+					Return([]models.Document{{ID: 1}}, int64(1), nil).Once()
+				mockService.On("GetDocumentFacets", mock.Anything, mock.AnythingOfType("repository.DocumentListParams")).
+					Return(&models.Facets{Status: []models.FacetValue{{Value: "draft", Count: 1}}}, nil).Once()
 			},
 			expectedCode: http.StatusOK,
 		},
@@ -224,10 +427,45 @@ func TestListDocuments(t *testing.T) {
 			query: "?search=test",
 			setupMock: func() {
 				mockService.On("ListDocuments", mock.Anything, mock.AnythingOfType("repository.DocumentListParams")).
-					Return([]models.Document{}, int64(0), nil)
+					Return([]models.Document{}, int64(0), nil).Once()
+				mockService.On("GetDocumentFacets", mock.Anything, mock.AnythingOfType("repository.DocumentListParams")).
+					Return(&models.Facets{}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Drill down by facet values",
+			query: "?content_type=application/pdf&updated=past_week",
+			setupMock: func() {
+				drillDown := mock.MatchedBy(func(params repository.DocumentListParams) bool {
+					return params.ContentType == "application/pdf" &&
+						params.UpdatedAfter != nil && params.UpdatedBefore == nil &&
+						params.CreatedAfter == nil
+				})
+				mockService.On("ListDocuments", mock.Anything, drillDown).
+					Return([]models.Document{{ID: 2}}, int64(1), nil).Once()
+				mockService.On("GetDocumentFacets", mock.Anything, drillDown).
+					Return(&models.Facets{}, nil).Once()
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:         "Unknown date bucket",
+			query:        "?created=yesterday",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "Facet failure",
+			query: "?status=draft",
+			setupMock: func() {
+				mockService.On("ListDocuments", mock.Anything, mock.AnythingOfType("repository.DocumentListParams")).
+					Return([]models.Document{}, int64(0), nil).Once()
+				mockService.On("GetDocumentFacets", mock.Anything, mock.AnythingOfType("repository.DocumentListParams")).
+					Return(nil, fmt.Errorf("database error")).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
 	}
 
 	for _, tt := range tests {
@@ -251,28 +489,65 @@ func TestManageTags(t *testing.T) {
 	tests := []struct {
 		name         string
 		documentID   string
-		request      map[string][]uint
+		request      map[string]interface{}
 		setupMock    func()
 		expectedCode int
 	}{
 		{
 			name:       "Add and remove tags",
 			documentID: "1",
-			request: map[string][]uint{
-				"add_tags":    {1, 2},
-				"remove_tags": {3, 4},
+			request: map[string]interface{}{
+				"add_tags":    []uint{1, 2},
+				"remove_tags": []uint{3, 4},
+			},
+			setupMock: func() {
+				mockService.On("ManageTags", mock.Anything, uint(1), uint(1), []uint{1, 2}, []uint{3, 4}).
+					Return(nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:       "Add tags by name",
+			documentID: "2",
+			request: map[string]interface{}{
+				"add_tag_names":    []string{"runbook", "oncall"},
+				"remove_tag_names": []string{"draft"},
 			},
 			setupMock: func() {
-				mockService.On("ManageTags", mock.Anything, uint(1), []uint{1, 2}, []uint{3, 4}).
+				mockService.On("ManageTagsByName", mock.Anything, uint(1), uint(2), []string{"runbook", "oncall"}, []string{"draft"}).
 					Return(nil)
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:       "Document of another user",
+			documentID: "3",
+			request: map[string]interface{}{
+				"add_tags": []uint{1},
+			},
+			setupMock: func() {
+				mockService.On("ManageTags", mock.Anything, uint(1), uint(3), []uint{1}, []uint(nil)).
+					Return(service.ErrDocumentEditForbidden)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:       "Locked by another user",
+			documentID: "4",
+			request: map[string]interface{}{
+				"remove_tag_names": []string{"draft"},
+			},
+			setupMock: func() {
+				mockService.On("ManageTagsByName", mock.Anything, uint(1), uint(4), []string(nil), []string{"draft"}).
+					Return(service.ErrDocumentLocked)
+			},
+			expectedCode: http.StatusLocked,
+		},
 		{
 			name:       "Invalid document ID",
 			documentID: "invalid",
-			request: map[string][]uint{
-				"add_tags": {1},
+			request: map[string]interface{}{
+				"add_tags": []uint{1},
 			},
 			setupMock:    func() {},
 			expectedCode: http.StatusBadRequest,
@@ -295,3 +570,194 @@ func TestManageTags(t *testing.T) {
 		})
 	}
 }
+
+type MockTagSuggestionService struct {
+	mock.Mock
+}
+
+func (m *MockTagSuggestionService) Suggest(ctx context.Context, userID, documentID uint) ([]models.TagSuggestion, error) {
+	args := m.Called(ctx, userID, documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TagSuggestion), args.Error(1)
+}
+
+func TestGetTagSuggestions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockTagSuggestionService)
+	controller := NewDocumentController(new(MockDocumentService), mockService, nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/documents/:id/tag-suggestions", controller.GetTagSuggestions)
+
+	tagID := uint(4)
+	tests := []struct {
+		name         string
+		documentID   string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:       "Suggestions",
+			documentID: "1",
+			setupMock: func() {
+				mockService.On("Suggest", mock.Anything, uint(1), uint(1)).Return([]models.TagSuggestion{
+					{DocumentID: 1, TagID: &tagID, Name: "database", Confidence: 0.9, Source: models.SuggestionSourceVocabulary},
+					{DocumentID: 1, Name: "replica", Confidence: 0.4, Source: models.SuggestionSourceKeyword},
+				}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"tag_id":4`, `"name":"replica"`, `"source":"keyword"`},
+		},
+		{
+			name:       "Document not found",
+			documentID: "999",
+			setupMock: func() {
+				mockService.On("Suggest", mock.Anything, uint(1), uint(999)).Return(nil, service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:       "Service error",
+			documentID: "2",
+			setupMock: func() {
+				mockService.On("Suggest", mock.Anything, uint(1), uint(2)).Return(nil, fmt.Errorf("db down")).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "Invalid document ID",
+			documentID:   "invalid",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, "/documents/"+tt.documentID+"/tag-suggestions", nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/documents/:id/tag-suggestions", NewDocumentController(new(MockDocumentService), nil, nil, nil).GetTagSuggestions)
+
+		req, _ := http.NewRequest(http.MethodGet, "/documents/1/tag-suggestions", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+// MockRenderService 模拟渲染服务
+type MockRenderService struct {
+	mock.Mock
+}
+
+func (m *MockRenderService) HandleDocumentEvent(ctx context.Context, event service.DocumentEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockRenderService) RenderDocument(ctx context.Context, userID, docID uint, version int) (*models.RenderedDocument, error) {
+	args := m.Called(ctx, userID, docID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RenderedDocument), args.Error(1)
+}
+
+func TestRenderDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockRenderService)
+	controller := NewDocumentController(new(MockDocumentService), nil, mockService, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/documents/:id/render", controller.RenderDocument)
+
+	rendered := &models.RenderedDocument{
+		DocumentID: 1,
+		Version:    2,
+		HTML:       `<h1 id="guide">Guide</h1>`,
+		TOC:        []models.TOCEntry{{Level: 1, Text: "Guide", ID: "guide"}},
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name: "Render current content",
+			url:  "/documents/1/render",
+			setupMock: func() {
+				mockService.On("RenderDocument", mock.Anything, uint(1), uint(1), 0).Return(rendered, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"html":"\u003ch1 id=\"guide\"\u003eGuide\u003c/h1\u003e"`, `"toc":[{"level":1,"text":"Guide","id":"guide"}]`},
+		},
+		{
+			name: "Render missing version",
+			url:  "/documents/1/render?version=7",
+			setupMock: func() {
+				mockService.On("RenderDocument", mock.Anything, uint(1), uint(1), 7).Return(nil, service.ErrVersionNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Render invalid version",
+			url:          "/documents/1/render?version=latest",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Render missing document",
+			url:  "/documents/9/render",
+			setupMock: func() {
+				mockService.On("RenderDocument", mock.Anything, uint(1), uint(9), 0).Return(nil, service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportController struct {
+	exportService service.ExportService
+}
+
+func NewExportController(exportService service.ExportService) *ExportController {
+	return &ExportController{
+		exportService: exportService,
+	}
+}
+
+// ExportDocument downloads a document in the format given by ?format=, or
+// the document and its descendants as a ZIP archive with ?subtree=true.
+// Large exports, and any with ?async=true, are run in the background and
+// answered with 202 and the job to poll.
+func (ec *ExportController) ExportDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	req := service.ExportRequest{
+		DocumentID: uint(id),
+		UserID:     c.GetUint("userID"),
+		Format:     c.DefaultQuery("format", "html"),
+	}
+	if req.Subtree, err = parseBoolQuery(c, "subtree"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subtree flag"})
+		return
+	}
+	if req.Async, err = parseBoolQuery(c, "async"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid async flag"})
+		return
+	}
+
+	result, err := ec.exportService.Export(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedExportFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported export format"})
+		case errors.Is(err, service.ErrDocumentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		case errors.Is(err, service.ErrDocumentQueueFull):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many exports in progress"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export document"})
+		}
+		return
+	}
+
+	if result.Job != nil {
+		c.JSON(http.StatusAccepted, result.Job)
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": result.FileName}))
+	c.Data(http.StatusOK, result.ContentType, result.Data)
+}
+
+// GetExportJob returns the status of an export job of the current user
+func (ec *ExportController) GetExportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export job ID"})
+		return
+	}
+
+	job, err := ec.exportService.GetJob(c.Request.Context(), uint(id), c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, service.ErrExportJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get export job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadExport downloads the file of a finished export job
+func (ec *ExportController) DownloadExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export job ID"})
+		return
+	}
+
+	job, rc, err := ec.exportService.OpenJob(c.Request.Context(), uint(id), c.GetUint("userID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrExportJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export job not found"})
+		case errors.Is(err, service.ErrExportNotReady):
+			c.JSON(http.StatusConflict, gin.H{"error": "Export is not finished"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read export"})
+		}
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, job.Size, job.ContentType, rc, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": job.FileName}),
+	})
+}
+
+// parseBoolQuery parses an optional boolean query parameter
+func parseBoolQuery(c *gin.Context, name string) (bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
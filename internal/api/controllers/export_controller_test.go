@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportService 模拟导出服务
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) Export(ctx context.Context, req service.ExportRequest) (*service.ExportResult, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ExportResult), args.Error(1)
+}
+
+func (m *MockExportService) GetJob(ctx context.Context, id uint, userID uint) (*models.ExportJob, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExportJob), args.Error(1)
+}
+
+func (m *MockExportService) OpenJob(ctx context.Context, id uint, userID uint) (*models.ExportJob, io.ReadCloser, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.ExportJob), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func setupExportTest() (*gin.Engine, *MockExportService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockExportService)
+	controller := NewExportController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/documents/:id/export", controller.ExportDocument)
+	r.GET("/exports/:id", controller.GetExportJob)
+	r.GET("/exports/:id/download", controller.DownloadExport)
+
+	return r, mockService
+}
+
+func TestExportController(t *testing.T) {
+	r, mockService := setupExportTest()
+
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func()
+		expectedCode   int
+		expectedBody   []string
+		expectedHeader string
+	}{
+		{
+			name: "Export as PDF",
+			url:  "/documents/3/export?format=pdf",
+			setupMock: func() {
+				mockService.On("Export", mock.Anything, service.ExportRequest{DocumentID: 3, UserID: 1, Format: "pdf"}).
+					Return(&service.ExportResult{FileName: "Guide.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.3")}, nil).Once()
+			},
+			expectedCode:   http.StatusOK,
+			expectedBody:   []string{"%PDF-1.3"},
+			expectedHeader: `attachment; filename=Guide.pdf`,
+		},
+		{
+			name: "Export subtree as background job",
+			url:  "/documents/3/export?format=md&subtree=true",
+			setupMock: func() {
+				mockService.On("Export", mock.Anything, service.ExportRequest{DocumentID: 3, UserID: 1, Format: "md", Subtree: true}).
+					Return(&service.ExportResult{Job: &models.ExportJob{ID: 4, Status: models.ExportStatusPending}}, nil).Once()
+			},
+			expectedCode: http.StatusAccepted,
+			expectedBody: []string{`"id":4`, `"status":"pending"`},
+		},
+		{
+			name:         "Invalid subtree flag",
+			url:          "/documents/3/export?subtree=maybe",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Unsupported format",
+			url:  "/documents/3/export?format=docx",
+			setupMock: func() {
+				mockService.On("Export", mock.Anything, service.ExportRequest{DocumentID: 3, UserID: 1, Format: "docx"}).
+					Return(nil, service.ErrUnsupportedExportFormat).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Export unreadable document",
+			url:  "/documents/5/export?format=html&async=1",
+			setupMock: func() {
+				mockService.On("Export", mock.Anything, service.ExportRequest{DocumentID: 5, UserID: 1, Format: "html", Async: true}).
+					Return(nil, service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "Get finished job",
+			url:  "/exports/4",
+			setupMock: func() {
+				mockService.On("GetJob", mock.Anything, uint(4), uint(1)).
+					Return(&models.ExportJob{ID: 4, Status: models.ExportStatusDone, DownloadURL: "/api/v1/exports/4/download"}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"download_url":"/api/v1/exports/4/download"`},
+		},
+		{
+			name: "Get job of another user",
+			url:  "/exports/6",
+			setupMock: func() {
+				mockService.On("GetJob", mock.Anything, uint(6), uint(1)).Return(nil, service.ErrExportJobNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "Download finished job",
+			url:  "/exports/4/download",
+			setupMock: func() {
+				job := &models.ExportJob{ID: 4, FileName: "Guide.zip", ContentType: "application/zip", Size: 4}
+				mockService.On("OpenJob", mock.Anything, uint(4), uint(1)).
+					Return(job, io.NopCloser(strings.NewReader("PK\x03\x04")), nil).Once()
+			},
+			expectedCode:   http.StatusOK,
+			expectedBody:   []string{"PK\x03\x04"},
+			expectedHeader: `attachment; filename=Guide.zip`,
+		},
+		{
+			name: "Download running job",
+			url:  "/exports/7/download",
+			setupMock: func() {
+				mockService.On("OpenJob", mock.Anything, uint(7), uint(1)).Return(nil, nil, service.ErrExportNotReady).Once()
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			if tt.expectedHeader != "" {
+				assert.Equal(t, tt.expectedHeader, w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -140,4 +142,95 @@ func (fc *FileController) AssociateWithDocument(c *gin.Context) {
 	}
 
 	c.Status(http.StatusOK)
-} 
\ No newline at end of file
+}
+
+// GetFileContent 下载文件内容，导入文档中的图片通过该接口引用
+func (fc *FileController) GetFileContent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, rc, err := fc.fileService.OpenFile(c.Request.Context(), uint(id), c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, service.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer rc.Close()
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, file.Size, contentType, rc, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": file.OriginalName}),
+	})
+}
+
+// GetFileText 获取文件提取的正文
+func (fc *FileController) GetFileText(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := fc.fileService.GetFileText(c.Request.Context(), uint(id), c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, service.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file text"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":          file.ID,
+		"text_status": file.TextStatus,
+		"text_error":  file.TextError,
+		"text":        file.Text,
+	})
+}
+
+// ImportAsDocument 将文件正文导入为新文档
+func (fc *FileController) ImportAsDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	// 标题可选，默认使用文件名
+	var req struct {
+		Title string `json:"title"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	doc, err := fc.fileService.ImportAsDocument(c.Request.Context(), uint(id), c.GetUint("userID"), req.Title)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		case errors.Is(err, service.ErrFileForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNoExtractedText), errors.Is(err, service.ErrDocumentTitleExists):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import file"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
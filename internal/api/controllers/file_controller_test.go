@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFileService 模拟文件服务
+type MockFileService struct {
+	mock.Mock
+}
+
+func (m *MockFileService) CreateFile(ctx context.Context, file *models.File, uploadedFile multipart.File) error {
+	return m.Called(ctx, file, uploadedFile).Error(0)
+}
+
+func (m *MockFileService) GetFile(ctx context.Context, id uint) (*models.File, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileService) OpenFile(ctx context.Context, id uint, userID uint) (*models.File, io.ReadCloser, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.File), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockFileService) GetFileText(ctx context.Context, id uint, userID uint) (*models.File, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileService) DeleteFile(ctx context.Context, id uint, userID uint) error {
+	return m.Called(ctx, id, userID).Error(0)
+}
+
+func (m *MockFileService) ListFiles(ctx context.Context, params models.FileListParams) ([]models.File, int64, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.File), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockFileService) UpdateFile(ctx context.Context, file *models.File) error {
+	return m.Called(ctx, file).Error(0)
+}
+
+func (m *MockFileService) ImportAsDocument(ctx context.Context, fileID uint, userID uint, title string) (*models.Document, error) {
+	args := m.Called(ctx, fileID, userID, title)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Document), args.Error(1)
+}
+
+func setupFileTest() (*gin.Engine, *MockFileService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockFileService)
+	controller := NewFileController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/files/:id/content", controller.GetFileContent)
+	r.GET("/files/:id/text", controller.GetFileText)
+	r.POST("/files/:id/import", controller.ImportAsDocument)
+
+	return r, mockService
+}
+
+func TestFileController(t *testing.T) {
+	r, mockService := setupFileTest()
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         map[string]interface{}
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Get extracted text",
+			method: http.MethodGet,
+			url:    "/files/5/text",
+			setupMock: func() {
+				mockService.On("GetFileText", mock.Anything, uint(5), uint(1)).
+					Return(&models.File{ID: 5, TextStatus: models.TextStatusExtracted, Text: "Storage report"}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"text":"Storage report"`, `"text_status":"extracted"`},
+		},
+		{
+			name:   "Get image content",
+			method: http.MethodGet,
+			url:    "/files/12/content",
+			setupMock: func() {
+				image := &models.File{ID: 12, OriginalName: "image1.png", ContentType: "image/png", Size: 4}
+				mockService.On("OpenFile", mock.Anything, uint(12), uint(1)).
+					Return(image, io.NopCloser(strings.NewReader("\x89PNG")), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{"\x89PNG"},
+		},
+		{
+			name:   "Get content of missing file",
+			method: http.MethodGet,
+			url:    "/files/13/content",
+			setupMock: func() {
+				mockService.On("OpenFile", mock.Anything, uint(13), uint(1)).Return(nil, nil, service.ErrFileNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "Get text of missing file",
+			method: http.MethodGet,
+			url:    "/files/6/text",
+			setupMock: func() {
+				mockService.On("GetFileText", mock.Anything, uint(6), uint(1)).Return(nil, service.ErrFileNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "Import with file name as title",
+			method: http.MethodPost,
+			url:    "/files/5/import",
+			setupMock: func() {
+				mockService.On("ImportAsDocument", mock.Anything, uint(5), uint(1), "").
+					Return(&models.Document{ID: 9, Title: "storage-report", CreatorID: 1}, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"id":9`, `"title":"storage-report"`},
+		},
+		{
+			name:   "Import with title",
+			method: http.MethodPost,
+			url:    "/files/5/import",
+			body:   map[string]interface{}{"title": "Q3 storage"},
+			setupMock: func() {
+				mockService.On("ImportAsDocument", mock.Anything, uint(5), uint(1), "Q3 storage").
+					Return(&models.Document{ID: 10, Title: "Q3 storage", CreatorID: 1}, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:   "Import file without text",
+			method: http.MethodPost,
+			url:    "/files/7/import",
+			setupMock: func() {
+				mockService.On("ImportAsDocument", mock.Anything, uint(7), uint(1), "").
+					Return(nil, service.ErrNoExtractedText).Once()
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Import file of another user",
+			method: http.MethodPost,
+			url:    "/files/8/import",
+			setupMock: func() {
+				mockService.On("ImportAsDocument", mock.Anything, uint(8), uint(1), "").
+					Return(nil, service.ErrFileForbidden).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			var body []byte
+			if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type HomeController struct {
+	homeService service.HomeService
+}
+
+func NewHomeController(homeService service.HomeService) *HomeController {
+	return &HomeController{
+		homeService: homeService,
+	}
+}
+
+// GetHome returns the home page of the current user: their favorites, the
+// documents they viewed, those updated in their workspaces and those
+// awaiting their review
+func (hc *HomeController) GetHome(c *gin.Context) {
+	home, err := hc.homeService.Home(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get home page"})
+		return
+	}
+
+	c.JSON(http.StatusOK, home)
+}
+
+// ListFavorites lists the documents the current user starred
+func (hc *HomeController) ListFavorites(c *gin.Context) {
+	hc.list(c, hc.homeService.Favorites)
+}
+
+// ListRecentlyViewed lists the documents the current user viewed
+func (hc *HomeController) ListRecentlyViewed(c *gin.Context) {
+	hc.list(c, hc.homeService.RecentlyViewed)
+}
+
+// ListRecentlyUpdated lists the documents updated in the workspaces of the
+// current user
+func (hc *HomeController) ListRecentlyUpdated(c *gin.Context) {
+	hc.list(c, hc.homeService.RecentlyUpdated)
+}
+
+// ListAwaitingReview lists the documents the current user was asked to review
+func (hc *HomeController) ListAwaitingReview(c *gin.Context) {
+	hc.list(c, hc.homeService.AwaitingReview)
+}
+
+// list responds with the documents of a personal list, at most "limit" of
+// them, 20 by default and 100 at most
+func (hc *HomeController) list(c *gin.Context, list func(context.Context, uint, int) ([]models.FeedItem, error)) {
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+	}
+
+	items, err := list(c.Request.Context(), c.GetUint("userID"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list documents"})
+		return
+	}
+	if items == nil {
+		items = []models.FeedItem{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": items})
+}
+
+// Star adds a document to the favorites of the current user
+func (hc *HomeController) Star(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	if err := hc.homeService.Star(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		if errors.Is(err, service.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to star document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document starred successfully"})
+}
+
+// Unstar removes a document from the favorites of the current user
+func (hc *HomeController) Unstar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	if err := hc.homeService.Unstar(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unstar document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document unstarred successfully"})
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHomeService 模拟个人主页服务
+type MockHomeService struct {
+	mock.Mock
+}
+
+func (m *MockHomeService) RecordView(ctx context.Context, userID, documentID uint) error {
+	return m.Called(ctx, userID, documentID).Error(0)
+}
+
+func (m *MockHomeService) Star(ctx context.Context, userID, documentID uint) error {
+	return m.Called(ctx, userID, documentID).Error(0)
+}
+
+func (m *MockHomeService) Unstar(ctx context.Context, userID, documentID uint) error {
+	return m.Called(ctx, userID, documentID).Error(0)
+}
+
+func (m *MockHomeService) Favorites(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]models.FeedItem), args.Error(1)
+}
+
+func (m *MockHomeService) RecentlyViewed(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]models.FeedItem), args.Error(1)
+}
+
+func (m *MockHomeService) RecentlyUpdated(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]models.FeedItem), args.Error(1)
+}
+
+func (m *MockHomeService) AwaitingReview(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]models.FeedItem), args.Error(1)
+}
+
+func (m *MockHomeService) Home(ctx context.Context, userID uint) (*models.Home, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Home), args.Error(1)
+}
+
+func setupHomeTest() (*gin.Engine, *MockHomeService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockHomeService)
+	controller := NewHomeController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/documents/:id/star", controller.Star)
+	r.DELETE("/documents/:id/star", controller.Unstar)
+	r.GET("/home", controller.GetHome)
+	r.GET("/home/favorites", controller.ListFavorites)
+	r.GET("/home/recent", controller.ListRecentlyViewed)
+	r.GET("/home/updates", controller.ListRecentlyUpdated)
+	r.GET("/home/reviews", controller.ListAwaitingReview)
+
+	return r, mockService
+}
+
+func TestHomeController(t *testing.T) {
+	r, mockService := setupHomeTest()
+
+	runbook := models.FeedItem{DocumentNode: models.DocumentNode{ID: 3, Title: "Oncall runbook", Slug: "oncall-runbook"}, ViewCount: 2}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Star",
+			method: http.MethodPost,
+			url:    "/documents/3/star",
+			setupMock: func() {
+				mockService.On("Star", mock.Anything, uint(1), uint(3)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Star an unreadable document",
+			method: http.MethodPost,
+			url:    "/documents/4/star",
+			setupMock: func() {
+				mockService.On("Star", mock.Anything, uint(1), uint(4)).Return(service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Star with invalid ID",
+			method:       http.MethodPost,
+			url:          "/documents/abc/star",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Unstar",
+			method: http.MethodDelete,
+			url:    "/documents/3/star",
+			setupMock: func() {
+				mockService.On("Unstar", mock.Anything, uint(1), uint(3)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Home",
+			method: http.MethodGet,
+			url:    "/home",
+			setupMock: func() {
+				home := &models.Home{Favorites: []models.FeedItem{}, RecentlyViewed: []models.FeedItem{runbook}, RecentlyUpdated: []models.FeedItem{}, AwaitingReview: []models.FeedItem{}}
+				mockService.On("Home", mock.Anything, uint(1)).Return(home, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"favorites":[]`, `"recently_viewed":[{"id":3,"title":"Oncall runbook","slug":"oncall-runbook"`, `"view_count":2`},
+		},
+		{
+			name:   "Home failure",
+			method: http.MethodGet,
+			url:    "/home",
+			setupMock: func() {
+				mockService.On("Home", mock.Anything, uint(1)).Return(nil, errors.New("db down")).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:   "Favorites",
+			method: http.MethodGet,
+			url:    "/home/favorites",
+			setupMock: func() {
+				mockService.On("Favorites", mock.Anything, uint(1), 20).Return([]models.FeedItem(nil), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"documents":[]`},
+		},
+		{
+			name:   "Recently viewed with a limit",
+			method: http.MethodGet,
+			url:    "/home/recent?limit=5",
+			setupMock: func() {
+				mockService.On("RecentlyViewed", mock.Anything, uint(1), 5).Return([]models.FeedItem{runbook}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"documents":[{"id":3`},
+		},
+		{
+			name:   "Recently updated with a limit too large",
+			method: http.MethodGet,
+			url:    "/home/updates?limit=500",
+			setupMock: func() {
+				mockService.On("RecentlyUpdated", mock.Anything, uint(1), 100).Return([]models.FeedItem{runbook}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Awaiting review failure",
+			method: http.MethodGet,
+			url:    "/home/reviews",
+			setupMock: func() {
+				mockService.On("AwaitingReview", mock.Anything, uint(1), 20).Return([]models.FeedItem(nil), errors.New("db down")).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"archive/zip"
+	"errors"
+	"net/http"
+
+	"github.com/Zhaoyikaiii/docmind/internal/importer"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxImportArchiveSize caps uploaded import archives when no limit
+// is configured
+const DefaultMaxImportArchiveSize = 256 << 20
+
+type ImportController struct {
+	importService  service.ImportService
+	maxArchiveSize int64
+}
+
+func NewImportController(importService service.ImportService, maxArchiveSize int64) *ImportController {
+	if maxArchiveSize <= 0 {
+		maxArchiveSize = DefaultMaxImportArchiveSize
+	}
+	return &ImportController{
+		importService:  importService,
+		maxArchiveSize: maxArchiveSize,
+	}
+}
+
+// ImportArchive imports the pages of an uploaded ZIP archive as documents:
+// a folder of Markdown files, or a Confluence or Notion export. The format
+// is detected unless given with ?format=. With ?dry_run=true nothing is
+// changed and the report tells what the import would do.
+func (ic *ImportController) ImportArchive(c *gin.Context) {
+	dryRun, err := parseBoolQuery(c, "dry_run")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run flag"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ic.maxArchiveSize)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Archive too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No archive uploaded"})
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ZIP archive"})
+		return
+	}
+
+	report, err := ic.importService.ImportTree(c.Request.Context(), service.ImportRequest{
+		FS:     archive,
+		UserID: c.GetUint("userID"),
+		Format: c.Query("format"),
+		DryRun: dryRun,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, importer.ErrUnsupportedFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, importer.ErrTooManyPages):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrChecksum), errors.Is(err, zip.ErrAlgorithm):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ZIP archive"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import documents"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/importer"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockImportService 模拟导入服务
+type MockImportService struct {
+	mock.Mock
+}
+
+func (m *MockImportService) ImportTree(ctx context.Context, req service.ImportRequest) (*models.ImportReport, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportReport), args.Error(1)
+}
+
+func setupImportTest() (*gin.Engine, *MockImportService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockImportService)
+	controller := NewImportController(mockService, 1<<20)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/documents/import", controller.ImportArchive)
+
+	return r, mockService
+}
+
+// uploadBody returns a multipart form with content as the file field
+func uploadBody(t *testing.T, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if content != nil {
+		part, err := form.CreateFormFile("file", "docs.zip")
+		require.NoError(t, err)
+		_, err = part.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+	return &body, form.FormDataContentType()
+}
+
+func TestImportController(t *testing.T) {
+	r, mockService := setupImportTest()
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	f, err := w.Create("guides/setup.md")
+	require.NoError(t, err)
+	_, err = f.Write([]byte("# Setup"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	importing := func(format string, dryRun bool) interface{} {
+		return mock.MatchedBy(func(req service.ImportRequest) bool {
+			_, err := fs.Stat(req.FS, "guides/setup.md")
+			return err == nil && req.UserID == 1 && req.Format == format && req.DryRun == dryRun
+		})
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		content      []byte
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:    "Dry run",
+			url:     "/documents/import?dry_run=true",
+			content: archive.Bytes(),
+			setupMock: func() {
+				mockService.On("ImportTree", mock.Anything, importing("", true)).
+					Return(&models.ImportReport{DryRun: true, Created: 2, Documents: []models.ImportedDocument{
+						{Path: "guides/", Title: "guides", Action: models.ImportActionCreate},
+						{Path: "guides/setup.md", ParentPath: "guides/", Title: "setup", Action: models.ImportActionCreate},
+					}}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"dry_run":true`, `"created":2`, `"path":"guides/setup.md"`},
+		},
+		{
+			name:    "Import",
+			url:     "/documents/import?format=markdown",
+			content: archive.Bytes(),
+			setupMock: func() {
+				mockService.On("ImportTree", mock.Anything, importing("markdown", false)).
+					Return(&models.ImportReport{Format: "markdown", Unchanged: 2}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"format":"markdown"`, `"unchanged":2`},
+		},
+		{
+			name:    "Unsupported format",
+			url:     "/documents/import?format=wordpress",
+			content: archive.Bytes(),
+			setupMock: func() {
+				mockService.On("ImportTree", mock.Anything, importing("wordpress", false)).
+					Return(nil, importer.ErrUnsupportedFormat).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Too many pages",
+			url:     "/documents/import",
+			content: archive.Bytes(),
+			setupMock: func() {
+				mockService.On("ImportTree", mock.Anything, importing("", false)).
+					Return(nil, importer.ErrTooManyPages).Once()
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Not a ZIP archive",
+			url:          "/documents/import",
+			content:      []byte("# Setup"),
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: []string{"Invalid ZIP archive"},
+		},
+		{
+			name:         "No archive",
+			url:          "/documents/import",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Archive too large",
+			url:          "/documents/import",
+			content:      make([]byte, 2<<20),
+			setupMock:    func() {},
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			body, contentType := uploadBody(t, tt.content)
+			req, _ := http.NewRequest(http.MethodPost, tt.url, body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
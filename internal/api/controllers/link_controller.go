@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type LinkController struct {
+	linkService service.LinkService
+}
+
+func NewLinkController(linkService service.LinkService) *LinkController {
+	return &LinkController{
+		linkService: linkService,
+	}
+}
+
+// GetBacklinks lists the documents linking to a document
+func (lc *LinkController) GetBacklinks(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	backlinks, err := lc.linkService.Backlinks(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list backlinks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backlinks": backlinks})
+}
+
+// ListBrokenLinks lists a page of the links leading nowhere in the readable
+// documents, optionally of one workspace
+func (lc *LinkController) ListBrokenLinks(c *gin.Context) {
+	params, ok := linkListParams(c)
+	if !ok {
+		return
+	}
+
+	broken, err := lc.linkService.BrokenLinks(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list broken links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"broken_links": broken})
+}
+
+// GetGraph returns a page of the readable documents, optionally of one
+// workspace, and the links between them for visualizing the knowledge base
+func (lc *LinkController) GetGraph(c *gin.Context) {
+	params, ok := linkListParams(c)
+	if !ok {
+		return
+	}
+
+	graph, err := lc.linkService.Graph(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build link graph"})
+		return
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
+
+// linkListParams parses the workspace_id, page and page_size query
+// parameters of link listings, responding 400 when workspace_id is invalid
+func linkListParams(c *gin.Context) (repository.LinkListParams, bool) {
+	params := repository.LinkListParams{ReadableBy: c.GetUint("userID")}
+
+	if raw := c.Query("workspace_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+			return params, false
+		}
+		workspaceID := uint(id)
+		params.WorkspaceID = &workspaceID
+	}
+
+	if page := c.Query("page"); page != "" {
+		if pageNum, err := strconv.Atoi(page); err == nil {
+			params.Page = pageNum
+		}
+	}
+
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if size, err := strconv.Atoi(pageSize); err == nil {
+			params.PageSize = size
+		}
+	}
+	return params, true
+}
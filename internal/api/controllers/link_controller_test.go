@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLinkService 模拟文档链接服务
+type MockLinkService struct {
+	service.DocumentListener
+	mock.Mock
+}
+
+func (m *MockLinkService) Backlinks(ctx context.Context, userID, documentID uint) ([]models.DocumentNode, error) {
+	args := m.Called(ctx, userID, documentID)
+	return args.Get(0).([]models.DocumentNode), args.Error(1)
+}
+
+func (m *MockLinkService) BrokenLinks(ctx context.Context, params repository.LinkListParams) ([]models.BrokenLink, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.BrokenLink), args.Error(1)
+}
+
+func (m *MockLinkService) Graph(ctx context.Context, params repository.LinkListParams) (*models.DocumentGraph, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DocumentGraph), args.Error(1)
+}
+
+func setupLinkTest() (*gin.Engine, *MockLinkService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockLinkService)
+	controller := NewLinkController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/documents/graph", controller.GetGraph)
+	r.GET("/documents/broken-links", controller.ListBrokenLinks)
+	r.GET("/documents/:id/backlinks", controller.GetBacklinks)
+
+	return r, mockService
+}
+
+func TestLinkController(t *testing.T) {
+	r, mockService := setupLinkTest()
+
+	runbook := models.DocumentNode{ID: 3, Title: "Oncall runbook", Status: models.DocumentStatusPublished}
+	workspaceID := uint(2)
+
+	tests := []struct {
+		name         string
+		url          string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name: "Backlinks",
+			url:  "/documents/7/backlinks",
+			setupMock: func() {
+				mockService.On("Backlinks", mock.Anything, uint(1), uint(7)).Return([]models.DocumentNode{runbook}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"backlinks":[{"id":3,"title":"Oncall runbook"`},
+		},
+		{
+			name: "Backlinks of an unreadable document",
+			url:  "/documents/8/backlinks",
+			setupMock: func() {
+				mockService.On("Backlinks", mock.Anything, uint(1), uint(8)).Return([]models.DocumentNode(nil), service.ErrDocumentNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Backlinks with invalid ID",
+			url:          "/documents/abc/backlinks",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Broken links",
+			url:  "/documents/broken-links",
+			setupMock: func() {
+				mockService.On("BrokenLinks", mock.Anything, repository.LinkListParams{ReadableBy: 1}).Return([]models.BrokenLink{{Document: runbook, Kind: models.DocumentLinkWiki, Target: "Failover"}}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"kind":"wiki"`, `"target":"Failover"`},
+		},
+		{
+			name: "Graph of a workspace",
+			url:  "/documents/graph?workspace_id=2&page=2&page_size=50",
+			setupMock: func() {
+				graph := &models.DocumentGraph{
+					Nodes: []models.DocumentNode{runbook, {ID: 4, Title: "Failover"}},
+					Edges: []models.DocumentEdge{{Source: 3, Target: 4}},
+				}
+				mockService.On("Graph", mock.Anything, repository.LinkListParams{ReadableBy: 1, WorkspaceID: &workspaceID, Page: 2, PageSize: 50}).Return(graph, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"edges":[{"source":3,"target":4}]`},
+		},
+		{
+			name: "Graph failure",
+			url:  "/documents/graph",
+			setupMock: func() {
+				mockService.On("Graph", mock.Anything, repository.LinkListParams{ReadableBy: 1}).Return(nil, errors.New("db down")).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "Graph with invalid workspace",
+			url:          "/documents/graph?workspace_id=abc",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type LockController struct {
+	lockService service.LockService
+}
+
+func NewLockController(lockService service.LockService) *LockController {
+	return &LockController{
+		lockService: lockService,
+	}
+}
+
+// CheckOut locks a document for the current user. When someone else holds
+// the lock, the response is 423 with their lock.
+func (lc *LockController) CheckOut(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	lock, err := lc.lockService.CheckOut(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if errors.Is(err, service.ErrDocumentLocked) && lock != nil {
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error(), "lock": lock})
+		return
+	}
+	if err != nil {
+		respondLockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// Heartbeat renews the current user's lock
+func (lc *LockController) Heartbeat(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	lock, err := lc.lockService.Heartbeat(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		respondLockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// CheckIn releases the current user's lock
+func (lc *LockController) CheckIn(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	if err := lc.lockService.CheckIn(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		respondLockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document checked in successfully"})
+}
+
+func (lc *LockController) GetLock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	lock, err := lc.lockService.GetLock(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		respondLockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// BreakLock removes the lock of a document whoever holds it. Admins only.
+func (lc *LockController) BreakLock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	if err := lc.lockService.BreakLock(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		respondLockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lock broken successfully"})
+}
+
+func respondLockError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDocumentNotFound), errors.Is(err, service.ErrDocumentNotLocked):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotDocumentEditor), errors.Is(err, service.ErrLockBreakForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLockNotHeld):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process lock request"})
+	}
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLockService 模拟文档锁服务
+type MockLockService struct {
+	mock.Mock
+}
+
+func (m *MockLockService) CheckOut(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error) {
+	args := m.Called(ctx, userID, documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DocumentLock), args.Error(1)
+}
+
+func (m *MockLockService) Heartbeat(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error) {
+	args := m.Called(ctx, userID, documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DocumentLock), args.Error(1)
+}
+
+func (m *MockLockService) CheckIn(ctx context.Context, userID, documentID uint) error {
+	return m.Called(ctx, userID, documentID).Error(0)
+}
+
+func (m *MockLockService) GetLock(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error) {
+	args := m.Called(ctx, userID, documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DocumentLock), args.Error(1)
+}
+
+func (m *MockLockService) BreakLock(ctx context.Context, userID, documentID uint) error {
+	return m.Called(ctx, userID, documentID).Error(0)
+}
+
+func setupLockTest() (*gin.Engine, *MockLockService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockLockService)
+	controller := NewLockController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/documents/:id/lock", controller.CheckOut)
+	r.GET("/documents/:id/lock", controller.GetLock)
+	r.DELETE("/documents/:id/lock", controller.CheckIn)
+	r.POST("/documents/:id/lock/heartbeat", controller.Heartbeat)
+	r.POST("/documents/:id/lock/break", controller.BreakLock)
+
+	return r, mockService
+}
+
+func TestLockController(t *testing.T) {
+	r, mockService := setupLockTest()
+
+	expiresAt := time.Date(2026, 10, 19, 12, 5, 0, 0, time.UTC)
+	own := &models.DocumentLock{DocumentID: 7, OwnerID: 1, ExpiresAt: expiresAt}
+	other := &models.DocumentLock{DocumentID: 8, OwnerID: 2, Owner: &models.User{ID: 2, Username: "bob"}, ExpiresAt: expiresAt}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Check out",
+			method: http.MethodPost,
+			url:    "/documents/7/lock",
+			setupMock: func() {
+				mockService.On("CheckOut", mock.Anything, uint(1), uint(7)).Return(own, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"owner_id":1`, `"expires_at":"2026-10-19T12:05:00Z"`},
+		},
+		{
+			name:   "Check out a document locked by another user",
+			method: http.MethodPost,
+			url:    "/documents/8/lock",
+			setupMock: func() {
+				mockService.On("CheckOut", mock.Anything, uint(1), uint(8)).Return(other, service.ErrDocumentLocked).Once()
+			},
+			expectedCode: http.StatusLocked,
+			expectedBody: []string{`"username":"bob"`},
+		},
+		{
+			name:   "Check out without edit rights",
+			method: http.MethodPost,
+			url:    "/documents/9/lock",
+			setupMock: func() {
+				mockService.On("CheckOut", mock.Anything, uint(1), uint(9)).Return(nil, service.ErrNotDocumentEditor).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "Heartbeat of an expired lock",
+			method: http.MethodPost,
+			url:    "/documents/7/lock/heartbeat",
+			setupMock: func() {
+				mockService.On("Heartbeat", mock.Anything, uint(1), uint(7)).Return(nil, service.ErrLockNotHeld).Once()
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:   "Get lock of an unlocked document",
+			method: http.MethodGet,
+			url:    "/documents/7/lock",
+			setupMock: func() {
+				mockService.On("GetLock", mock.Anything, uint(1), uint(7)).Return(nil, service.ErrDocumentNotLocked).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "Check in",
+			method: http.MethodDelete,
+			url:    "/documents/7/lock",
+			setupMock: func() {
+				mockService.On("CheckIn", mock.Anything, uint(1), uint(7)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Break lock as non-admin",
+			method: http.MethodPost,
+			url:    "/documents/8/lock/break",
+			setupMock: func() {
+				mockService.On("BreakLock", mock.Anything, uint(1), uint(8)).Return(service.ErrLockBreakForbidden).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
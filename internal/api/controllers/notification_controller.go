@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService service.NotificationService
+}
+
+func NewNotificationController(notificationService service.NotificationService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+type markReadRequest struct {
+	IDs []uint `json:"ids"` // all notifications when empty
+}
+
+// ListNotifications lists the current user's notifications, newest first.
+// "unread=true" leaves out those already read.
+func (nc *NotificationController) ListNotifications(c *gin.Context) {
+	page, pageSize := 1, 20
+
+	if p := c.Query("page"); p != "" {
+		if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		if size, err := strconv.Atoi(ps); err == nil && size > 0 {
+			pageSize = min(size, 100)
+		}
+	}
+
+	unread, _ := strconv.ParseBool(c.Query("unread"))
+
+	notifications, total, err := nc.notificationService.ListNotifications(c.Request.Context(), c.GetUint("userID"), unread, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"page":          page,
+		"page_size":     pageSize,
+	})
+}
+
+// MarkRead marks the given notifications, or all of them, as read
+func (nc *NotificationController) MarkRead(c *gin.Context) {
+	var req markReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	updated, err := nc.notificationService.MarkRead(c.Request.Context(), c.GetUint("userID"), req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
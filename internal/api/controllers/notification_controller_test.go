@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockNotificationService 模拟通知服务
+type MockNotificationService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationService) Notify(ctx context.Context, notifications ...*models.Notification) error {
+	return m.Called(ctx, notifications).Error(0)
+}
+
+func (m *MockNotificationService) ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, error) {
+	args := m.Called(ctx, userID, unreadOnly, page, pageSize)
+	return args.Get(0).([]models.Notification), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockNotificationService) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	args := m.Called(ctx, userID, ids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func setupNotificationTest() (*gin.Engine, *MockNotificationService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockNotificationService)
+	controller := NewNotificationController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/notifications", controller.ListNotifications)
+	r.POST("/notifications/read", controller.MarkRead)
+
+	return r, mockService
+}
+
+func TestNotificationController(t *testing.T) {
+	r, mockService := setupNotificationTest()
+
+	documentID := uint(7)
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "List unread notifications",
+			method: http.MethodGet,
+			url:    "/notifications?unread=true",
+			setupMock: func() {
+				mockService.On("ListNotifications", mock.Anything, uint(1), true, 1, 20).
+					Return([]models.Notification{{ID: 3, UserID: 1, Type: models.NotificationMention, DocumentID: &documentID}}, int64(1), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"type":"mention"`, `"total":1`},
+		},
+		{
+			name:   "Mark some read",
+			method: http.MethodPost,
+			url:    "/notifications/read",
+			body:   `{"ids":[3,4]}`,
+			setupMock: func() {
+				mockService.On("MarkRead", mock.Anything, uint(1), []uint{3, 4}).Return(int64(2), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"updated":2`},
+		},
+		{
+			name:   "Mark all read",
+			method: http.MethodPost,
+			url:    "/notifications/read",
+			setupMock: func() {
+				mockService.On("MarkRead", mock.Anything, uint(1), []uint(nil)).Return(int64(5), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"updated":5`},
+		},
+		{
+			name:         "Invalid body",
+			method:       http.MethodPost,
+			url:          "/notifications/read",
+			body:         `{"ids":"all"}`,
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReviewService 模拟文档评审服务
+type MockReviewService struct {
+	mock.Mock
+}
+
+func (m *MockReviewService) RequestReview(ctx context.Context, userID, documentID uint, reviewerIDs []uint) ([]*models.ReviewRequest, error) {
+	args := m.Called(ctx, userID, documentID, reviewerIDs)
+	return args.Get(0).([]*models.ReviewRequest), args.Error(1)
+}
+
+func (m *MockReviewService) CompleteReview(ctx context.Context, userID, documentID uint) error {
+	return m.Called(ctx, userID, documentID).Error(0)
+}
+
+func setupReviewTest() (*gin.Engine, *MockReviewService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockReviewService)
+	controller := NewReviewController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/documents/:id/reviews", controller.RequestReview)
+	r.POST("/documents/:id/reviews/complete", controller.CompleteReview)
+
+	return r, mockService
+}
+
+func TestReviewController(t *testing.T) {
+	r, mockService := setupReviewTest()
+
+	tests := []struct {
+		name         string
+		url          string
+		body         string
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name: "Request review",
+			url:  "/documents/3/reviews",
+			body: `{"reviewer_ids":[2]}`,
+			setupMock: func() {
+				requests := []*models.ReviewRequest{{DocumentID: 3, ReviewerID: 2, RequesterID: 1, Status: models.ReviewStatusPending}}
+				mockService.On("RequestReview", mock.Anything, uint(1), uint(3), []uint{2}).Return(requests, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"reviewer_id":2`, `"status":"pending"`},
+		},
+		{
+			name:         "Request review without reviewers",
+			url:          "/documents/3/reviews",
+			body:         `{"reviewer_ids":[]}`,
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Request review as a reader",
+			url:  "/documents/4/reviews",
+			body: `{"reviewer_ids":[2]}`,
+			setupMock: func() {
+				mockService.On("RequestReview", mock.Anything, uint(1), uint(4), []uint{2}).Return([]*models.ReviewRequest(nil), service.ErrReviewForbidden).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name: "Request review from an invalid reviewer",
+			url:  "/documents/3/reviews",
+			body: `{"reviewer_ids":[9]}`,
+			setupMock: func() {
+				mockService.On("RequestReview", mock.Anything, uint(1), uint(3), []uint{9}).Return([]*models.ReviewRequest(nil), service.ErrInvalidReviewer).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Complete review",
+			url:  "/documents/3/reviews/complete",
+			setupMock: func() {
+				mockService.On("CompleteReview", mock.Anything, uint(1), uint(3)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Complete a review never requested",
+			url:  "/documents/5/reviews/complete",
+			setupMock: func() {
+				mockService.On("CompleteReview", mock.Anything, uint(1), uint(5)).Return(service.ErrReviewNotRequested).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	searchService   service.SearchService
+	semanticService service.SemanticSearchService
+}
+
+// NewSearchController creates the search controller. semanticService may be
+// nil when no embedding provider is configured.
+func NewSearchController(searchService service.SearchService, semanticService service.SemanticSearchService) *SearchController {
+	return &SearchController{
+		searchService:   searchService,
+		semanticService: semanticService,
+	}
+}
+
+// Search runs a ranked full-text search over the documents and files the
+// user can read
+func (sc *SearchController) Search(c *gin.Context) {
+	params := repository.SearchParams{
+		Query:    c.Query("q"),
+		UserID:   c.GetUint("userID"),
+		Page:     1,
+		PageSize: 10,
+	}
+
+	if page := c.Query("page"); page != "" {
+		if pageNum, err := strconv.Atoi(page); err == nil && pageNum > 0 {
+			params.Page = pageNum
+		}
+	}
+
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if size, err := strconv.Atoi(pageSize); err == nil && size > 0 {
+			params.PageSize = size
+		}
+	}
+
+	// type=document,file 限定搜索范围
+	if types := c.Query("type"); types != "" {
+		params.Types = strings.Split(types, ",")
+	}
+
+	// 以下过滤条件对应返回的 facets
+	if tags := c.QueryArray("tags"); len(tags) > 0 {
+		params.Tags = tags
+	}
+	if status := c.Query("status"); status != "" {
+		params.Status = &status
+	}
+	if creatorID := c.Query("creator_id"); creatorID != "" {
+		id, err := strconv.ParseUint(creatorID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid creator ID"})
+			return
+		}
+		uid := uint(id)
+		params.CreatorID = &uid
+	}
+	params.ContentType = c.Query("content_type")
+
+	now := time.Now()
+	var ok bool
+	if params.CreatedAfter, params.CreatedBefore, ok = dateBucketFilter(c, "created", now); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created date bucket"})
+		return
+	}
+	if params.UpdatedAfter, params.UpdatedBefore, ok = dateBucketFilter(c, "updated", now); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid updated date bucket"})
+		return
+	}
+
+	hits, total, err := sc.searchService.Search(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, service.ErrEmptySearchQuery) || errors.Is(err, service.ErrInvalidSearchType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	facets, err := sc.searchService.Facets(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hits":      hits,
+		"total":     total,
+		"facets":    facets,
+		"page":      params.Page,
+		"page_size": params.PageSize,
+	})
+}
+
+// SemanticSearch returns the passages closest in meaning to the query from
+// documents the user can read
+func (sc *SearchController) SemanticSearch(c *gin.Context) {
+	if sc.semanticService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Semantic search is not enabled"})
+		return
+	}
+
+	limit := service.DefaultSemanticLimit
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	passages, err := sc.semanticService.SearchReadable(c.Request.Context(), c.Query("q"), limit, c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, service.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"passages": passages,
+	})
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSearchService 模拟搜索服务
+type MockSearchService struct {
+	mock.Mock
+}
+
+func (m *MockSearchService) Search(ctx context.Context, params repository.SearchParams) ([]models.SearchHit, int64, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]models.SearchHit), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockSearchService) Facets(ctx context.Context, params repository.SearchParams) (*models.Facets, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Facets), args.Error(1)
+}
+
+// MockSemanticSearchService 模拟语义搜索服务
+type MockSemanticSearchService struct {
+	mock.Mock
+}
+
+func (m *MockSemanticSearchService) Search(ctx context.Context, query string, limit int) ([]models.Passage, error) {
+	args := m.Called(ctx, query, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Passage), args.Error(1)
+}
+
+func (m *MockSemanticSearchService) SearchDocuments(ctx context.Context, query string, limit int, documentIDs []uint) ([]models.Passage, error) {
+	args := m.Called(ctx, query, limit, documentIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Passage), args.Error(1)
+}
+
+func (m *MockSemanticSearchService) SearchReadable(ctx context.Context, query string, limit int, userID uint) ([]models.Passage, error) {
+	args := m.Called(ctx, query, limit, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Passage), args.Error(1)
+}
+
+func setupSearchTest() (*gin.Engine, *MockSearchService) {
+	r, mockService, _ := setupSearchControllerTest()
+	return r, mockService
+}
+
+func setupSearchControllerTest() (*gin.Engine, *MockSearchService, *MockSemanticSearchService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockSearchService)
+	mockSemantic := new(MockSemanticSearchService)
+	controller := NewSearchController(mockService, mockSemantic)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.GET("/search", controller.Search)
+	r.GET("/search/semantic", controller.SemanticSearch)
+
+	return r, mockService, mockSemantic
+}
+
+func TestSearch(t *testing.T) {
+	r, mockService := setupSearchTest()
+
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func()
+		expectedCode int
+		expectedHits int
+		// expectedFacets is the number of content type facet values
+		expectedFacets int
+	}{
+		{
+			name:  "Search documents and files",
+			query: "?q=oncall&page_size=5",
+			setupMock: func() {
+				mockService.On("Search", mock.Anything, repository.SearchParams{Query: "oncall", UserID: 1, Page: 1, PageSize: 5}).
+					Return([]models.SearchHit{
+						{Type: models.SearchHitDocument, ID: 1, Title: "Oncall", Highlight: "<mark>Oncall</mark>", Rank: 0.8},
+						{Type: models.SearchHitFile, ID: 7, Title: "oncall.pdf", Rank: 0.1},
+					}, int64(2), nil)
+				mockService.On("Facets", mock.Anything, repository.SearchParams{Query: "oncall", UserID: 1, Page: 1, PageSize: 5}).
+					Return(&models.Facets{
+						Status:       []models.FacetValue{{Value: "published", Count: 1}},
+						ContentTypes: []models.FacetValue{{Value: "application/pdf", Count: 2}},
+					}, nil)
+			},
+			expectedCode:   http.StatusOK,
+			expectedHits:   2,
+			expectedFacets: 1,
+		},
+		{
+			name:  "Restrict to documents",
+			query: "?q=runbook&type=document",
+			setupMock: func() {
+				mockService.On("Search", mock.Anything, repository.SearchParams{
+					Query: "runbook", Types: []string{"document"}, UserID: 1, Page: 1, PageSize: 10,
+				}).Return([]models.SearchHit{}, int64(0), nil)
+				mockService.On("Facets", mock.Anything, repository.SearchParams{
+					Query: "runbook", Types: []string{"document"}, UserID: 1, Page: 1, PageSize: 10,
+				}).Return(&models.Facets{}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Filter by facet values",
+			query: "?q=runbook&tags=team/platform&status=published&creator_id=2&content_type=application/pdf&created=past_month",
+			setupMock: func() {
+				filtered := mock.MatchedBy(func(params repository.SearchParams) bool {
+					return params.Query == "runbook" && slices.Equal(params.Tags, []string{"team/platform"}) &&
+						params.Status != nil && *params.Status == "published" &&
+						params.CreatorID != nil && *params.CreatorID == 2 &&
+						params.ContentType == "application/pdf" &&
+						params.CreatedAfter != nil && params.CreatedBefore == nil && params.UpdatedAfter == nil
+				})
+				mockService.On("Search", mock.Anything, filtered).Return([]models.SearchHit{}, int64(0), nil).Once()
+				mockService.On("Facets", mock.Anything, filtered).Return(&models.Facets{}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Unknown date bucket",
+			query:        "?q=runbook&updated=yesterday",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid creator",
+			query:        "?q=runbook&creator_id=alice",
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "Missing query",
+			query: "",
+			setupMock: func() {
+				mockService.On("Search", mock.Anything, repository.SearchParams{UserID: 1, Page: 1, PageSize: 10}).
+					Return(nil, int64(0), service.ErrEmptySearchQuery)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, "/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response struct {
+					Hits   []models.SearchHit `json:"hits"`
+					Facets models.Facets      `json:"facets"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Len(t, response.Hits, tt.expectedHits)
+				assert.Len(t, response.Facets.ContentTypes, tt.expectedFacets)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	r, _, mockService := setupSearchControllerTest()
+
+	tests := []struct {
+		name             string
+		query            string
+		setupMock        func()
+		expectedCode     int
+		expectedPassages int
+	}{
+		{
+			name:  "Top passages",
+			query: "?q=how+to+fail+over+the+database&limit=2",
+			setupMock: func() {
+				mockService.On("SearchReadable", mock.Anything, "how to fail over the database", 2, uint(1)).
+					Return([]models.Passage{
+						{DocumentID: 1, ChunkIndex: 0, Title: "Oncall runbook", Text: "Promote the replica.", Score: 0.82},
+						{DocumentID: 3, ChunkIndex: 2, Title: "Database migrations", Text: "Run migrations first.", Score: 0.41},
+					}, nil)
+			},
+			expectedCode:     http.StatusOK,
+			expectedPassages: 2,
+		},
+		{
+			name:  "Default limit",
+			query: "?q=release",
+			setupMock: func() {
+				mockService.On("SearchReadable", mock.Anything, "release", service.DefaultSemanticLimit, uint(1)).
+					Return([]models.Passage{}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Missing query",
+			query: "",
+			setupMock: func() {
+				mockService.On("SearchReadable", mock.Anything, "", service.DefaultSemanticLimit, uint(1)).
+					Return(nil, service.ErrEmptySearchQuery)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req, _ := http.NewRequest(http.MethodGet, "/search/semantic"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response struct {
+					Passages []models.Passage `json:"passages"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Len(t, response.Passages, tt.expectedPassages)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsStream reports whether the client asked for server-sent events,
+// either in the request body or with "Accept: text/event-stream"
+func wantsStream(c *gin.Context, requested bool) bool {
+	return requested || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// streamGeneration runs generate and streams its tokens as "token" events
+// followed by a "done" event with the result, or an "error" event. An error
+// before the first token is answered with respondError as plain JSON.
+func streamGeneration(c *gin.Context, generate func(onToken func(string) error) (any, error), respondError func(*gin.Context, error)) {
+	// 首个 token 到达前出错仍可返回普通的 JSON 错误
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+	}
+
+	result, err := generate(func(token string) error {
+		start()
+		c.SSEvent("token", gin.H{"text": token})
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	})
+	if err != nil {
+		if !started {
+			respondError(c, err)
+			return
+		}
+		c.SSEvent("error", gin.H{"error": "Failed to generate answer"})
+		c.Writer.Flush()
+		return
+	}
+
+	start()
+	c.SSEvent("done", result)
+	c.Writer.Flush()
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type TagController struct {
+	tagService service.TagService
+}
+
+func NewTagController(tagService service.TagService) *TagController {
+	return &TagController{
+		tagService: tagService,
+	}
+}
+
+type tagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateTag creates a new tag
+func (tc *TagController) CreateTag(c *gin.Context) {
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag format"})
+		return
+	}
+
+	tag, err := tc.tagService.CreateTag(c.Request.Context(), req.Name)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// RenameTag changes the name of an existing tag
+func (tc *TagController) RenameTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag format"})
+		return
+	}
+
+	tag, err := tc.tagService.RenameTag(c.Request.Context(), c.GetUint("userID"), uint(id), req.Name)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag deletes a tag and detaches it from all documents
+func (tc *TagController) DeleteTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	if err := tc.tagService.DeleteTag(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTag retrieves a specific tag
+func (tc *TagController) GetTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	tag, err := tc.tagService.GetTag(c.Request.Context(), uint(id))
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// ListTags retrieves tags together with their usage counts
+func (tc *TagController) ListTags(c *gin.Context) {
+	params := repository.TagListParams{
+		Page:     1,
+		PageSize: 50,
+	}
+
+	if page := c.Query("page"); page != "" {
+		if pageNum, err := strconv.Atoi(page); err == nil {
+			params.Page = pageNum
+		}
+	}
+
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if size, err := strconv.Atoi(pageSize); err == nil {
+			params.PageSize = size
+		}
+	}
+
+	params.Search = c.Query("search")
+	params.Namespace = c.Query("namespace")
+
+	tags, total, err := tc.tagService.ListTags(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":      tags,
+		"total":     total,
+		"page":      params.Page,
+		"page_size": params.PageSize,
+	})
+}
+
+// GetTagTree returns the tag hierarchy with per-node document counts
+func (tc *TagController) GetTagTree(c *gin.Context) {
+	tree, err := tc.tagService.GetTagTree(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tree})
+}
+
+// MergeTags moves all documents of a tag onto another tag and deletes the first one
+func (tc *TagController) MergeTags(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	var req struct {
+		TargetID uint `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tag, err := tc.tagService.MergeTags(c.Request.Context(), c.GetUint("userID"), uint(id), req.TargetID)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func respondTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+	case errors.Is(err, service.ErrTagExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Tag with this name already exists"})
+	case errors.Is(err, service.ErrTagHasChildren):
+		c.JSON(http.StatusConflict, gin.H{"error": "Tag has nested tags"})
+	case errors.Is(err, service.ErrTagAdminOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTagName), errors.Is(err, service.ErrMergeSameTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
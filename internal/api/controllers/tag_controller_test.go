@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTagService 模拟标签服务
+type MockTagService struct {
+	mock.Mock
+}
+
+func (m *MockTagService) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tag), args.Error(1)
+}
+
+func (m *MockTagService) RenameTag(ctx context.Context, userID, id uint, name string) (*models.Tag, error) {
+	args := m.Called(ctx, userID, id, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tag), args.Error(1)
+}
+
+func (m *MockTagService) DeleteTag(ctx context.Context, userID, id uint) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockTagService) GetTag(ctx context.Context, id uint) (*models.Tag, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tag), args.Error(1)
+}
+
+func (m *MockTagService) GetTagsByName(ctx context.Context, names []string) ([]models.Tag, error) {
+	args := m.Called(ctx, names)
+	return args.Get(0).([]models.Tag), args.Error(1)
+}
+
+func (m *MockTagService) ListTags(ctx context.Context, params repository.TagListParams) ([]models.TagWithCount, int64, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.TagWithCount), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTagService) GetTagTree(ctx context.Context) ([]*models.TagNode, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.TagNode), args.Error(1)
+}
+
+func (m *MockTagService) MergeTags(ctx context.Context, userID, sourceID, targetID uint) (*models.Tag, error) {
+	args := m.Called(ctx, userID, sourceID, targetID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tag), args.Error(1)
+}
+
+func (m *MockTagService) ResolveTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	args := m.Called(ctx, names)
+	return args.Get(0).([]models.Tag), args.Error(1)
+}
+
+func setupTagTest() (*gin.Engine, *MockTagService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockTagService)
+	controller := NewTagController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	tags := r.Group("/tags")
+	{
+		tags.POST("", controller.CreateTag)
+		tags.GET("", controller.ListTags)
+		tags.GET("/tree", controller.GetTagTree)
+		tags.GET("/:id", controller.GetTag)
+		tags.PUT("/:id", controller.RenameTag)
+		tags.DELETE("/:id", controller.DeleteTag)
+		tags.POST("/:id/merge", controller.MergeTags)
+	}
+
+	return r, mockService
+}
+
+func TestCreateTag(t *testing.T) {
+	r, mockService := setupTagTest()
+
+	tests := []struct {
+		name         string
+		request      map[string]string
+		setupMock    func()
+		expectedCode int
+	}{
+		{
+			name:    "Create new tag",
+			request: map[string]string{"name": "runbook"},
+			setupMock: func() {
+				mockService.On("CreateTag", mock.Anything, "runbook").
+					Return(&models.Tag{ID: 1, Name: "runbook"}, nil)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:    "Duplicate tag name",
+			request: map[string]string{"name": "existing"},
+			setupMock: func() {
+				mockService.On("CreateTag", mock.Anything, "existing").
+					Return(nil, service.ErrTagExists)
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Missing name",
+			request:      map[string]string{},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonReq, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest(http.MethodPost, "/tags", bytes.NewBuffer(jsonReq))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListTags(t *testing.T) {
+	r, mockService := setupTagTest()
+
+	mockService.On("ListTags", mock.Anything, repository.TagListParams{Search: "run", Page: 1, PageSize: 50}).
+		Return([]models.TagWithCount{{Tag: models.Tag{ID: 1, Name: "runbook"}, DocumentCount: 3}}, int64(1), nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/tags?search=run", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Tags  []models.TagWithCount `json:"tags"`
+		Total int64                 `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.Total)
+	assert.Equal(t, int64(3), response.Tags[0].DocumentCount)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetTagTree(t *testing.T) {
+	r, mockService := setupTagTest()
+
+	tree := []*models.TagNode{{
+		ID:            1,
+		Name:          "team",
+		FullName:      "team",
+		DocumentCount: 2,
+		Children: []*models.TagNode{
+			{ID: 2, Name: "platform", FullName: "team/platform", DocumentCount: 1},
+		},
+	}}
+	mockService.On("GetTagTree", mock.Anything).Return(tree, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/tags/tree", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Tags []*models.TagNode `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Tags, 1)
+	assert.Equal(t, "team/platform", response.Tags[0].Children[0].FullName)
+	mockService.AssertExpectations(t)
+}
+
+func TestMergeTags(t *testing.T) {
+	r, mockService := setupTagTest()
+
+	tests := []struct {
+		name         string
+		tagID        string
+		request      map[string]uint
+		setupMock    func()
+		expectedCode int
+	}{
+		{
+			name:    "Merge into another tag",
+			tagID:   "1",
+			request: map[string]uint{"target_id": 2},
+			setupMock: func() {
+				mockService.On("MergeTags", mock.Anything, uint(1), uint(1), uint(2)).
+					Return(&models.Tag{ID: 2, Name: "ops"}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:    "Merge into itself",
+			tagID:   "3",
+			request: map[string]uint{"target_id": 3},
+			setupMock: func() {
+				mockService.On("MergeTags", mock.Anything, uint(1), uint(3), uint(3)).
+					Return(nil, service.ErrMergeSameTag)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Tag with nested tags",
+			tagID:   "4",
+			request: map[string]uint{"target_id": 2},
+			setupMock: func() {
+				mockService.On("MergeTags", mock.Anything, uint(1), uint(4), uint(2)).
+					Return(nil, service.ErrTagHasChildren)
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:    "Not an admin",
+			tagID:   "5",
+			request: map[string]uint{"target_id": 2},
+			setupMock: func() {
+				mockService.On("MergeTags", mock.Anything, uint(1), uint(5), uint(2)).
+					Return(nil, service.ErrTagAdminOnly)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:    "Unknown tag",
+			tagID:   "9",
+			request: map[string]uint{"target_id": 2},
+			setupMock: func() {
+				mockService.On("MergeTags", mock.Anything, uint(1), uint(9), uint(2)).
+					Return(nil, service.ErrTagNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonReq, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest(http.MethodPost, "/tags/"+tt.tagID+"/merge", bytes.NewBuffer(jsonReq))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type TemplateController struct {
+	templateService service.TemplateService
+}
+
+func NewTemplateController(templateService service.TemplateService) *TemplateController {
+	return &TemplateController{
+		templateService: templateService,
+	}
+}
+
+type saveTemplateRequest struct {
+	DocumentID    uint                      `json:"document_id" binding:"required"`
+	Name          string                    `json:"name" binding:"required"`
+	Description   string                    `json:"description"`
+	Category      string                    `json:"category"` // e.g. adr, postmortem, rfc
+	Title         string                    `json:"title"`
+	Variables     []models.TemplateVariable `json:"variables"`
+	DefaultTags   []string                  `json:"default_tags"`
+	DefaultStatus string                    `json:"default_status"`
+}
+
+type createFromTemplateRequest struct {
+	Title    string            `json:"title"`
+	Values   map[string]string `json:"values"`
+	Tags     []string          `json:"tags"`
+	ParentID *uint             `json:"parent_id"`
+}
+
+// SaveTemplate saves a document as a template of the workspace
+func (tc *TemplateController) SaveTemplate(c *gin.Context) {
+	workspaceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+		return
+	}
+
+	var req saveTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	template, err := tc.templateService.SaveTemplate(c.Request.Context(), service.SaveTemplateRequest{
+		UserID:        c.GetUint("userID"),
+		WorkspaceID:   uint(workspaceID),
+		DocumentID:    req.DocumentID,
+		Name:          req.Name,
+		Description:   req.Description,
+		Category:      req.Category,
+		Title:         req.Title,
+		Variables:     req.Variables,
+		DefaultTags:   req.DefaultTags,
+		DefaultStatus: req.DefaultStatus,
+	})
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// ListTemplates lists the templates of a workspace, optionally of one
+// category
+func (tc *TemplateController) ListTemplates(c *gin.Context) {
+	workspaceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+		return
+	}
+
+	templates, err := tc.templateService.ListTemplates(c.Request.Context(), c.GetUint("userID"), uint(workspaceID), c.Query("category"))
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+func (tc *TemplateController) GetTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	template, err := tc.templateService.GetTemplate(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (tc *TemplateController) DeleteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	if err := tc.templateService.DeleteTemplate(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// CreateDocument creates a document from a template, filling in its
+// placeholders from the given values
+func (tc *TemplateController) CreateDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var req createFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	doc, err := tc.templateService.CreateDocument(c.Request.Context(), service.CreateFromTemplateRequest{
+		UserID:     c.GetUint("userID"),
+		TemplateID: uint(id),
+		Title:      req.Title,
+		Values:     req.Values,
+		Tags:       req.Tags,
+		ParentID:   req.ParentID,
+	})
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
+
+func respondTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound), errors.Is(err, service.ErrWorkspaceNotFound),
+		errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTemplateNameExists), errors.Is(err, service.ErrDocumentTitleExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTemplateNotDeletable):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTemplateNameRequired), errors.Is(err, service.ErrInvalidTemplateVariable),
+		errors.Is(err, service.ErrInvalidTemplateStatus), errors.Is(err, service.ErrTemplateValuesMissing),
+		errors.Is(err, service.ErrTemplateTitleRequired), errors.Is(err, service.ErrInvalidTagName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process template request"})
+	}
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTemplateService 模拟模板服务
+type MockTemplateService struct {
+	mock.Mock
+}
+
+func (m *MockTemplateService) SaveTemplate(ctx context.Context, req service.SaveTemplateRequest) (*models.Template, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Template), args.Error(1)
+}
+
+func (m *MockTemplateService) ListTemplates(ctx context.Context, userID, workspaceID uint, category string) ([]models.Template, error) {
+	args := m.Called(ctx, userID, workspaceID, category)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Template), args.Error(1)
+}
+
+func (m *MockTemplateService) GetTemplate(ctx context.Context, userID, templateID uint) (*models.Template, error) {
+	args := m.Called(ctx, userID, templateID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Template), args.Error(1)
+}
+
+func (m *MockTemplateService) DeleteTemplate(ctx context.Context, userID, templateID uint) error {
+	return m.Called(ctx, userID, templateID).Error(0)
+}
+
+func (m *MockTemplateService) CreateDocument(ctx context.Context, req service.CreateFromTemplateRequest) (*models.Document, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Document), args.Error(1)
+}
+
+func setupTemplateTest() (*gin.Engine, *MockTemplateService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockTemplateService)
+	controller := NewTemplateController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/workspaces/:id/templates", controller.SaveTemplate)
+	r.GET("/workspaces/:id/templates", controller.ListTemplates)
+	r.GET("/templates/:id", controller.GetTemplate)
+	r.DELETE("/templates/:id", controller.DeleteTemplate)
+	r.POST("/templates/:id/documents", controller.CreateDocument)
+
+	return r, mockService
+}
+
+func TestTemplateController(t *testing.T) {
+	r, mockService := setupTemplateTest()
+
+	adr := &models.Template{
+		ID:          5,
+		WorkspaceID: 2,
+		Name:        "ADR",
+		Category:    "adr",
+		Title:       "ADR-{{number}}: {{decision}}",
+		Variables:   []models.TemplateVariable{{Name: "number", Required: true}, {Name: "decision"}},
+		DefaultTags: []string{"adr"},
+	}
+	workspaceID := uint(2)
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         map[string]interface{}
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Save document as template",
+			method: http.MethodPost,
+			url:    "/workspaces/2/templates",
+			body: map[string]interface{}{
+				"document_id": 8,
+				"name":        "ADR",
+				"category":    "adr",
+				"variables":   []map[string]interface{}{{"name": "number", "required": true}},
+			},
+			setupMock: func() {
+				mockService.On("SaveTemplate", mock.Anything, service.SaveTemplateRequest{
+					UserID:      1,
+					WorkspaceID: 2,
+					DocumentID:  8,
+					Name:        "ADR",
+					Category:    "adr",
+					Variables:   []models.TemplateVariable{{Name: "number", Required: true}},
+				}).Return(adr, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"name":"ADR"`, `"default_tags":["adr"]`},
+		},
+		{
+			name:   "Save template with taken name",
+			method: http.MethodPost,
+			url:    "/workspaces/2/templates",
+			body:   map[string]interface{}{"document_id": 9, "name": "ADR"},
+			setupMock: func() {
+				mockService.On("SaveTemplate", mock.Anything, service.SaveTemplateRequest{UserID: 1, WorkspaceID: 2, DocumentID: 9, Name: "ADR"}).
+					Return(nil, service.ErrTemplateNameExists).Once()
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Save template without document",
+			method:       http.MethodPost,
+			url:          "/workspaces/2/templates",
+			body:         map[string]interface{}{"name": "ADR"},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "List templates of a category",
+			method: http.MethodGet,
+			url:    "/workspaces/2/templates?category=adr",
+			setupMock: func() {
+				mockService.On("ListTemplates", mock.Anything, uint(1), uint(2), "adr").
+					Return([]models.Template{*adr}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"templates":[{"id":5`},
+		},
+		{
+			name:   "List templates of another workspace",
+			method: http.MethodGet,
+			url:    "/workspaces/3/templates",
+			setupMock: func() {
+				mockService.On("ListTemplates", mock.Anything, uint(1), uint(3), "").
+					Return(nil, service.ErrWorkspaceNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "Get template",
+			method: http.MethodGet,
+			url:    "/templates/5",
+			setupMock: func() {
+				mockService.On("GetTemplate", mock.Anything, uint(1), uint(5)).Return(adr, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"variables":[{"name":"number","required":true},{"name":"decision"}]`},
+		},
+		{
+			name:   "Delete template of another member",
+			method: http.MethodDelete,
+			url:    "/templates/6",
+			setupMock: func() {
+				mockService.On("DeleteTemplate", mock.Anything, uint(1), uint(6)).
+					Return(service.ErrTemplateNotDeletable).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "Create document from template",
+			method: http.MethodPost,
+			url:    "/templates/5/documents",
+			body: map[string]interface{}{
+				"values": map[string]string{"number": "002", "decision": "Use Kafka"},
+				"tags":   []string{"messaging"},
+			},
+			setupMock: func() {
+				mockService.On("CreateDocument", mock.Anything, service.CreateFromTemplateRequest{
+					UserID:     1,
+					TemplateID: 5,
+					Values:     map[string]string{"number": "002", "decision": "Use Kafka"},
+					Tags:       []string{"messaging"},
+				}).Return(&models.Document{ID: 12, Title: "ADR-002: Use Kafka", WorkspaceID: &workspaceID}, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"title":"ADR-002: Use Kafka"`, `"workspace_id":2`},
+		},
+		{
+			name:   "Create document without required values",
+			method: http.MethodPost,
+			url:    "/templates/5/documents",
+			body:   map[string]interface{}{},
+			setupMock: func() {
+				mockService.On("CreateDocument", mock.Anything, service.CreateFromTemplateRequest{UserID: 1, TemplateID: 5}).
+					Return(nil, fmt.Errorf("%w: number", service.ErrTemplateValuesMissing)).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: []string{"missing values for required template variables: number"},
+		},
+		{
+			name:   "Create document with existing title",
+			method: http.MethodPost,
+			url:    "/templates/5/documents",
+			body:   map[string]interface{}{"title": "ADR-001: Use PostgreSQL"},
+			setupMock: func() {
+				mockService.On("CreateDocument", mock.Anything, service.CreateFromTemplateRequest{UserID: 1, TemplateID: 5, Title: "ADR-001: Use PostgreSQL"}).
+					Return(nil, service.ErrDocumentTitleExists).Once()
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			var body []byte
+			if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWorkspaceService 模拟工作区服务
+type MockWorkspaceService struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceService) CreateWorkspace(ctx context.Context, userID uint, name, description string) (*models.Workspace, error) {
+	args := m.Called(ctx, userID, name, description)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) ListWorkspaces(ctx context.Context, userID uint) ([]models.Workspace, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) GetWorkspace(ctx context.Context, userID, workspaceID uint) (*models.Workspace, error) {
+	args := m.Called(ctx, userID, workspaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) Membership(ctx context.Context, userID, workspaceID uint) (*models.WorkspaceMember, error) {
+	args := m.Called(ctx, userID, workspaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockWorkspaceService) AddMember(ctx context.Context, userID, workspaceID, memberID uint, role string) (*models.WorkspaceMember, error) {
+	args := m.Called(ctx, userID, workspaceID, memberID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockWorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID uint) error {
+	return m.Called(ctx, userID, workspaceID, memberID).Error(0)
+}
+
+func setupWorkspaceTest() (*gin.Engine, *MockWorkspaceService) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockWorkspaceService)
+	controller := NewWorkspaceController(mockService)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/workspaces", controller.CreateWorkspace)
+	r.GET("/workspaces", controller.ListWorkspaces)
+	r.GET("/workspaces/:id", controller.GetWorkspace)
+	r.POST("/workspaces/:id/members", controller.AddMember)
+	r.DELETE("/workspaces/:id/members/:user_id", controller.RemoveMember)
+
+	return r, mockService
+}
+
+func TestWorkspaceController(t *testing.T) {
+	r, mockService := setupWorkspaceTest()
+
+	workspace := &models.Workspace{ID: 2, Name: "Platform", CreatorID: 1}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         map[string]interface{}
+		setupMock    func()
+		expectedCode int
+		expectedBody []string
+	}{
+		{
+			name:   "Create workspace",
+			method: http.MethodPost,
+			url:    "/workspaces",
+			body:   map[string]interface{}{"name": "Platform"},
+			setupMock: func() {
+				mockService.On("CreateWorkspace", mock.Anything, uint(1), "Platform", "").Return(workspace, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: []string{`"name":"Platform"`},
+		},
+		{
+			name:         "Create workspace without name",
+			method:       http.MethodPost,
+			url:          "/workspaces",
+			body:         map[string]interface{}{"description": "Platform team"},
+			setupMock:    func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "List workspaces",
+			method: http.MethodGet,
+			url:    "/workspaces",
+			setupMock: func() {
+				mockService.On("ListWorkspaces", mock.Anything, uint(1)).Return([]models.Workspace{*workspace}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"workspaces":[{"id":2`},
+		},
+		{
+			name:   "Get workspace of others",
+			method: http.MethodGet,
+			url:    "/workspaces/3",
+			setupMock: func() {
+				mockService.On("GetWorkspace", mock.Anything, uint(1), uint(3)).Return(nil, service.ErrWorkspaceNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "Add member",
+			method: http.MethodPost,
+			url:    "/workspaces/2/members",
+			body:   map[string]interface{}{"user_id": 4},
+			setupMock: func() {
+				mockService.On("AddMember", mock.Anything, uint(1), uint(2), uint(4), "").
+					Return(&models.WorkspaceMember{WorkspaceID: 2, UserID: 4, Role: models.WorkspaceRoleMember}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`"role":"member"`},
+		},
+		{
+			name:   "Add member as non-owner",
+			method: http.MethodPost,
+			url:    "/workspaces/2/members",
+			body:   map[string]interface{}{"user_id": 5, "role": "owner"},
+			setupMock: func() {
+				mockService.On("AddMember", mock.Anything, uint(1), uint(2), uint(5), "owner").
+					Return(nil, service.ErrNotWorkspaceOwner).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "Remove member",
+			method: http.MethodDelete,
+			url:    "/workspaces/2/members/4",
+			setupMock: func() {
+				mockService.On("RemoveMember", mock.Anything, uint(1), uint(2), uint(4)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			var body []byte
+			if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
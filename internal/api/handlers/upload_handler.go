@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -37,10 +38,54 @@ func NewUploadHandler(config UploadConfig, fileService service.FileService) *Upl
 }
 
 func (h *UploadHandler) HandleFileUpload(c *gin.Context) {
+	fileRecord, ok := h.receiveFile(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"id":            fileRecord.ID,
+		"original_name": fileRecord.OriginalName,
+		"size":          fileRecord.Size,
+		"content_type":  fileRecord.ContentType,
+		"text_status":   fileRecord.TextStatus,
+		"created_at":    fileRecord.CreatedAt,
+	})
+}
+
+// HandleDocumentImport 上传文件并导入为新文档，Word 文档转换为 Markdown
+func (h *UploadHandler) HandleDocumentImport(c *gin.Context) {
+	fileRecord, ok := h.receiveFile(c)
+	if !ok {
+		return
+	}
+
+	doc, err := h.fileService.ImportAsDocument(c.Request.Context(), fileRecord.ID, fileRecord.UploaderID, c.PostForm("title"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNoExtractedText), errors.Is(err, service.ErrDocumentTitleExists):
+			c.JSON(422, gin.H{"error": err.Error(), "file_id": fileRecord.ID})
+		default:
+			utils.Logger.Error("Failed to import file",
+				zap.Error(err),
+				zap.String("filename", fileRecord.OriginalName))
+			c.JSON(500, gin.H{"error": "Failed to import file", "file_id": fileRecord.ID})
+		}
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"file_id":  fileRecord.ID,
+		"document": doc,
+	})
+}
+
+// receiveFile 校验并保存上传的文件，失败时已写入错误响应
+func (h *UploadHandler) receiveFile(c *gin.Context) (*models.File, bool) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(400, gin.H{"error": "No file uploaded"})
-		return
+		return nil, false
 	}
 	defer file.Close()
 
@@ -49,7 +94,7 @@ func (h *UploadHandler) HandleFileUpload(c *gin.Context) {
 		c.JSON(400, gin.H{
 			"error": fmt.Sprintf("File too large. Maximum size is %d MB", h.maxSize/(1024*1024)),
 		})
-		return
+		return nil, false
 	}
 
 	// 验证文件类型
@@ -57,7 +102,7 @@ func (h *UploadHandler) HandleFileUpload(c *gin.Context) {
 		c.JSON(400, gin.H{
 			"error": "File type not allowed. Supported types: PDF, DOC, DOCX, TXT, MD",
 		})
-		return
+		return nil, false
 	}
 
 	// 创建上传目录
@@ -67,7 +112,7 @@ func (h *UploadHandler) HandleFileUpload(c *gin.Context) {
 			zap.Error(err),
 			zap.String("path", uploadPath))
 		c.JSON(500, gin.H{"error": "Failed to create upload directory"})
-		return
+		return nil, false
 	}
 
 	// 生成存储文件名（使用UUID）
@@ -80,7 +125,7 @@ func (h *UploadHandler) HandleFileUpload(c *gin.Context) {
 			zap.Error(err),
 			zap.String("filename", header.Filename))
 		c.JSON(500, gin.H{"error": "Failed to save file"})
-		return
+		return nil, false
 	}
 
 	// 创建文件记录
@@ -97,16 +142,10 @@ func (h *UploadHandler) HandleFileUpload(c *gin.Context) {
 		// 如果数据库保存失败，删除已上传的文件
 		os.Remove(storagePath)
 		c.JSON(500, gin.H{"error": "Failed to save file metadata"})
-		return
+		return nil, false
 	}
 
-	c.JSON(200, gin.H{
-		"id":            fileRecord.ID,
-		"original_name": fileRecord.OriginalName,
-		"size":          fileRecord.Size,
-		"content_type":  fileRecord.ContentType,
-		"created_at":    fileRecord.CreatedAt,
-	})
+	return fileRecord, true
 }
 
 func (h *UploadHandler) isAllowedFileType(filename string) bool {
@@ -6,6 +6,7 @@ import (
 	"github.com/Zhaoyikaiii/docmind/pkg/auth"
 	"github.com/Zhaoyikaiii/docmind/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
@@ -13,6 +14,10 @@ import (
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		// 浏览器无法为 WebSocket 设置请求头，升级请求可通过查询参数传递令牌
+		if token := c.Query("access_token"); authHeader == "" && token != "" && websocket.IsWebSocketUpgrade(c.Request) {
+			authHeader = "Bearer " + token
+		}
 		if authHeader == "" {
 			c.JSON(401, gin.H{"error": "Authorization header is required"})
 			c.Abort()
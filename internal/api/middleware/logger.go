@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/Zhaoyikaiii/docmind/pkg/utils"
@@ -13,7 +14,7 @@ func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL.RawQuery)
 
 		requestID := utils.GenerateRequestID()
 		c.Set("RequestID", requestID)
@@ -35,3 +36,18 @@ func RequestLogger() gin.HandlerFunc {
 		)
 	}
 }
+
+// redactQuery hides the access token WebSocket upgrades pass in the query
+// so that it does not end up in the logs
+func redactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// 无法解析时不记录原始查询，避免泄露令牌
+		return ""
+	}
+	if !values.Has("access_token") {
+		return rawQuery
+	}
+	values.Set("access_token", "REDACTED")
+	return values.Encode()
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, dc *controllers.DocumentController, uh *handlers.UploadHandler, fc *controllers.FileController) {
+func SetupRoutes(r *gin.Engine, dc *controllers.DocumentController, uh *handlers.UploadHandler, fc *controllers.FileController, tc *controllers.TagController, sc *controllers.SearchController, ac *controllers.AskController, cc *controllers.ChatController, ec *controllers.ExportController, ic *controllers.ImportController, wc *controllers.WorkspaceController, tmc *controllers.TemplateController, cmc *controllers.CommentController, nc *controllers.NotificationController, clc *controllers.CollabController, lc *controllers.LockController, lnc *controllers.LinkController, hc *controllers.HomeController, rc *controllers.ReviewController) {
 	// Apply global middleware
 	middleware.ApplyMiddleware(r)
 
@@ -30,19 +30,123 @@ func SetupRoutes(r *gin.Engine, dc *controllers.DocumentController, uh *handlers
 		docs := protected.Group("/documents")
 		{
 			docs.POST("", dc.CreateDocument)
+			docs.POST("/import", ic.ImportArchive)
 			docs.PUT("/:id", dc.UpdateDocument)
 			docs.DELETE("/:id", dc.DeleteDocument)
 			docs.GET("/:id", dc.GetDocument)
+			docs.GET("/by-path/*path", dc.GetDocumentByPath)
 			docs.GET("", dc.ListDocuments)
+			docs.GET("/graph", lnc.GetGraph)
+			docs.GET("/broken-links", lnc.ListBrokenLinks)
 			docs.POST("/:id/versions", dc.CreateVersion)
 			docs.GET("/:id/versions", dc.GetVersions)
 			docs.POST("/:id/tags", dc.ManageTags)
+			docs.GET("/:id/tag-suggestions", dc.GetTagSuggestions)
+			docs.GET("/:id/render", dc.RenderDocument)
+			docs.GET("/:id/export", ec.ExportDocument)
+			docs.POST("/:id/comments", cmc.AddComment)
+			docs.GET("/:id/comments", cmc.ListComments)
+			docs.GET("/:id/collab", clc.EditDocument)
+			docs.POST("/:id/lock", lc.CheckOut)
+			docs.GET("/:id/lock", lc.GetLock)
+			docs.DELETE("/:id/lock", lc.CheckIn)
+			docs.POST("/:id/lock/heartbeat", lc.Heartbeat)
+			docs.POST("/:id/lock/break", lc.BreakLock)
+			docs.GET("/:id/backlinks", lnc.GetBacklinks)
+			docs.POST("/:id/star", hc.Star)
+			docs.DELETE("/:id/star", hc.Unstar)
+			docs.POST("/:id/reviews", rc.RequestReview)
+			docs.POST("/:id/reviews/complete", rc.CompleteReview)
+		}
+
+		// Home routes
+		home := protected.Group("/home")
+		{
+			home.GET("", hc.GetHome)
+			home.GET("/favorites", hc.ListFavorites)
+			home.GET("/recent", hc.ListRecentlyViewed)
+			home.GET("/updates", hc.ListRecentlyUpdated)
+			home.GET("/reviews", hc.ListAwaitingReview)
+		}
+
+		// Comment routes
+		comments := protected.Group("/comments")
+		{
+			comments.PUT("/:id", cmc.EditComment)
+			comments.DELETE("/:id", cmc.DeleteComment)
+			comments.POST("/:id/resolve", cmc.ResolveThread)
+			comments.POST("/:id/reopen", cmc.ReopenThread)
+		}
+
+		// Notification routes
+		notifications := protected.Group("/notifications")
+		{
+			notifications.GET("", nc.ListNotifications)
+			notifications.POST("/read", nc.MarkRead)
+		}
+
+		// Export job routes
+		exports := protected.Group("/exports")
+		{
+			exports.GET("/:id", ec.GetExportJob)
+			exports.GET("/:id/download", ec.DownloadExport)
+		}
+
+		// Tag routes
+		tags := protected.Group("/tags")
+		{
+			tags.POST("", tc.CreateTag)
+			tags.GET("", tc.ListTags)
+			tags.GET("/tree", tc.GetTagTree)
+			tags.GET("/:id", tc.GetTag)
+			tags.PUT("/:id", tc.RenameTag)
+			tags.DELETE("/:id", tc.DeleteTag)
+			tags.POST("/:id/merge", tc.MergeTags)
+		}
+
+		// Workspace routes
+		workspaces := protected.Group("/workspaces")
+		{
+			workspaces.POST("", wc.CreateWorkspace)
+			workspaces.GET("", wc.ListWorkspaces)
+			workspaces.GET("/:id", wc.GetWorkspace)
+			workspaces.POST("/:id/members", wc.AddMember)
+			workspaces.DELETE("/:id/members/:user_id", wc.RemoveMember)
+			workspaces.POST("/:id/templates", tmc.SaveTemplate)
+			workspaces.GET("/:id/templates", tmc.ListTemplates)
+		}
+
+		// Template routes
+		templates := protected.Group("/templates")
+		{
+			templates.GET("/:id", tmc.GetTemplate)
+			templates.DELETE("/:id", tmc.DeleteTemplate)
+			templates.POST("/:id/documents", tmc.CreateDocument)
+		}
+
+		// Search routes
+		protected.GET("/search", sc.Search)
+		protected.GET("/search/semantic", sc.SemanticSearch)
+
+		// Question answering routes
+		protected.POST("/ask", ac.Ask)
+
+		// Chat routes
+		chat := protected.Group("/chat")
+		{
+			chat.POST("/sessions", cc.CreateSession)
+			chat.GET("/sessions", cc.ListSessions)
+			chat.GET("/sessions/:id", cc.GetSession)
+			chat.DELETE("/sessions/:id", cc.DeleteSession)
+			chat.POST("/sessions/:id/messages", cc.SendMessage)
+			chat.PUT("/messages/:id/feedback", cc.SetFeedback)
 		}
 
 		// File upload routes
 		upload := protected.Group("/upload")
 		{
 			upload.POST("/file", uh.HandleFileUpload)
+			upload.POST("/document", uh.HandleDocumentImport)
 		}
 
 		// File routes
@@ -52,6 +156,9 @@ func SetupRoutes(r *gin.Engine, dc *controllers.DocumentController, uh *handlers
 			files.GET("", fc.ListFiles)
 			files.DELETE("/:id", fc.DeleteFile)
 			files.POST("/:id/document", fc.AssociateWithDocument)
+			files.GET("/:id/content", fc.GetFileContent)
+			files.GET("/:id/text", fc.GetFileText)
+			files.POST("/:id/import", fc.ImportAsDocument)
 		}
 	}
 } 
\ No newline at end of file
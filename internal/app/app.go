@@ -0,0 +1,322 @@
+// Package app builds the services of the DocMind server from its settings
+// and serves them through the API routes.
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/api/controllers"
+	"github.com/Zhaoyikaiii/docmind/internal/api/handlers"
+	"github.com/Zhaoyikaiii/docmind/internal/api/routes"
+	"github.com/Zhaoyikaiii/docmind/internal/export"
+	"github.com/Zhaoyikaiii/docmind/internal/extract"
+	"github.com/Zhaoyikaiii/docmind/internal/importer"
+	"github.com/Zhaoyikaiii/docmind/internal/render"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+	"github.com/Zhaoyikaiii/docmind/internal/service"
+	"github.com/Zhaoyikaiii/docmind/internal/storage"
+	"github.com/Zhaoyikaiii/docmind/internal/vector"
+	"github.com/Zhaoyikaiii/docmind/pkg/config"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// collabPersistInterval is how often collaborative sessions are saved
+const collabPersistInterval = 5 * time.Second
+
+// App holds the controllers of the server and the background workers of
+// its services
+type App struct {
+	documents     *controllers.DocumentController
+	uploads       *handlers.UploadHandler
+	files         *controllers.FileController
+	tags          *controllers.TagController
+	search        *controllers.SearchController
+	ask           *controllers.AskController
+	chat          *controllers.ChatController
+	exports       *controllers.ExportController
+	imports       *controllers.ImportController
+	workspaces    *controllers.WorkspaceController
+	templates     *controllers.TemplateController
+	comments      *controllers.CommentController
+	notifications *controllers.NotificationController
+	collab        *controllers.CollabController
+	locks         *controllers.LockController
+	links         *controllers.LinkController
+	home          *controllers.HomeController
+	reviews       *controllers.ReviewController
+
+	// closers stop the workers, those of services before the indexes they
+	// write to
+	closers []func()
+}
+
+// New builds the services on db. It migrates the document slugs, and on
+// PostgreSQL the search columns, and rebuilds the search and vector indexes when they
+// start empty.
+func New(ctx context.Context, db *gorm.DB, fileOperator storage.FileOperator, upload handlers.UploadConfig, settings *config.Settings) (*App, error) {
+	a := &App{}
+	if err := a.build(ctx, db, fileOperator, upload, settings); err != nil {
+		a.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *App) build(ctx context.Context, db *gorm.DB, fileOperator storage.FileOperator, upload handlers.UploadConfig, settings *config.Settings) error {
+	docRepo := repository.NewDocumentRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	lockRepo := repository.NewDocumentLockRepository(db)
+	reviewRepo := repository.NewReviewRequestRepository(db)
+	fileRepo := repository.NewFileRepository(db)
+
+	searchRepo, err := repository.NewSearchRepository(db, settings.Search.TextConfig)
+	if err != nil {
+		return err
+	}
+	// 全文检索的列和索引使用 PostgreSQL 的语法
+	if repository.IsPostgres(db) {
+		if err := searchRepo.Migrate(ctx); err != nil {
+			return fmt.Errorf("failed to migrate search: %w", err)
+		}
+	}
+	if err := service.MigrateDocumentSlugs(ctx, docRepo); err != nil {
+		return fmt.Errorf("failed to migrate document slugs: %w", err)
+	}
+
+	engine, err := a.searchEngine(settings.Search)
+	if err != nil {
+		return err
+	}
+	embedder, err := newEmbedder(settings.AI.Embedding)
+	if err != nil {
+		return err
+	}
+	model, err := newChatModel(settings.AI.Chat)
+	if err != nil {
+		return err
+	}
+	// 向量索引与搜索索引按相同的间隔写盘
+	store, err := vector.NewHNSW(settings.AI.VectorDir, vector.Options{
+		Dimensions:    embedder.Dimensions(),
+		FlushInterval: time.Duration(settings.Search.FlushInterval) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	a.closers = append(a.closers, closeIndex("vector store", store.Close))
+
+	summarizer := ai.NewExtractiveSummarizer(settings.AI.Summary.Sentences, settings.AI.Summary.KeyPoints)
+	switch settings.AI.Summary.Provider {
+	case "", config.ProviderExtractive:
+	case config.ProviderModel:
+		summarizer = ai.NewModelSummarizer(model, summarizer, settings.AI.Summary.KeyPoints)
+	default:
+		return fmt.Errorf("unsupported summary provider: %s", settings.AI.Summary.Provider)
+	}
+
+	workspaces := service.NewWorkspaceService(repository.NewWorkspaceRepository(db), userRepo)
+	tags := service.NewTagService(repository.NewTagRepository(db), userRepo)
+	notifications := service.NewNotificationService(repository.NewNotificationRepository(db))
+	semantic := service.NewSemanticSearchService(embedder, store, docRepo)
+	indexer := service.NewSemanticIndexer(embedder, store, ai.NewChunker(settings.AI.ChunkSize, settings.AI.ChunkOverlap))
+	summaries := service.NewSummaryService(docRepo, repository.NewDocumentSummaryRepository(db), summarizer)
+	renderer := service.NewRenderService(docRepo, render.NewMarkdownRenderer(), settings.Render.CacheSize)
+	comments := service.NewCommentService(commentRepo, docRepo, userRepo, notifications)
+	chat := service.NewChatService(repository.NewChatRepository(db), docRepo, tags, semantic, model, settings.AI.AskSources)
+	a.closers = append(a.closers, indexer.Close, summaries.Close, comments.Close)
+
+	listeners := []service.DocumentListener{indexer, summaries, renderer, comments, chat}
+	if engine != nil {
+		listeners = append(listeners, service.NewSearchIndexer(engine))
+	}
+	// 标签建议依赖词频统计，其他引擎没有时用只在内存中的索引统计
+	stats, ok := engine.(search.CorpusStats)
+	if !ok {
+		index, err := corpusIndex(ctx, docRepo, settings.Search)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, service.NewSearchIndexer(index))
+		stats = index
+	}
+	var reviewer ai.ChatModel
+	if settings.AI.TagSuggestions.UseModel {
+		reviewer = model
+	}
+	suggester := service.NewTagSuggester(repository.NewTagSuggestionRepository(db), docRepo, repository.NewTagRepository(db), stats, reviewer, service.TagSuggestOptions{
+		Limit:     settings.AI.TagSuggestions.Limit,
+		AutoApply: settings.AI.TagSuggestions.AutoApply,
+		Threshold: settings.AI.TagSuggestions.AutoApplyThreshold,
+	})
+	a.closers = append(a.closers, suggester.Close)
+	listeners = append(listeners, suggester)
+
+	documents := service.NewDocumentService(docRepo, tags, engine, summaries, commentRepo, lockRepo,
+		repository.NewDocumentSlugRepository(db), workspaces, listeners...)
+	links := service.NewLinkService(repository.NewDocumentLinkRepository(db), docRepo, documents)
+	collab := service.NewCollabService(docRepo, documents, workspaces, userRepo, lockRepo, collabPersistInterval)
+	documents.Subscribe(links)
+	documents.Subscribe(collab)
+
+	exporters := export.NewRegistry()
+	if settings.Export.PDFFont != "" {
+		exporters.Register("pdf", export.NewPDF(settings.Export.PDFFont))
+	}
+	exporter := service.NewDocumentExporter(docRepo, fileRepo, repository.NewExportJobRepository(db), fileOperator, renderer, exporters, service.ExportOptions{
+		MaxSyncDocuments: settings.Export.MaxSyncDocuments,
+		MaxSyncBytes:     settings.Export.MaxSyncBytes,
+	})
+	a.closers = append(a.closers, exporter.Close)
+
+	if index, ok := engine.(*search.Index); ok && index.Len() == 0 {
+		if err := service.RebuildSearchIndex(ctx, docRepo, index); err != nil {
+			return fmt.Errorf("failed to rebuild search index: %w", err)
+		}
+	}
+	if store.Len() == 0 {
+		if err := indexer.Rebuild(ctx, docRepo); err != nil {
+			return fmt.Errorf("failed to rebuild vector store: %w", err)
+		}
+	}
+
+	files := service.NewFileService(fileRepo, fileOperator, extract.NewRegistry(), documents, docRepo)
+	home := service.NewHomeService(repository.NewHomeRepository(db), reviewRepo, docRepo)
+
+	a.documents = controllers.NewDocumentController(documents, suggester, renderer, home)
+	a.uploads = handlers.NewUploadHandler(upload, files)
+	a.files = controllers.NewFileController(files)
+	a.tags = controllers.NewTagController(tags)
+	a.search = controllers.NewSearchController(service.NewSearchService(searchRepo), semantic)
+	a.ask = controllers.NewAskController(service.NewAskService(semantic, docRepo, model, settings.AI.AskSources))
+	a.chat = controllers.NewChatController(chat)
+	a.exports = controllers.NewExportController(exporter)
+	a.imports = controllers.NewImportController(
+		service.NewImportService(docRepo, documents, userRepo, fileRepo, fileOperator, importer.NewRegistry()),
+		settings.Import.MaxArchiveSize)
+	a.workspaces = controllers.NewWorkspaceController(workspaces)
+	a.templates = controllers.NewTemplateController(
+		service.NewTemplateService(repository.NewTemplateRepository(db), workspaces, docRepo, documents, userRepo))
+	a.comments = controllers.NewCommentController(comments)
+	a.notifications = controllers.NewNotificationController(notifications)
+	a.collab = controllers.NewCollabController(collab)
+	a.locks = controllers.NewLockController(
+		service.NewLockService(lockRepo, docRepo, workspaces, userRepo, notifications, service.DefaultLockTTL))
+	a.links = controllers.NewLinkController(links)
+	a.home = controllers.NewHomeController(home)
+	a.reviews = controllers.NewReviewController(
+		service.NewReviewService(reviewRepo, docRepo, workspaces, userRepo, notifications))
+	return nil
+}
+
+// SetupRoutes serves the controllers on r
+func (a *App) SetupRoutes(r *gin.Engine) {
+	routes.SetupRoutes(r, a.documents, a.uploads, a.files, a.tags, a.search, a.ask, a.chat, a.exports, a.imports,
+		a.workspaces, a.templates, a.comments, a.notifications, a.collab, a.locks, a.links, a.home, a.reviews)
+}
+
+// Close processes the queued work and persists the indexes
+func (a *App) Close() {
+	// 后创建的服务先关闭，索引最后关闭
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+// searchEngine opens the configured search engine; the database engine is
+// nil, leaving searches to SQL
+func (a *App) searchEngine(settings config.SearchSettings) (search.SearchEngine, error) {
+	switch settings.Engine {
+	case "", config.SearchEngineDatabase:
+		return nil, nil
+	case config.SearchEngineEmbedded:
+	default:
+		return nil, fmt.Errorf("unsupported search engine: %s", settings.Engine)
+	}
+
+	opts, err := searchOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	index, err := search.NewIndex(settings.DataDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, closeIndex("search index", index.Close))
+	return index, nil
+}
+
+// corpusIndex builds an in-memory index of the stored documents for the
+// term statistics of tag suggestions when the search engine has none
+func corpusIndex(ctx context.Context, repo repository.DocumentRepository, settings config.SearchSettings) (*search.Index, error) {
+	opts, err := searchOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	index, err := search.NewIndex("", search.Options{Dictionary: opts.Dictionary})
+	if err != nil {
+		return nil, err
+	}
+	if err := service.RebuildSearchIndex(ctx, repo, index); err != nil {
+		return nil, fmt.Errorf("failed to build tag suggestion statistics: %w", err)
+	}
+	return index, nil
+}
+
+func searchOptions(settings config.SearchSettings) (search.Options, error) {
+	opts := search.Options{FlushInterval: time.Duration(settings.FlushInterval) * time.Second}
+	if settings.CJKDictionary != "" {
+		dict, err := search.LoadDictionary(settings.CJKDictionary)
+		if err != nil {
+			return opts, err
+		}
+		opts.Dictionary = dict
+	}
+	return opts, nil
+}
+
+func newEmbedder(settings config.ModelSettings) (ai.Embedder, error) {
+	switch settings.Provider {
+	case "", config.ProviderHash:
+		return ai.NewHashEmbedder(settings.Dimensions), nil
+	case config.ProviderOpenAI:
+		return ai.NewOpenAIEmbedder(openAIConfig(settings))
+	}
+	return nil, fmt.Errorf("unsupported embedding provider: %s", settings.Provider)
+}
+
+func newChatModel(settings config.ModelSettings) (ai.ChatModel, error) {
+	switch settings.Provider {
+	case "", config.ProviderFake:
+		return ai.NewFakeChatModel(""), nil
+	case config.ProviderOpenAI:
+		return ai.NewOpenAIChatModel(openAIConfig(settings))
+	}
+	return nil, fmt.Errorf("unsupported chat provider: %s", settings.Provider)
+}
+
+func openAIConfig(settings config.ModelSettings) ai.OpenAIConfig {
+	return ai.OpenAIConfig{
+		BaseURL:    settings.BaseURL,
+		APIKey:     settings.APIKey,
+		Model:      settings.Model,
+		Dimensions: settings.Dimensions,
+	}
+}
+
+// closeIndex returns a closer logging the error of closing an index
+func closeIndex(name string, close func() error) func() {
+	return func() {
+		if err := close(); err != nil {
+			utils.Logger.Warn("Failed to close "+name, zap.Error(err))
+		}
+	}
+}
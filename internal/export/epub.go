@@ -0,0 +1,231 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EPUB writes an EPUB 3 book with one chapter holding the document and a
+// navigation document built from its table of contents. Images are bundled
+// in the book; other attachments cannot be read by e-readers and are listed
+// by name only.
+type EPUB struct{}
+
+func (EPUB) Extension() string   { return ".epub" }
+func (EPUB) ContentType() string { return "application/epub+zip" }
+
+// epubImageTypes are the image types EPUB readers must support
+var epubImageTypes = map[string]bool{
+	"image/gif":     true,
+	"image/jpeg":    true,
+	"image/png":     true,
+	"image/svg+xml": true,
+	"image/webp":    true,
+}
+
+type epubItem struct {
+	ID        string
+	Href      string
+	MediaType string
+}
+
+func (EPUB) Export(ctx context.Context, w io.Writer, doc *Document) error {
+	var images []epubItem
+	var unsupported []*Attachment
+	imageData := make(map[string][]byte)
+	body, unreferenced := doc.replaceAttachmentURLs(doc.HTML, func(a *Attachment) string {
+		if !epubImageTypes[a.ContentType] {
+			unsupported = append(unsupported, a)
+			return "#"
+		}
+		href := fmt.Sprintf("images/%d%s", a.ID, path.Ext(a.Name))
+		if _, ok := imageData[href]; !ok {
+			images = append(images, epubItem{ID: fmt.Sprintf("image-%d", a.ID), Href: href, MediaType: a.ContentType})
+			imageData[href] = a.Data
+		}
+		return href
+	})
+
+	unreferenced = append(unreferenced, unsupported...)
+
+	xhtml, err := toXHTML(body)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"ID":           fmt.Sprintf("urn:docmind:document:%d:%d", doc.ID, doc.UpdatedAt.Unix()),
+		"Title":        doc.Title,
+		"Author":       doc.Author,
+		"Language":     guessLanguage(doc.Markdown),
+		"Modified":     doc.UpdatedAt.UTC().Format(time.RFC3339),
+		"Images":       images,
+		"Body":         xhtml,
+		"TOC":          doc.TOC,
+		"Unreferenced": unreferenced,
+	}
+	if doc.UpdatedAt.IsZero() {
+		data["Modified"] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	archive := zip.NewWriter(w)
+	// mimetype 必须是第一个且不压缩的条目
+	mimetype, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	parts := []struct {
+		name     string
+		template string
+	}{
+		{"META-INF/container.xml", "container"},
+		{"OEBPS/content.opf", "opf"},
+		{"OEBPS/nav.xhtml", "nav"},
+		{"OEBPS/content.xhtml", "content"},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if err := epubTemplates.ExecuteTemplate(f, part.template, data); err != nil {
+			return err
+		}
+	}
+	for _, image := range images {
+		f, err := archive.Create("OEBPS/" + image.Href)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(imageData[image.Href]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// toXHTML reserializes an HTML fragment as well-formed XHTML
+func toXHTML(fragment string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	for _, n := range nodes {
+		if err := html.Render(&b, n); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// guessLanguage returns the language tag of the book: Chinese when the text
+// contains Han characters, English otherwise
+func guessLanguage(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return "zh"
+		}
+	}
+	return "en"
+}
+
+var epubTemplates = template.Must(template.New("epub").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`
+{{- define "container" -}}
+<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+{{end}}
+{{- define "opf" -}}
+<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{xml .ID}}</dc:identifier>
+    <dc:title>{{xml .Title}}</dc:title>
+    <dc:language>{{.Language}}</dc:language>
+    {{- if .Author}}
+    <dc:creator>{{xml .Author}}</dc:creator>
+    {{- end}}
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="content" href="content.xhtml" media-type="application/xhtml+xml"/>
+    {{- range .Images}}
+    <item id="{{.ID}}" href="{{xml .Href}}" media-type="{{.MediaType}}"/>
+    {{- end}}
+  </manifest>
+  <spine>
+    <itemref idref="content"/>
+  </spine>
+</package>
+{{end}}
+{{- define "nav" -}}
+<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="{{.Language}}">
+<head><title>{{xml .Title}}</title></head>
+<body>
+<nav epub:type="toc">
+<h1>{{xml .Title}}</h1>
+{{- if .TOC}}
+{{template "toc" .TOC}}
+{{- else}}
+<ol><li><a href="content.xhtml">{{xml .Title}}</a></li></ol>
+{{- end}}
+</nav>
+</body>
+</html>
+{{end}}
+{{- define "toc" -}}
+<ol>
+{{- range .}}
+<li><a href="content.xhtml#{{xml .ID}}">{{xml .Text}}</a>{{if .Children}}{{template "toc" .Children}}{{end}}</li>
+{{- end}}
+</ol>
+{{- end}}
+{{- define "content" -}}
+<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" lang="{{.Language}}">
+<head><title>{{xml .Title}}</title></head>
+<body>
+{{.Body}}
+{{- if .Unreferenced}}
+<h2>Attachments</h2>
+<ul>
+{{- range .Unreferenced}}
+<li>{{xml .Name}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+{{end}}
+`))
+
+func xmlEscape(s string) string {
+	return html.EscapeString(s)
+}
@@ -0,0 +1,122 @@
+// Package export writes documents in downloadable formats.
+package export
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Attachment is a file attached to an exported document. URL is how the
+// document content references the file.
+type Attachment struct {
+	ID          uint
+	Name        string
+	ContentType string
+	URL         string
+	Data        []byte
+}
+
+// IsImage reports whether the attachment can be shown inline
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// DataURI returns the attachment encoded as a data URI
+func (a *Attachment) DataURI() string {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// Document is a document prepared for export. HTML is the sanitized
+// rendering of Markdown and TOC its outline.
+type Document struct {
+	ID          uint
+	Title       string
+	Markdown    string
+	HTML        string
+	TOC         []models.TOCEntry
+	Tags        []string
+	Status      string
+	Author      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Attachments []Attachment
+}
+
+// replaceAttachmentURLs replaces the references to attachments in s with the
+// URL returned by replace. It returns the attachments that are not
+// referenced, which exporters list or bundle separately.
+func (d *Document) replaceAttachmentURLs(s string, replace func(a *Attachment) string) (string, []*Attachment) {
+	var unreferenced []*Attachment
+	for i := range d.Attachments {
+		a := &d.Attachments[i]
+		if a.URL == "" || !strings.Contains(s, a.URL) {
+			unreferenced = append(unreferenced, a)
+			continue
+		}
+		s = strings.ReplaceAll(s, a.URL, replace(a))
+	}
+	return s, unreferenced
+}
+
+// Exporter writes documents in one format
+type Exporter interface {
+	// Extension is the file name extension of exported files, e.g. ".pdf"
+	Extension() string
+	ContentType() string
+	Export(ctx context.Context, w io.Writer, doc *Document) error
+}
+
+// Registry selects the exporter of a format by name
+type Registry struct {
+	exporters map[string]Exporter
+}
+
+// NewRegistry returns a registry with the built-in exporters for HTML,
+// Markdown, PDF and EPUB. The PDF exporter uses the core fonts; register a
+// PDF exporter with a font file to export text outside of Latin-1.
+func NewRegistry() *Registry {
+	r := &Registry{exporters: make(map[string]Exporter)}
+	r.Register("html", HTML{})
+	r.Register("md", Markdown{})
+	r.Register("markdown", Markdown{})
+	r.Register("pdf", NewPDF(""))
+	r.Register("epub", EPUB{})
+	return r
+}
+
+// Register sets the exporter of format
+func (r *Registry) Register(format string, exporter Exporter) {
+	r.exporters[strings.ToLower(format)] = exporter
+}
+
+// Get returns the exporter of format
+func (r *Registry) Get(format string) (Exporter, error) {
+	exporter, ok := r.exporters[strings.ToLower(format)]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+	return exporter, nil
+}
+
+// Formats lists the registered format names
+func (r *Registry) Formats() []string {
+	formats := make([]string, 0, len(r.exporters))
+	for format := range r.exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
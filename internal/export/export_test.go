@@ -0,0 +1,116 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDocument(t *testing.T) *Document {
+	t.Helper()
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	return &Document{
+		ID:    7,
+		Title: "Deployment guide",
+		Markdown: "# Deployment guide\n\nRun **make** with `-j4`.\n\n![diagram](/api/v1/files/1/content)\n\n" +
+			"## Steps\n\n- [x] build\n- deploy\n\n| Host | Port |\n| --- | --- |\n| db | 5432 |\n\n```go\nfmt.Println(\"hi\")\n```\n",
+		HTML: `<h1 id="deployment-guide">Deployment guide</h1><p>Run <strong>make</strong> with <code>-j4</code>.</p>` +
+			`<p><img src="/api/v1/files/1/content" alt="diagram"><br></p><h2 id="steps">Steps</h2>`,
+		TOC: []models.TOCEntry{{Level: 1, Text: "Deployment guide", ID: "deployment-guide", Children: []models.TOCEntry{
+			{Level: 2, Text: "Steps", ID: "steps"},
+		}}},
+		Tags:      []string{"ops"},
+		Status:    "published",
+		Author:    "alice",
+		CreatedAt: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+		Attachments: []Attachment{
+			{ID: 1, Name: "diagram.png", ContentType: "image/png", URL: "/api/v1/files/1/content", Data: img.Bytes()},
+			{ID: 2, Name: "notes.txt", ContentType: "text/plain", URL: "/api/v1/files/2/content", Data: []byte("notes")},
+		},
+	}
+}
+
+func exportString(t *testing.T, format string, doc *Document) string {
+	t.Helper()
+	exporter, err := NewRegistry().Get(format)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, exporter.Export(context.Background(), &buf, doc))
+	return buf.String()
+}
+
+func TestExportMarkdown(t *testing.T) {
+	doc := testDocument(t)
+	out := exportString(t, "MD", doc)
+
+	assert.True(t, strings.HasPrefix(out, "---\ntitle: Deployment guide\n"))
+	assert.Contains(t, out, "tags:\n    - ops\n")
+	assert.Contains(t, out, "![diagram](data:image/png;base64,")
+	assert.NotContains(t, out, "/api/v1/files/1/content")
+	// 未在正文引用的附件列在文末
+	assert.Contains(t, out, "## Attachments")
+	assert.Contains(t, out, "[notes.txt](data:text/plain;base64,bm90ZXM=)")
+}
+
+func TestExportHTML(t *testing.T) {
+	out := exportString(t, "html", testDocument(t))
+
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(t, out, "<title>Deployment guide</title>")
+	assert.Contains(t, out, `<img src="data:image/png;base64,`)
+	assert.Contains(t, out, `href="#steps"`)
+	assert.Contains(t, out, "notes.txt")
+}
+
+func TestExportEPUB(t *testing.T) {
+	out := exportString(t, "epub", testDocument(t))
+
+	archive, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	require.NotEmpty(t, archive.File)
+	assert.Equal(t, "mimetype", archive.File[0].Name)
+	assert.Equal(t, zip.Store, archive.File[0].Method)
+
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(data)
+	}
+	assert.Equal(t, "application/epub+zip", files["mimetype"])
+	assert.Contains(t, files, "META-INF/container.xml")
+	assert.Contains(t, files["OEBPS/content.opf"], `href="images/1.png"`)
+	assert.Contains(t, files["OEBPS/nav.xhtml"], `content.xhtml#steps`)
+	assert.Contains(t, files["OEBPS/content.xhtml"], `<img src="images/1.png" alt="diagram"/>`)
+	assert.Contains(t, files["OEBPS/content.xhtml"], "<br/>")
+	assert.Contains(t, files, "OEBPS/images/1.png")
+}
+
+func TestExportPDF(t *testing.T) {
+	out := exportString(t, "pdf", testDocument(t))
+
+	assert.True(t, strings.HasPrefix(out, "%PDF-"))
+	assert.Contains(t, out, "/Subtype /Image")
+	assert.Contains(t, out, "/EmbeddedFiles")
+}
+
+func TestRegistryUnsupportedFormat(t *testing.T) {
+	_, err := NewRegistry().Get("docx")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	assert.Equal(t, []string{"epub", "html", "markdown", "md", "pdf"}, NewRegistry().Formats())
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"context"
+	"html/template"
+	"io"
+)
+
+// HTML writes a standalone page with the rendered document, its table of
+// contents and the attachments inlined as data URIs
+type HTML struct{}
+
+func (HTML) Extension() string   { return ".html" }
+func (HTML) ContentType() string { return "text/html; charset=utf-8" }
+
+// attachmentLink is an attachment listed at the end of an exported page
+type attachmentLink struct {
+	Name string
+	URL  template.URL
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 48em; margin: 2em auto; padding: 0 1em; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.6; color: #1f2328; }
+pre { padding: 1em; overflow: auto; background: #f6f8fa; border-radius: 6px; }
+code { font-family: SFMono-Regular, Consolas, "Liberation Mono", monospace; font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.3em 0.8em; }
+img { max-width: 100%; }
+nav.toc { border-bottom: 1px solid #d0d7de; margin-bottom: 2em; }
+</style>
+</head>
+<body>
+{{- if .TOC}}
+<nav class="toc">
+{{template "toc" .TOC}}
+</nav>
+{{- end}}
+<article>
+{{.Body}}
+</article>
+{{- if .Attachments}}
+<section class="attachments">
+<h2>Attachments</h2>
+<ul>
+{{- range .Attachments}}
+<li><a href="{{.URL}}" download="{{.Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+</body>
+</html>
+{{define "toc"}}<ul>
+{{- range .}}
+<li><a href="#{{.ID}}">{{.Text}}</a>{{if .Children}}{{template "toc" .Children}}{{end}}</li>
+{{- end}}
+</ul>{{end}}
+`))
+
+func (HTML) Export(ctx context.Context, w io.Writer, doc *Document) error {
+	body, unreferenced := doc.replaceAttachmentURLs(doc.HTML, (*Attachment).DataURI)
+
+	links := make([]attachmentLink, 0, len(unreferenced))
+	for _, a := range unreferenced {
+		links = append(links, attachmentLink{Name: a.Name, URL: template.URL(a.DataURI())})
+	}
+
+	// 只有一个标题时目录没有意义
+	toc := doc.TOC
+	if len(toc) == 1 && len(toc[0].Children) == 0 {
+		toc = nil
+	}
+
+	return pageTemplate.Execute(w, map[string]any{
+		"Title":       doc.Title,
+		"TOC":         toc,
+		"Body":        template.HTML(body),
+		"Attachments": links,
+	})
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Markdown writes the document source with a YAML front matter holding its
+// metadata. Attachments are inlined as data URIs; those the content does not
+// reference are linked in a closing "Attachments" section.
+type Markdown struct{}
+
+func (Markdown) Extension() string   { return ".md" }
+func (Markdown) ContentType() string { return "text/markdown; charset=utf-8" }
+
+// frontMatter is the metadata written ahead of exported Markdown
+type frontMatter struct {
+	Title   string    `yaml:"title"`
+	Tags    []string  `yaml:"tags,omitempty"`
+	Status  string    `yaml:"status,omitempty"`
+	Author  string    `yaml:"author,omitempty"`
+	Created time.Time `yaml:"created,omitempty"`
+	Updated time.Time `yaml:"updated,omitempty"`
+}
+
+func (Markdown) Export(ctx context.Context, w io.Writer, doc *Document) error {
+	meta, err := yaml.Marshal(frontMatter{
+		Title:   doc.Title,
+		Tags:    doc.Tags,
+		Status:  doc.Status,
+		Author:  doc.Author,
+		Created: doc.CreatedAt,
+		Updated: doc.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	content, unreferenced := doc.replaceAttachmentURLs(doc.Markdown, (*Attachment).DataURI)
+
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	b.Write(meta)
+	b.WriteString("---\n\n")
+	b.WriteString(strings.TrimSpace(content))
+	b.WriteString("\n")
+	if len(unreferenced) > 0 {
+		b.WriteString("\n## Attachments\n\n")
+		for _, a := range unreferenced {
+			fmt.Fprintf(&b, "- [%s](%s)\n", escapeLinkText(a.Name), a.DataURI())
+		}
+	}
+
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+func escapeLinkText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
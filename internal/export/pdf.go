@@ -0,0 +1,414 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// PDF typesets the Markdown source of a document on A4 pages. Headings
+// become bookmarks, images attached to the document are placed inline and
+// every attachment is embedded in the file.
+//
+// Without a font file only the core fonts are available, which cover
+// Latin-1; other characters are printed as dots. A TrueType font with the
+// needed glyphs, e.g. Noto Sans CJK, is used for all text when set.
+type PDF struct {
+	fontPath string
+}
+
+func NewPDF(fontPath string) *PDF {
+	return &PDF{fontPath: fontPath}
+}
+
+func (*PDF) Extension() string   { return ".pdf" }
+func (*PDF) ContentType() string { return "application/pdf" }
+
+// pdfImageTypes maps the image types gofpdf can embed to its type names
+var pdfImageTypes = map[string]string{
+	"image/png":  "PNG",
+	"image/jpeg": "JPG",
+	"image/gif":  "GIF",
+}
+
+const (
+	pdfFontSize   = 11.0
+	pdfLineHeight = 5.5
+)
+
+var pdfHeadingSizes = []float64{0, 20, 17, 15, 13, 12, 11}
+
+func (p *PDF) Export(ctx context.Context, w io.Writer, doc *Document) error {
+	source := []byte(doc.Markdown)
+	root := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	t := &pdfTypesetter{pdf: pdf, source: source, doc: doc, body: "Helvetica", mono: "Courier"}
+	if p.fontPath != "" {
+		for _, style := range []string{"", "B", "I", "BI"} {
+			pdf.AddUTF8Font("text", style, p.fontPath)
+		}
+		t.body, t.mono = "text", "text"
+		t.tr = func(s string) string { return s }
+	} else {
+		t.tr = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	pdf.SetTitle(doc.Title, true)
+	pdf.SetAuthor(doc.Author, true)
+	if !doc.UpdatedAt.IsZero() {
+		pdf.SetCreationDate(doc.UpdatedAt)
+		pdf.SetModificationDate(doc.UpdatedAt)
+	}
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(t.body, "", 8)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(0, 8, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	attachments := make([]gofpdf.Attachment, 0, len(doc.Attachments))
+	for _, a := range doc.Attachments {
+		attachments = append(attachments, gofpdf.Attachment{Content: a.Data, Filename: a.Name})
+	}
+	pdf.SetAttachments(attachments)
+
+	pdf.AddPage()
+	// 正文不以一级标题开头时补上文档标题
+	if h, ok := root.FirstChild().(*ast.Heading); !ok || h.Level != 1 {
+		t.heading(1, doc.Title)
+	}
+	if err := t.blocks(ctx, root); err != nil {
+		return err
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to typeset PDF: %w", err)
+	}
+	return pdf.Output(w)
+}
+
+type pdfTypesetter struct {
+	pdf        *gofpdf.Fpdf
+	source     []byte
+	doc        *Document
+	body, mono string
+	tr         func(string) string
+
+	bookmarkLevel int
+	textColor     [3]int
+}
+
+func (t *pdfTypesetter) blocks(ctx context.Context, parent ast.Node) error {
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := t.pdf.Error(); err != nil {
+			return err
+		}
+
+		switch node := n.(type) {
+		case *ast.Heading:
+			t.heading(node.Level, plainText(node, t.source))
+		case *ast.Paragraph, *ast.TextBlock:
+			t.pdf.SetFont(t.body, "", pdfFontSize)
+			t.inline(node, "")
+			t.pdf.Ln(pdfLineHeight)
+			if _, ok := node.(*ast.Paragraph); ok {
+				t.pdf.Ln(2)
+			}
+		case *ast.List:
+			if err := t.list(ctx, node); err != nil {
+				return err
+			}
+		case *ast.Blockquote:
+			color := t.textColor
+			t.textColor = [3]int{100, 100, 100}
+			if err := t.indented(ctx, 6, node); err != nil {
+				return err
+			}
+			t.textColor = color
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			t.code(node)
+		case *ast.ThematicBreak:
+			left, _, right, _ := t.pdf.GetMargins()
+			width, _ := t.pdf.GetPageSize()
+			y := t.pdf.GetY() + 2
+			t.pdf.SetDrawColor(200, 200, 200)
+			t.pdf.Line(left, y, width-right, y)
+			t.pdf.Ln(5)
+		case *east.Table:
+			t.table(node)
+		case *ast.HTMLBlock:
+			// 原始 HTML 不输出
+		default:
+			if err := t.blocks(ctx, node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *pdfTypesetter) heading(level int, title string) {
+	size := pdfHeadingSizes[min(level, len(pdfHeadingSizes)-1)]
+	t.pdf.Ln(3)
+	// 书签层级不能跳级
+	bookmark := min(level-1, t.bookmarkLevel+1)
+	t.pdf.Bookmark(t.tr(title), bookmark, -1)
+	t.bookmarkLevel = bookmark
+
+	t.pdf.SetFont(t.body, "B", size)
+	t.pdf.SetTextColor(t.textColor[0], t.textColor[1], t.textColor[2])
+	t.pdf.MultiCell(0, size*0.5, t.tr(title), "", "L", false)
+	t.pdf.Ln(2)
+}
+
+// indented typesets the children of n with a larger left margin
+func (t *pdfTypesetter) indented(ctx context.Context, indent float64, n ast.Node) error {
+	left, _, _, _ := t.pdf.GetMargins()
+	t.pdf.SetLeftMargin(left + indent)
+	t.pdf.SetX(left + indent)
+	err := t.blocks(ctx, n)
+	t.pdf.SetLeftMargin(left)
+	t.pdf.SetX(left)
+	return err
+}
+
+func (t *pdfTypesetter) list(ctx context.Context, list *ast.List) error {
+	number := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "•"
+		if list.IsOrdered() {
+			marker = strconv.Itoa(number) + "."
+			number++
+		}
+
+		left, _, _, _ := t.pdf.GetMargins()
+		t.pdf.SetFont(t.body, "", pdfFontSize)
+		t.pdf.SetTextColor(t.textColor[0], t.textColor[1], t.textColor[2])
+		t.pdf.SetX(left)
+		t.pdf.CellFormat(6, pdfLineHeight, t.tr(marker), "", 0, "L", false, 0, "")
+		if err := t.indented(ctx, 6, item); err != nil {
+			return err
+		}
+	}
+	t.pdf.Ln(1)
+	return nil
+}
+
+func (t *pdfTypesetter) code(n ast.Node) {
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		code.Write(segment.Value(t.source))
+	}
+
+	t.pdf.SetFont(t.mono, "", 9)
+	t.pdf.SetFillColor(246, 248, 250)
+	t.pdf.SetTextColor(36, 41, 47)
+	t.pdf.MultiCell(0, 4.5, t.tr(strings.TrimRight(code.String(), "\n")), "", "L", true)
+	t.pdf.Ln(3)
+}
+
+func (t *pdfTypesetter) table(table *east.Table) {
+	columns := 0
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		columns = max(columns, row.ChildCount())
+	}
+	if columns == 0 {
+		return
+	}
+
+	left, _, right, bottom := t.pdf.GetMargins()
+	pageWidth, pageHeight := t.pdf.GetPageSize()
+	width := (pageWidth - left - right) / float64(columns)
+	const lineHeight = 5.0
+
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		_, header := row.(*east.TableHeader)
+		style := ""
+		if header {
+			style = "B"
+		}
+		t.pdf.SetFont(t.body, style, 10)
+
+		var cells [][]string
+		lines := 1
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			split := t.pdf.SplitText(t.tr(plainText(cell, t.source)), width-2)
+			cells = append(cells, split)
+			lines = max(lines, len(split))
+		}
+		height := float64(lines)*lineHeight + 2
+
+		if t.pdf.GetY()+height > pageHeight-bottom {
+			t.pdf.AddPage()
+		}
+		y := t.pdf.GetY()
+		t.pdf.SetDrawColor(208, 215, 222)
+		t.pdf.SetFillColor(246, 248, 250)
+		t.pdf.SetTextColor(t.textColor[0], t.textColor[1], t.textColor[2])
+		for i := 0; i < columns; i++ {
+			x := left + float64(i)*width
+			box := "D"
+			if header {
+				box = "FD"
+			}
+			t.pdf.Rect(x, y, width, height, box)
+			if i >= len(cells) {
+				continue
+			}
+			for j, line := range cells[i] {
+				t.pdf.SetXY(x+1, y+1+float64(j)*lineHeight)
+				t.pdf.CellFormat(width-2, lineHeight, line, "", 0, "L", false, 0, "")
+			}
+		}
+		t.pdf.SetXY(left, y+height)
+	}
+	t.pdf.Ln(3)
+}
+
+// inline writes the inline content of n with the font style in effect
+func (t *pdfTypesetter) inline(n ast.Node, style string) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		t.pdf.SetFont(t.body, style, pdfFontSize)
+		t.pdf.SetTextColor(t.textColor[0], t.textColor[1], t.textColor[2])
+
+		switch node := c.(type) {
+		case *ast.Text:
+			t.pdf.Write(pdfLineHeight, t.tr(string(node.Segment.Value(t.source))))
+			if node.HardLineBreak() {
+				t.pdf.Ln(pdfLineHeight)
+			} else if node.SoftLineBreak() {
+				t.pdf.Write(pdfLineHeight, " ")
+			}
+		case *ast.String:
+			t.pdf.Write(pdfLineHeight, t.tr(string(node.Value)))
+		case *ast.Emphasis:
+			if node.Level == 1 {
+				t.inline(node, addStyle(style, "I"))
+			} else {
+				t.inline(node, addStyle(style, "B"))
+			}
+		case *east.Strikethrough:
+			t.inline(node, addStyle(style, "S"))
+		case *ast.CodeSpan:
+			t.pdf.SetFont(t.mono, style, pdfFontSize-1)
+			t.pdf.Write(pdfLineHeight, t.tr(plainText(node, t.source)))
+		case *ast.Link:
+			t.link(plainText(node, t.source), string(node.Destination), style)
+		case *ast.AutoLink:
+			url := string(node.URL(t.source))
+			t.link(string(node.Label(t.source)), url, style)
+		case *ast.Image:
+			t.image(string(node.Destination), plainText(node, t.source))
+		case *east.TaskCheckBox:
+			box := "[ ] "
+			if node.IsChecked {
+				box = "[x] "
+			}
+			t.pdf.Write(pdfLineHeight, box)
+		case *ast.RawHTML:
+			// 原始 HTML 不输出
+		default:
+			t.inline(node, style)
+		}
+	}
+}
+
+func (t *pdfTypesetter) link(label, url, style string) {
+	for i := range t.doc.Attachments {
+		if t.doc.Attachments[i].URL == url {
+			// 附件已嵌入文件，不链接到服务端地址
+			url = ""
+			break
+		}
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "mailto:") {
+		t.pdf.Write(pdfLineHeight, t.tr(label))
+		return
+	}
+	t.pdf.SetFont(t.body, addStyle(style, "U"), pdfFontSize)
+	t.pdf.SetTextColor(9, 105, 218)
+	t.pdf.WriteLinkString(pdfLineHeight, t.tr(label), url)
+}
+
+// image places an attached image on its own lines, scaled down to the text
+// width; other images are replaced by their description
+func (t *pdfTypesetter) image(url, alt string) {
+	var attachment *Attachment
+	for i := range t.doc.Attachments {
+		if t.doc.Attachments[i].URL == url {
+			attachment = &t.doc.Attachments[i]
+			break
+		}
+	}
+	imageType, ok := "", false
+	if attachment != nil {
+		imageType, ok = pdfImageTypes[attachment.ContentType]
+	}
+	if !ok {
+		if alt != "" {
+			t.pdf.Write(pdfLineHeight, t.tr("["+alt+"]"))
+		}
+		return
+	}
+
+	name := fmt.Sprintf("image-%d", attachment.ID)
+	options := gofpdf.ImageOptions{ImageType: imageType, ReadDpi: true}
+	info := t.pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(attachment.Data))
+	if info == nil || t.pdf.Error() != nil {
+		// 图片损坏时不中断导出
+		t.pdf.ClearError()
+		t.pdf.Write(pdfLineHeight, t.tr("["+alt+"]"))
+		return
+	}
+
+	left, _, right, _ := t.pdf.GetMargins()
+	pageWidth, _ := t.pdf.GetPageSize()
+	width := min(info.Width(), pageWidth-left-right)
+	if t.pdf.GetX() > left {
+		t.pdf.Ln(pdfLineHeight)
+	}
+	t.pdf.ImageOptions(name, left, 0, width, 0, true, options, 0, "")
+}
+
+func addStyle(style, s string) string {
+	if strings.Contains(style, s) {
+		return style
+	}
+	return style + s
+}
+
+// plainText concatenates the text of the inline children of n
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch node := c.(type) {
+		case *ast.Text:
+			b.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(node.Value)
+		default:
+			b.WriteString(plainText(c, source))
+		}
+	}
+	return b.String()
+}
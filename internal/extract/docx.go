@@ -0,0 +1,118 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxPartSize caps the uncompressed size of a single part read from an
+// Office archive, guarding against zip bombs
+const maxPartSize = 64 << 20
+
+var errPartTooLarge = errors.New("archive part too large")
+
+// DOCX extracts the body text of Word documents, one line per paragraph
+// and per table row with tab-separated cells
+type DOCX struct{}
+
+func (DOCX) Extract(ctx context.Context, r io.ReaderAt, size int64) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open DOCX: %w", err)
+	}
+
+	data, err := readPart(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var b, cell strings.Builder
+	var row []string
+	cellDepth := 0
+	out := &b
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse DOCX: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				out.WriteByte('\t')
+			case "br", "cr":
+				out.WriteByte('\n')
+			case "tc":
+				// 单元格内容先收集，整行输出为一行
+				if cellDepth++; cellDepth == 1 {
+					cell.Reset()
+					out = &cell
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				out.WriteByte('\n')
+			case "tc":
+				if cellDepth--; cellDepth == 0 {
+					row = append(row, strings.Join(strings.Fields(cell.String()), " "))
+					out = &b
+				}
+			case "tr":
+				if cellDepth == 0 {
+					b.WriteString(strings.Join(row, "\t"))
+					b.WriteByte('\n')
+					row = row[:0]
+				}
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// readPart reads the named part of an Office archive
+func readPart(archive *zip.Reader, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+		if f.UncompressedSize64 > maxPartSize {
+			return nil, errPartTooLarge
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxPartSize {
+			return nil, errPartTooLarge
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("missing %s", name)
+}
@@ -0,0 +1,621 @@
+package extract
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Image is an image embedded in a converted document
+type Image struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// ImageSaver stores an embedded image and returns the URL the generated
+// Markdown links to
+type ImageSaver func(ctx context.Context, image Image) (string, error)
+
+// DOCXToMarkdown converts a Word document to Markdown, keeping headings,
+// bullet and numbered lists, tables, bold, italic and struck-through text,
+// links and images. Images are passed to saveImage; when saveImage is nil
+// they are dropped.
+func DOCXToMarkdown(ctx context.Context, r io.ReaderAt, size int64, saveImage ImageSaver) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open DOCX: %w", err)
+	}
+
+	var body xmlNode
+	if err := unmarshalPart(archive, "word/document.xml", &body); err != nil {
+		return "", err
+	}
+
+	c := &docxConverter{
+		ctx:       ctx,
+		archive:   archive,
+		saveImage: saveImage,
+		images:    make(map[string]string),
+	}
+	// 关系、样式和编号定义都是可选部件
+	var rels, styles, numbering xmlNode
+	if unmarshalPart(archive, "word/_rels/document.xml.rels", &rels) == nil {
+		c.rels = parseRelationships(&rels)
+	}
+	if unmarshalPart(archive, "word/styles.xml", &styles) == nil {
+		c.headings = parseHeadingStyles(&styles)
+	}
+	if unmarshalPart(archive, "word/numbering.xml", &numbering) == nil {
+		c.ordered = parseNumbering(&numbering)
+	}
+
+	if b := body.child("body"); b != nil {
+		if err := c.blocks(b.Nodes); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(c.out.String(), "\n\n")), nil
+}
+
+// xmlNode is a generic XML element; names are matched by their local part
+// since WordprocessingML uses several namespace prefixes
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []xmlNode  `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *xmlNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// find returns the first descendant named name, depth first
+func (n *xmlNode) find(name string) *xmlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+		if found := n.Nodes[i].find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// on reports whether a toggle property such as <w:b/> is set
+func (n *xmlNode) on(name string) bool {
+	prop := n.child(name)
+	if prop == nil {
+		return false
+	}
+	switch prop.attr("val") {
+	case "0", "false", "off", "none":
+		return false
+	}
+	return true
+}
+
+func unmarshalPart(archive *zip.Reader, name string, v any) error {
+	data, err := readPart(archive, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+type relationship struct {
+	target   string
+	external bool
+}
+
+func parseRelationships(rels *xmlNode) map[string]relationship {
+	m := make(map[string]relationship)
+	for _, rel := range rels.Nodes {
+		m[rel.attr("Id")] = relationship{
+			target:   rel.attr("Target"),
+			external: rel.attr("TargetMode") == "External",
+		}
+	}
+	return m
+}
+
+var headingStyleName = regexp.MustCompile(`^(?i)heading\s*([1-6])$`)
+
+// parseHeadingStyles maps paragraph style IDs to heading levels. Style IDs
+// are localized ("berschrift1"), so levels come from the style name or its
+// outline level.
+func parseHeadingStyles(styles *xmlNode) map[string]int {
+	m := make(map[string]int)
+	for _, style := range styles.Nodes {
+		if style.XMLName.Local != "style" || style.attr("type") != "paragraph" {
+			continue
+		}
+		id := style.attr("styleId")
+		var name string
+		if n := style.child("name"); n != nil {
+			name = n.attr("val")
+		}
+		switch {
+		case strings.EqualFold(name, "title"):
+			m[id] = 1
+		case headingStyleName.MatchString(name):
+			m[id], _ = strconv.Atoi(headingStyleName.FindStringSubmatch(name)[1])
+		default:
+			if level := outlineLevel(style.child("pPr")); level > 0 {
+				m[id] = level
+			}
+		}
+	}
+	return m
+}
+
+// outlineLevel returns the heading level set by <w:outlineLvl> in
+// paragraph properties, or 0
+func outlineLevel(pPr *xmlNode) int {
+	if pPr == nil {
+		return 0
+	}
+	lvl := pPr.child("outlineLvl")
+	if lvl == nil {
+		return 0
+	}
+	level, err := strconv.Atoi(lvl.attr("val"))
+	if err != nil || level < 0 || level > 5 {
+		return 0
+	}
+	return level + 1
+}
+
+// parseNumbering reports for each numbering ID and list level whether the
+// list is numbered rather than bulleted
+func parseNumbering(numbering *xmlNode) map[string]map[string]bool {
+	abstract := make(map[string]map[string]bool)
+	for _, n := range numbering.Nodes {
+		if n.XMLName.Local != "abstractNum" {
+			continue
+		}
+		levels := make(map[string]bool)
+		for _, lvl := range n.Nodes {
+			if lvl.XMLName.Local != "lvl" {
+				continue
+			}
+			if format := lvl.child("numFmt"); format != nil {
+				levels[lvl.attr("ilvl")] = format.attr("val") != "bullet" && format.attr("val") != "none"
+			}
+		}
+		abstract[n.attr("abstractNumId")] = levels
+	}
+
+	m := make(map[string]map[string]bool)
+	for _, n := range numbering.Nodes {
+		if n.XMLName.Local != "num" {
+			continue
+		}
+		if id := n.child("abstractNumId"); id != nil {
+			m[n.attr("numId")] = abstract[id.attr("val")]
+		}
+	}
+	return m
+}
+
+type docxConverter struct {
+	ctx       context.Context
+	archive   *zip.Reader
+	rels      map[string]relationship
+	headings  map[string]int
+	ordered   map[string]map[string]bool
+	saveImage ImageSaver
+	images    map[string]string // 图片部件路径 -> 保存后的 URL
+
+	out    strings.Builder
+	inList bool
+}
+
+func (c *docxConverter) blocks(nodes []xmlNode) error {
+	for i := range nodes {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		node := &nodes[i]
+		switch node.XMLName.Local {
+		case "p":
+			if err := c.paragraph(node); err != nil {
+				return err
+			}
+		case "tbl":
+			if err := c.table(node); err != nil {
+				return err
+			}
+		case "sdt":
+			if content := node.child("sdtContent"); content != nil {
+				if err := c.blocks(content.Nodes); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *docxConverter) paragraph(p *xmlNode) error {
+	text, err := c.inline(p)
+	if err != nil {
+		return err
+	}
+
+	var level int
+	var numID string
+	ilvl := "0"
+	if pPr := p.child("pPr"); pPr != nil {
+		if style := pPr.child("pStyle"); style != nil {
+			level = c.headings[style.attr("val")]
+		}
+		if l := outlineLevel(pPr); l > 0 {
+			level = l
+		}
+		if numPr := pPr.child("numPr"); numPr != nil {
+			if n := numPr.child("numId"); n != nil {
+				numID = n.attr("val")
+			}
+			if l := numPr.child("ilvl"); l != nil {
+				ilvl = l.attr("val")
+			}
+		}
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	switch {
+	case level > 0:
+		c.block(strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\\\n", " "))
+	case numID != "" && numID != "0":
+		depth, _ := strconv.Atoi(ilvl)
+		marker := "- "
+		if c.ordered[numID][ilvl] {
+			marker = "1. "
+		}
+		if !c.inList {
+			c.out.WriteString("\n")
+		}
+		c.out.WriteString(strings.Repeat("    ", depth) + marker + text + "\n")
+		c.inList = true
+	default:
+		c.block(escapeLineStart(text))
+	}
+	return nil
+}
+
+// block writes a block separated from its neighbours by blank lines
+func (c *docxConverter) block(text string) {
+	c.out.WriteString("\n")
+	c.out.WriteString(text)
+	c.out.WriteString("\n")
+	c.inList = false
+}
+
+func (c *docxConverter) table(tbl *xmlNode) error {
+	var rows [][]string
+	columns := 0
+	for i := range tbl.Nodes {
+		if tbl.Nodes[i].XMLName.Local != "tr" {
+			continue
+		}
+		var row []string
+		for j := range tbl.Nodes[i].Nodes {
+			tc := &tbl.Nodes[i].Nodes[j]
+			if tc.XMLName.Local != "tc" {
+				continue
+			}
+			cell, err := c.cell(tc)
+			if err != nil {
+				return err
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return nil
+	}
+
+	// Markdown 表格需要表头，第一行作为表头
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	c.block(strings.TrimSuffix(b.String(), "\n"))
+	return nil
+}
+
+// cell renders the paragraphs of a table cell on one line
+func (c *docxConverter) cell(tc *xmlNode) (string, error) {
+	var lines []string
+	var walk func(nodes []xmlNode) error
+	walk = func(nodes []xmlNode) error {
+		for i := range nodes {
+			switch nodes[i].XMLName.Local {
+			case "p":
+				text, err := c.inline(&nodes[i])
+				if err != nil {
+					return err
+				}
+				if text = strings.TrimSpace(strings.ReplaceAll(text, "\\\n", " ")); text != "" {
+					lines = append(lines, text)
+				}
+			case "tbl", "tr", "tc", "sdt", "sdtContent":
+				if err := walk(nodes[i].Nodes); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(tc.Nodes); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(strings.Join(lines, "<br>"), "|", `\|`), nil
+}
+
+// span is a run of text sharing the same formatting
+type span struct {
+	text                 string
+	bold, italic, strike bool
+	raw                  bool // 已是 Markdown，不转义也不加格式
+}
+
+// inline renders the runs, links and images of a paragraph
+func (c *docxConverter) inline(p *xmlNode) (string, error) {
+	var spans []span
+	if err := c.runs(p.Nodes, &spans); err != nil {
+		return "", err
+	}
+	return renderSpans(spans), nil
+}
+
+func (c *docxConverter) runs(nodes []xmlNode, spans *[]span) error {
+	for i := range nodes {
+		node := &nodes[i]
+		switch node.XMLName.Local {
+		case "r":
+			if err := c.run(node, spans); err != nil {
+				return err
+			}
+		case "hyperlink":
+			var inner []span
+			if err := c.runs(node.Nodes, &inner); err != nil {
+				return err
+			}
+			text := renderSpans(inner)
+			target := c.linkTarget(node)
+			if target == "" || strings.TrimSpace(text) == "" {
+				*spans = append(*spans, inner...)
+				continue
+			}
+			*spans = append(*spans, span{text: "[" + text + "](" + escapeURL(target) + ")", raw: true})
+		case "ins", "smartTag", "fldSimple":
+			if err := c.runs(node.Nodes, spans); err != nil {
+				return err
+			}
+		case "sdt":
+			if content := node.child("sdtContent"); content != nil {
+				if err := c.runs(content.Nodes, spans); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *docxConverter) run(r *xmlNode, spans *[]span) error {
+	format := span{}
+	if rPr := r.child("rPr"); rPr != nil {
+		format.bold = rPr.on("b")
+		format.italic = rPr.on("i")
+		format.strike = rPr.on("strike") || rPr.on("dstrike")
+	}
+
+	for i := range r.Nodes {
+		node := &r.Nodes[i]
+		s := format
+		switch node.XMLName.Local {
+		case "t":
+			s.text = node.Text
+		case "tab":
+			s.text = " "
+		case "br", "cr":
+			if node.attr("type") == "page" || node.attr("type") == "column" {
+				continue
+			}
+			s = span{text: "\\\n", raw: true}
+		case "drawing", "pict", "object":
+			image, err := c.image(node)
+			if err != nil {
+				return err
+			}
+			if image == "" {
+				continue
+			}
+			s = span{text: image, raw: true}
+		default:
+			continue
+		}
+		*spans = append(*spans, s)
+	}
+	return nil
+}
+
+func (c *docxConverter) linkTarget(link *xmlNode) string {
+	if id := link.attr("id"); id != "" {
+		if rel, ok := c.rels[id]; ok && rel.external {
+			return rel.target
+		}
+	}
+	if anchor := link.attr("anchor"); anchor != "" {
+		return "#" + anchor
+	}
+	return ""
+}
+
+// image saves the picture of a drawing and returns its Markdown
+func (c *docxConverter) image(drawing *xmlNode) (string, error) {
+	if c.saveImage == nil {
+		return "", nil
+	}
+
+	var id string
+	if blip := drawing.find("blip"); blip != nil {
+		id = blip.attr("embed")
+	} else if data := drawing.find("imagedata"); data != nil {
+		id = data.attr("id")
+	}
+	rel, ok := c.rels[id]
+	if !ok || rel.external {
+		return "", nil
+	}
+
+	var alt string
+	if props := drawing.find("docPr"); props != nil {
+		alt = props.attr("descr")
+		if alt == "" {
+			alt = props.attr("title")
+		}
+	}
+
+	// 关系目标相对于 word/ 目录
+	name := path.Clean(path.Join("word", rel.target))
+	if strings.HasPrefix(rel.target, "/") {
+		name = strings.TrimPrefix(rel.target, "/")
+	}
+	url, ok := c.images[name]
+	if !ok {
+		data, err := readPart(c.archive, name)
+		if err != nil {
+			return "", nil
+		}
+		url, err = c.saveImage(c.ctx, Image{
+			Name:        path.Base(name),
+			ContentType: mime.TypeByExtension(path.Ext(name)),
+			Data:        data,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to save image %s: %w", path.Base(name), err)
+		}
+		c.images[name] = url
+	}
+	return "![" + escapeMarkdown(alt) + "](" + escapeURL(url) + ")", nil
+}
+
+// renderSpans merges adjacent spans with the same formatting and wraps them
+// in emphasis markers, keeping surrounding spaces outside of the markers
+func renderSpans(spans []span) string {
+	var merged []span
+	for _, s := range spans {
+		if !s.raw {
+			s.text = escapeMarkdown(s.text)
+		}
+		if n := len(merged); n > 0 && !s.raw && !merged[n-1].raw &&
+			merged[n-1].bold == s.bold && merged[n-1].italic == s.italic && merged[n-1].strike == s.strike {
+			merged[n-1].text += s.text
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	var b strings.Builder
+	for _, s := range merged {
+		if s.raw {
+			b.WriteString(s.text)
+			continue
+		}
+		trimmed := strings.TrimSpace(s.text)
+		if trimmed == "" || !(s.bold || s.italic || s.strike) {
+			b.WriteString(s.text)
+			continue
+		}
+
+		marker := ""
+		if s.strike {
+			marker += "~~"
+		}
+		if s.bold {
+			marker += "**"
+		}
+		if s.italic {
+			marker += "*"
+		}
+		closing := []rune(marker)
+		for i, j := 0, len(closing)-1; i < j; i, j = i+1, j-1 {
+			closing[i], closing[j] = closing[j], closing[i]
+		}
+
+		start := strings.Index(s.text, trimmed)
+		b.WriteString(s.text[:start])
+		b.WriteString(marker + trimmed + string(closing))
+		b.WriteString(s.text[start+len(trimmed):])
+	}
+	return b.String()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`,
+	`[`, `\[`, `]`, `\]`, `<`, `\<`, `~`, `\~`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var blockMarker = regexp.MustCompile(`^(#{1,6}\s|>|[-+]\s|\d+[.)]\s)`)
+
+// escapeLineStart escapes text of a plain paragraph that Markdown would
+// otherwise read as a heading, quote or list item
+func escapeLineStart(s string) string {
+	if loc := blockMarker.FindStringIndex(s); loc != nil {
+		marker := s[:loc[1]]
+		if i := strings.IndexAny(marker, "#>-+.)"); i >= 0 {
+			return s[:i] + `\` + s[i:]
+		}
+	}
+	return s
+}
+
+func escapeURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+}
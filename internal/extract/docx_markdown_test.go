@@ -0,0 +1,98 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testStyles = `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:style w:type="paragraph" w:styleId="berschrift1"><w:name w:val="heading 1"/></w:style>
+<w:style w:type="paragraph" w:styleId="Subheading"><w:name w:val="Subheading"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>
+</w:styles>`
+	testNumbering = `<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/></w:lvl><w:lvl w:ilvl="1"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>
+<w:abstractNum w:abstractNumId="1"><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>
+</w:numbering>`
+	testRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/runbook" TargetMode="External"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"/>
+</Relationships>`
+)
+
+func listItem(numID, ilvl, text string) string {
+	return `<w:p><w:pPr><w:numPr><w:ilvl w:val="` + ilvl + `"/><w:numId w:val="` + numID + `"/></w:numPr></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func TestDOCXToMarkdown(t *testing.T) {
+	body := `<w:p><w:pPr><w:pStyle w:val="berschrift1"/></w:pPr><w:r><w:t>Failover guide</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">Promote the </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">replica </w:t></w:r>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:t>now</w:t></w:r><w:r><w:t xml:space="preserve">, see </w:t></w:r>` +
+		`<w:hyperlink r:id="rId1"><w:r><w:rPr><w:i/></w:rPr><w:t>the runbook</w:t></w:r></w:hyperlink>` +
+		`<w:r><w:rPr><w:b w:val="0"/><w:strike/></w:rPr><w:t>old_step</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Subheading"/></w:pPr><w:r><w:t>Steps</w:t></w:r></w:p>` +
+		listItem("1", "0", "Check lag") + listItem("1", "1", "Read the metrics") + listItem("2", "0", "Promote") +
+		`<w:p><w:r><w:t>1. Not a list</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Host</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Port</w:t></w:r></w:p></w:tc></w:tr>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>db|1</w:t></w:r></w:p><w:p><w:r><w:t>db2</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Picture 1" descr="Topology"/>` +
+		`<a:graphic><a:graphicData><a:blip r:embed="rId2"/></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+		`<w:p><w:r><w:t>Line one</w:t><w:br/><w:t>line two</w:t></w:r></w:p>`
+
+	data := buildDOCX(t, body, map[string]string{
+		"word/styles.xml":              testStyles,
+		"word/numbering.xml":           testNumbering,
+		"word/_rels/document.xml.rels": testRels,
+		"word/media/image1.png":        "\x89PNG",
+	})
+
+	var saved []Image
+	saveImage := func(ctx context.Context, image Image) (string, error) {
+		saved = append(saved, image)
+		return "/api/v1/files/42/content", nil
+	}
+
+	markdown, err := DOCXToMarkdown(context.Background(), bytes.NewReader(data), int64(len(data)), saveImage)
+	require.NoError(t, err)
+
+	assert.Equal(t, "# Failover guide\n\n"+
+		"Promote the **replica now**, see [*the runbook*](https://example.com/runbook)~~old\\_step~~\n\n"+
+		"## Steps\n\n"+
+		"- Check lag\n    1. Read the metrics\n1. Promote\n\n"+
+		"1\\. Not a list\n\n"+
+		"| Host | Port |\n| --- | --- |\n| db\\|1<br>db2 |  |\n\n"+
+		"![Topology](/api/v1/files/42/content)\n\n"+
+		"Line one\\\nline two", markdown)
+
+	require.Len(t, saved, 1)
+	assert.Equal(t, "image1.png", saved[0].Name)
+	assert.Equal(t, "image/png", saved[0].ContentType)
+	assert.Equal(t, []byte("\x89PNG"), saved[0].Data)
+}
+
+func TestDOCXToMarkdownErrors(t *testing.T) {
+	_, err := DOCXToMarkdown(context.Background(), bytes.NewReader([]byte("plain")), 5, nil)
+	assert.Error(t, err)
+
+	data := buildDOCX(t, `<w:p><w:r><w:drawing><a:blip r:embed="rId2"/></w:drawing></w:r></w:p>`, map[string]string{
+		"word/_rels/document.xml.rels": testRels,
+		"word/media/image1.png":        "\x89PNG",
+	})
+	failing := func(ctx context.Context, image Image) (string, error) {
+		return "", errors.New("storage unavailable")
+	}
+	_, err = DOCXToMarkdown(context.Background(), bytes.NewReader(data), int64(len(data)), failing)
+	assert.Error(t, err)
+
+	// 未提供保存函数时忽略图片
+	markdown, err := DOCXToMarkdown(context.Background(), bytes.NewReader(data), int64(len(data)), nil)
+	require.NoError(t, err)
+	assert.Empty(t, markdown)
+}
@@ -0,0 +1,93 @@
+// Package extract turns uploaded files into plain text so that they can be
+// searched and imported as documents.
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MaxTextLength caps the extracted text kept per file, in bytes
+const MaxTextLength = 4 << 20
+
+var ErrUnsupportedFormat = errors.New("unsupported file format")
+
+// TextExtractor extracts the text of one file format
+type TextExtractor interface {
+	Extract(ctx context.Context, r io.ReaderAt, size int64) (string, error)
+}
+
+// Registry selects the extractor of a file by its extension
+type Registry struct {
+	extractors map[string]TextExtractor
+}
+
+// NewRegistry returns a registry with the built-in extractors for PDF,
+// DOCX, Markdown and plain text files
+func NewRegistry() *Registry {
+	r := &Registry{extractors: make(map[string]TextExtractor)}
+	r.Register(".txt", PlainText{})
+	r.Register(".md", PlainText{})
+	r.Register(".markdown", PlainText{})
+	r.Register(".pdf", PDF{})
+	r.Register(".docx", DOCX{})
+	return r
+}
+
+// Register sets the extractor for files with extension ext, e.g. ".pdf"
+func (r *Registry) Register(ext string, extractor TextExtractor) {
+	r.extractors[strings.ToLower(ext)] = extractor
+}
+
+// For returns the extractor for filename
+func (r *Registry) For(filename string) (TextExtractor, bool) {
+	extractor, ok := r.extractors[strings.ToLower(filepath.Ext(filename))]
+	return extractor, ok
+}
+
+// Extract extracts and normalizes the text of a file. Extractors of
+// third-party formats may panic on malformed input, which is reported as an
+// error.
+func (r *Registry) Extract(ctx context.Context, filename string, rd io.ReaderAt, size int64) (text string, err error) {
+	extractor, ok := r.For(filename)
+	if !ok {
+		return "", ErrUnsupportedFormat
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			text, err = "", fmt.Errorf("malformed file: %v", p)
+		}
+	}()
+
+	text, err = extractor.Extract(ctx, rd, size)
+	if err != nil {
+		return "", err
+	}
+	return normalize(text), nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// normalize unifies line endings, removes trailing spaces and runs of blank
+// lines, and truncates the text to MaxTextLength
+func normalize(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t ")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	text = strings.TrimSpace(text)
+
+	if len(text) > MaxTextLength {
+		text = strings.ToValidUTF8(text[:MaxTextLength], "")
+	}
+	return text
+}
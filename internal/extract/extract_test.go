@@ -0,0 +1,115 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func extractBytes(t *testing.T, filename string, data []byte) (string, error) {
+	t.Helper()
+	return NewRegistry().Extract(context.Background(), filename, bytes.NewReader(data), int64(len(data)))
+}
+
+func TestExtractPlainText(t *testing.T) {
+	text, err := extractBytes(t, "notes.TXT", []byte("\xEF\xBB\xBFFirst line  \r\n\r\n\r\n\r\nSecond line\xFF\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "First line\n\nSecond line", text)
+
+	// UTF-16LE with byte order mark
+	text, err = extractBytes(t, "readme.md", []byte{0xFF, 0xFE, '#', 0, ' ', 0, 0x2D, 0x4E, 0x87, 0x65})
+	require.NoError(t, err)
+	assert.Equal(t, "# 中文", text)
+}
+
+// buildDOCX writes a Word archive with the given body and extra parts
+func buildDOCX(t *testing.T, body string, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create("word/document.xml")
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"
+	xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	require.NoError(t, err)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+func TestExtractDOCX(t *testing.T) {
+	data := buildDOCX(t, `<w:p><w:r><w:t>Deployment </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>guide</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t>Step</w:t><w:tab/><w:t>one</w:t><w:br/><w:t>&amp; two</w:t></w:r></w:p>`+
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Host</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Port</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`, nil)
+
+	text, err := extractBytes(t, "guide.docx", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Deployment guide\nStep\tone\n& two\nHost\tPort", text)
+
+	_, err = extractBytes(t, "guide.docx", []byte("not a zip archive"))
+	assert.Error(t, err)
+}
+
+// buildPDF writes a single-page PDF that shows text in Helvetica
+func buildPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	text, err := extractBytes(t, "spec.pdf", buildPDF("Quarterly storage report"))
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly storage report", text)
+
+	_, err = extractBytes(t, "spec.pdf", []byte("%PDF-1.4\ngarbage"))
+	assert.Error(t, err)
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	_, err := extractBytes(t, "legacy.doc", []byte{0xD0, 0xCF, 0x11, 0xE0})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, ok := NewRegistry().For("archive")
+	assert.False(t, ok)
+}
+
+func TestNormalizeTruncates(t *testing.T) {
+	text := normalize(strings.Repeat("中", MaxTextLength))
+	assert.LessOrEqual(t, len(text), MaxTextLength)
+	assert.True(t, strings.HasSuffix(text, "中"))
+}
@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDF extracts the text layer of PDF files, one paragraph per page. Scanned
+// pages without a text layer yield no text.
+type PDF struct{}
+
+func (PDF) Extract(ctx context.Context, r io.ReaderAt, size int64) (string, error) {
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	var b strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage(); i++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		// 缓存字体，避免每页重复解析字符映射
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", fmt.Errorf("failed to read page %d: %w", i, err)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			b.WriteString(text)
+			b.WriteString("\n\n")
+		}
+	}
+	return b.String(), nil
+}
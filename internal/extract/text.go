@@ -0,0 +1,41 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// PlainText reads text and Markdown files. UTF-16 files are recognized by
+// their byte order mark; other bytes that are not valid UTF-8 are dropped.
+type PlainText struct{}
+
+func (PlainText) Extract(ctx context.Context, r io.ReaderAt, size int64) (string, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return "", err
+	}
+	return decodeText(data)
+}
+
+func decodeText(data []byte) (string, error) {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		decoder := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder()
+		decoded, _, err := transform.Bytes(decoder, data)
+		if err != nil {
+			return "", err
+		}
+		data = decoded
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		return strings.ToValidUTF8(string(data), ""), nil
+	}
+	return string(data), nil
+}
@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// confluencePageID matches the page ID Confluence appends to the file names
+// of exported pages, as in "Getting-Started_65539.html"
+var confluencePageID = regexp.MustCompile(`_\d+$`)
+
+// Confluence reads the HTML export of Confluence spaces. Each space is a
+// folder with an index.html overview, a file per page and the attachments
+// of the pages under attachments/. The hierarchy is taken from the
+// breadcrumbs of the pages, tags from their labels and the author from the
+// page metadata.
+type Confluence struct{}
+
+func (Confluence) Detect(fsys fs.FS) bool {
+	return len(confluenceSpaces(fsys)) > 0
+}
+
+// confluenceSpaces returns the folders holding a Confluence space export,
+// at the top of the tree or one level down
+func confluenceSpaces(fsys fs.FS) []string {
+	var dirs []string
+	for _, pattern := range []string{"index.html", "*/index.html"} {
+		matches, _ := fs.Glob(fsys, pattern)
+		for _, name := range matches {
+			f, err := fsys.Open(name)
+			if err != nil {
+				continue
+			}
+			head, _ := io.ReadAll(io.LimitReader(f, 1<<20))
+			f.Close()
+			if bytes.Contains(bytes.ToLower(head), []byte("confluence")) {
+				dirs = append(dirs, path.Dir(name))
+			}
+		}
+	}
+	return dirs
+}
+
+func (Confluence) Read(fsys fs.FS) ([]*Page, error) {
+	var pages []*Page
+	for _, space := range confluenceSpaces(fsys) {
+		files, err := walkFiles(fsys, func(name string) bool {
+			return path.Dir(name) == space && strings.EqualFold(path.Ext(name), ".html") && path.Base(name) != "index.html"
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(pages)+len(files) > MaxPages {
+			return nil, ErrTooManyPages
+		}
+		for _, name := range files {
+			page := &Page{Path: name}
+			if err := readConfluencePage(fsys, name, page); err != nil {
+				return nil, err
+			}
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+func readConfluencePage(fsys fs.FS, name string, page *Page) error {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	page.Title = strings.ReplaceAll(confluencePageID.ReplaceAllString(base, ""), "-", " ")
+	data, ok, err := readFile(fsys, name, page)
+	if err != nil || !ok {
+		return err
+	}
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		page.Warnings = append(page.Warnings, fmt.Sprintf("invalid HTML: %v", err))
+		return nil
+	}
+
+	// 标题形如 "Space : Page"
+	title := byID(doc, "title-text")
+	if title == nil {
+		title = firstElement(doc, atom.Title)
+	}
+	if title != nil {
+		text := strings.TrimSpace(spaces.ReplaceAllString(textContent(title), " "))
+		if _, pageTitle, ok := strings.Cut(text, " : "); ok {
+			text = strings.TrimSpace(pageTitle)
+		}
+		if text != "" {
+			page.Title = text
+		}
+	}
+
+	if breadcrumbs := byID(doc, "breadcrumbs"); breadcrumbs != nil {
+		for _, a := range findAll(breadcrumbs, func(n *html.Node) bool { return n.DataAtom == atom.A }) {
+			href, ok := resolve(name, attr(a, "href"))
+			if ok && href != "" && path.Base(href) != "index.html" {
+				page.ParentPath = href
+			}
+		}
+	}
+
+	if metadata := find(doc, func(n *html.Node) bool { return hasClass(n, "page-metadata") }); metadata != nil {
+		if author := find(metadata, func(n *html.Node) bool { return hasClass(n, "author") }); author != nil {
+			page.Author = strings.TrimSpace(textContent(author))
+		}
+	}
+
+	for _, list := range findAll(doc, func(n *html.Node) bool { return hasClass(n, "label-list") }) {
+		for _, li := range findAll(list, func(n *html.Node) bool { return n.DataAtom == atom.Li }) {
+			if label := strings.TrimSpace(textContent(li)); label != "" {
+				page.Tags = append(page.Tags, label)
+			}
+		}
+	}
+
+	body := byID(doc, "main-content")
+	if body == nil {
+		body = firstElement(doc, atom.Body)
+	}
+	if body == nil {
+		body = doc
+	}
+	page.Source = name
+	page.Content = htmlToMarkdown(body) + confluenceAttachments(doc, body)
+	return nil
+}
+
+// confluenceAttachments lists the attachments of a page that its content
+// does not reference, which Confluence only lists at the end of the page
+func confluenceAttachments(doc, body *html.Node) string {
+	referenced := make(map[string]bool)
+	for _, n := range findAll(body, func(n *html.Node) bool { return n.DataAtom == atom.A || n.DataAtom == atom.Img }) {
+		for _, key := range []string{"href", "src"} {
+			if target, ok := resolve("", attr(n, key)); ok {
+				referenced[target] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, a := range findAll(doc, func(n *html.Node) bool { return n.DataAtom == atom.A }) {
+		href := attr(a, "href")
+		target, ok := resolve("", href)
+		if !ok || !strings.HasPrefix(target, "attachments/") || referenced[target] {
+			continue
+		}
+		referenced[target] = true
+		text := strings.TrimSpace(spaces.ReplaceAllString(textContent(a), " "))
+		if text == "" {
+			text = path.Base(target)
+		}
+		b.WriteString("- [" + escapeMarkdown(text) + "](" + linkDestination(href) + ")\n")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\n## Attachments\n\n" + b.String()
+}
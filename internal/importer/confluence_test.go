@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// confluencePage returns a page as written by the Confluence HTML export
+func confluencePage(title, breadcrumbs, author, body, footer string) []byte {
+	return []byte(`<!DOCTYPE html>
+<html>
+<head><title>Engineering : ` + title + `</title></head>
+<body class="theme-default aui-theme-default">
+<div id="page">
+<div id="main" class="aui-page-panel">
+<div id="main-header">
+<div id="breadcrumb-section"><ol id="breadcrumbs">` + breadcrumbs + `</ol></div>
+<h1 id="title-heading" class="pagetitle"><span id="title-text"> Engineering : ` + title + ` </span></h1>
+</div>
+<div id="content" class="view">
+<div class="page-metadata">Created by <span class='author'> ` + author + `</span>, last modified on Mar 02, 2021</div>
+<div id="main-content" class="wiki-content group">` + body + `</div>
+` + footer + `
+</div>
+</div>
+<div id="footer" role="contentinfo"><section class="footer-body"><p>Document generated by Confluence on Mar 02, 2021 10:00</p></section></div>
+</div>
+</body>
+</html>`)
+}
+
+func TestConfluence(t *testing.T) {
+	crumbs := `<li class="first"><span><a href="index.html">Engineering</a></span></li>`
+	fsys := fstest.MapFS{
+		"ENG/index.html": {Data: []byte("<html><head><title>Engineering</title></head><body>" +
+			"<ul><li><a href=\"Home_65537.html\">Home</a></li></ul><p>Document generated by Confluence</p></body></html>")},
+		"ENG/Home_65537.html": {Data: confluencePage("Home", crumbs, "Alice Liddell",
+			`<p>Start with <a href="Getting-Started_65540.html">Getting Started</a>.</p>
+<div class="confluence-information-macro confluence-information-macro-note"><div class="confluence-information-macro-body"><p>Read this <strong>first</strong>.</p></div></div>`, "")},
+		"ENG/Getting-Started_65540.html": {Data: confluencePage("Getting Started",
+			crumbs+`<li><span><a href="Home_65537.html">Home</a></span></li>`, "bob",
+			`<h2 id="GettingStarted-Setup">Setup</h2>
+<ol><li>Install <code>make</code></li><li>Run:<ul><li>make build</li></ul></li></ol>
+<div class="code panel pdl"><div class="codeContent panelContent pdl">
+<pre class="syntaxhighlighter-pre" data-syntaxhighlighter-params="brush: bash; gutter: false">make build &amp;&amp; make test</pre>
+</div></div>
+<p><span class="confluence-embedded-file-wrapper"><a href="attachments/65540/65541.png"><img class="confluence-embedded-image" src="attachments/65540/65541.png?version=1&amp;api=v2" alt="diagram"></a></span></p>
+<div class="table-wrap"><table class="confluenceTable"><tbody>
+<tr><th class="confluenceTh">Step</th><th class="confluenceTh">Owner</th></tr>
+<tr><td class="confluenceTd">Build | test</td><td class="confluenceTd"><a href="https://example.com/~bob" class="confluence-userlink">Bob</a></td></tr>
+</tbody></table></div>
+<p>Back to <a href="Home_65537.html#Home-Intro">Home</a>, see <a href="Missing_99.html">old page</a>.</p>`,
+			`<div class="pageSection group"><div class="pageSectionHeader"><h2 id="attachments" class="pageSectionTitle">Attachments:</h2></div>
+<div class="greybox" align="left"><img src="images/icons/bullet_blue.gif" height="8" width="8" alt=""/>
+<a href="attachments/65540/65541.png">diagram.png</a> (image/png)<br/>
+<img src="images/icons/bullet_blue.gif" height="8" width="8" alt=""/>
+<a href="attachments/65540/65542.pdf">runbook.pdf</a> (application/pdf)<br/></div></div>
+<div id="labels-section" class="pageSection"><div class="labels-content"><ul class="label-list">
+<li class="aui-label"><a class="aui-label-split-main" href="labels/onboarding">onboarding</a></li>
+<li class="aui-label"><a class="aui-label-split-main" href="labels/setup">setup</a></li></ul></div></div>`)},
+		"ENG/attachments/65540/65541.png":  {Data: []byte("\x89PNG")},
+		"ENG/attachments/65540/65542.pdf":  {Data: []byte("%PDF")},
+		"ENG/images/icons/bullet_blue.gif": {Data: []byte("GIF89a")},
+		"ENG/styles/site.css":              {Data: []byte("body {}")},
+	}
+
+	format, pages, err := NewRegistry().Scan(fsys, "")
+	require.NoError(t, err)
+	assert.Equal(t, "confluence", format)
+	require.Len(t, pages, 2)
+
+	home, started := pages[0], pages[1]
+	assert.Equal(t, "ENG/Home_65537.html", home.Path)
+	assert.Equal(t, "Home", home.Title)
+	assert.Empty(t, home.ParentPath)
+	assert.Equal(t, "Alice Liddell", home.Author)
+	assert.Equal(t, "Start with [Getting Started](Getting-Started_65540.html).\n\n> Read this **first**.\n", home.Content)
+	assert.Equal(t, []Link{{Ref: "Getting-Started_65540.html", Path: "ENG/Getting-Started_65540.html"}}, home.Links)
+
+	assert.Equal(t, "Getting Started", started.Title)
+	assert.Equal(t, "ENG/Home_65537.html", started.ParentPath)
+	assert.Equal(t, "bob", started.Author)
+	assert.Equal(t, []string{"onboarding", "setup"}, started.Tags)
+	assert.Equal(t, "## Setup\n\n"+
+		"1. Install `make`\n2. Run:\n   - make build\n\n"+
+		"```bash\nmake build && make test\n```\n\n"+
+		"![diagram](attachments/65540/65541.png?version=1&api=v2)\n\n"+
+		"| Step | Owner |\n| --- | --- |\n| Build \\| test | [Bob](https://example.com/~bob) |\n\n"+
+		"Back to [Home](Home_65537.html#Home-Intro), see [old page](Missing_99.html).\n\n"+
+		"## Attachments\n\n- [runbook.pdf](attachments/65540/65542.pdf)\n", started.Content)
+	assert.Equal(t, []File{{Ref: "attachments/65540/65541.png?version=1&api=v2", Path: "ENG/attachments/65540/65541.png"}}, started.Images)
+	assert.Equal(t, []File{{Ref: "attachments/65540/65542.pdf", Path: "ENG/attachments/65540/65542.pdf"}}, started.Attachments)
+	assert.Equal(t, []Link{{Ref: "Home_65537.html#Home-Intro", Path: "ENG/Home_65537.html"}}, started.Links)
+	assert.Equal(t, []string{"link Missing_99.html not found"}, started.Warnings)
+}
+
+func TestHTMLToMarkdownEscaping(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<p>Confluence</p>")},
+		"Notes_1.html": {Data: confluencePage("Notes", "", "",
+			"<p># not a heading<br/>1. not a list</p><p>Use *stars* and <em> spaced </em>text<br></p><blockquote><p>Quoted</p><p>twice</p></blockquote><hr>", "")},
+	}
+
+	pages, err := Scan(fsys, Confluence{})
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	assert.Equal(t, "\\# not a heading\\\n1\\. not a list\n\nUse \\*stars\\* and *spaced* text\n\n> Quoted\n>\n> twice\n\n---\n", pages[0].Content)
+}
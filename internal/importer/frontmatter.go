@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter holds the metadata of a Markdown file. Tags may be a list or
+// a comma-separated string; the draft flag of static site generators sets
+// the status when none is given.
+type frontMatter struct {
+	Title  string
+	Tags   []string
+	Status string
+	Author string
+}
+
+type rawFrontMatter struct {
+	Title  string    `yaml:"title"`
+	Tags   yaml.Node `yaml:"tags"`
+	Status string    `yaml:"status"`
+	Draft  *bool     `yaml:"draft"`
+	Author string    `yaml:"author"`
+}
+
+// parseFrontMatter splits a leading YAML block delimited by "---" lines from
+// the content. The content is returned without the block even when the
+// block cannot be parsed.
+func parseFrontMatter(source string) (frontMatter, string, error) {
+	var meta frontMatter
+	source = strings.TrimPrefix(source, "\ufeff")
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	if !strings.HasPrefix(source, "---\n") {
+		return meta, source, nil
+	}
+
+	rest := source[len("---\n"):]
+	end := strings.Index(rest, "\n---\n")
+	block, content := "", ""
+	switch {
+	case end >= 0:
+		block, content = rest[:end], rest[end+len("\n---\n"):]
+	case strings.HasSuffix(rest, "\n---"):
+		block = strings.TrimSuffix(rest, "\n---")
+	case strings.HasPrefix(rest, "---\n") || rest == "---":
+		// 空的 front matter
+		content = strings.TrimPrefix(strings.TrimPrefix(rest, "---"), "\n")
+	default:
+		// 没有结束分隔符，按普通内容处理
+		return meta, source, nil
+	}
+	content = strings.TrimLeft(content, "\n")
+
+	var raw rawFrontMatter
+	if err := yaml.Unmarshal([]byte(block), &raw); err != nil {
+		return meta, content, err
+	}
+
+	meta.Title = strings.TrimSpace(raw.Title)
+	meta.Author = strings.TrimSpace(raw.Author)
+	meta.Status = strings.ToLower(strings.TrimSpace(raw.Status))
+	if meta.Status == "" && raw.Draft != nil {
+		meta.Status = "published"
+		if *raw.Draft {
+			meta.Status = "draft"
+		}
+	}
+
+	switch raw.Tags.Kind {
+	case yaml.SequenceNode:
+		for _, item := range raw.Tags.Content {
+			if tag := strings.TrimSpace(item.Value); item.Kind == yaml.ScalarNode && tag != "" {
+				meta.Tags = append(meta.Tags, tag)
+			}
+		}
+	case yaml.ScalarNode:
+		for _, tag := range strings.Split(raw.Tags.Value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				meta.Tags = append(meta.Tags, tag)
+			}
+		}
+	}
+	return meta, content, nil
+}
@@ -0,0 +1,429 @@
+package importer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spaces = regexp.MustCompile(`\s+`)
+	// codeLanguage finds the language of a code block in its class, such as
+	// "language-go", or in the parameters of Confluence code macros
+	codeLanguage = regexp.MustCompile(`(?:\blanguage-|\blang-|\bbrush:\s*)([\w+#-]+)`)
+	// orderedMarker matches text that would start an ordered list item
+	orderedMarker = regexp.MustCompile(`^(\d+)([.)])`)
+)
+
+// hardBreak stands for a line break in inline content until paragraph
+// turns it into Markdown
+const hardBreak = "\x00"
+
+// blockElements are the elements converted to Markdown blocks; all others
+// are converted inline
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Body: true, atom.Dd: true, atom.Details: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true,
+	atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Summary: true, atom.Table: true, atom.Ul: true,
+}
+
+// htmlToMarkdown converts the children of n to GitHub flavored Markdown.
+// Elements without a Markdown equivalent keep their text only.
+func htmlToMarkdown(n *html.Node) string {
+	return strings.Join(blocks(n), "\n\n") + "\n"
+}
+
+// blocks converts the children of n to Markdown blocks, running inline
+// content together into paragraphs
+func blocks(n *html.Node) []string {
+	var out []string
+	var text strings.Builder
+	flush := func() {
+		if p := paragraph(text.String()); p != "" {
+			out = append(out, p)
+		}
+		text.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockElements[c.DataAtom] {
+			flush()
+			out = append(out, block(c)...)
+			continue
+		}
+		text.WriteString(inline(c))
+	}
+	flush()
+	return out
+}
+
+func block(n *html.Node) []string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := paragraph(strings.ReplaceAll(inlineChildren(n), hardBreak, " "))
+		if text == "" {
+			return nil
+		}
+		return []string{strings.Repeat("#", level) + " " + text}
+	case atom.Ul, atom.Ol:
+		if list := listBlock(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case atom.Pre:
+		return []string{codeBlock(n)}
+	case atom.Blockquote:
+		return quote(blocks(n))
+	case atom.Hr:
+		return []string{"---"}
+	case atom.Table:
+		if table := tableBlock(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case atom.Div:
+		// Confluence 的提示框转为引用
+		if hasClass(n, "confluence-information-macro") || (hasClass(n, "panel") && !hasClass(n, "code")) {
+			return quote(blocks(n))
+		}
+	}
+	return blocks(n)
+}
+
+func quote(content []string) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.Join(content, "\n\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return []string{strings.Join(lines, "\n")}
+}
+
+func listBlock(n *html.Node) string {
+	start := 1
+	if s, err := strconv.Atoi(attr(n, "start")); err == nil {
+		start = s
+	}
+
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(start+len(items)) + ". "
+		}
+		content := strings.Join(blocks(li), "\n")
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(content, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, strings.TrimRight(marker+strings.Join(lines, "\n"), " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+func codeBlock(n *html.Node) string {
+	language := attr(n, "data-language")
+	for _, node := range []*html.Node{n, firstElement(n, atom.Code)} {
+		if node == nil || language != "" {
+			continue
+		}
+		if m := codeLanguage.FindStringSubmatch(attr(node, "class") + " " + attr(node, "data-syntaxhighlighter-params")); m != nil {
+			language = m[1]
+		}
+	}
+
+	code := strings.TrimSuffix(textContent(n), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+func tableBlock(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Th || cell.DataAtom == atom.Td) {
+						row = append(row, tableCell(cell))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// tableCell converts a cell to a single line, as GFM tables require
+func tableCell(n *html.Node) string {
+	var parts []string
+	for _, b := range blocks(n) {
+		b = strings.ReplaceAll(b, "\\\n", "<br>")
+		parts = append(parts, strings.ReplaceAll(b, "\n", " "))
+	}
+	return strings.Join(parts, "<br>")
+}
+
+// inline converts n to inline Markdown. Text is escaped, with whitespace
+// left for paragraph to collapse.
+func inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escapeMarkdown(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head, atom.Title:
+		return ""
+	case atom.Br:
+		return hardBreak
+	case atom.Strong, atom.B:
+		return emphasize(inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return emphasize(inlineChildren(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return emphasize(inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return codeSpan(textContent(n))
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + escapeMarkdown(spaces.ReplaceAllString(attr(n, "alt"), " ")) + "](" + linkDestination(src) + ")"
+	case atom.A:
+		text := inlineChildren(n)
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		// 只包着图片的链接（如缩略图）保留图片本身
+		if img := firstElement(n, atom.Img); img != nil && strings.TrimSpace(textContent(n)) == "" {
+			return text
+		}
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			trimmed = escapeMarkdown(href)
+		}
+		return leadingSpace(text) + "[" + trimmed + "](" + linkDestination(href) + ")" + trailingSpace(text)
+	}
+	return inlineChildren(n)
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockElements[c.DataAtom] {
+			// 行内元素里的块元素按换行处理
+			b.WriteString(" " + strings.Join(blocks(c), " ") + " ")
+			continue
+		}
+		b.WriteString(inline(c))
+	}
+	return b.String()
+}
+
+// paragraph collapses the whitespace of inline Markdown and escapes what
+// would otherwise start a block
+func paragraph(text string) string {
+	lines := strings.Split(text, hardBreak)
+	var kept []string
+	for _, line := range lines {
+		line = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+		kept = append(kept, escapeLineStart(line))
+	}
+	// 去掉首尾的空行
+	for len(kept) > 0 && kept[0] == "" {
+		kept = kept[1:]
+	}
+	for len(kept) > 0 && kept[len(kept)-1] == "" {
+		kept = kept[:len(kept)-1]
+	}
+	return strings.Join(kept, "\\\n")
+}
+
+// emphasize wraps text in marker, keeping surrounding whitespace outside as
+// Markdown requires
+func emphasize(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	return leadingSpace(text) + marker + trimmed + marker + trailingSpace(text)
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+}
+
+func trailingSpace(s string) string {
+	return s[len(strings.TrimRightFunc(s, unicode.IsSpace)):]
+}
+
+func codeSpan(code string) string {
+	code = spaces.ReplaceAllString(code, " ")
+	if code == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+func linkDestination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "~", `\~`, "|", `\|`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeLineStart escapes characters that would turn a line into a heading,
+// quote, list item or thematic break
+func escapeLineStart(line string) string {
+	if line == "" {
+		return line
+	}
+	switch line[0] {
+	case '#', '>', '-', '+', '=':
+		return `\` + line
+	}
+	if m := orderedMarker.FindStringSubmatch(line); m != nil {
+		return m[1] + `\` + line[len(m[1]):]
+	}
+	return line
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+			return
+		}
+		if node.Type == html.ElementNode && node.DataAtom == atom.Br {
+			b.WriteString("\n")
+			return
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the first descendant of n, depth first, matching match
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := find(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var found []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, findAll(c, match)...)
+	}
+	return found
+}
+
+func firstElement(n *html.Node, a atom.Atom) *html.Node {
+	return find(n, func(c *html.Node) bool { return c.DataAtom == a })
+}
+
+func byID(n *html.Node, id string) *html.Node {
+	return find(n, func(c *html.Node) bool { return attr(c, "id") == id })
+}
@@ -0,0 +1,192 @@
+// Package importer reads exported documentation, such as an unpacked folder
+// of Markdown files or a Confluence or Notion export, into pages ready to be
+// stored as documents.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+const (
+	// MaxPages caps the number of pages of one import
+	MaxPages = 20000
+	// MaxPageSize caps the size of a page file
+	MaxPageSize = 4 << 20
+)
+
+var (
+	ErrTooManyPages      = errors.New("too many pages to import")
+	ErrUnsupportedFormat = errors.New("unsupported import format")
+)
+
+// Page is a document to import. Path identifies the page within the tree,
+// such as the slash-separated path of its file; folders without a page of
+// their own get the path of the folder with a trailing slash. ParentPath is
+// empty for pages at the top of the tree.
+type Page struct {
+	Path       string
+	ParentPath string
+	// Source is the file the content was read from, against which relative
+	// links are resolved; it is empty for folders without content
+	Source  string
+	Title   string
+	Content string
+	Tags    []string
+	Status  string
+	// Author is the name of the user who wrote the page in the exporting
+	// application, if known
+	Author string
+	// Images are the files of the tree shown by the content; Attachments
+	// the files it links to
+	Images      []File
+	Attachments []File
+	// Links are the links of the content to other pages of the tree
+	Links []Link
+	// Warnings describe problems that did not prevent the import of the page
+	Warnings []string
+}
+
+// File is a file of the tree referenced by a relative link of a page
+type File struct {
+	// Ref is the link as written in the content
+	Ref string
+	// Path is where the file is in the tree
+	Path string
+}
+
+// Link is a relative link of a page to another page of the tree
+type Link struct {
+	// Ref is the link as written in the content
+	Ref string
+	// Path is the path of the linked page
+	Path string
+}
+
+// Format reads the pages of one kind of export. Formats convert content to
+// Markdown and set the hierarchy, leaving references between the files of
+// the tree to Scan.
+type Format interface {
+	// Detect reports whether fsys holds an export of the format
+	Detect(fsys fs.FS) bool
+	Read(fsys fs.FS) ([]*Page, error)
+}
+
+// Registry selects the format of an export by name or by its content
+type Registry struct {
+	formats map[string]Format
+	names   []string
+}
+
+// NewRegistry returns a registry with the Confluence and Notion formats,
+// detected in that order, and plain Markdown as the fallback
+func NewRegistry() *Registry {
+	r := &Registry{formats: make(map[string]Format)}
+	r.Register("confluence", Confluence{})
+	r.Register("notion", Notion{})
+	r.Register("markdown", Markdown{})
+	return r
+}
+
+// Register adds a format; formats are detected in the order registered
+func (r *Registry) Register(name string, format Format) {
+	if _, ok := r.formats[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.formats[name] = format
+}
+
+// Scan reads fsys with the named format, or with the first format that
+// detects it when name is empty, and returns the name of the format used
+func (r *Registry) Scan(fsys fs.FS, name string) (string, []*Page, error) {
+	if name != "" {
+		format, ok := r.formats[name]
+		if !ok {
+			return "", nil, ErrUnsupportedFormat
+		}
+		pages, err := Scan(fsys, format)
+		return name, pages, err
+	}
+
+	for _, name := range r.names {
+		if format := r.formats[name]; format.Detect(fsys) {
+			pages, err := Scan(fsys, format)
+			return name, pages, err
+		}
+	}
+	return "", nil, ErrUnsupportedFormat
+}
+
+// Scan reads the pages of fsys with format and resolves their references.
+// Pages are returned parents before their children and siblings by path.
+func Scan(fsys fs.FS, format Format) ([]*Page, error) {
+	pages, err := format.Read(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) > MaxPages {
+		return nil, ErrTooManyPages
+	}
+	resolveReferences(fsys, pages)
+	return order(pages), nil
+}
+
+// order sorts pages parents first. Pages whose parent is missing, or that
+// are their own ancestors, are moved to the top of the tree.
+func order(pages []*Page) []*Page {
+	byPath := make(map[string]*Page, len(pages))
+	for _, page := range pages {
+		byPath[page.Path] = page
+	}
+	children := make(map[string][]*Page)
+	var roots []*Page
+	for _, page := range pages {
+		if page.ParentPath != "" && byPath[page.ParentPath] == nil {
+			page.Warnings = append(page.Warnings, fmt.Sprintf("parent page %s not found", page.ParentPath))
+			page.ParentPath = ""
+		}
+		if page.ParentPath == "" {
+			roots = append(roots, page)
+			continue
+		}
+		children[page.ParentPath] = append(children[page.ParentPath], page)
+	}
+
+	sorted := make([]*Page, 0, len(pages))
+	visited := make(map[string]bool, len(pages))
+	var visit func(level []*Page)
+	visit = func(level []*Page) {
+		sort.Slice(level, func(i, j int) bool {
+			return level[i].Path < level[j].Path
+		})
+		for _, page := range level {
+			if visited[page.Path] {
+				continue
+			}
+			visited[page.Path] = true
+			sorted = append(sorted, page)
+			visit(children[page.Path])
+		}
+	}
+	visit(roots)
+
+	// 剩下的页面处在循环中，断开后放到顶层
+	for len(sorted) < len(pages) {
+		var cyclic []*Page
+		for _, page := range pages {
+			if !visited[page.Path] {
+				cyclic = append(cyclic, page)
+			}
+		}
+		sort.Slice(cyclic, func(i, j int) bool {
+			return cyclic[i].Path < cyclic[j].Path
+		})
+		page := cyclic[0]
+		page.Warnings = append(page.Warnings, fmt.Sprintf("parent page %s is a child of the page", page.ParentPath))
+		page.ParentPath = ""
+		visit([]*Page{page})
+	}
+	return sorted
+}
@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"intro.md": {Data: []byte("---\ntitle: Introduction\ntags: [onboarding, docs]\nstatus: Published\nauthor: alice\n---\n\n# Welcome\n" +
+			"See [setup](guides/setup.md#install), [all guides](guides/README.md), [the spec](files/spec.pdf) and [the wiki](https://example.com).\n")},
+		"files/spec.pdf":   {Data: []byte("%PDF")},
+		"guides/README.md": {Data: []byte("---\ntags: guides\n---\nAll guides.\n")},
+		"guides/index.md":  {Data: []byte("Ignored.\n")},
+		"guides/setup.md": {Data: []byte("---\ndraft: true\n---\n![diagram](img/flow%20chart.png \"Flow\")\n" +
+			"<img src=\"../logo.svg\" width=\"20\">\n![remote](https://example.com/a.png) ![gone](missing.png) ![up](../../x.png)\n")},
+		"guides/img/flow chart.png":  {Data: []byte("png")},
+		"guides/deep/linux/shell.md": {Data: []byte("Use the shell.")},
+		"logo.svg":                   {Data: []byte("<svg/>")},
+		".git/HEAD.md":               {Data: []byte("hidden")},
+		"__MACOSX/intro.md":          {Data: []byte("fork")},
+		"notes.txt":                  {Data: []byte("not markdown")},
+	}
+
+	pages, err := Scan(fsys, Markdown{})
+	require.NoError(t, err)
+
+	var paths []string
+	byPath := make(map[string]*Page)
+	for _, page := range pages {
+		paths = append(paths, page.Path)
+		byPath[page.Path] = page
+	}
+	assert.Equal(t, []string{"guides/", "guides/deep/", "guides/deep/linux/", "guides/deep/linux/shell.md", "guides/setup.md", "intro.md"}, paths)
+
+	intro := byPath["intro.md"]
+	assert.Equal(t, "Introduction", intro.Title)
+	assert.Equal(t, []string{"onboarding", "docs"}, intro.Tags)
+	assert.Equal(t, "published", intro.Status)
+	assert.Equal(t, "alice", intro.Author)
+	assert.Empty(t, intro.ParentPath)
+	assert.Equal(t, []Link{
+		{Ref: "guides/setup.md#install", Path: "guides/setup.md"},
+		{Ref: "guides/README.md", Path: "guides/"},
+	}, intro.Links)
+	assert.Equal(t, []File{{Ref: "files/spec.pdf", Path: "files/spec.pdf"}}, intro.Attachments)
+
+	guides := byPath["guides/"]
+	assert.Equal(t, "guides", guides.Title)
+	assert.Equal(t, "All guides.\n", guides.Content)
+	assert.Equal(t, []string{"guides"}, guides.Tags)
+	assert.Equal(t, []string{"guides/index.md ignored, folder content taken from guides/README.md"}, guides.Warnings)
+
+	shell := byPath["guides/deep/linux/shell.md"]
+	assert.Equal(t, "shell", shell.Title)
+	assert.Equal(t, "guides/deep/linux/", shell.ParentPath)
+	assert.Equal(t, "guides/deep/", byPath["guides/deep/linux/"].ParentPath)
+
+	setup := byPath["guides/setup.md"]
+	assert.Equal(t, "draft", setup.Status)
+	assert.Equal(t, []File{
+		{Ref: "img/flow%20chart.png", Path: "guides/img/flow chart.png"},
+		{Ref: "../logo.svg", Path: "logo.svg"},
+	}, setup.Images)
+	assert.Equal(t, []string{"image missing.png not found", "image ../../x.png is outside of the imported folder"}, setup.Warnings)
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	meta, content, err := parseFrontMatter("---\r\ntitle: [broken\r\n---\r\nBody")
+	assert.Error(t, err)
+	assert.Equal(t, "Body", content)
+	assert.Empty(t, meta.Title)
+
+	// 没有结束分隔符时整段作为正文
+	_, content, err = parseFrontMatter("---\nnot front matter")
+	require.NoError(t, err)
+	assert.Equal(t, "---\nnot front matter", content)
+
+	meta, content, err = parseFrontMatter("---\n---\nBody")
+	require.NoError(t, err)
+	assert.Equal(t, "Body", content)
+	assert.Empty(t, meta.Tags)
+}
+
+func TestRewriteImages(t *testing.T) {
+	content := "![a](<img/a b.png>) ![b](img/b.png 'B') <img alt=\"c\" src='img/c.png'> ![d](https://example.com/d.png)"
+	urls := map[string]string{"img/a b.png": "/files/1", "img/b.png": "/files/2", "img/c.png": "/files/3"}
+
+	rewritten := RewriteImages(content, func(ref string) (string, bool) {
+		url, ok := urls[ref]
+		return url, ok
+	})
+	assert.Equal(t, "![a](/files/1) ![b](/files/2 'B') <img alt=\"c\" src='/files/3'> ![d](https://example.com/d.png)", rewritten)
+}
@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Markdown reads a tree of Markdown files. Every file becomes a page and
+// every folder a page holding the pages inside it, with its content taken
+// from an index.md or README.md in the folder, if any. Hidden files and
+// folders, and folders without Markdown files, are skipped.
+type Markdown struct{}
+
+// Detect accepts any tree, Markdown being the fallback format
+func (Markdown) Detect(fsys fs.FS) bool {
+	return true
+}
+
+func (Markdown) Read(fsys fs.FS) ([]*Page, error) {
+	files, err := walkFiles(fsys, isMarkdown)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]*Page)
+	indexes := make(map[string]string)
+	for _, name := range files {
+		dir := path.Dir(name)
+		if dir != "." && isIndex(name) {
+			// index.md 和 README.md 作为所在目录的内容
+			folder := folderPage(pages, dir)
+			if other, ok := indexes[dir]; ok {
+				folder.Warnings = append(folder.Warnings, fmt.Sprintf("%s ignored, folder content taken from %s", name, other))
+				continue
+			}
+			indexes[dir] = name
+			if err := readMarkdown(fsys, name, folder); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		page := &Page{Path: name}
+		if dir != "." {
+			page.ParentPath = folderPage(pages, dir).Path
+		}
+		if err := readMarkdown(fsys, name, page); err != nil {
+			return nil, err
+		}
+		pages[page.Path] = page
+	}
+
+	list := make([]*Page, 0, len(pages))
+	for _, page := range pages {
+		list = append(list, page)
+	}
+	return list, nil
+}
+
+// walkFiles returns the files of fsys selected by match, skipping hidden
+// files and folders
+func walkFiles(fsys fs.FS, match func(name string) bool) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && skipped(d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && match(name) {
+			files = append(files, name)
+			if len(files) > MaxPages {
+				return ErrTooManyPages
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// folderPage returns the page of a folder, creating it and the pages of its
+// parent folders when missing
+func folderPage(pages map[string]*Page, dir string) *Page {
+	key := dir + "/"
+	if page, ok := pages[key]; ok {
+		return page
+	}
+	page := &Page{Path: key, Title: path.Base(dir)}
+	if parent := path.Dir(dir); parent != "." {
+		page.ParentPath = folderPage(pages, parent).Path
+	}
+	pages[key] = page
+	return page
+}
+
+// readFile reads a page file, warning instead when it is too large
+func readFile(fsys fs.FS, name string, page *Page) ([]byte, bool, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxPageSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > MaxPageSize {
+		page.Warnings = append(page.Warnings, fmt.Sprintf("%s is larger than %d bytes and was not imported", name, MaxPageSize))
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+// readMarkdown sets the content of page from a Markdown file
+func readMarkdown(fsys fs.FS, name string, page *Page) error {
+	if page.Title == "" {
+		page.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	data, ok, err := readFile(fsys, name, page)
+	if err != nil || !ok {
+		return err
+	}
+
+	meta, content, err := parseFrontMatter(string(data))
+	if err != nil {
+		page.Warnings = append(page.Warnings, fmt.Sprintf("invalid front matter: %v", err))
+	}
+	if meta.Title != "" {
+		page.Title = meta.Title
+	}
+	page.Source = name
+	page.Tags = meta.Tags
+	page.Status = meta.Status
+	page.Author = meta.Author
+	page.Content = content
+	return nil
+}
+
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+func isIndex(name string) bool {
+	base := strings.ToLower(strings.TrimSuffix(path.Base(name), path.Ext(name)))
+	return base == "index" || base == "readme"
+}
+
+// skipped reports whether a file or folder is left out of imports, such as
+// .git or the resource forks macOS adds to archives
+func skipped(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "__MACOSX"
+}
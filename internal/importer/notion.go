@@ -0,0 +1,253 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// notionID matches the ID Notion appends to exported file and folder
+	// names, as in "Getting Started 0b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d"
+	notionID = regexp.MustCompile(`\s+[0-9a-f]{32}$`)
+	// notionProperty matches a "Name: value" property line
+	notionProperty = regexp.MustCompile(`^([^:\n]{1,64}): (.*)$`)
+)
+
+// Notion reads a Notion export. Pages are Markdown files whose child pages
+// are in a folder of the same name, and databases CSV files whose rows are
+// pages in such a folder. Notion writes the title as the first heading of a
+// page and its properties as "Name: value" lines below it; tags, status and
+// author are taken from the Tags, Status and Created By properties.
+type Notion struct{}
+
+func (Notion) Detect(fsys fs.FS) bool {
+	found := false
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && skipped(d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && isNotionFile(name) && notionID.MatchString(notionStem(name)) {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+func (Notion) Read(fsys fs.FS) ([]*Page, error) {
+	files, err := walkFiles(fsys, isNotionFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一个数据库可能同时导出 "X.csv" 和包含全部列的 "X_all.csv"，
+	// 页面使用链接指向的 "X.csv"
+	byStem := make(map[string]string, len(files))
+	for _, name := range files {
+		stem := notionStem(name)
+		if other, ok := byStem[stem]; !ok || strings.HasSuffix(other, "_all.csv") {
+			byStem[stem] = name
+		}
+	}
+
+	pages := make(map[string]*Page, len(byStem))
+	for stem, name := range byStem {
+		page := &Page{Path: name, Title: notionTitle(stem)}
+		page.ParentPath = notionParent(pages, byStem, path.Dir(stem))
+		var err error
+		if isMarkdown(name) {
+			err = readNotionPage(fsys, name, page)
+		} else {
+			err = readNotionDatabase(fsys, name, page, byStem)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pages[page.Path] = page
+	}
+
+	list := make([]*Page, 0, len(pages))
+	for _, page := range pages {
+		list = append(list, page)
+	}
+	return list, nil
+}
+
+func isNotionFile(name string) bool {
+	return isMarkdown(name) || strings.EqualFold(path.Ext(name), ".csv")
+}
+
+// notionStem returns the path of a page file without its extension, which
+// is also the path of the folder of its children
+func notionStem(name string) string {
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	if strings.EqualFold(path.Ext(name), ".csv") {
+		stem = strings.TrimSuffix(stem, "_all")
+	}
+	return stem
+}
+
+func notionTitle(stem string) string {
+	return notionID.ReplaceAllString(path.Base(stem), "")
+}
+
+// notionParent returns the path of the page holding the pages of folder
+// dir: the page of the same name, or a page for the folder itself when the
+// export has none
+func notionParent(pages map[string]*Page, byStem map[string]string, dir string) string {
+	if dir == "." {
+		return ""
+	}
+	if name, ok := byStem[dir]; ok {
+		return name
+	}
+	key := dir + "/"
+	if _, ok := pages[key]; !ok {
+		pages[key] = &Page{Path: key, Title: notionTitle(dir), ParentPath: notionParent(pages, byStem, path.Dir(dir))}
+	}
+	return key
+}
+
+func readNotionPage(fsys fs.FS, name string, page *Page) error {
+	data, ok, err := readFile(fsys, name, page)
+	if err != nil || !ok {
+		return err
+	}
+	content := strings.ReplaceAll(strings.TrimPrefix(string(data), "\ufeff"), "\r\n", "\n")
+
+	if rest, ok := strings.CutPrefix(content, "# "); ok {
+		title, body, _ := strings.Cut(rest, "\n")
+		if title = strings.TrimSpace(title); title != "" {
+			page.Title = title
+		}
+		content = strings.TrimLeft(body, "\n")
+	}
+
+	// 标题下的第一段全部是 "名称: 值" 时视为页面属性
+	block, body, _ := strings.Cut(content, "\n\n")
+	lines := strings.Split(block, "\n")
+	properties := true
+	for _, line := range lines {
+		properties = properties && notionProperty.MatchString(line)
+	}
+	if properties {
+		var kept []string
+		for _, line := range lines {
+			m := notionProperty.FindStringSubmatch(line)
+			if !page.setProperty(strings.TrimSpace(m[1]), strings.TrimSpace(m[2])) {
+				kept = append(kept, line)
+			}
+		}
+		content = body
+		if len(kept) > 0 {
+			content = strings.Join(kept, "\n") + "\n\n" + body
+		}
+	}
+
+	page.Source = name
+	page.Content = content
+	return nil
+}
+
+// setProperty maps a Notion property to the page, reporting whether the
+// property was used
+func (p *Page) setProperty(name, value string) bool {
+	switch strings.ToLower(name) {
+	case "tags", "tag", "labels":
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				p.Tags = append(p.Tags, tag)
+			}
+		}
+		return true
+	case "status":
+		switch status := strings.ToLower(value); status {
+		case "draft", "published", "archived":
+			p.Status = status
+			return true
+		}
+	case "created by", "author":
+		if value != "" {
+			p.Author = value
+			return true
+		}
+	}
+	return false
+}
+
+// readNotionDatabase sets the content of a database page to a table of its
+// rows, linking rows to their pages. The rows are read from the export of
+// all columns when there is one.
+func readNotionDatabase(fsys fs.FS, name string, page *Page, byStem map[string]string) error {
+	stem := notionStem(name)
+	source := name
+	if all := stem + "_all.csv"; all != name {
+		if _, err := fs.Stat(fsys, all); err == nil {
+			source = all
+		}
+	}
+	data, ok, err := readFile(fsys, source, page)
+	if err != nil || !ok {
+		return err
+	}
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		page.Warnings = append(page.Warnings, fmt.Sprintf("invalid CSV: %v", err))
+		return nil
+	}
+
+	// 行页面位于与数据库同名的目录中，按去掉 ID 后的标题匹配
+	rows := make(map[string]string)
+	for rowStem, rowName := range byStem {
+		if path.Dir(rowStem) == stem && isMarkdown(rowName) {
+			rows[notionTitle(rowStem)] = rowName
+		}
+	}
+
+	var b strings.Builder
+	for i, record := range records {
+		cells := make([]string, len(record))
+		for j, value := range record {
+			cells[j] = strings.ReplaceAll(escapeMarkdown(strings.TrimSpace(value)), "\n", "<br>")
+		}
+		if i > 0 && len(record) > 0 {
+			if rowName, ok := rows[strings.TrimSpace(record[0])]; ok {
+				cells[0] = "[" + cells[0] + "](" + relativeRef(name, rowName) + ")"
+			}
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", len(record)) + "\n")
+		}
+	}
+
+	page.Source = name
+	page.Content = b.String()
+	return nil
+}
+
+// relativeRef returns an escaped link from the file name to target
+func relativeRef(name, target string) string {
+	rel := strings.TrimPrefix(target, path.Dir(name)+"/")
+	segments := strings.Split(rel, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotion(t *testing.T) {
+	const (
+		wiki  = "Wiki 0b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d"
+		tasks = "Tasks 1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f"
+	)
+	fsys := fstest.MapFS{
+		wiki + ".md": {Data: []byte("# Wiki\n\nWelcome. See [Tasks](Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d/Tasks%201c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f.csv).\n\n" +
+			"![Untitled](Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d/Untitled.png)\n")},
+		wiki + "/Untitled.png":      {Data: []byte("\x89PNG")},
+		wiki + "/" + tasks + ".csv": {Data: []byte("\ufeffName,Tags,Status\nWrite docs,docs,Published\n")},
+		wiki + "/" + tasks + "_all.csv": {Data: []byte("\ufeffName,Tags,Status,Created By\n" +
+			"Write docs,docs,Published,alice\n\"Fix | bug\",,Draft,bob\n")},
+		wiki + "/" + tasks + "/Write docs 2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a.md": {Data: []byte("# Write docs\n\n" +
+			"Tags: docs, writing\nStatus: Published\nCreated By: alice\nDue: March 2, 2021\n\nBack to the [wiki](../../Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d.md).\n")},
+		"Archive 3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b/Note 4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c.md": {Data: []byte("# Note\n\nNote: properties need all lines to match\n")},
+	}
+
+	format, pages, err := NewRegistry().Scan(fsys, "")
+	require.NoError(t, err)
+	assert.Equal(t, "notion", format)
+
+	var paths []string
+	byPath := make(map[string]*Page)
+	for _, page := range pages {
+		paths = append(paths, page.Path)
+		byPath[page.Path] = page
+	}
+	assert.Equal(t, []string{
+		"Archive 3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b/",
+		"Archive 3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b/Note 4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c.md",
+		wiki + ".md",
+		wiki + "/" + tasks + ".csv",
+		wiki + "/" + tasks + "/Write docs 2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a.md",
+	}, paths)
+
+	assert.Equal(t, "Archive", byPath["Archive 3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b/"].Title)
+	note := byPath["Archive 3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b/Note 4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c.md"]
+	assert.Equal(t, "Note: properties need all lines to match\n", note.Content)
+
+	home := byPath[wiki+".md"]
+	assert.Equal(t, "Wiki", home.Title)
+	assert.Equal(t, []Link{{Ref: "Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d/Tasks%201c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f.csv", Path: wiki + "/" + tasks + ".csv"}}, home.Links)
+	assert.Equal(t, []File{{Ref: "Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d/Untitled.png", Path: wiki + "/Untitled.png"}}, home.Images)
+
+	database := byPath[wiki+"/"+tasks+".csv"]
+	assert.Equal(t, "Tasks", database.Title)
+	assert.Equal(t, wiki+".md", database.ParentPath)
+	assert.Equal(t, "| Name | Tags | Status | Created By |\n| --- | --- | --- | --- |\n"+
+		"| [Write docs](Tasks%201c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f/Write%20docs%202d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a.md) | docs | Published | alice |\n"+
+		"| Fix \\| bug |  | Draft | bob |\n", database.Content)
+	require.Len(t, database.Links, 1)
+
+	row := byPath[wiki+"/"+tasks+"/Write docs 2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a.md"]
+	assert.Equal(t, "Write docs", row.Title)
+	assert.Equal(t, database.Path, row.ParentPath)
+	assert.Equal(t, []string{"docs", "writing"}, row.Tags)
+	assert.Equal(t, "published", row.Status)
+	assert.Equal(t, "alice", row.Author)
+	assert.Equal(t, "Due: March 2, 2021\n\nBack to the [wiki](../../Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d.md).\n", row.Content)
+	assert.Equal(t, []Link{{Ref: "../../Wiki%200b1f6c1e8f3c4b4d9d7a2e5f6a7b8c9d.md", Path: wiki + ".md"}}, row.Links)
+}
+
+func TestRegistryScan(t *testing.T) {
+	fsys := fstest.MapFS{"notes.md": {Data: []byte("# Notes")}}
+
+	format, pages, err := NewRegistry().Scan(fsys, "")
+	require.NoError(t, err)
+	assert.Equal(t, "markdown", format)
+	assert.Len(t, pages, 1)
+
+	_, _, err = NewRegistry().Scan(fsys, "wordpress")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
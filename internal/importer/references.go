@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// markdownImage matches ![alt](target "title"), capturing the target
+	markdownImage = regexp.MustCompile(`!\[(?:[^\]\\]|\\.)*\]\(\s*(<[^>\n]*>|[^\s)]+)(?:\s+(?:"[^"]*"|'[^']*'|\([^)]*\)))?\s*\)`)
+	// markdownLink matches [text](target "title") and images, which are
+	// told apart by the leading "!"
+	markdownLink = regexp.MustCompile(`!?\[(?:[^\[\]\\]|\\.)*\]\(\s*(<[^>\n]*>|[^\s)]+)(?:\s+(?:"[^"]*"|'[^']*'|\([^)]*\)))?\s*\)`)
+	// htmlImage matches the source of an <img> tag
+	htmlImage = regexp.MustCompile(`(?i)<img\b[^>]*?\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// resolveReferences sets the images, attachments and links of pages from
+// the relative links of their content, warning of links to missing files
+func resolveReferences(fsys fs.FS, pages []*Page) {
+	targets := make(map[string]string, len(pages))
+	for _, page := range pages {
+		targets[page.Path] = page.Path
+		if page.Source != "" {
+			targets[page.Source] = page.Path
+		}
+		if folder := strings.TrimSuffix(page.Path, "/"); folder != page.Path {
+			targets[folder] = page.Path
+		}
+	}
+
+	for _, page := range pages {
+		if page.Source == "" {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		eachImageRef(page.Content, func(ref string) {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+			if target, ok := page.resolveFile(fsys, "image", ref); ok {
+				page.Images = append(page.Images, File{Ref: ref, Path: target})
+			}
+		})
+
+		seen = make(map[string]bool)
+		eachLinkRef(page.Content, func(ref string) {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+			target, ok := resolve(page.Source, ref)
+			if !ok {
+				return
+			}
+			if linked, ok := targets[target]; ok && target != "" {
+				page.Links = append(page.Links, Link{Ref: ref, Path: linked})
+				return
+			}
+			if target, ok := page.resolveFile(fsys, "link", ref); ok {
+				page.Attachments = append(page.Attachments, File{Ref: ref, Path: target})
+			}
+		})
+	}
+}
+
+// resolveFile returns the file of the tree a relative link of the page
+// points to. It warns of links to files outside of the tree or missing.
+func (p *Page) resolveFile(fsys fs.FS, kind, ref string) (string, bool) {
+	target, ok := resolve(p.Source, ref)
+	if !ok {
+		return "", false
+	}
+	if target == "" {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%s %s is outside of the imported folder", kind, ref))
+		return "", false
+	}
+	info, err := fs.Stat(fsys, target)
+	if err != nil || info.IsDir() {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%s %s not found", kind, ref))
+		return "", false
+	}
+	return target, true
+}
+
+// RewriteImages replaces the targets of image links in content with the
+// URLs returned by replace; links for which replace returns false are kept
+func RewriteImages(content string, replace func(ref string) (string, bool)) string {
+	for _, re := range []*regexp.Regexp{markdownImage, htmlImage} {
+		content = rewriteRefs(content, re, replace)
+	}
+	return content
+}
+
+// RewriteLinks replaces the targets of Markdown links that are not images,
+// like RewriteImages
+func RewriteLinks(content string, replace func(ref string) (string, bool)) string {
+	return rewriteRefs(content, markdownLink, replace)
+}
+
+func rewriteRefs(content string, re *regexp.Regexp, replace func(ref string) (string, bool)) string {
+	var b strings.Builder
+	last := 0
+	eachMatch(content, re, func(start, end int) {
+		url, ok := replace(refTarget(content[start:end]))
+		if !ok {
+			return
+		}
+		b.WriteString(content[last:start])
+		b.WriteString(url)
+		last = end
+	})
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// eachImageRef calls fn with the target of every image link of content
+func eachImageRef(content string, fn func(ref string)) {
+	for _, re := range []*regexp.Regexp{markdownImage, htmlImage} {
+		eachMatch(content, re, func(start, end int) {
+			fn(refTarget(content[start:end]))
+		})
+	}
+}
+
+// eachLinkRef calls fn with the target of every Markdown link of content
+// that is not an image
+func eachLinkRef(content string, fn func(ref string)) {
+	eachMatch(content, markdownLink, func(start, end int) {
+		fn(refTarget(content[start:end]))
+	})
+}
+
+// eachMatch calls fn with the bounds of the target of every match of re,
+// skipping images when re matches links
+func eachMatch(content string, re *regexp.Regexp, fn func(start, end int)) {
+	for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+		if re == markdownLink && content[m[0]] == '!' {
+			continue
+		}
+		fn(targetGroup(m))
+	}
+}
+
+// targetGroup returns the bounds of the first matched group
+func targetGroup(m []int) (int, int) {
+	for i := 2; i+1 < len(m); i += 2 {
+		if m[i] >= 0 {
+			return m[i], m[i+1]
+		}
+	}
+	return m[0], m[0]
+}
+
+func refTarget(target string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+}
+
+// resolve returns the path in the tree of a link of the file name. ok is
+// false for links that are not relative; the path is empty when the link
+// points outside of the tree.
+func resolve(name, ref string) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "#") || urlScheme.MatchString(ref) {
+		return "", false
+	}
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	target := path.Join(path.Dir(name), ref)
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return "", true
+	}
+	return target, true
+}
@@ -0,0 +1,154 @@
+// Package links finds the links between documents in their content: wiki
+// links naming a document by title, [[Title]] or [[Title|label]], and links
+// to a document's URL, /documents/:id, bare or as Markdown links.
+package links
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Link kinds
+const (
+	Wiki = "wiki" // [[Title]]
+	URL  = "url"  // /documents/:id
+)
+
+var (
+	// wikiLink matches [[Title]] and [[Title|label]]
+	wikiLink = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+	// markdownLink matches [label](/documents/:id...), with or without the
+	// API prefix the importer writes
+	markdownLink = regexp.MustCompile(`\[([^\[\]\n]*)\]\(\s*(?:/api/v1)?/documents/(\d+)([/?#][^\s)]*)?\s*\)`)
+	// bareLink matches /documents/:id not preceded by a host or a path
+	bareLink = regexp.MustCompile(`(?:^|[^\w/.\-])((?:/api/v1)?/documents/(\d+))\b`)
+)
+
+// Link is a link found in a text. Start and End are the byte offsets of the
+// whole link. Title is the title named by a wiki link and ID the document of
+// a URL link; Label is the text shown for the link, if any.
+type Link struct {
+	Kind  string
+	Start int
+	End   int
+	Title string
+	ID    uint
+	Label string
+	// Markdown is set for URL links written as [label](url)
+	Markdown bool
+	// Text is the link as written
+	Text string
+}
+
+// Target returns the target as written: the title of a wiki link or the
+// path of a URL link
+func (l Link) Target() string {
+	if l.Kind == Wiki {
+		return l.Title
+	}
+	return fmt.Sprintf("/documents/%d", l.ID)
+}
+
+// Parse returns the links of text in order
+func Parse(text string) []Link {
+	var found []Link
+	for _, m := range wikiLink.FindAllStringSubmatchIndex(text, -1) {
+		title := strings.TrimSpace(text[m[2]:m[3]])
+		if title == "" {
+			continue
+		}
+		link := Link{Kind: Wiki, Start: m[0], End: m[1], Title: title, Text: text[m[0]:m[1]]}
+		if m[4] >= 0 {
+			link.Label = strings.TrimSpace(text[m[4]:m[5]])
+		}
+		found = append(found, link)
+	}
+
+	for _, m := range markdownLink.FindAllStringSubmatchIndex(text, -1) {
+		id, ok := parseID(text[m[4]:m[5]])
+		if !ok || overlaps(found, m[0], m[1]) {
+			continue
+		}
+		found = append(found, Link{Kind: URL, Start: m[0], End: m[1], ID: id, Label: text[m[2]:m[3]], Markdown: true, Text: text[m[0]:m[1]]})
+	}
+
+	for _, m := range bareLink.FindAllStringSubmatchIndex(text, -1) {
+		id, ok := parseID(text[m[4]:m[5]])
+		if !ok || overlaps(found, m[2], m[3]) {
+			continue
+		}
+		found = append(found, Link{Kind: URL, Start: m[2], End: m[3], ID: id, Text: text[m[2]:m[3]]})
+	}
+
+	slices.SortFunc(found, func(a, b Link) int { return a.Start - b.Start })
+	return found
+}
+
+// Rewrite replaces the links of text for which replace returns true by the
+// returned text
+func Rewrite(text string, replace func(Link) (string, bool)) string {
+	var b strings.Builder
+	last := 0
+	for _, link := range Parse(text) {
+		replacement, ok := replace(link)
+		if !ok {
+			continue
+		}
+		b.WriteString(text[last:link.Start])
+		b.WriteString(replacement)
+		last = link.End
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Relabel returns a Markdown link with its label replaced
+func (l Link) Relabel(label string) string {
+	if !l.Markdown {
+		return l.Text
+	}
+	return "[" + cleanLabel(label) + l.Text[1+len(l.Label):]
+}
+
+// CanWikiLink reports whether title can be written in a wiki link
+func CanWikiLink(title string) bool {
+	return strings.TrimSpace(title) == title && title != "" && !strings.ContainsAny(title, "[]|\n")
+}
+
+// WikiLink formats a wiki link to title, showing label unless it is empty
+func WikiLink(title, label string) string {
+	if label == "" {
+		return "[[" + title + "]]"
+	}
+	return "[[" + title + "|" + label + "]]"
+}
+
+// URLLink formats a Markdown link to the document id
+func URLLink(id uint, label string) string {
+	return fmt.Sprintf("[%s](/documents/%d)", cleanLabel(label), id)
+}
+
+// cleanLabel drops the characters that would end a Markdown link label
+func cleanLabel(label string) string {
+	return strings.NewReplacer("[", "", "]", "", "\n", " ").Replace(label)
+}
+
+func parseID(s string) (uint, bool) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	return uint(id), err == nil && id > 0
+}
+
+func overlaps(found []Link, start, end int) bool {
+	for _, link := range found {
+		if start < link.End && link.Start < end {
+			return true
+		}
+	}
+	return false
+}
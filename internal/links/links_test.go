@@ -0,0 +1,46 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	text := "See [[Oncall runbook]] and [[ Failover | the failover steps ]].\n" +
+		"Imported: [Architecture](/api/v1/documents/12), [comments](/documents/7/comments?page=2).\n" +
+		"Bare /documents/9, not https://example.com/documents/8 or /v2/documents/6, nor [[]] or /documents/abc."
+
+	found := Parse(text)
+	if !assert.Len(t, found, 5) {
+		return
+	}
+	assert.Equal(t, Link{Kind: Wiki, Start: 4, End: 22, Title: "Oncall runbook", Text: "[[Oncall runbook]]"}, found[0])
+	assert.Equal(t, "Failover", found[1].Title)
+	assert.Equal(t, "the failover steps", found[1].Label)
+	assert.Equal(t, Link{Kind: URL, Start: 74, End: 110, ID: 12, Label: "Architecture", Markdown: true, Text: "[Architecture](/api/v1/documents/12)"}, found[2])
+	assert.Equal(t, uint(7), found[3].ID)
+	assert.Equal(t, "/documents/9", found[4].Text)
+	assert.Equal(t, "/documents/9", found[4].Target())
+	assert.Equal(t, "Oncall runbook", found[0].Target())
+}
+
+func TestRewrite(t *testing.T) {
+	text := "[[Runbook]], [[runbook|the runbook]], [Runbook](/documents/3#steps) and /documents/3."
+
+	rewritten := Rewrite(text, func(link Link) (string, bool) {
+		switch {
+		case link.Kind == Wiki:
+			return WikiLink("Oncall runbook", link.Label), true
+		case link.Markdown:
+			return link.Relabel("Oncall [runbook]"), true
+		}
+		return "", false
+	})
+	assert.Equal(t, "[[Oncall runbook]], [[Oncall runbook|the runbook]], [Oncall runbook](/documents/3#steps) and /documents/3.", rewritten)
+
+	assert.Equal(t, text, Rewrite(text, func(Link) (string, bool) { return "", false }))
+	assert.Equal(t, "[Post|mortem](/documents/4)", URLLink(4, "Post|mortem"))
+	assert.False(t, CanWikiLink("Post|mortem"))
+	assert.True(t, CanWikiLink("Oncall runbook"))
+}
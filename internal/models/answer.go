@@ -0,0 +1,19 @@
+package models
+
+// Citation points an answer back to the passage it is based on. Index is the
+// number the answer uses to cite it, e.g. "[1]".
+type Citation struct {
+	Index      int    `json:"index"`
+	DocumentID uint   `json:"document_id"`
+	Version    int    `json:"version"`
+	Title      string `json:"title"`
+	ChunkIndex int    `json:"chunk_index"`
+	Text       string `json:"text"`
+}
+
+// Answer is a generated answer to a question about the documents
+type Answer struct {
+	Question  string     `json:"question"`
+	Answer    string     `json:"answer"`
+	Citations []Citation `json:"citations"`
+}
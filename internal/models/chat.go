@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Chat session scopes
+const (
+	ChatScopeDocument = "document" // a single document
+	ChatScopeFolder   = "folder"   // a document and all its descendants
+	ChatScopeTag      = "tag"      // documents carrying a tag or one of its descendants
+)
+
+// Feedback values of assistant messages
+const (
+	FeedbackNone = 0
+	FeedbackUp   = 1
+	FeedbackDown = -1
+)
+
+// ChatSession is a multi-turn conversation about the documents in its scope.
+// Sessions are deleted when the permissions of a document they draw on
+// change, so they never outlive the user's access to their context.
+type ChatSession struct {
+	ID        uint          `gorm:"primarykey" json:"id"`
+	UserID    uint          `gorm:"not null;index" json:"user_id"`
+	Title     string        `gorm:"size:255" json:"title"`
+	ScopeType string        `gorm:"size:20;not null;index:idx_chat_sessions_scope" json:"scope_type"`
+	ScopeID   uint          `gorm:"not null;index:idx_chat_sessions_scope" json:"scope_id"`
+	Messages  []ChatMessage `gorm:"foreignKey:SessionID" json:"messages,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ChatMessage is one turn of a chat session. Citations hold the passages
+// retrieved as context for an assistant answer.
+type ChatMessage struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	SessionID uint       `gorm:"not null;index" json:"session_id"`
+	Role      string     `gorm:"size:20;not null" json:"role"` // user, assistant
+	Content   string     `gorm:"type:text" json:"content"`
+	Citations []Citation `gorm:"serializer:json;type:text" json:"citations,omitempty"`
+	Feedback  int        `gorm:"default:0" json:"feedback"` // 1 thumbs up, -1 thumbs down
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ChatSessionDocument records that a session used a document as context
+type ChatSessionDocument struct {
+	SessionID  uint `gorm:"primaryKey"`
+	DocumentID uint `gorm:"primaryKey;index"`
+}
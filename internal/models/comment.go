@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AnchorStatusAnchored = "anchored"
+	AnchorStatusOrphaned = "orphaned" // the anchored text was removed from the document
+)
+
+// CommentAnchor attaches a thread to a range of the document's content.
+// Start and End are character offsets into the content; Quote is the text
+// of the range and Prefix and Suffix the text around it, which are used to
+// find the range again after the document is edited.
+type CommentAnchor struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Quote  string `json:"quote"`
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// Comment is a remark on a document. A comment without ParentID starts a
+// thread, which can be resolved; replies belong to the thread's first
+// comment. Every comment records the document version it was written
+// against, so its context can be shown after the document changed. A thread
+// can be anchored to a range of the content, which follows the text as the
+// document is edited.
+type Comment struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	DocumentID   uint           `gorm:"not null;index" json:"document_id"`
+	Version      int            `gorm:"not null" json:"version"`
+	ContentHash  string         `gorm:"size:64;not null" json:"content_hash"` // SHA-256 of the content commented on
+	ParentID     *uint          `gorm:"index" json:"parent_id,omitempty"`
+	AuthorID     uint           `gorm:"not null" json:"author_id"`
+	Author       *User          `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	Content      string         `gorm:"type:text;not null" json:"content"`
+	Anchor       *CommentAnchor `gorm:"serializer:json;type:text" json:"anchor,omitempty"`
+	AnchorStatus string         `gorm:"size:20;index" json:"anchor_status,omitempty"`
+	Mentions     []uint         `gorm:"serializer:json;type:text" json:"mentions,omitempty"` // IDs of the mentioned users
+	ResolvedAt   *time.Time     `json:"resolved_at,omitempty"`
+	ResolvedByID *uint          `json:"resolved_by_id,omitempty"`
+	EditedAt     *time.Time     `json:"edited_at,omitempty"`
+	Replies      []Comment      `gorm:"foreignKey:ParentID" json:"replies,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
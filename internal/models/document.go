@@ -6,30 +6,69 @@ import (
 	"gorm.io/gorm"
 )
 
+// Document statuses
+const (
+	DocumentStatusDraft     = "draft"
+	DocumentStatusPublished = "published"
+	DocumentStatusArchived  = "archived"
+)
+
+// Document is a document of the knowledge base. Its Slug, made from the
+// title, is unique among the documents of its workspace, or the creator's
+// documents outside of workspaces.
 type Document struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Title     string         `gorm:"size:255;not null;uniqueIndex:idx_title_creator" json:"title"`
-	Content   string         `gorm:"type:text" json:"content"`
-	Version   int            `gorm:"default:1" json:"version"`
-	Status    string         `gorm:"size:20;default:'draft'" json:"status"` // draft, published, archived
-	CreatorID uint           `gorm:"not null;uniqueIndex:idx_title_creator" json:"creator_id"`
-	Creator   User           `gorm:"foreignKey:CreatorID" json:"creator"`
-	ParentID  *uint          `gorm:"default:null" json:"parent_id"`
-	Path      string         `gorm:"size:255" json:"path"`
-	Tags      []Tag          `gorm:"many2many:document_tags;" json:"tags"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Title       string         `gorm:"size:255;not null;uniqueIndex:idx_title_creator" json:"title"`
+	Content     string         `gorm:"type:text" json:"content"`
+	Version     int            `gorm:"default:1" json:"version"`
+	Status      string         `gorm:"size:20;default:'draft'" json:"status"` // draft, published, archived
+	CreatorID   uint           `gorm:"not null;uniqueIndex:idx_title_creator" json:"creator_id"`
+	Creator     User           `gorm:"foreignKey:CreatorID" json:"creator"`
+	AuthorID    *uint          `gorm:"index" json:"author_id,omitempty"` // who wrote imported content, when known
+	Author      *User          `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	ParentID    *uint          `gorm:"default:null" json:"parent_id"`
+	WorkspaceID *uint          `gorm:"index" json:"workspace_id,omitempty"`
+	Path        string         `gorm:"size:255;index" json:"path"` // where an imported document came from
+	Slug        string         `gorm:"size:255;index" json:"slug"`
+	SlugPath    string         `gorm:"-" json:"slug_path,omitempty"` // the slugs of the document and its parents, "handbook/oncall"
+	Tags        []Tag          `gorm:"many2many:document_tags;" json:"tags"`
+	Summary     *Summary       `gorm:"-" json:"summary,omitempty"`
+	Annotations []Comment      `gorm:"-" json:"annotations,omitempty"` // unresolved anchored threads, for highlighting
+	Lock        *DocumentLock  `gorm:"-" json:"lock,omitempty"`        // the active edit lock
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
+// Tag is a document label. Names may be hierarchical ("team/platform/infra")
+// with each level stored as its own tag linked through ParentID, or
+// namespaced ("env:prod") with the part before the colon kept in Namespace.
 type Tag struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
-	Name      string         `gorm:"size:50;not null;unique" json:"name"`
+	Name      string         `gorm:"size:255;not null;unique" json:"name"`
+	Namespace string         `gorm:"size:50;index" json:"namespace,omitempty"`
+	ParentID  *uint          `gorm:"index" json:"parent_id,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
+// TagWithCount is a tag together with the number of documents using it
+type TagWithCount struct {
+	Tag
+	DocumentCount int64 `json:"document_count"`
+}
+
+// TagNode is a node of the tag tree used for navigation. DocumentCount
+// counts the distinct documents tagged with the node or any descendant.
+type TagNode struct {
+	ID            uint       `json:"id,omitempty"`
+	Name          string     `json:"name"`
+	FullName      string     `json:"full_name"`
+	DocumentCount int64      `json:"document_count"`
+	Children      []*TagNode `json:"children,omitempty"`
+}
+
 // DocumentVersion records the version history of a document
 type DocumentVersion struct {
 	ID         uint      `gorm:"primarykey" json:"id"`
@@ -40,3 +79,15 @@ type DocumentVersion struct {
 	CreatedBy  uint      `gorm:"not null" json:"created_by"`
 	CreatedAt  time.Time `json:"created_at"`
 }
+
+// Summary is the generated abstract of a document as returned with it.
+// Stale is set when the document changed since the summary was generated;
+// a new one is then being computed in the background.
+type Summary struct {
+	Text        string    `json:"text"`
+	KeyPoints   []string  `json:"key_points"`
+	Source      string    `json:"source"`
+	Version     int       `json:"version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Stale       bool      `json:"stale"`
+}
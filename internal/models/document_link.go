@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Document link kinds
+const (
+	DocumentLinkWiki = "wiki" // [[Title]]
+	DocumentLinkURL  = "url"  // /documents/:id
+)
+
+// DocumentLink is a link from the content of a document to another
+// document. Target is the target as written, a title or a URL; TargetID is
+// nil while the link is broken, because no document has the title or the
+// linked document was deleted.
+type DocumentLink struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	SourceID  uint      `gorm:"not null;index" json:"source_id"`
+	TargetID  *uint     `gorm:"index" json:"target_id,omitempty"`
+	Kind      string    `gorm:"size:10;not null" json:"kind"` // wiki, url
+	Target    string    `gorm:"size:255;not null" json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DocumentNode is a document as shown in listings, such as backlinks, the
+// link graph and the home feed
+type DocumentNode struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Slug        string    `json:"slug"`
+	Status      string    `json:"status"`
+	WorkspaceID *uint     `json:"workspace_id,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DocumentEdge links the document Source to the document Target
+type DocumentEdge struct {
+	Source uint `json:"source"`
+	Target uint `json:"target"`
+}
+
+// DocumentGraph is the graph of the links between documents
+type DocumentGraph struct {
+	Nodes []DocumentNode `json:"nodes"`
+	Edges []DocumentEdge `json:"edges"`
+}
+
+// BrokenLink is a link of Document that leads nowhere
+type BrokenLink struct {
+	Document DocumentNode `json:"document"`
+	Kind     string       `json:"kind"`
+	Target   string       `json:"target"`
+}
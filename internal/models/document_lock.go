@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DocumentLock is an exclusive check-out of a document for editing. While it
+// is held only its owner may update the document. A lock lapses at
+// ExpiresAt unless the owner renews it with heartbeats.
+type DocumentLock struct {
+	DocumentID uint      `gorm:"primarykey;autoIncrement:false" json:"document_id"`
+	OwnerID    uint      `gorm:"not null;index" json:"owner_id"`
+	Owner      *User     `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	AcquiredAt time.Time `gorm:"not null" json:"acquired_at"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DocumentSlug is a slug a document had before it was renamed or moved, so
+// that links using it still lead to the document
+type DocumentSlug struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	DocumentID  uint      `gorm:"not null;index" json:"document_id"`
+	WorkspaceID *uint     `gorm:"index" json:"workspace_id,omitempty"`
+	CreatorID   uint      `gorm:"not null" json:"creator_id"`
+	Slug        string    `gorm:"size:255;not null;index" json:"slug"`
+	CreatedAt   time.Time `json:"created_at"`
+}
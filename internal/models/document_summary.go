@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DocumentSummary is a summary generated from the content of a document.
+// It is keyed by the hash of the title and content it was generated from,
+// so that the version history only records versions saved by users.
+type DocumentSummary struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	DocumentID  uint      `gorm:"not null;uniqueIndex:idx_document_summaries_content" json:"document_id"`
+	ContentHash string    `gorm:"size:64;not null;uniqueIndex:idx_document_summaries_content" json:"-"`
+	Version     int       `gorm:"not null" json:"version"`
+	Summary     string    `gorm:"type:text" json:"summary"`
+	KeyPoints   []string  `gorm:"serializer:json;type:text" json:"key_points"`
+	Source      string    `gorm:"size:20" json:"source"` // model, extractive
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
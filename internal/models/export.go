@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Export job statuses
+const (
+	ExportStatusPending = "pending"
+	ExportStatusRunning = "running"
+	ExportStatusDone    = "done"
+	ExportStatusFailed  = "failed"
+)
+
+// ExportJob is an export run in the background. Once done, the exported
+// file is kept in storage at Path and downloaded through DownloadURL.
+type ExportJob struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	DocumentID  uint       `gorm:"not null" json:"document_id"`
+	Format      string     `gorm:"size:20;not null" json:"format"`
+	Subtree     bool       `json:"subtree"`
+	Status      string     `gorm:"size:20;not null" json:"status"`
+	Error       string     `gorm:"size:255" json:"error,omitempty"`
+	FileName    string     `gorm:"size:255" json:"file_name,omitempty"`
+	ContentType string     `gorm:"size:128" json:"content_type,omitempty"`
+	Path        string     `gorm:"size:512" json:"-"`
+	Size        int64      `json:"size,omitempty"`
+	DownloadURL string     `gorm:"-" json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
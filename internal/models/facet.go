@@ -0,0 +1,30 @@
+package models
+
+// Date buckets of the created and updated facets. Buckets are cumulative:
+// a document updated an hour ago is counted in every "past_" bucket.
+const (
+	DateBucketPastDay   = "past_day"
+	DateBucketPastWeek  = "past_week"
+	DateBucketPastMonth = "past_month"
+	DateBucketPastYear  = "past_year"
+	DateBucketOlder     = "older"
+)
+
+// FacetValue is one value of a facet and the number of results having it.
+// Label is a display name when Value is an ID.
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// Facets holds result counts per filter value. They are computed over the
+// full filtered result set, not just the returned page.
+type Facets struct {
+	Tags         []FacetValue `json:"tags"`
+	Status       []FacetValue `json:"status"`
+	Creators     []FacetValue `json:"creators"`
+	ContentTypes []FacetValue `json:"content_types"`
+	Created      []FacetValue `json:"created"`
+	Updated      []FacetValue `json:"updated"`
+}
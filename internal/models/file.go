@@ -16,8 +16,18 @@ type File struct {
 	UploaderID    uint           `gorm:"not null" json:"uploader_id"`
 	Uploader      User           `gorm:"foreignKey:UploaderID" json:"-"`
 	DocumentID    *uint          `gorm:"index" json:"document_id,omitempty"`
+	Text          string         `gorm:"type:text" json:"-"`
+	TextStatus    string         `gorm:"size:20" json:"text_status,omitempty"`
+	TextError     string         `gorm:"size:255" json:"text_error,omitempty"`
 	Document      *Document      `gorm:"foreignKey:DocumentID" json:"-"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-} 
\ No newline at end of file
+}
+
+// 文本提取状态
+const (
+	TextStatusExtracted   = "extracted"
+	TextStatusUnsupported = "unsupported"
+	TextStatusFailed      = "failed"
+)
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DocumentView records that a user opened a document: when they last did
+// and how many times
+type DocumentView struct {
+	UserID     uint      `gorm:"primarykey;autoIncrement:false" json:"user_id"`
+	DocumentID uint      `gorm:"primarykey;autoIncrement:false;index" json:"document_id"`
+	ViewedAt   time.Time `gorm:"not null;index" json:"viewed_at"`
+	ViewCount  int       `gorm:"not null;default:1" json:"view_count"`
+}
+
+// DocumentStar is a document a user added to their favorites
+type DocumentStar struct {
+	UserID     uint      `gorm:"primarykey;autoIncrement:false" json:"user_id"`
+	DocumentID uint      `gorm:"primarykey;autoIncrement:false;index" json:"document_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FeedItem is a document of a personal list, with what put it there: when
+// the user starred it, last viewed it, or was asked to review it
+type FeedItem struct {
+	DocumentNode
+	StarredAt   *time.Time `json:"starred_at,omitempty"`
+	ViewedAt    *time.Time `json:"viewed_at,omitempty"`
+	ViewCount   int        `json:"view_count,omitempty"`
+	RequestedAt *time.Time `json:"requested_at,omitempty"` // when the review was requested
+	RequesterID *uint      `json:"requester_id,omitempty"`
+}
+
+// Home is the personal home page of a user
+type Home struct {
+	Favorites       []FeedItem `json:"favorites"`
+	RecentlyViewed  []FeedItem `json:"recently_viewed"`
+	RecentlyUpdated []FeedItem `json:"recently_updated"` // in the user's workspaces
+	AwaitingReview  []FeedItem `json:"awaiting_review"`
+}
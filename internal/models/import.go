@@ -0,0 +1,41 @@
+package models
+
+// Import actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionFailed    = "failed"
+)
+
+// ImportReport describes the outcome of a bulk import, or with DryRun set
+// what an import would do without changing anything. Format is the format
+// the archive was read as, such as "notion"; Images and Attachments count
+// the uploaded files shown and linked to by the documents.
+type ImportReport struct {
+	Format      string             `json:"format"`
+	DryRun      bool               `json:"dry_run"`
+	Created     int                `json:"created"`
+	Updated     int                `json:"updated"`
+	Unchanged   int                `json:"unchanged"`
+	Failed      int                `json:"failed"`
+	Images      int                `json:"images"`
+	Attachments int                `json:"attachments"`
+	Documents   []ImportedDocument `json:"documents"`
+}
+
+// ImportedDocument is the outcome for one page of an import. Path is the
+// path of the page in the imported tree, stored as the document path, and
+// AuthorID the user the author of the page maps to.
+type ImportedDocument struct {
+	Path        string   `json:"path"`
+	ParentPath  string   `json:"parent_path,omitempty"`
+	Title       string   `json:"title"`
+	Action      string   `json:"action"`
+	DocumentID  uint     `json:"document_id,omitempty"`
+	AuthorID    *uint    `json:"author_id,omitempty"`
+	Images      int      `json:"images,omitempty"`
+	Attachments int      `json:"attachments,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Notification types
+const (
+	NotificationMention    = "mention"     // mentioned in a comment
+	NotificationLockBroken = "lock_broken" // an admin broke the user's lock on a document
+	NotificationReview     = "review"      // asked to review a document
+)
+
+// Notification tells a user about something that happened to them, such as
+// being mentioned in a comment. ActorID is the user who caused it.
+type Notification struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Type       string     `gorm:"size:20;not null" json:"type"`
+	ActorID    *uint      `json:"actor_id,omitempty"`
+	DocumentID *uint      `json:"document_id,omitempty"`
+	CommentID  *uint      `json:"comment_id,omitempty"`
+	Message    string     `gorm:"size:512" json:"message"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
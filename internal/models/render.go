@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// TOCEntry is a heading in the outline of a rendered document. ID is the
+// anchor of the heading in the rendered HTML.
+type TOCEntry struct {
+	Level    int        `json:"level"`
+	Text     string     `json:"text"`
+	ID       string     `json:"id"`
+	Children []TOCEntry `json:"children,omitempty"`
+}
+
+// RenderedDocument is the sanitized HTML of a document version together with
+// its table of contents.
+type RenderedDocument struct {
+	DocumentID uint       `json:"document_id"`
+	Version    int        `json:"version"`
+	HTML       string     `json:"html"`
+	TOC        []TOCEntry `json:"toc"`
+	RenderedAt time.Time  `json:"rendered_at"`
+}
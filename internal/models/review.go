@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Review request statuses
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusReviewed = "reviewed"
+)
+
+// ReviewRequest asks a user to review a document. A document has at most
+// one request per reviewer; asking again reopens it.
+type ReviewRequest struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	DocumentID  uint       `gorm:"not null;uniqueIndex:idx_review_document_reviewer" json:"document_id"`
+	ReviewerID  uint       `gorm:"not null;uniqueIndex:idx_review_document_reviewer;index" json:"reviewer_id"`
+	Reviewer    *User      `gorm:"foreignKey:ReviewerID" json:"reviewer,omitempty"`
+	RequesterID uint       `gorm:"not null" json:"requester_id"`
+	Status      string     `gorm:"size:20;not null;default:'pending'" json:"status"` // pending, reviewed
+	RequestedAt time.Time  `gorm:"not null" json:"requested_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
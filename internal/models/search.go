@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Search hit types
+const (
+	SearchHitDocument = "document"
+	SearchHitFile     = "file"
+)
+
+// SearchHit is a single ranked result of a full-text search. Highlight and
+// Snippet are HTML-escaped with the matched terms wrapped in <mark></mark>.
+type SearchHit struct {
+	Type       string    `json:"type"`
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	Highlight  string    `json:"highlight"`
+	Snippet    string    `json:"snippet"`
+	Rank       float64   `json:"rank"`
+	DocumentID *uint     `json:"document_id,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Passage is a part of a document returned by semantic search. Score is the
+// cosine similarity between the passage and the query.
+type Passage struct {
+	DocumentID uint    `json:"document_id"`
+	ChunkIndex int     `json:"chunk_index"`
+	Title      string  `json:"title"`
+	Text       string  `json:"text"`
+	Score      float64 `json:"score"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Tag suggestion sources
+const (
+	SuggestionSourceVocabulary = "vocabulary" // an existing tag matching the content
+	SuggestionSourceKeyword    = "keyword"    // a distinctive term of the content
+	SuggestionSourceModel      = "model"      // proposed by the language model
+	SuggestionSourceNone       = "none"       // marks a document with no suggestions
+)
+
+// TagSuggestion is a tag proposed for a document. TagID is set when the tag
+// already exists. Confidence ranges from 0 to 1.
+type TagSuggestion struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	DocumentID uint      `gorm:"not null;index" json:"document_id"`
+	TagID      *uint     `json:"tag_id,omitempty"`
+	Name       string    `gorm:"size:255;not null" json:"name"`
+	Confidence float64   `gorm:"not null" json:"confidence"`
+	Source     string    `gorm:"size:20" json:"source"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Template is the fixed structure of a kind of document, such as an ADR, a
+// postmortem or an RFC. Title and Content may contain placeholders like
+// {{date}}, {{author}} or {{service}} that are filled in when a document is
+// created from the template.
+type Template struct {
+	ID            uint               `gorm:"primarykey" json:"id"`
+	WorkspaceID   uint               `gorm:"not null;uniqueIndex:idx_template_workspace_name" json:"workspace_id"`
+	Name          string             `gorm:"size:255;not null;uniqueIndex:idx_template_workspace_name" json:"name"`
+	Description   string             `gorm:"size:1024" json:"description"`
+	Category      string             `gorm:"size:50;index" json:"category,omitempty"`
+	Title         string             `gorm:"size:255" json:"title"`
+	Content       string             `gorm:"type:text" json:"content"`
+	Variables     []TemplateVariable `gorm:"serializer:json;type:text" json:"variables"`
+	DefaultTags   []string           `gorm:"serializer:json;type:text" json:"default_tags"`
+	DefaultStatus string             `gorm:"size:20;default:'draft'" json:"default_status"`
+	CreatorID     uint               `gorm:"not null" json:"creator_id"`
+	DocumentID    *uint              `json:"document_id,omitempty"` // the document the template was saved from
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt     `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// TemplateVariable is a custom placeholder of a template. Default is used
+// when no value is given; a required variable without either is an error.
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
@@ -6,6 +6,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles
+const (
+	UserRoleAdmin = "admin"
+	UserRoleUser  = "user"
+)
+
 // User 用户模型
 type User struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Workspace member roles
+const (
+	WorkspaceRoleOwner  = "owner"  // manages members and every template
+	WorkspaceRoleMember = "member" // uses the workspace's templates and documents
+)
+
+// Workspace is the space of a team: its members share the workspace's
+// templates, and documents created from them belong to the workspace.
+type Workspace struct {
+	ID          uint              `gorm:"primarykey" json:"id"`
+	Name        string            `gorm:"size:255;not null" json:"name"`
+	Description string            `gorm:"size:1024" json:"description"`
+	CreatorID   uint              `gorm:"not null" json:"creator_id"`
+	Members     []WorkspaceMember `gorm:"foreignKey:WorkspaceID" json:"members,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// WorkspaceMember grants a user access to a workspace
+type WorkspaceMember struct {
+	WorkspaceID uint      `gorm:"primaryKey" json:"workspace_id"`
+	UserID      uint      `gorm:"primaryKey;index" json:"user_id"`
+	User        *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role        string    `gorm:"size:20;not null;default:'member'" json:"role"` // owner, member
+	CreatedAt   time.Time `json:"created_at"`
+}
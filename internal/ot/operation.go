@@ -0,0 +1,288 @@
+// Package ot implements operational transformation for plain text, so that
+// concurrent edits of one text can be merged. An edit is an Operation that
+// walks the whole text; two operations made on the same text are transformed
+// against each other so that applying them in either order gives the same
+// result.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var ErrInvalidOperation = errors.New("invalid text operation")
+
+// Component is one step of an operation: retain (skip) Retain characters,
+// insert Insert, or delete Delete characters. Exactly one field is set.
+// Lengths count characters (Unicode code points), not bytes.
+type Component struct {
+	Retain int
+	Insert string
+	Delete int
+}
+
+// Operation is an edit of a whole text. Its components walk the text from
+// the start, and must cover all of it. In JSON an operation is an array of
+// positive integers (retain), strings (insert) and negative integers
+// (delete), the encoding used by ot.js.
+type Operation []Component
+
+// Retain appends retaining n characters
+func (o Operation) Retain(n int) Operation {
+	if n <= 0 {
+		return o
+	}
+	if last := len(o) - 1; last >= 0 && o[last].Retain > 0 {
+		o[last].Retain += n
+		return o
+	}
+	return append(o, Component{Retain: n})
+}
+
+// Insert appends inserting s. Inserts are kept before adjacent deletes so
+// that equal edits have equal operations.
+func (o Operation) Insert(s string) Operation {
+	if s == "" {
+		return o
+	}
+	last := len(o) - 1
+	if last >= 0 && o[last].Insert != "" {
+		o[last].Insert += s
+		return o
+	}
+	if last >= 0 && o[last].Delete > 0 {
+		if last > 0 && o[last-1].Insert != "" {
+			o[last-1].Insert += s
+			return o
+		}
+		o = append(o, o[last])
+		o[last] = Component{Insert: s}
+		return o
+	}
+	return append(o, Component{Insert: s})
+}
+
+// Delete appends deleting n characters
+func (o Operation) Delete(n int) Operation {
+	if n <= 0 {
+		return o
+	}
+	if last := len(o) - 1; last >= 0 && o[last].Delete > 0 {
+		o[last].Delete += n
+		return o
+	}
+	return append(o, Component{Delete: n})
+}
+
+// BaseLen returns the length of the texts o applies to
+func (o Operation) BaseLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + c.Delete
+	}
+	return n
+}
+
+// TargetLen returns the length of the texts o produces
+func (o Operation) TargetLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + utf8.RuneCountInString(c.Insert)
+	}
+	return n
+}
+
+// IsNoop reports whether o leaves texts unchanged
+func (o Operation) IsNoop() bool {
+	for _, c := range o {
+		if c.Retain == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns text edited by o
+func (o Operation) Apply(text string) (string, error) {
+	runes := []rune(text)
+	if o.BaseLen() != len(runes) {
+		return "", ErrInvalidOperation
+	}
+
+	result := make([]rune, 0, o.TargetLen())
+	pos := 0
+	for _, c := range o {
+		switch {
+		case c.Retain > 0:
+			result = append(result, runes[pos:pos+c.Retain]...)
+			pos += c.Retain
+		case c.Insert != "":
+			result = append(result, []rune(c.Insert)...)
+		default:
+			pos += c.Delete
+		}
+	}
+	return string(result), nil
+}
+
+// Transform transforms a and b, both made on the same text, into a1 and b1
+// such that applying a then b1 gives the same text as applying b then a1.
+// When both insert at the same position, the text of a comes first.
+func Transform(a, b Operation) (a1, b1 Operation, err error) {
+	if a.BaseLen() != b.BaseLen() {
+		return nil, nil, ErrInvalidOperation
+	}
+
+	i, j := 0, 0
+	var ca, cb *Component
+	for {
+		if ca == nil && i < len(a) {
+			c := a[i]
+			ca = &c
+			i++
+		}
+		if cb == nil && j < len(b) {
+			c := b[j]
+			cb = &c
+			j++
+		}
+		if ca == nil && cb == nil {
+			return a1, b1, nil
+		}
+
+		// 插入不依赖对方的内容，直接保留到对方的结果中
+		if ca != nil && ca.Insert != "" {
+			a1 = a1.Insert(ca.Insert)
+			b1 = b1.Retain(utf8.RuneCountInString(ca.Insert))
+			ca = nil
+			continue
+		}
+		if cb != nil && cb.Insert != "" {
+			a1 = a1.Retain(utf8.RuneCountInString(cb.Insert))
+			b1 = b1.Insert(cb.Insert)
+			cb = nil
+			continue
+		}
+		if ca == nil || cb == nil {
+			return nil, nil, ErrInvalidOperation
+		}
+
+		n := min(ca.Retain+ca.Delete, cb.Retain+cb.Delete)
+		switch {
+		case ca.Retain > 0 && cb.Retain > 0:
+			a1 = a1.Retain(n)
+			b1 = b1.Retain(n)
+		case ca.Delete > 0 && cb.Retain > 0:
+			a1 = a1.Delete(n)
+		case ca.Retain > 0 && cb.Delete > 0:
+			b1 = b1.Delete(n)
+		}
+		// deleted by both: nothing left to do
+		ca, cb = consume(ca, n), consume(cb, n)
+	}
+}
+
+// consume returns c with n characters retained or deleted, nil when c is
+// used up
+func consume(c *Component, n int) *Component {
+	if c.Retain > 0 {
+		c.Retain -= n
+		if c.Retain == 0 {
+			return nil
+		}
+		return c
+	}
+	c.Delete -= n
+	if c.Delete == 0 {
+		return nil
+	}
+	return c
+}
+
+// TransformIndex returns where the character offset index of a text is
+// after o is applied. Text inserted at index moves it forward.
+func TransformIndex(index int, o Operation) int {
+	moved, pos := index, 0
+	for _, c := range o {
+		if pos > index {
+			break
+		}
+		switch {
+		case c.Retain > 0:
+			pos += c.Retain
+		case c.Insert != "":
+			moved += utf8.RuneCountInString(c.Insert)
+		default:
+			moved -= min(c.Delete, index-pos)
+			pos += c.Delete
+		}
+	}
+	return moved
+}
+
+// Diff returns an operation turning from into to, replacing what lies
+// between their common beginning and end
+func Diff(from, to string) Operation {
+	a, b := []rune(from), []rune(to)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var o Operation
+	return o.Retain(prefix).
+		Insert(string(b[prefix : len(b)-suffix])).
+		Delete(len(a) - prefix - suffix).
+		Retain(suffix)
+}
+
+func (o Operation) MarshalJSON() ([]byte, error) {
+	items := make([]any, 0, len(o))
+	for _, c := range o {
+		switch {
+		case c.Retain > 0:
+			items = append(items, c.Retain)
+		case c.Insert != "":
+			items = append(items, c.Insert)
+		default:
+			items = append(items, -c.Delete)
+		}
+	}
+	return json.Marshal(items)
+}
+
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+
+	var op Operation
+	for _, item := range items {
+		var n int
+		if err := json.Unmarshal(item, &n); err == nil {
+			switch {
+			case n > 0:
+				op = op.Retain(n)
+			case n < 0:
+				op = op.Delete(-n)
+			default:
+				return ErrInvalidOperation
+			}
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(item, &s); err != nil || s == "" {
+			return ErrInvalidOperation
+		}
+		op = op.Insert(s)
+	}
+	*o = op
+	return nil
+}
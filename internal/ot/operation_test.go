@@ -0,0 +1,111 @@
+package ot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	var op Operation
+	op = op.Retain(8).Insert("新的").Delete(3).Retain(8)
+	assert.Equal(t, 19, op.BaseLen())
+	assert.Equal(t, 18, op.TargetLen())
+
+	text, err := op.Apply("Promote old replica.")
+	require.ErrorIs(t, err, ErrInvalidOperation)
+
+	text, err = op.Apply("Promote the replica")
+	require.NoError(t, err)
+	assert.Equal(t, "Promote 新的 replica", text)
+}
+
+func TestTransform(t *testing.T) {
+	text := "Check lag. Promote the replica."
+	tests := []struct {
+		name     string
+		a, b     Operation
+		expected string
+	}{
+		{
+			name:     "Inserts at different places",
+			a:        Operation{}.Retain(10).Insert(" Page the DBA.").Retain(21),
+			b:        Operation{}.Retain(23).Insert("new ").Retain(8),
+			expected: "Check lag. Page the DBA. Promote the new replica.",
+		},
+		{
+			name:     "Inserts at the same place keep a first",
+			a:        Operation{}.Insert("1. ").Retain(31),
+			b:        Operation{}.Insert("Step: ").Retain(31),
+			expected: "1. Step: Check lag. Promote the replica.",
+		},
+		{
+			name:     "Overlapping deletes",
+			a:        Operation{}.Delete(11).Retain(20),
+			b:        Operation{}.Retain(6).Delete(16).Retain(9),
+			expected: " replica.",
+		},
+		{
+			name:     "Insert inside deleted text",
+			a:        Operation{}.Retain(11).Delete(12).Retain(8),
+			b:        Operation{}.Retain(19).Insert("primary's ").Retain(12),
+			expected: "Check lag. primary's replica.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a1, b1, err := Transform(tt.a, tt.b)
+			require.NoError(t, err)
+
+			afterA, err := tt.a.Apply(text)
+			require.NoError(t, err)
+			ab, err := b1.Apply(afterA)
+			require.NoError(t, err)
+			afterB, err := tt.b.Apply(text)
+			require.NoError(t, err)
+			ba, err := a1.Apply(afterB)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, ab)
+			assert.Equal(t, tt.expected, ba)
+		})
+	}
+
+	_, _, err := Transform(Operation{}.Retain(3), Operation{}.Retain(4))
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+}
+
+func TestTransformIndex(t *testing.T) {
+	op := Operation{}.Retain(5).Insert("abc").Retain(5).Delete(5).Retain(5)
+	assert.Equal(t, 2, TransformIndex(2, op))
+	assert.Equal(t, 8, TransformIndex(5, op), "inserts at the index move it")
+	assert.Equal(t, 13, TransformIndex(12, op), "indexes in deleted text move to its start")
+	assert.Equal(t, 18, TransformIndex(20, op))
+}
+
+func TestDiff(t *testing.T) {
+	from, to := "Promote the replica.", "Promote the new replica!"
+	op := Diff(from, to)
+	assert.Equal(t, Operation{{Retain: 12}, {Insert: "new replica!"}, {Delete: 8}}, op)
+	text, err := op.Apply(from)
+	require.NoError(t, err)
+	assert.Equal(t, to, text)
+	assert.True(t, Diff(from, from).IsNoop())
+}
+
+func TestOperationJSON(t *testing.T) {
+	var op Operation
+	require.NoError(t, json.Unmarshal([]byte(`[3,"ab",-2,1,1]`), &op))
+	assert.Equal(t, Operation{{Retain: 3}, {Insert: "ab"}, {Delete: 2}, {Retain: 2}}, op)
+
+	data, err := json.Marshal(op)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[3,"ab",-2,2]`, string(data))
+
+	for _, invalid := range []string{`[0]`, `[""]`, `[true]`, `{"retain":1}`} {
+		assert.ErrorIs(t, json.Unmarshal([]byte(invalid), &op), ErrInvalidOperation, invalid)
+	}
+}
@@ -0,0 +1,242 @@
+// Package render turns Markdown documents into sanitized HTML.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Result is a rendered document
+type Result struct {
+	HTML string
+	TOC  []models.TOCEntry
+}
+
+// Renderer renders document content to HTML
+type Renderer interface {
+	Render(source string) (*Result, error)
+}
+
+type markdownRenderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+// NewMarkdownRenderer returns a renderer for CommonMark with the GitHub
+// extensions (tables, task lists, strikethrough, autolinks) and footnotes.
+// Headings get anchors, fenced code blocks are highlighted with inline
+// styles, and raw HTML is dropped before the output is sanitized.
+func NewMarkdownRenderer() Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(
+			html.WithXHTML(),
+			renderer.WithNodeRenderers(util.Prioritized(newCodeBlockRenderer(), 100)),
+		),
+	)
+	return &markdownRenderer{md: md, policy: newPolicy()}
+}
+
+func (r *markdownRenderer) Render(source string) (*Result, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := r.md.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, fmt.Errorf("failed to render markdown: %w", err)
+	}
+
+	return &Result{
+		HTML: r.policy.Sanitize(buf.String()),
+		TOC:  outline(doc, src),
+	}, nil
+}
+
+var (
+	anchorPattern = regexp.MustCompile(`^[\p{L}\p{N}_:.-]+$`)
+	classPattern  = regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)
+)
+
+// newPolicy extends the policy for user generated content with the
+// attributes produced for anchors, footnotes, task lists and highlighting
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(anchorPattern).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("class").Matching(classPattern).OnElements("a", "code", "div", "li", "pre", "section", "span")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).OnElements("a", "section")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration", "display").
+		OnElements("pre", "span")
+	return p
+}
+
+// outline builds the nested table of contents from the headings of doc
+func outline(doc ast.Node, source []byte) []models.TOCEntry {
+	var toc []models.TOCEntry
+	// 当前各级标题的路径，用于挂接子标题
+	var path []*models.TOCEntry
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		entry := models.TOCEntry{Level: heading.Level, Text: strings.TrimSpace(nodeText(heading, source))}
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				entry.ID = string(b)
+			}
+		}
+
+		for len(path) > 0 && path[len(path)-1].Level >= entry.Level {
+			path = path[:len(path)-1]
+		}
+		if len(path) == 0 {
+			toc = append(toc, entry)
+			path = append(path, &toc[len(toc)-1])
+		} else {
+			parent := path[len(path)-1]
+			parent.Children = append(parent.Children, entry)
+			path = append(path, &parent.Children[len(parent.Children)-1])
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return toc
+}
+
+// nodeText concatenates the text of the inline children of n
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		default:
+			b.WriteString(nodeText(c, source))
+		}
+	}
+	return b.String()
+}
+
+// headingIDs generates anchors that keep letters of any script, so
+// headings such as "部署指南" get readable anchors as well
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		case unicode.IsSpace(r) || r == '-':
+			dash = true
+		}
+	}
+
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+	unique := id
+	for i := 1; s.used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	s.used[unique] = true
+	return []byte(unique)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
+
+// codeBlockRenderer highlights fenced code blocks whose language chroma
+// knows; other blocks are rendered as plain preformatted text
+type codeBlockRenderer struct {
+	formatter *chromahtml.Formatter
+	style     *chroma.Style
+}
+
+func newCodeBlockRenderer() renderer.NodeRenderer {
+	return &codeBlockRenderer{
+		formatter: chromahtml.New(chromahtml.PreventSurroundingPre(false)),
+		style:     styles.Get("github"),
+	}
+}
+
+func (r *codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r *codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.FencedCodeBlock)
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		code.Write(segment.Value(source))
+	}
+
+	language := string(n.Language(source))
+	if lexer := lexers.Get(language); lexer != nil && language != "" {
+		iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+		var out bytes.Buffer
+		if err == nil && r.formatter.Format(&out, r.style, iterator) == nil {
+			w.Write(out.Bytes())
+			return ast.WalkSkipChildren, nil
+		}
+	}
+
+	w.WriteString("<pre><code")
+	if language != "" {
+		w.WriteString(` class="language-`)
+		w.Write(util.EscapeHTML([]byte(language)))
+		w.WriteString(`"`)
+	}
+	w.WriteString(">")
+	w.Write(util.EscapeHTML(code.Bytes()))
+	w.WriteString("</code></pre>\n")
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const guide = "# Deployment guide\n\n" +
+	"Intro with a footnote[^1] and <script>alert(1)</script> [a link](javascript:alert(1)).\n\n" +
+	"## Prerequisites\n\n" +
+	"- [x] Access to the cluster\n- [ ] ~~VPN~~\n\n" +
+	"| Host | Port |\n| --- | --- |\n| db | 5432 |\n\n" +
+	"### 部署 步骤\n\n" +
+	"```go\nfunc main() {}\n```\n\n" +
+	"```unknown-lang\n<b>raw</b>\n```\n\n" +
+	"## Prerequisites\n\n" +
+	"# Appendix\n\n" +
+	"[^1]: The footnote.\n"
+
+func TestMarkdownRenderer(t *testing.T) {
+	result, err := NewMarkdownRenderer().Render(guide)
+	require.NoError(t, err)
+	html := result.HTML
+
+	assert.Contains(t, html, `<h1 id="deployment-guide">Deployment guide</h1>`)
+	assert.Contains(t, html, `<h3 id="部署-步骤">`)
+	assert.Contains(t, html, `<h2 id="prerequisites-1">`)
+	assert.Contains(t, html, `<table>`)
+	assert.Contains(t, html, `<del>VPN</del>`)
+	assert.Contains(t, html, `<input checked="" disabled="" type="checkbox"`)
+	assert.Contains(t, html, `id="fn:1"`)
+	assert.Contains(t, html, `style="color:`)
+	assert.Contains(t, html, `class="language-unknown-lang">&lt;b&gt;raw&lt;/b&gt;`)
+	assert.NotContains(t, html, "<script")
+	assert.NotContains(t, html, "javascript:")
+
+	assert.Equal(t, []models.TOCEntry{
+		{Level: 1, Text: "Deployment guide", ID: "deployment-guide", Children: []models.TOCEntry{
+			{Level: 2, Text: "Prerequisites", ID: "prerequisites", Children: []models.TOCEntry{
+				{Level: 3, Text: "部署 步骤", ID: "部署-步骤"},
+			}},
+			{Level: 2, Text: "Prerequisites", ID: "prerequisites-1"},
+		}},
+		{Level: 1, Text: "Appendix", ID: "appendix"},
+	}, result.TOC)
+}
+
+func TestMarkdownRendererEmpty(t *testing.T) {
+	result, err := NewMarkdownRenderer().Render("")
+	require.NoError(t, err)
+	assert.Empty(t, result.HTML)
+	assert.Empty(t, result.TOC)
+}
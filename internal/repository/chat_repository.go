@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type ChatRepository interface {
+	CreateSession(ctx context.Context, session *models.ChatSession) error
+	UpdateSession(ctx context.Context, session *models.ChatSession) error
+	// GetSession returns a session with its messages in order
+	GetSession(ctx context.Context, id uint) (*models.ChatSession, error)
+	ListSessions(ctx context.Context, userID uint, page, pageSize int) ([]models.ChatSession, int64, error)
+	DeleteSession(ctx context.Context, id uint) error
+	// AddMessages stores messages of a session and records the documents
+	// they used as context
+	AddMessages(ctx context.Context, sessionID uint, messages []*models.ChatMessage, documentIDs []uint) error
+	GetMessage(ctx context.Context, id uint) (*models.ChatMessage, error)
+	SetFeedback(ctx context.Context, messageID uint, feedback int) error
+	// DeleteSessionsFor deletes every session that may draw on a document:
+	// sessions scoped to the document, to one of folderIDs or tagIDs, or
+	// that used the document as context. It returns the number deleted.
+	DeleteSessionsFor(ctx context.Context, documentID uint, folderIDs, tagIDs []uint) (int64, error)
+}
+
+type chatRepository struct {
+	db *gorm.DB
+}
+
+func NewChatRepository(db *gorm.DB) ChatRepository {
+	return &chatRepository{db: db}
+}
+
+func (r *chatRepository) CreateSession(ctx context.Context, session *models.ChatSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *chatRepository) UpdateSession(ctx context.Context, session *models.ChatSession) error {
+	return r.db.WithContext(ctx).Omit("Messages").Save(session).Error
+}
+
+func (r *chatRepository) GetSession(ctx context.Context, id uint) (*models.ChatSession, error) {
+	var session models.ChatSession
+	err := r.db.WithContext(ctx).
+		Preload("Messages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("chat_messages.id")
+		}).
+		First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *chatRepository) ListSessions(ctx context.Context, userID uint, page, pageSize int) ([]models.ChatSession, int64, error) {
+	var sessions []models.ChatSession
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.ChatSession{}).Where("user_id = ?", userID)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Order("updated_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&sessions).Error
+
+	return sessions, total, err
+}
+
+func (r *chatRepository) DeleteSession(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteSessions(tx, []uint{id})
+	})
+}
+
+func (r *chatRepository) AddMessages(ctx context.Context, sessionID uint, messages []*models.ChatMessage, documentIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, msg := range messages {
+			msg.SessionID = sessionID
+			if err := tx.Create(msg).Error; err != nil {
+				return err
+			}
+		}
+		for _, docID := range documentIDs {
+			err := tx.Exec(`INSERT INTO chat_session_documents (session_id, document_id)
+				SELECT ?, ? WHERE NOT EXISTS
+					(SELECT 1 FROM chat_session_documents WHERE session_id = ? AND document_id = ?)`,
+				sessionID, docID, sessionID, docID).Error
+			if err != nil {
+				return err
+			}
+		}
+		// 更新会话时间，使最近使用的会话排在前面
+		return tx.Model(&models.ChatSession{}).
+			Where("id = ?", sessionID).
+			Update("updated_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+	})
+}
+
+func (r *chatRepository) GetMessage(ctx context.Context, id uint) (*models.ChatMessage, error) {
+	var msg models.ChatMessage
+	err := r.db.WithContext(ctx).First(&msg, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *chatRepository) SetFeedback(ctx context.Context, messageID uint, feedback int) error {
+	return r.db.WithContext(ctx).Model(&models.ChatMessage{}).
+		Where("id = ?", messageID).
+		Update("feedback", feedback).Error
+}
+
+func (r *chatRepository) DeleteSessionsFor(ctx context.Context, documentID uint, folderIDs, tagIDs []uint) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cond := tx.Where("scope_type = ? AND scope_id = ?", models.ChatScopeDocument, documentID).
+			Or("id IN (?)", tx.Table("chat_session_documents").
+				Select("session_id").
+				Where("document_id = ?", documentID))
+		if len(folderIDs) > 0 {
+			cond = cond.Or("scope_type = ? AND scope_id IN ?", models.ChatScopeFolder, folderIDs)
+		}
+		if len(tagIDs) > 0 {
+			cond = cond.Or("scope_type = ? AND scope_id IN ?", models.ChatScopeTag, tagIDs)
+		}
+
+		var ids []uint
+		if err := tx.Model(&models.ChatSession{}).Where(cond).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		deleted = int64(len(ids))
+		return deleteSessions(tx, ids)
+	})
+	return deleted, err
+}
+
+// deleteSessions removes sessions together with their messages and document
+// links
+func deleteSessions(tx *gorm.DB, ids []uint) error {
+	if err := tx.Where("session_id IN ?", ids).Delete(&models.ChatMessage{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("session_id IN ?", ids).Delete(&models.ChatSessionDocument{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.ChatSession{}, ids).Error
+}
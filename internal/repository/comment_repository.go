@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type CommentRepository interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	Update(ctx context.Context, comment *models.Comment) error
+	// GetByID returns a comment with its author
+	GetByID(ctx context.Context, id uint) (*models.Comment, error)
+	// ListThreads returns the threads of a document in order of creation,
+	// each first comment with its replies, only unresolved threads when
+	// unresolvedOnly is set
+	ListThreads(ctx context.Context, documentID uint, unresolvedOnly bool, page, pageSize int) ([]models.Comment, int64, error)
+	// ListAnnotations returns the anchored threads of a document, orphaned
+	// ones included, each with its replies
+	ListAnnotations(ctx context.Context, documentID uint, unresolvedOnly bool) ([]models.Comment, error)
+	// UpdateAnchor moves the anchor of a thread
+	UpdateAnchor(ctx context.Context, id uint, anchor *models.CommentAnchor, status string) error
+	// Delete deletes a comment together with its replies
+	Delete(ctx context.Context, id uint) error
+}
+
+type commentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+func (r *commentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Omit("Author", "Replies").Create(comment).Error
+}
+
+func (r *commentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Omit("Author", "Replies").Save(comment).Error
+}
+
+func (r *commentRepository) GetByID(ctx context.Context, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := r.db.WithContext(ctx).Preload("Author").First(&comment, id).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *commentRepository) ListThreads(ctx context.Context, documentID uint, unresolvedOnly bool, page, pageSize int) ([]models.Comment, int64, error) {
+	var threads []models.Comment
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.Comment{}).
+		Where("document_id = ? AND parent_id IS NULL", documentID)
+	if unresolvedOnly {
+		query = query.Where("resolved_at IS NULL")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Preload("Author").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("comments.created_at, comments.id")
+		}).
+		Preload("Replies.Author").
+		Order("created_at, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&threads).Error
+	return threads, total, err
+}
+
+func (r *commentRepository) ListAnnotations(ctx context.Context, documentID uint, unresolvedOnly bool) ([]models.Comment, error) {
+	var threads []models.Comment
+	query := r.db.WithContext(ctx).
+		Where("document_id = ? AND parent_id IS NULL AND anchor_status <> ''", documentID)
+	if unresolvedOnly {
+		query = query.Where("resolved_at IS NULL")
+	}
+	err := query.
+		Preload("Author").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("comments.created_at, comments.id")
+		}).
+		Preload("Replies.Author").
+		Order("created_at, id").
+		Find(&threads).Error
+	return threads, err
+}
+
+func (r *commentRepository) UpdateAnchor(ctx context.Context, id uint, anchor *models.CommentAnchor, status string) error {
+	// 只更新锚点列，不影响评论的 updated_at
+	return r.db.WithContext(ctx).Model(&models.Comment{ID: id}).
+		Select("Anchor", "AnchorStatus").
+		UpdateColumns(&models.Comment{Anchor: anchor, AnchorStatus: status}).Error
+}
+
+func (r *commentRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? OR parent_id = ?", id, id).
+		Delete(&models.Comment{}).Error
+}
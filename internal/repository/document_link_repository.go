@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type DocumentLinkRepository interface {
+	// Replace sets the outgoing links of a document
+	Replace(ctx context.Context, sourceID uint, links []models.DocumentLink) error
+	// ListByTarget returns the links to a document
+	ListByTarget(ctx context.Context, targetID uint) ([]models.DocumentLink, error)
+	// ListBroken returns a page of the broken links of the documents
+	// selected by params, by source document
+	ListBroken(ctx context.Context, params LinkListParams) ([]models.DocumentLink, error)
+	// ListNodes returns a page of the documents selected by params, by ID
+	ListNodes(ctx context.Context, params LinkListParams) ([]models.DocumentNode, error)
+	// ListEdges returns the distinct links between the given documents,
+	// which callers keep to a page of nodes
+	ListEdges(ctx context.Context, ids []uint) ([]models.DocumentEdge, error)
+	// Nodes returns the documents with the given IDs that exist, by ID
+	Nodes(ctx context.Context, ids []uint) ([]models.DocumentNode, error)
+	// FindByTitles returns the documents in a link scope with one of the
+	// titles, compared case-insensitively, oldest first. The scope is a
+	// workspace, or the documents of creatorID outside of workspaces when
+	// workspaceID is nil.
+	FindByTitles(ctx context.Context, workspaceID *uint, creatorID uint, titles []string) ([]models.DocumentNode, error)
+	// ResolveWiki points the broken wiki links naming title from documents of
+	// a link scope to targetID
+	ResolveWiki(ctx context.Context, workspaceID *uint, creatorID uint, title string, targetID uint) error
+	// Unlink breaks the links to a document
+	Unlink(ctx context.Context, targetID uint) error
+}
+
+// LinkListParams selects the documents whose links are listed
+type LinkListParams struct {
+	ReadableBy  uint  // only documents this user can read are listed
+	WorkspaceID *uint // restricts the listing to the documents of a workspace
+	Page        int
+	PageSize    int
+}
+
+// documentNodeColumns are the document columns of models.DocumentNode
+var documentNodeColumns = []string{"documents.id", "documents.title", "documents.slug", "documents.status", "documents.workspace_id", "documents.updated_at"}
+
+type documentLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentLinkRepository(db *gorm.DB) DocumentLinkRepository {
+	return &documentLinkRepository{db: db}
+}
+
+func (r *documentLinkRepository) Replace(ctx context.Context, sourceID uint, links []models.DocumentLink) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", sourceID).Delete(&models.DocumentLink{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Create(&links).Error
+	})
+}
+
+func (r *documentLinkRepository) ListByTarget(ctx context.Context, targetID uint) ([]models.DocumentLink, error) {
+	var links []models.DocumentLink
+	err := r.db.WithContext(ctx).
+		Where("target_id = ?", targetID).
+		Order("source_id, id").
+		Find(&links).Error
+	return links, err
+}
+
+func (r *documentLinkRepository) ListBroken(ctx context.Context, params LinkListParams) ([]models.DocumentLink, error) {
+	var links []models.DocumentLink
+	err := r.db.WithContext(ctx).
+		Where("target_id IS NULL AND source_id IN (?)", r.listedDocuments(ctx, params).Select("documents.id")).
+		Order("source_id, id").
+		Offset((params.Page - 1) * params.PageSize).
+		Limit(params.PageSize).
+		Find(&links).Error
+	return links, err
+}
+
+func (r *documentLinkRepository) ListNodes(ctx context.Context, params LinkListParams) ([]models.DocumentNode, error) {
+	var nodes []models.DocumentNode
+	err := r.listedDocuments(ctx, params).
+		Select(documentNodeColumns).
+		Order("documents.id").
+		Offset((params.Page - 1) * params.PageSize).
+		Limit(params.PageSize).
+		Scan(&nodes).Error
+	return nodes, err
+}
+
+// listedDocuments selects the documents of a link listing; the readability
+// check stays in SQL so that listings don't bind an ID per document
+func (r *documentLinkRepository) listedDocuments(ctx context.Context, params LinkListParams) *gorm.DB {
+	query := readableBy(r.db.WithContext(ctx).Model(&models.Document{}), params.ReadableBy)
+	if params.WorkspaceID != nil {
+		query = query.Where("documents.workspace_id = ?", *params.WorkspaceID)
+	}
+	return query
+}
+
+func (r *documentLinkRepository) ListEdges(ctx context.Context, ids []uint) ([]models.DocumentEdge, error) {
+	var edges []models.DocumentEdge
+	if len(ids) == 0 {
+		return edges, nil
+	}
+	err := r.db.WithContext(ctx).Model(&models.DocumentLink{}).
+		Distinct("source_id AS source", "target_id AS target").
+		Where("source_id IN ? AND target_id IN ?", ids, ids).
+		Order("source, target").
+		Scan(&edges).Error
+	return edges, err
+}
+
+func (r *documentLinkRepository) Nodes(ctx context.Context, ids []uint) ([]models.DocumentNode, error) {
+	var nodes []models.DocumentNode
+	if len(ids) == 0 {
+		return nodes, nil
+	}
+	err := r.db.WithContext(ctx).Model(&models.Document{}).
+		Select(documentNodeColumns).
+		Where("id IN ?", ids).
+		Order("id").
+		Scan(&nodes).Error
+	return nodes, err
+}
+
+func (r *documentLinkRepository) FindByTitles(ctx context.Context, workspaceID *uint, creatorID uint, titles []string) ([]models.DocumentNode, error) {
+	var nodes []models.DocumentNode
+	if len(titles) == 0 {
+		return nodes, nil
+	}
+	lowered := make([]string, 0, len(titles))
+	for _, title := range titles {
+		lowered = append(lowered, strings.ToLower(title))
+	}
+	err := r.linkScope(ctx, workspaceID, creatorID).
+		Select(documentNodeColumns).
+		Where("LOWER(title) IN ?", lowered).
+		Order("id").
+		Scan(&nodes).Error
+	return nodes, err
+}
+
+func (r *documentLinkRepository) ResolveWiki(ctx context.Context, workspaceID *uint, creatorID uint, title string, targetID uint) error {
+	return r.db.WithContext(ctx).Model(&models.DocumentLink{}).
+		Where("target_id IS NULL AND kind = ? AND LOWER(target) = ?", models.DocumentLinkWiki, strings.ToLower(title)).
+		Where("source_id IN (?)", r.linkScope(ctx, workspaceID, creatorID).Select("id")).
+		Where("source_id <> ?", targetID).
+		Update("target_id", targetID).Error
+}
+
+func (r *documentLinkRepository) Unlink(ctx context.Context, targetID uint) error {
+	return r.db.WithContext(ctx).Model(&models.DocumentLink{}).
+		Where("target_id = ?", targetID).
+		Update("target_id", nil).Error
+}
+
+// linkScope selects the documents wiki links of a document resolve among:
+// those of its workspace, or the creator's documents outside of workspaces
+func (r *documentLinkRepository) linkScope(ctx context.Context, workspaceID *uint, creatorID uint) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.Document{})
+	if workspaceID != nil {
+		return query.Where("workspace_id = ?", *workspaceID)
+	}
+	return query.Where("workspace_id IS NULL AND creator_id = ?", creatorID)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DocumentLockRepository interface {
+	// Acquire locks a document for lock.OwnerID, unless another user holds a
+	// lock that hasn't expired, and reports whether it did. A lock the owner
+	// already holds is taken over with the new times.
+	Acquire(ctx context.Context, lock *models.DocumentLock) (bool, error)
+	// Renew moves the expiry of the unexpired lock of ownerID, and reports
+	// whether there was one
+	Renew(ctx context.Context, documentID, ownerID uint, expiresAt time.Time) (bool, error)
+	// GetActive returns the unexpired lock of a document with its owner
+	GetActive(ctx context.Context, documentID uint) (*models.DocumentLock, error)
+	// Release deletes the lock of ownerID, and reports whether there was one
+	Release(ctx context.Context, documentID, ownerID uint) (bool, error)
+	// Delete deletes lock, whoever holds it, unless it was taken over or
+	// acquired again since it was read. It reports whether it was deleted.
+	Delete(ctx context.Context, lock *models.DocumentLock) (bool, error)
+}
+
+type documentLockRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentLockRepository(db *gorm.DB) DocumentLockRepository {
+	return &documentLockRepository{db: db}
+}
+
+func (r *documentLockRepository) Acquire(ctx context.Context, lock *models.DocumentLock) (bool, error) {
+	// 单条语句完成抢锁：仅当旧锁已过期或属于同一用户时覆盖
+	result := r.db.WithContext(ctx).Omit("Owner").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "document_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"owner_id", "acquired_at", "expires_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{clause.Expr{
+				SQL:  "document_locks.expires_at < ? OR document_locks.owner_id = ?",
+				Vars: []interface{}{lock.AcquiredAt, lock.OwnerID},
+			}}},
+		}).
+		Create(lock)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *documentLockRepository) Renew(ctx context.Context, documentID, ownerID uint, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.DocumentLock{}).
+		Where("document_id = ? AND owner_id = ? AND expires_at >= ?", documentID, ownerID, time.Now()).
+		Update("expires_at", expiresAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *documentLockRepository) GetActive(ctx context.Context, documentID uint) (*models.DocumentLock, error) {
+	var lock models.DocumentLock
+	err := r.db.WithContext(ctx).
+		Preload("Owner").
+		Where("document_id = ? AND expires_at >= ?", documentID, time.Now()).
+		First(&lock).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (r *documentLockRepository) Release(ctx context.Context, documentID, ownerID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("document_id = ? AND owner_id = ?", documentID, ownerID).
+		Delete(&models.DocumentLock{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *documentLockRepository) Delete(ctx context.Context, lock *models.DocumentLock) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("document_id = ? AND owner_id = ? AND acquired_at = ?", lock.DocumentID, lock.OwnerID, lock.AcquiredAt).
+		Delete(&models.DocumentLock{})
+	return result.RowsAffected > 0, result.Error
+}
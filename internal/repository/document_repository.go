@@ -2,17 +2,56 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/Zhaoyikaiii/docmind/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrSlugTaken is returned when saving a document whose slug another
+// document of its scope took in the meantime
+var ErrSlugTaken = errors.New("document slug is taken")
+
 type DocumentRepository interface {
+	// Create and Update return ErrSlugTaken when the slug of doc is taken
 	Create(ctx context.Context, doc *models.Document) error
 	Update(ctx context.Context, doc *models.Document) error
 	Delete(ctx context.Context, id uint) error
 	GetByID(ctx context.Context, id uint) (*models.Document, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]models.Document, error)
 	List(ctx context.Context, params DocumentListParams) ([]models.Document, int64, error)
+	// FilterIDs returns the IDs of all documents matching params, ignoring paging
+	FilterIDs(ctx context.Context, params DocumentListParams) ([]uint, error)
+	// Facets counts facet values over all documents matching params
+	Facets(ctx context.Context, params DocumentListParams) (*models.Facets, error)
+	// SubtreeIDs returns the ID of a document and of all its descendants
+	SubtreeIDs(ctx context.Context, rootID uint) ([]uint, error)
+	// AncestorIDs returns the IDs of the parents of a document, nearest first
+	AncestorIDs(ctx context.Context, id uint) ([]uint, error)
 	ExistsByTitleAndCreator(ctx context.Context, title string, creatorID uint) (bool, error)
+	// ListSlugs returns the slugs equal to base or starting with base and a
+	// hyphen in a slug scope, leaving out the document excludeID. The scope
+	// is a workspace, or the documents of creatorID outside of workspaces
+	// when workspaceID is nil.
+	ListSlugs(ctx context.Context, workspaceID *uint, creatorID uint, base string, excludeID uint) ([]string, error)
+	// ListWithoutSlug returns up to limit documents after afterID that have
+	// no slug, by ID
+	ListWithoutSlug(ctx context.Context, afterID uint, limit int) ([]models.Document, error)
+	// UpdateSlug sets the slug of a document, returning ErrSlugTaken when it
+	// is taken
+	UpdateSlug(ctx context.Context, id uint, slug string) error
+	// ClearDuplicateSlugs clears the slugs that documents share with an
+	// older document of their scope, so that they can be assigned again
+	ClearDuplicateSlugs(ctx context.Context) error
+	// MigrateSlugs creates the unique indexes keeping slugs unique in their
+	// scope
+	MigrateSlugs(ctx context.Context) error
+	// GetByPaths returns the documents of a creator with one of the paths,
+	// with their tags
+	GetByPaths(ctx context.Context, creatorID uint, paths []string) ([]models.Document, error)
 	CreateVersion(ctx context.Context, version *models.DocumentVersion) error
 	GetVersions(ctx context.Context, documentID uint) ([]models.DocumentVersion, error)
 	AddTags(ctx context.Context, docID uint, tagIDs []uint) error
@@ -20,12 +59,22 @@ type DocumentRepository interface {
 }
 
 type DocumentListParams struct {
-	CreatorID *uint
-	Status    *string
-	Tags      []string
-	Search    string
-	Page      int
-	PageSize  int
+	IDs           []uint // restricts the result to these documents when set
+	ReadableBy    *uint  // restricts the result to documents this user created, of their workspaces, or published
+	CreatorID     *uint
+	WorkspaceID   *uint
+	Slug          string
+	Status        *string
+	Tags          []string
+	ContentType   string // documents with an attached file of this type
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Search        string
+	AfterID       uint // documents with a greater ID, for keyset paging over all documents
+	Page          int
+	PageSize      int
 }
 
 type documentRepository struct {
@@ -37,11 +86,11 @@ func NewDocumentRepository(db *gorm.DB) DocumentRepository {
 }
 
 func (r *documentRepository) Create(ctx context.Context, doc *models.Document) error {
-	return r.db.WithContext(ctx).Create(doc).Error
+	return slugError(r.db.WithContext(ctx).Create(doc).Error)
 }
 
 func (r *documentRepository) Update(ctx context.Context, doc *models.Document) error {
-	return r.db.WithContext(ctx).Save(doc).Error
+	return slugError(r.db.WithContext(ctx).Save(doc).Error)
 }
 
 func (r *documentRepository) Delete(ctx context.Context, id uint) error {
@@ -52,6 +101,7 @@ func (r *documentRepository) GetByID(ctx context.Context, id uint) (*models.Docu
 	var doc models.Document
 	err := r.db.WithContext(ctx).
 		Preload("Creator").
+		Preload("Author").
 		Preload("Tags").
 		First(&doc, id).Error
 	if err != nil {
@@ -60,24 +110,101 @@ func (r *documentRepository) GetByID(ctx context.Context, id uint) (*models.Docu
 	return &doc, nil
 }
 
+func (r *documentRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Document, error) {
+	var docs []models.Document
+	err := r.db.WithContext(ctx).
+		Preload("Creator").
+		Preload("Author").
+		Preload("Tags").
+		Where("id IN ?", ids).
+		Find(&docs).Error
+	return docs, err
+}
+
 func (r *documentRepository) List(ctx context.Context, params DocumentListParams) ([]models.Document, int64, error) {
 	var docs []models.Document
 	var total int64
 
+	query := r.filter(ctx, params)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Order("documents.id").
+		Offset((params.Page - 1) * params.PageSize).
+		Limit(params.PageSize).
+		Preload("Creator").
+		Preload("Tags").
+		Find(&docs).Error
+
+	return docs, total, err
+}
+
+func (r *documentRepository) FilterIDs(ctx context.Context, params DocumentListParams) ([]uint, error) {
+	var ids []uint
+	err := r.filter(ctx, params).Pluck("documents.id", &ids).Error
+	return ids, err
+}
+
+func (r *documentRepository) Facets(ctx context.Context, params DocumentListParams) (*models.Facets, error) {
+	return documentFacets(ctx, r.db, r.filter(ctx, params).Select("documents.id"))
+}
+
+// filter builds the query shared by List, FilterIDs and Facets
+func (r *documentRepository) filter(ctx context.Context, params DocumentListParams) *gorm.DB {
 	query := r.db.WithContext(ctx).Model(&models.Document{})
 
+	if params.IDs != nil {
+		query = query.Where("documents.id IN ?", params.IDs)
+	}
+
+	if params.ReadableBy != nil {
+		query = readableBy(query, *params.ReadableBy)
+	}
+
+	if params.AfterID > 0 {
+		query = query.Where("documents.id > ?", params.AfterID)
+	}
+
 	if params.CreatorID != nil {
 		query = query.Where("creator_id = ?", *params.CreatorID)
 	}
 
+	if params.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *params.WorkspaceID)
+	}
+
+	if params.Slug != "" {
+		query = query.Where("slug = ?", params.Slug)
+	}
+
 	if params.Status != nil {
 		query = query.Where("status = ?", *params.Status)
 	}
 
 	if len(params.Tags) > 0 {
-		query = query.Joins("JOIN document_tags ON documents.id = document_tags.document_id").
-			Joins("JOIN tags ON document_tags.tag_id = tags.id").
-			Where("tags.name IN ?", params.Tags)
+		query = query.Where("documents.id IN (?)", r.taggedDocumentIDs(params.Tags))
+	}
+
+	if params.ContentType != "" {
+		query = query.Where("documents.id IN (?)", r.db.Model(&models.File{}).
+			Select("document_id").
+			Where("content_type = ?", params.ContentType))
+	}
+
+	if params.CreatedAfter != nil {
+		query = query.Where("documents.created_at >= ?", *params.CreatedAfter)
+	}
+	if params.CreatedBefore != nil {
+		query = query.Where("documents.created_at < ?", *params.CreatedBefore)
+	}
+	if params.UpdatedAfter != nil {
+		query = query.Where("documents.updated_at >= ?", *params.UpdatedAfter)
+	}
+	if params.UpdatedBefore != nil {
+		query = query.Where("documents.updated_at < ?", *params.UpdatedBefore)
 	}
 
 	if params.Search != "" {
@@ -85,18 +212,79 @@ func (r *documentRepository) List(ctx context.Context, params DocumentListParams
 			"%"+params.Search+"%", "%"+params.Search+"%")
 	}
 
-	err := query.Count(&total).Error
-	if err != nil {
-		return nil, 0, err
+	return query
+}
+
+// taggedDocumentIDs builds a subquery selecting the documents that carry any
+// of the given tags. A parent tag ("team") also matches its descendants
+// ("team/platform"), and a bare namespace ("env:") matches every tag in it.
+func (r *documentRepository) taggedDocumentIDs(tags []string) *gorm.DB {
+	cond := r.db.Where("1 = 0")
+	for _, tag := range tags {
+		if namespace, ok := strings.CutSuffix(tag, ":"); ok {
+			cond = cond.Or("tags.namespace = ?", namespace)
+			continue
+		}
+		cond = cond.Or("tags.name = ?", tag).
+			Or("tags.name LIKE ? ESCAPE '\\'", escapeLike(tag)+"/%")
 	}
 
-	err = query.Offset((params.Page - 1) * params.PageSize).
-		Limit(params.PageSize).
-		Preload("Creator").
-		Preload("Tags").
-		Find(&docs).Error
+	return r.db.Table("document_tags").
+		Select("document_tags.document_id").
+		Joins("JOIN tags ON document_tags.tag_id = tags.id").
+		Where(cond)
+}
 
-	return docs, total, err
+// maxTreeDepth guards tree walks against parent cycles
+const maxTreeDepth = 100
+
+func (r *documentRepository) SubtreeIDs(ctx context.Context, rootID uint) ([]uint, error) {
+	ids := []uint{rootID}
+	seen := map[uint]bool{rootID: true}
+	level := []uint{rootID}
+
+	// 逐层查询子文档，避免依赖数据库的递归查询
+	for depth := 0; len(level) > 0 && depth < maxTreeDepth; depth++ {
+		var children []uint
+		err := r.db.WithContext(ctx).Model(&models.Document{}).
+			Where("parent_id IN ?", level).
+			Pluck("id", &children).Error
+		if err != nil {
+			return nil, err
+		}
+
+		level = level[:0]
+		for _, id := range children {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+				level = append(level, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *documentRepository) AncestorIDs(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	seen := map[uint]bool{id: true}
+
+	for depth := 0; depth < maxTreeDepth; depth++ {
+		var doc models.Document
+		err := r.db.WithContext(ctx).Unscoped().
+			Select("id", "parent_id").
+			First(&doc, id).Error
+		if err != nil {
+			return nil, err
+		}
+		if doc.ParentID == nil || seen[*doc.ParentID] {
+			break
+		}
+		id = *doc.ParentID
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 func (r *documentRepository) CreateVersion(ctx context.Context, version *models.DocumentVersion) error {
@@ -112,10 +300,24 @@ func (r *documentRepository) GetVersions(ctx context.Context, documentID uint) (
 	return versions, err
 }
 
+// documentTag maps a row of the document_tags join table
+type documentTag struct {
+	DocumentID uint `gorm:"primaryKey"`
+	TagID      uint `gorm:"primaryKey"`
+}
+
+func (documentTag) TableName() string {
+	return "document_tags"
+}
+
 func (r *documentRepository) AddTags(ctx context.Context, docID uint, tagIDs []uint) error {
-	return r.db.WithContext(ctx).Exec(
-		"INSERT INTO document_tags (document_id, tag_id) SELECT ? as document_id, unnest(?::int[]) as tag_id",
-		docID, tagIDs).Error
+	rows := make([]documentTag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		rows = append(rows, documentTag{DocumentID: docID, TagID: tagID})
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&rows).Error
 }
 
 func (r *documentRepository) RemoveTags(ctx context.Context, docID uint, tagIDs []uint) error {
@@ -132,3 +334,103 @@ func (r *documentRepository) ExistsByTitleAndCreator(ctx context.Context, title
 		Count(&count).Error
 	return count > 0, err
 }
+
+func (r *documentRepository) ListSlugs(ctx context.Context, workspaceID *uint, creatorID uint, base string, excludeID uint) ([]string, error) {
+	query := r.db.WithContext(ctx).Model(&models.Document{}).
+		Where("(slug = ? OR slug LIKE ? ESCAPE '\\')", base, escapeLike(base)+"-%").
+		Where("id <> ?", excludeID)
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	} else {
+		query = query.Where("workspace_id IS NULL AND creator_id = ?", creatorID)
+	}
+
+	var slugs []string
+	err := query.Pluck("slug", &slugs).Error
+	return slugs, err
+}
+
+func (r *documentRepository) ListWithoutSlug(ctx context.Context, afterID uint, limit int) ([]models.Document, error) {
+	var docs []models.Document
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND (slug IS NULL OR slug = '')", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&docs).Error
+	return docs, err
+}
+
+func (r *documentRepository) UpdateSlug(ctx context.Context, id uint, slug string) error {
+	// 只更新 slug，不改变更新时间
+	return slugError(r.db.WithContext(ctx).Model(&models.Document{}).
+		Where("id = ?", id).
+		UpdateColumn("slug", slug).Error)
+}
+
+func (r *documentRepository) ClearDuplicateSlugs(ctx context.Context) error {
+	return r.db.WithContext(ctx).Exec(`UPDATE documents SET slug = '' WHERE id IN (
+		SELECT id FROM (
+			SELECT id, row_number() OVER (
+				PARTITION BY workspace_id, CASE WHEN workspace_id IS NULL THEN creator_id END, slug
+				ORDER BY id) AS n
+			FROM documents
+			WHERE deleted_at IS NULL AND slug <> ''
+		) ranked WHERE n > 1)`).Error
+}
+
+// slugIndexes keep slugs unique among the live documents of a workspace, and
+// among the creator's documents outside of workspaces
+var slugIndexes = map[string]string{
+	"idx_documents_workspace_slug": `CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_workspace_slug ON documents (workspace_id, slug)
+		WHERE workspace_id IS NOT NULL AND slug <> '' AND deleted_at IS NULL`,
+	"idx_documents_creator_slug": `CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_creator_slug ON documents (creator_id, slug)
+		WHERE workspace_id IS NULL AND slug <> '' AND deleted_at IS NULL`,
+}
+
+func (r *documentRepository) MigrateSlugs(ctx context.Context) error {
+	// 部分唯一索引是 PostgreSQL 的语法，其他数据库只靠保存时的重试避免重复
+	if !IsPostgres(r.db) {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range slugIndexes {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// slugError turns violations of the slug indexes into ErrSlugTaken
+func slugError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for name := range slugIndexes {
+		if strings.Contains(err.Error(), name) {
+			return ErrSlugTaken
+		}
+	}
+	return err
+}
+
+func (r *documentRepository) GetByPaths(ctx context.Context, creatorID uint, paths []string) ([]models.Document, error) {
+	var docs []models.Document
+	if len(paths) == 0 {
+		return docs, nil
+	}
+	err := r.db.WithContext(ctx).
+		Preload("Tags").
+		Where("creator_id = ? AND path IN ?", creatorID, paths).
+		Find(&docs).Error
+	return docs, err
+}
+
+// readableBy restricts query to the documents userID may read; raw queries
+// use the same condition through readableDocumentSQL
+func readableBy(query *gorm.DB, userID uint) *gorm.DB {
+	// 作者和工作区成员可以读所有文档，其他人只能读已发布的文档
+	return query.Where("(documents.creator_id = ? OR documents.status = ? OR documents.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ?))",
+		userID, models.DocumentStatusPublished, userID)
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type DocumentSlugRepository interface {
+	// Record keeps a former slug of a document
+	Record(ctx context.Context, slug *models.DocumentSlug) error
+	// ListBySlug returns the records of a former slug, newest first, only
+	// those of a workspace when workspaceID is set
+	ListBySlug(ctx context.Context, slug string, workspaceID *uint) ([]models.DocumentSlug, error)
+}
+
+type documentSlugRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentSlugRepository(db *gorm.DB) DocumentSlugRepository {
+	return &documentSlugRepository{db: db}
+}
+
+func (r *documentSlugRepository) Record(ctx context.Context, slug *models.DocumentSlug) error {
+	return r.db.WithContext(ctx).Create(slug).Error
+}
+
+func (r *documentSlugRepository) ListBySlug(ctx context.Context, slug string, workspaceID *uint) ([]models.DocumentSlug, error) {
+	query := r.db.WithContext(ctx).Where("slug = ?", slug)
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+
+	var slugs []models.DocumentSlug
+	err := query.Order("id DESC").Find(&slugs).Error
+	return slugs, err
+}
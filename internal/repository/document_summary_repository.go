@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DocumentSummaryRepository interface {
+	// Latest returns the most recently generated summary of each document
+	Latest(ctx context.Context, documentIDs []uint) ([]models.DocumentSummary, error)
+	// Save stores a summary, replacing the one generated from the same
+	// content of the document
+	Save(ctx context.Context, summary *models.DocumentSummary) error
+}
+
+type documentSummaryRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentSummaryRepository(db *gorm.DB) DocumentSummaryRepository {
+	return &documentSummaryRepository{db: db}
+}
+
+func (r *documentSummaryRepository) Latest(ctx context.Context, documentIDs []uint) ([]models.DocumentSummary, error) {
+	var summaries []models.DocumentSummary
+	if len(documentIDs) == 0 {
+		return summaries, nil
+	}
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT DISTINCT ON (document_id) * FROM document_summaries
+			WHERE document_id IN ? ORDER BY document_id, updated_at DESC, id DESC`, documentIDs).
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+func (r *documentSummaryRepository) Save(ctx context.Context, summary *models.DocumentSummary) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "document_id"}, {Name: "content_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"version", "summary", "key_points", "source", "updated_at"}),
+		}).
+		Create(summary).Error
+}
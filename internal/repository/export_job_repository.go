@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *models.ExportJob) error
+	Update(ctx context.Context, job *models.ExportJob) error
+	GetByID(ctx context.Context, id uint) (*models.ExportJob, error)
+}
+
+type exportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) ExportJobRepository {
+	return &exportJobRepository{db: db}
+}
+
+func (r *exportJobRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *exportJobRepository) Update(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+func (r *exportJobRepository) GetByID(ctx context.Context, id uint) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxFacetValues caps the values returned for open-ended facets such as tags
+const maxFacetValues = 50
+
+type dateBucket struct {
+	key string
+	age time.Duration
+}
+
+var dateBuckets = []dateBucket{
+	{key: models.DateBucketPastDay, age: 24 * time.Hour},
+	{key: models.DateBucketPastWeek, age: 7 * 24 * time.Hour},
+	{key: models.DateBucketPastMonth, age: 30 * 24 * time.Hour},
+	{key: models.DateBucketPastYear, age: 365 * 24 * time.Hour},
+}
+
+// DateBucketRange returns the time range covered by a date bucket relative to
+// now, for turning a selected facet value into a list filter. after or before
+// is nil when the range is open on that side.
+func DateBucketRange(key string, now time.Time) (after, before *time.Time, ok bool) {
+	for _, bucket := range dateBuckets {
+		if bucket.key == key {
+			since := now.Add(-bucket.age)
+			return &since, nil, true
+		}
+	}
+	if key == models.DateBucketOlder {
+		until := now.Add(-dateBuckets[len(dateBuckets)-1].age)
+		return nil, &until, true
+	}
+	return nil, nil, false
+}
+
+// documentFacets counts facet values over the documents selected by ids, a
+// subquery returning document IDs.
+func documentFacets(ctx context.Context, db *gorm.DB, ids *gorm.DB) (*models.Facets, error) {
+	db = db.WithContext(ctx)
+	facets := &models.Facets{}
+
+	err := db.Table("document_tags").
+		Select("tags.name AS value, COUNT(DISTINCT document_tags.document_id) AS count").
+		Joins("JOIN tags ON tags.id = document_tags.tag_id AND tags.deleted_at IS NULL").
+		Where("document_tags.document_id IN (?)", ids).
+		Group("tags.name").
+		Order("count DESC, value").
+		Limit(maxFacetValues).
+		Scan(&facets.Tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+
+	err = db.Model(&models.Document{}).
+		Select("status AS value, COUNT(*) AS count").
+		Where("id IN (?)", ids).
+		Group("status").
+		Order("count DESC, value").
+		Scan(&facets.Status).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count statuses: %w", err)
+	}
+
+	err = db.Model(&models.Document{}).
+		Select("documents.creator_id AS value, users.username AS label, COUNT(*) AS count").
+		Joins("LEFT JOIN users ON users.id = documents.creator_id").
+		Where("documents.id IN (?)", ids).
+		Group("documents.creator_id, users.username").
+		Order("count DESC, label").
+		Limit(maxFacetValues).
+		Scan(&facets.Creators).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count creators: %w", err)
+	}
+
+	// 一个文档可能有多个同类型附件，按文档去重计数
+	err = db.Model(&models.File{}).
+		Select("content_type AS value, COUNT(DISTINCT document_id) AS count").
+		Where("document_id IN (?) AND content_type <> ''", ids).
+		Group("content_type").
+		Order("count DESC, value").
+		Limit(maxFacetValues).
+		Scan(&facets.ContentTypes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count content types: %w", err)
+	}
+
+	now := time.Now()
+	if facets.Created, err = dateFacet(db, ids, "created_at", now); err != nil {
+		return nil, fmt.Errorf("failed to count creation dates: %w", err)
+	}
+	if facets.Updated, err = dateFacet(db, ids, "updated_at", now); err != nil {
+		return nil, fmt.Errorf("failed to count update dates: %w", err)
+	}
+
+	// 空结果返回 [] 而不是 null，方便前端直接渲染
+	for _, values := range []*[]models.FacetValue{&facets.Tags, &facets.Status, &facets.Creators, &facets.ContentTypes} {
+		if *values == nil {
+			*values = []models.FacetValue{}
+		}
+	}
+	return facets, nil
+}
+
+// dateFacet counts the documents in each date bucket of column with a single
+// query of conditional counts.
+func dateFacet(db *gorm.DB, ids *gorm.DB, column string, now time.Time) ([]models.FacetValue, error) {
+	exprs := make([]string, 0, len(dateBuckets)+1)
+	args := make([]interface{}, 0, len(dateBuckets)+1)
+	for _, bucket := range dateBuckets {
+		exprs = append(exprs, "COUNT(CASE WHEN "+column+" >= ? THEN 1 END)")
+		args = append(args, now.Add(-bucket.age))
+	}
+	exprs = append(exprs, "COUNT(CASE WHEN "+column+" < ? THEN 1 END)")
+	args = append(args, now.Add(-dateBuckets[len(dateBuckets)-1].age))
+
+	counts := make([]int64, len(exprs))
+	dest := make([]interface{}, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	err := db.Model(&models.Document{}).
+		Select(strings.Join(exprs, ", "), args...).
+		Where("id IN (?)", ids).
+		Row().Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]models.FacetValue, 0, len(counts))
+	for i, bucket := range dateBuckets {
+		values = append(values, models.FacetValue{Value: bucket.key, Count: counts[i]})
+	}
+	values = append(values, models.FacetValue{Value: models.DateBucketOlder, Count: counts[len(counts)-1]})
+	return values, nil
+}
+
+// emptyDateFacet returns the date buckets with zero counts
+func emptyDateFacet() []models.FacetValue {
+	values := make([]models.FacetValue, 0, len(dateBuckets)+1)
+	for _, bucket := range dateBuckets {
+		values = append(values, models.FacetValue{Value: bucket.key})
+	}
+	return append(values, models.FacetValue{Value: models.DateBucketOlder})
+}
+
+// mergeFacetValues adds up the counts of values present in both lists and
+// orders the result by count.
+func mergeFacetValues(a, b []models.FacetValue) []models.FacetValue {
+	merged := make([]models.FacetValue, 0, len(a)+len(b))
+	index := make(map[string]int, len(a)+len(b))
+	for _, list := range [][]models.FacetValue{a, b} {
+		for _, v := range list {
+			if i, ok := index[v.Value]; ok {
+				merged[i].Count += v.Count
+				continue
+			}
+			index[v.Value] = len(merged)
+			merged = append(merged, v)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Count != merged[j].Count {
+			return merged[i].Count > merged[j].Count
+		}
+		return merged[i].Value < merged[j].Value
+	})
+	return merged
+}
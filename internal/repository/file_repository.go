@@ -13,6 +13,9 @@ type FileRepository interface {
 	Delete(ctx context.Context, id uint) error
 	GetByID(ctx context.Context, id uint) (*models.File, error)
 	List(ctx context.Context, params FileListParams) ([]models.File, int64, error)
+	// ListByDocuments returns the files attached to the documents, without
+	// their extracted text
+	ListByDocuments(ctx context.Context, documentIDs []uint) ([]models.File, error)
 }
 
 type FileListParams struct {
@@ -80,9 +83,24 @@ func (r *fileRepository) List(ctx context.Context, params FileListParams) ([]mod
 		return nil, 0, err
 	}
 
-	err = query.Offset((params.Page - 1) * params.PageSize).
+	// 列表不返回提取的正文
+	err = query.Omit("text").
+		Offset((params.Page - 1) * params.PageSize).
 		Limit(params.PageSize).
 		Find(&files).Error
 
 	return files, total, err
-} 
\ No newline at end of file
+}
+
+func (r *fileRepository) ListByDocuments(ctx context.Context, documentIDs []uint) ([]models.File, error) {
+	var files []models.File
+	if len(documentIDs) == 0 {
+		return files, nil
+	}
+	err := r.db.WithContext(ctx).
+		Omit("text").
+		Where("document_id IN ?", documentIDs).
+		Order("id").
+		Find(&files).Error
+	return files, err
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HomeRepository stores the document views and stars of users and lists
+// the documents of their home feed. The lists only hold documents the user
+// may read, newest first.
+type HomeRepository interface {
+	// RecordView stores that userID viewed a document at viewedAt
+	RecordView(ctx context.Context, userID, documentID uint, viewedAt time.Time) error
+	// Star adds a document to the favorites of userID
+	Star(ctx context.Context, userID, documentID uint) error
+	// Unstar removes a document from the favorites of userID
+	Unstar(ctx context.Context, userID, documentID uint) error
+	// ListStarred returns the favorites of userID, last starred first
+	ListStarred(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+	// ListViewed returns the documents userID viewed, last viewed first
+	ListViewed(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+	// ListUpdated returns the documents of the workspaces userID is a member
+	// of, last updated first
+	ListUpdated(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+}
+
+type homeRepository struct {
+	db *gorm.DB
+}
+
+func NewHomeRepository(db *gorm.DB) HomeRepository {
+	return &homeRepository{db: db}
+}
+
+func (r *homeRepository) RecordView(ctx context.Context, userID, documentID uint, viewedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "document_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"viewed_at":  viewedAt,
+				"view_count": gorm.Expr("document_views.view_count + 1"),
+			}),
+		}).
+		Create(&models.DocumentView{UserID: userID, DocumentID: documentID, ViewedAt: viewedAt, ViewCount: 1}).Error
+}
+
+func (r *homeRepository) Star(ctx context.Context, userID, documentID uint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.DocumentStar{UserID: userID, DocumentID: documentID}).Error
+}
+
+func (r *homeRepository) Unstar(ctx context.Context, userID, documentID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND document_id = ?", userID, documentID).
+		Delete(&models.DocumentStar{}).Error
+}
+
+func (r *homeRepository) ListStarred(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	err := readableBy(r.db.WithContext(ctx).Model(&models.Document{}), userID).
+		Select(append(documentNodeColumns, "document_stars.created_at AS starred_at")).
+		Joins("JOIN document_stars ON document_stars.document_id = documents.id AND document_stars.user_id = ?", userID).
+		Order("document_stars.created_at DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+func (r *homeRepository) ListViewed(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	err := readableBy(r.db.WithContext(ctx).Model(&models.Document{}), userID).
+		Select(append(documentNodeColumns, "document_views.viewed_at", "document_views.view_count")).
+		Joins("JOIN document_views ON document_views.document_id = documents.id AND document_views.user_id = ?", userID).
+		Order("document_views.viewed_at DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+func (r *homeRepository) ListUpdated(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	err := readableBy(r.db.WithContext(ctx).Model(&models.Document{}), userID).
+		Select(documentNodeColumns).
+		Where("documents.workspace_id IN (?)", r.db.Model(&models.WorkspaceMember{}).
+			Select("workspace_id").
+			Where("user_id = ?", userID)).
+		Order("documents.updated_at DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReviewRequestRepository interface {
+	// Request stores review requests, reopening those already made for the
+	// same document and reviewer
+	Request(ctx context.Context, requests []*models.ReviewRequest) error
+	// Complete marks the pending review of reviewerID as done, and reports
+	// whether there was one
+	Complete(ctx context.Context, documentID, reviewerID uint, reviewedAt time.Time) (bool, error)
+	// ListPending returns the documents reviewerID may read and was asked to
+	// review, oldest request first
+	ListPending(ctx context.Context, reviewerID uint, limit int) ([]models.FeedItem, error)
+}
+
+type reviewRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRequestRepository(db *gorm.DB) ReviewRequestRepository {
+	return &reviewRequestRepository{db: db}
+}
+
+func (r *reviewRequestRepository) Request(ctx context.Context, requests []*models.ReviewRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit("Reviewer").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "document_id"}, {Name: "reviewer_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"requester_id", "status", "requested_at", "reviewed_at"}),
+		}).
+		Create(requests).Error
+}
+
+func (r *reviewRequestRepository) Complete(ctx context.Context, documentID, reviewerID uint, reviewedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.ReviewRequest{}).
+		Where("document_id = ? AND reviewer_id = ? AND status = ?", documentID, reviewerID, models.ReviewStatusPending).
+		Updates(map[string]interface{}{"status": models.ReviewStatusReviewed, "reviewed_at": reviewedAt})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *reviewRequestRepository) ListPending(ctx context.Context, reviewerID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	err := readableBy(r.db.WithContext(ctx).Model(&models.Document{}), reviewerID).
+		Select(append(documentNodeColumns, "review_requests.requested_at", "review_requests.requester_id")).
+		Joins("JOIN review_requests ON review_requests.document_id = documents.id").
+		Where("review_requests.reviewer_id = ? AND review_requests.status = ?", reviewerID, models.ReviewStatusPending).
+		Order("review_requests.requested_at").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultTextSearchConfig is the PostgreSQL text search configuration used
+// for document titles and content when none is configured.
+const DefaultTextSearchConfig = "english"
+
+var textSearchConfigPattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// SearchRepository runs full-text searches backed by PostgreSQL tsvector
+// columns and GIN indexes.
+type SearchRepository interface {
+	// Migrate creates the tsvector column and indexes the search relies on
+	Migrate(ctx context.Context) error
+	Search(ctx context.Context, params SearchParams) ([]models.SearchHit, int64, error)
+	// Facets counts facet values over all hits. The content type facet counts
+	// document hits by attachment type and file hits by their own type; the
+	// other facets cover document hits only.
+	Facets(ctx context.Context, params SearchParams) (*models.Facets, error)
+}
+
+type SearchParams struct {
+	Query string
+	Types []string // models.SearchHitDocument, models.SearchHitFile
+	// UserID limits hits to documents the user can read and to files they
+	// uploaded or that are attached to such documents
+	UserID uint
+	// The filters take the values of the facets. ContentType selects
+	// documents with an attachment of that type and files of that type; the
+	// others select documents and the files attached to them.
+	Tags          []string
+	Status        *string
+	CreatorID     *uint
+	ContentType   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Page          int
+	PageSize      int
+}
+
+// documentFilter returns the document filters of params, without the
+// content type
+func (p SearchParams) documentFilter() (DocumentListParams, bool) {
+	filter := DocumentListParams{
+		Tags:          p.Tags,
+		Status:        p.Status,
+		CreatorID:     p.CreatorID,
+		CreatedAfter:  p.CreatedAfter,
+		CreatedBefore: p.CreatedBefore,
+		UpdatedAfter:  p.UpdatedAfter,
+		UpdatedBefore: p.UpdatedBefore,
+	}
+	set := len(p.Tags) > 0 || p.Status != nil || p.CreatorID != nil ||
+		p.CreatedAfter != nil || p.CreatedBefore != nil || p.UpdatedAfter != nil || p.UpdatedBefore != nil
+	return filter, set
+}
+
+type searchRepository struct {
+	db         *gorm.DB
+	textConfig string
+}
+
+// IsPostgres reports whether db is a PostgreSQL database, whose syntax the
+// search and slug migrations use
+func IsPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+func NewSearchRepository(db *gorm.DB, textConfig string) (SearchRepository, error) {
+	if textConfig == "" {
+		textConfig = DefaultTextSearchConfig
+	}
+	// 配置名会被拼接进 DDL，只允许合法的标识符
+	if !textSearchConfigPattern.MatchString(textConfig) {
+		return nil, fmt.Errorf("invalid text search config: %s", textConfig)
+	}
+	return &searchRepository{db: db, textConfig: textConfig}, nil
+}
+
+func (r *searchRepository) Migrate(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE documents ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('%[1]s'::regconfig, coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('%[1]s'::regconfig, coalesce(content, '')), 'B')
+			) STORED`, r.textConfig),
+		`CREATE INDEX IF NOT EXISTS idx_documents_search_vector ON documents USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_files_name_search ON files
+			USING GIN (to_tsvector('simple'::regconfig, ` + fileNameDocument + `))`,
+		fmt.Sprintf(`ALTER TABLE files ADD COLUMN IF NOT EXISTS text_vector tsvector
+			GENERATED ALWAYS AS (to_tsvector('%s'::regconfig, coalesce(text, ''))) STORED`, r.textConfig),
+		`CREATE INDEX IF NOT EXISTS idx_files_text_vector ON files USING GIN (text_vector)`,
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// fileNameDocument splits file names such as "oncall-runbook.pdf" into
+// separate words; it must match the expression of idx_files_name_search.
+const fileNameDocument = `translate(original_name, '._-', '   ')`
+
+// ts_headline wraps matches in these private use characters; the text is
+// HTML-escaped before they are turned into <mark> tags so that document
+// content cannot inject markup into results
+const (
+	headlineStartSel = "\uE000"
+	headlineStopSel  = "\uE001"
+)
+
+const (
+	titleHeadlineOptions   = `'HighlightAll=true, StartSel=` + headlineStartSel + `, StopSel=` + headlineStopSel + `'`
+	snippetHeadlineOptions = `'StartSel=` + headlineStartSel + `, StopSel=` + headlineStopSel + `, MaxFragments=2, MaxWords=30, MinWords=10'`
+)
+
+var headlineMarks = strings.NewReplacer(headlineStartSel, "<mark>", headlineStopSel, "</mark>")
+
+// markHeadline escapes a ts_headline result and marks its matches
+func markHeadline(headline string) string {
+	return headlineMarks.Replace(html.EscapeString(headline))
+}
+
+// readableDocumentSQL is the condition of readableBy for raw queries, with
+// the documents table aliased as %s and the user bound to @user
+const readableDocumentSQL = `(%[1]s.creator_id = @user OR %[1]s.status = @published
+	OR %[1]s.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = @user))`
+
+// searchSource is the SQL searching one kind of object; the select list
+// and the FROM/WHERE clause are kept apart so totals can be counted without
+// computing headlines.
+type searchSource struct {
+	selectSQL string
+	fromSQL   string
+}
+
+var searchSources = map[string]searchSource{
+	models.SearchHitDocument: {
+		selectSQL: `SELECT 'document' AS type, d.id, d.title,
+			ts_headline(@config::regconfig, d.title, q, ` + titleHeadlineOptions + `) AS highlight,
+			ts_headline(@config::regconfig, coalesce(d.content, ''), q, ` + snippetHeadlineOptions + `) AS snippet,
+			ts_rank_cd(d.search_vector, q) AS rank,
+			NULL::integer AS document_id,
+			d.updated_at`,
+		fromSQL: `FROM documents d, websearch_to_tsquery(@config::regconfig, @query) q
+			WHERE d.deleted_at IS NULL AND d.search_vector @@ q
+				AND ` + fmt.Sprintf(readableDocumentSQL, "d"),
+	},
+	// 文件名按 simple 配置匹配，提取的正文按文档相同的配置匹配
+	models.SearchHitFile: {
+		selectSQL: `SELECT 'file' AS type, f.id, f.original_name AS title,
+			ts_headline('simple'::regconfig, ` + fileNameDocument + `, q, ` + titleHeadlineOptions + `) AS highlight,
+			ts_headline(@config::regconfig, coalesce(f.text, ''), tq, ` + snippetHeadlineOptions + `) AS snippet,
+			ts_rank_cd(to_tsvector('simple'::regconfig, ` + fileNameDocument + `), q) +
+				ts_rank_cd(f.text_vector, tq) * 0.5 AS rank,
+			f.document_id,
+			f.updated_at`,
+		fromSQL: `FROM files f, websearch_to_tsquery('simple'::regconfig, @query) q,
+				websearch_to_tsquery(@config::regconfig, @query) tq
+			WHERE f.deleted_at IS NULL
+				AND (to_tsvector('simple'::regconfig, ` + fileNameDocument + `) @@ q OR f.text_vector @@ tq)
+				AND (f.uploader_id = @user OR f.document_id IN (
+					SELECT fd.id FROM documents fd WHERE fd.deleted_at IS NULL AND ` + fmt.Sprintf(readableDocumentSQL, "fd") + `))`,
+	},
+}
+
+// searchArgs returns the named arguments shared by the search queries,
+// with the subqueries of the filters of params
+func (r *searchRepository) searchArgs(ctx context.Context, params SearchParams) map[string]interface{} {
+	args := map[string]interface{}{
+		"config":       r.textConfig,
+		"query":        params.Query,
+		"user":         params.UserID,
+		"published":    models.DocumentStatusPublished,
+		"content_type": params.ContentType,
+	}
+
+	docs := &documentRepository{db: r.db}
+	filter, set := params.documentFilter()
+	if set {
+		args["file_documents"] = docs.filter(ctx, filter).Select("documents.id")
+	}
+	if set || params.ContentType != "" {
+		filter.ContentType = params.ContentType
+		args["documents"] = docs.filter(ctx, filter).Select("documents.id")
+	}
+	return args
+}
+
+// fromSQL returns the FROM/WHERE clause of a search source restricted by
+// the filters in args
+func fromSQL(t string, args map[string]interface{}) string {
+	from := searchSources[t].fromSQL
+	switch t {
+	case models.SearchHitDocument:
+		if _, ok := args["documents"]; ok {
+			from += " AND d.id IN (@documents)"
+		}
+	case models.SearchHitFile:
+		if args["content_type"] != "" {
+			from += " AND f.content_type = @content_type"
+		}
+		if _, ok := args["file_documents"]; ok {
+			from += " AND f.document_id IN (@file_documents)"
+		}
+	}
+	return from
+}
+
+func (r *searchRepository) Search(ctx context.Context, params SearchParams) ([]models.SearchHit, int64, error) {
+	args := r.searchArgs(ctx, params)
+
+	var selects, counts []string
+	for _, t := range params.Types {
+		source, ok := searchSources[t]
+		if !ok {
+			continue
+		}
+		from := fromSQL(t, args)
+		selects = append(selects, source.selectSQL+"\n"+from)
+		counts = append(counts, "(SELECT count(*) "+from+")")
+	}
+	if len(selects) == 0 {
+		return nil, 0, nil
+	}
+
+	args["limit"] = params.PageSize
+	args["offset"] = (params.Page - 1) * params.PageSize
+
+	var total int64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT "+strings.Join(counts, " + "), args).
+		Scan(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var hits []models.SearchHit
+	err = r.db.WithContext(ctx).
+		Raw("SELECT * FROM ("+strings.Join(selects, "\nUNION ALL\n")+") hits "+
+			"ORDER BY rank DESC, updated_at DESC LIMIT @limit OFFSET @offset", args).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range hits {
+		hits[i].Highlight = markHeadline(hits[i].Highlight)
+		hits[i].Snippet = markHeadline(hits[i].Snippet)
+	}
+	return hits, total, nil
+}
+
+func (r *searchRepository) Facets(ctx context.Context, params SearchParams) (*models.Facets, error) {
+	args := r.searchArgs(ctx, params)
+
+	facets := &models.Facets{
+		Tags:         []models.FacetValue{},
+		Status:       []models.FacetValue{},
+		Creators:     []models.FacetValue{},
+		ContentTypes: []models.FacetValue{},
+	}
+	for _, t := range params.Types {
+		switch t {
+		case models.SearchHitDocument:
+			ids := r.db.Raw("SELECT d.id "+fromSQL(t, args), args)
+			docFacets, err := documentFacets(ctx, r.db, ids)
+			if err != nil {
+				return nil, err
+			}
+			docFacets.ContentTypes = mergeFacetValues(facets.ContentTypes, docFacets.ContentTypes)
+			facets = docFacets
+
+		case models.SearchHitFile:
+			var fileTypes []models.FacetValue
+			err := r.db.WithContext(ctx).
+				Raw(`SELECT f.content_type AS value, COUNT(*) AS count `+fromSQL(t, args)+
+					` AND f.content_type <> '' GROUP BY f.content_type`, args).
+				Scan(&fileTypes).Error
+			if err != nil {
+				return nil, fmt.Errorf("failed to count file types: %w", err)
+			}
+			facets.ContentTypes = mergeFacetValues(facets.ContentTypes, fileTypes)
+		}
+	}
+
+	if facets.Created == nil {
+		facets.Created = emptyDateFacet()
+		facets.Updated = emptyDateFacet()
+	}
+	return facets, nil
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type TagRepository interface {
+	Create(ctx context.Context, tag *models.Tag) error
+	Update(ctx context.Context, tag *models.Tag) error
+	Delete(ctx context.Context, id uint) error
+	GetByID(ctx context.Context, id uint) (*models.Tag, error)
+	GetByName(ctx context.Context, name string) (*models.Tag, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]models.Tag, error)
+	GetByNames(ctx context.Context, names []string) ([]models.Tag, error)
+	List(ctx context.Context, params TagListParams) ([]models.TagWithCount, int64, error)
+	ListAll(ctx context.Context) ([]models.Tag, error)
+	GetDescendants(ctx context.Context, name string) ([]models.Tag, error)
+	CountChildren(ctx context.Context, id uint) (int64, error)
+	ListDocumentPairs(ctx context.Context) ([]TagDocumentPair, error)
+	Merge(ctx context.Context, sourceID, targetID uint) error
+	// Rename saves a renamed tag together with its renamed descendants
+	Rename(ctx context.Context, tag *models.Tag, descendants []models.Tag) error
+}
+
+type TagListParams struct {
+	Namespace string
+	Search    string
+	Page      int
+	PageSize  int
+}
+
+// TagDocumentPair is one tag assignment of a live document
+type TagDocumentPair struct {
+	TagID      uint
+	DocumentID uint
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+func (r *tagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Save(tag).Error
+}
+
+// Delete removes the tag and detaches it from every document. Tags are
+// hard-deleted so that the unique name can be reused afterwards.
+func (r *tagRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM document_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Tag{}, id).Error
+	})
+}
+
+func (r *tagRepository) GetByID(ctx context.Context, id uint) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).First(&tag, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *tagRepository) GetByName(ctx context.Context, name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *tagRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) GetByNames(ctx context.Context, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.WithContext(ctx).Where("name IN ?", names).Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) List(ctx context.Context, params TagListParams) ([]models.TagWithCount, int64, error) {
+	var tags []models.TagWithCount
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.Tag{})
+
+	if params.Namespace != "" {
+		query = query.Where("tags.namespace = ?", params.Namespace)
+	}
+
+	if params.Search != "" {
+		query = query.Where("tags.name LIKE ? ESCAPE '\\'", "%"+escapeLike(params.Search)+"%")
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Select("tags.*, COUNT(documents.id) AS document_count").
+		Joins("LEFT JOIN document_tags ON document_tags.tag_id = tags.id").
+		Joins("LEFT JOIN documents ON documents.id = document_tags.document_id AND documents.deleted_at IS NULL").
+		Group("tags.id").
+		Order("tags.name").
+		Offset((params.Page - 1) * params.PageSize).
+		Limit(params.PageSize).
+		Scan(&tags).Error
+
+	return tags, total, err
+}
+
+func (r *tagRepository) ListAll(ctx context.Context) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.WithContext(ctx).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+// GetDescendants returns all tags nested below the tag with the given name
+func (r *tagRepository) GetDescendants(ctx context.Context, name string) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.WithContext(ctx).
+		Where("name LIKE ? ESCAPE '\\'", escapeLike(name)+"/%").
+		Order("name").
+		Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Tag{}).
+		Where("parent_id = ?", id).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *tagRepository) ListDocumentPairs(ctx context.Context) ([]TagDocumentPair, error) {
+	var pairs []TagDocumentPair
+	err := r.db.WithContext(ctx).
+		Table("document_tags").
+		Select("document_tags.tag_id, document_tags.document_id").
+		Joins("JOIN documents ON documents.id = document_tags.document_id AND documents.deleted_at IS NULL").
+		Scan(&pairs).Error
+	return pairs, err
+}
+
+// Merge moves every document of the source tag onto the target tag and then
+// deletes the source tag.
+func (r *tagRepository) Merge(ctx context.Context, sourceID, targetID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO document_tags (document_id, tag_id)
+			SELECT document_id, ? FROM document_tags
+			WHERE tag_id = ? AND document_id NOT IN
+				(SELECT document_id FROM document_tags WHERE tag_id = ?)`,
+			targetID, sourceID, targetID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM document_tags WHERE tag_id = ?", sourceID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Tag{}, sourceID).Error
+	})
+}
+
+// Rename saves the tag and its descendants in one transaction so that a
+// failure leaves no half-renamed subtree behind.
+func (r *tagRepository) Rename(ctx context.Context, tag *models.Tag, descendants []models.Tag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tag).Error; err != nil {
+			return err
+		}
+		for i := range descendants {
+			if err := tx.Save(&descendants[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// escapeLike escapes the LIKE wildcards in s so it can be used as a literal
+// prefix together with ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type TagSuggestionRepository interface {
+	// Replace stores the suggestions of a document in place of earlier ones.
+	// No suggestions are stored as a marker row, so that they are known to
+	// have been computed.
+	Replace(ctx context.Context, documentID uint, suggestions []models.TagSuggestion) error
+	// ListByDocument returns the suggestions of a document by descending
+	// confidence, and whether any were stored
+	ListByDocument(ctx context.Context, documentID uint) ([]models.TagSuggestion, bool, error)
+	DeleteByDocument(ctx context.Context, documentID uint) error
+}
+
+type tagSuggestionRepository struct {
+	db *gorm.DB
+}
+
+func NewTagSuggestionRepository(db *gorm.DB) TagSuggestionRepository {
+	return &tagSuggestionRepository{db: db}
+}
+
+func (r *tagSuggestionRepository) Replace(ctx context.Context, documentID uint, suggestions []models.TagSuggestion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", documentID).Delete(&models.TagSuggestion{}).Error; err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return tx.Create(&models.TagSuggestion{DocumentID: documentID, Source: models.SuggestionSourceNone}).Error
+		}
+		for i := range suggestions {
+			suggestions[i].ID = 0
+			suggestions[i].DocumentID = documentID
+		}
+		return tx.Create(&suggestions).Error
+	})
+}
+
+func (r *tagSuggestionRepository) ListByDocument(ctx context.Context, documentID uint) ([]models.TagSuggestion, bool, error) {
+	var stored []models.TagSuggestion
+	err := r.db.WithContext(ctx).
+		Where("document_id = ?", documentID).
+		Order("confidence DESC, name").
+		Find(&stored).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	suggestions := stored[:0]
+	for _, suggestion := range stored {
+		if suggestion.Source != models.SuggestionSourceNone {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, len(stored) > 0, nil
+}
+
+func (r *tagSuggestionRepository) DeleteByDocument(ctx context.Context, documentID uint) error {
+	return r.db.WithContext(ctx).Where("document_id = ?", documentID).Delete(&models.TagSuggestion{}).Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type TemplateRepository interface {
+	Create(ctx context.Context, template *models.Template) error
+	Update(ctx context.Context, template *models.Template) error
+	GetByID(ctx context.Context, id uint) (*models.Template, error)
+	// ListByWorkspace returns the templates of a workspace by name, only those
+	// of category when it is not empty
+	ListByWorkspace(ctx context.Context, workspaceID uint, category string) ([]models.Template, error)
+	ExistsByName(ctx context.Context, workspaceID uint, name string) (bool, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type templateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+func (r *templateRepository) Create(ctx context.Context, template *models.Template) error {
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+func (r *templateRepository) Update(ctx context.Context, template *models.Template) error {
+	return r.db.WithContext(ctx).Save(template).Error
+}
+
+func (r *templateRepository) GetByID(ctx context.Context, id uint) (*models.Template, error) {
+	var template models.Template
+	if err := r.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *templateRepository) ListByWorkspace(ctx context.Context, workspaceID uint, category string) ([]models.Template, error) {
+	var templates []models.Template
+	query := r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	err := query.Order("name").Find(&templates).Error
+	return templates, err
+}
+
+func (r *templateRepository) ExistsByName(ctx context.Context, workspaceID uint, name string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Template{}).
+		Where("workspace_id = ? AND name = ?", workspaceID, name).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *templateRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Template{}, id).Error
+}
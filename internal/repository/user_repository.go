@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	// FindByNames returns the users whose username, email or full name is
+	// one of names, ignoring case
+	FindByNames(ctx context.Context, names []string) ([]models.User, error)
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByNames(ctx context.Context, names []string) ([]models.User, error) {
+	var users []models.User
+	if len(names) == 0 {
+		return users, nil
+	}
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+	err := r.db.WithContext(ctx).
+		Where("LOWER(username) IN ? OR LOWER(email) IN ? OR LOWER(full_name) IN ?", lower, lower, lower).
+		Order("id").
+		Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"gorm.io/gorm"
+)
+
+type WorkspaceRepository interface {
+	// Create stores a workspace together with its members
+	Create(ctx context.Context, workspace *models.Workspace) error
+	// GetByID returns a workspace with its members and their users
+	GetByID(ctx context.Context, id uint) (*models.Workspace, error)
+	// ListByMember returns the workspaces userID is a member of, by name
+	ListByMember(ctx context.Context, userID uint) ([]models.Workspace, error)
+	// GetMember returns gorm.ErrRecordNotFound when userID is not a member
+	GetMember(ctx context.Context, workspaceID, userID uint) (*models.WorkspaceMember, error)
+	// SaveMember adds a member or changes the role of an existing one
+	SaveMember(ctx context.Context, member *models.WorkspaceMember) error
+	RemoveMember(ctx context.Context, workspaceID, userID uint) error
+}
+
+type workspaceRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepository(db *gorm.DB) WorkspaceRepository {
+	return &workspaceRepository{db: db}
+}
+
+func (r *workspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	return r.db.WithContext(ctx).Create(workspace).Error
+}
+
+func (r *workspaceRepository) GetByID(ctx context.Context, id uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.db.WithContext(ctx).
+		Preload("Members", func(db *gorm.DB) *gorm.DB {
+			return db.Order("workspace_members.created_at")
+		}).
+		Preload("Members.User").
+		First(&workspace, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *workspaceRepository) ListByMember(ctx context.Context, userID uint) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := r.db.WithContext(ctx).
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ?", userID).
+		Order("workspaces.name").
+		Find(&workspaces).Error
+	return workspaces, err
+}
+
+func (r *workspaceRepository) GetMember(ctx context.Context, workspaceID, userID uint) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *workspaceRepository) SaveMember(ctx context.Context, member *models.WorkspaceMember) error {
+	return r.db.WithContext(ctx).Omit("User").Save(member).Error
+}
+
+func (r *workspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Delete(&models.WorkspaceMember{}).Error
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Languages detected for documents
+const (
+	LanguageEnglish  = "en"
+	LanguageChinese  = "zh"
+	LanguageJapanese = "ja"
+	LanguageKorean   = "ko"
+	LanguageOther    = ""
+)
+
+// Token is a normalized term and its position within the field
+type Token struct {
+	Term     string
+	Position int
+}
+
+// Tokenizer splits text into tokens
+type Tokenizer interface {
+	Tokenize(text string) []Token
+}
+
+// TokenFilter rewrites a term; returning "" drops the token
+type TokenFilter func(term string) string
+
+// Analyzer is a tokenizer followed by a chain of token filters
+type Analyzer struct {
+	Tokenizer Tokenizer
+	Filters   []TokenFilter
+}
+
+// Analyze runs the pipeline over text
+func (a *Analyzer) Analyze(text string) []Token {
+	tokens := a.Tokenizer.Tokenize(text)
+	result := tokens[:0]
+	for _, token := range tokens {
+		for _, filter := range a.Filters {
+			if token.Term = filter(token.Term); token.Term == "" {
+				break
+			}
+		}
+		if token.Term != "" {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// UnicodeTokenizer splits text on anything that is not a letter or digit.
+// Runs of CJK characters are segmented with the dictionary, falling back to
+// bigrams when it is nil or has no matching word.
+type UnicodeTokenizer struct {
+	Dictionary *Dictionary
+}
+
+func (t *UnicodeTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	var word, cjk []rune
+
+	emit := func(term string) {
+		tokens = append(tokens, Token{Term: term, Position: len(tokens)})
+	}
+	flushWord := func() {
+		if len(word) > 0 {
+			emit(string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) > 0 {
+			for _, term := range segmentCJK(cjk, t.Dictionary) {
+				emit(term)
+			}
+			cjk = cjk[:0]
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return tokens
+}
+
+// LowercaseFilter lower-cases terms
+func LowercaseFilter(term string) string {
+	return strings.ToLower(term)
+}
+
+// ASCIIFoldingFilter strips diacritics so that "café" matches "cafe"
+func ASCIIFoldingFilter(term string) string {
+	for _, r := range term {
+		if r >= utf8.RuneSelf {
+			folded, _, err := transform.String(foldTransformer(), term)
+			if err != nil {
+				return term
+			}
+			return folded
+		}
+	}
+	return term
+}
+
+// foldTransformer decomposes, drops combining marks and recomposes. A new
+// transformer is built per call because transformers keep state.
+func foldTransformer() transform.Transformer {
+	return transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+}
+
+// EnglishStemFilter reduces English words to their Porter stem
+func EnglishStemFilter(term string) string {
+	return porterStem(term)
+}
+
+// Analyzers holds the analyzer of each language. Documents are analyzed
+// with the analyzer of their detected language.
+type Analyzers struct {
+	byLanguage map[string]*Analyzer
+	fallback   *Analyzer
+}
+
+// NewAnalyzers builds the standard analyzers. dict may be nil, in which case
+// CJK text is indexed as bigrams.
+func NewAnalyzers(dict *Dictionary) *Analyzers {
+	tokenizer := &UnicodeTokenizer{Dictionary: dict}
+	standard := &Analyzer{
+		Tokenizer: tokenizer,
+		Filters:   []TokenFilter{LowercaseFilter, ASCIIFoldingFilter},
+	}
+	english := &Analyzer{
+		Tokenizer: tokenizer,
+		Filters:   []TokenFilter{LowercaseFilter, ASCIIFoldingFilter, EnglishStemFilter},
+	}
+
+	return &Analyzers{
+		byLanguage: map[string]*Analyzer{
+			LanguageEnglish:  english,
+			LanguageChinese:  standard,
+			LanguageJapanese: standard,
+			LanguageKorean:   standard,
+		},
+		fallback: standard,
+	}
+}
+
+// For returns the analyzer of a language
+func (a *Analyzers) For(language string) *Analyzer {
+	if analyzer, ok := a.byLanguage[language]; ok {
+		return analyzer
+	}
+	return a.fallback
+}
+
+// DetectLanguage guesses the language of text from the scripts it uses.
+// Text with a noticeable share of CJK characters is classified by the
+// presence of kana or hangul; mostly ASCII Latin text is taken as English.
+func DetectLanguage(text string) string {
+	var han, kana, hangul, ascii, otherLetters int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			ascii++
+		case unicode.IsLetter(r):
+			otherLetters++
+		}
+	}
+
+	cjk := han + kana + hangul
+	latin := ascii + otherLetters
+	if cjk+latin == 0 {
+		return LanguageOther
+	}
+
+	// 一个汉字约相当于一个英文单词（约 5 个字母），按词计 CJK 占比超过 1/5 即视为 CJK 文档
+	if cjk > 0 && cjk*20 >= latin {
+		switch {
+		case kana > 0 && kana*10 >= cjk:
+			return LanguageJapanese
+		case hangul > han:
+			return LanguageKorean
+		default:
+			return LanguageChinese
+		}
+	}
+
+	if ascii*10 >= latin*9 {
+		return LanguageEnglish
+	}
+	return LanguageOther
+}
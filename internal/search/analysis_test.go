@@ -0,0 +1,81 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPorterStem(t *testing.T) {
+	tests := map[string]string{
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"cats":        "cat",
+		"agreed":      "agre",
+		"hopping":     "hop",
+		"filing":      "file",
+		"happy":       "happi",
+		"relational":  "relat",
+		"migrations":  "migrat",
+		"deployed":    "deploi",
+		"generalize":  "gener",
+		"controlling": "control",
+		"go":          "go",
+		"Running":     "Running",
+	}
+
+	for word, expected := range tests {
+		assert.Equal(t, expected, porterStem(word), word)
+	}
+}
+
+func TestSegmentCJK(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		dict     *Dictionary
+		expected []string
+	}{
+		{name: "bigrams", text: "数据库迁移", expected: []string{"数据", "据库", "库迁", "迁移"}},
+		{name: "single character", text: "库", expected: []string{"库"}},
+		{name: "dictionary", text: "数据库迁移", dict: NewDictionary([]string{"数据库", "迁移"}), expected: []string{"数据库", "迁移"}},
+		{name: "longest match wins", text: "数据库", dict: NewDictionary([]string{"数据", "数据库"}), expected: []string{"数据库"}},
+		{name: "unknown characters fall back", text: "新数据库表", dict: NewDictionary([]string{"数据库"}), expected: []string{"新", "数据库", "表"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, segmentCJK([]rune(tt.text), tt.dict))
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	analyzers := NewAnalyzers(nil)
+
+	tokens := analyzers.For(LanguageEnglish).Analyze("Café migrations, 数据库")
+	assert.Equal(t, []string{"cafe", "migrat", "数据", "据库"}, termsOf(tokens))
+	assert.Equal(t, 3, tokens[3].Position)
+
+	tokens = analyzers.For(LanguageChinese).Analyze("部署Kubernetes集群")
+	assert.Equal(t, []string{"部署", "kubernetes", "集群"}, termsOf(tokens))
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "How to handle database failover", expected: LanguageEnglish},
+		{text: "如何处理数据库故障切换", expected: LanguageChinese},
+		{text: "使用 Kubernetes 部署服务", expected: LanguageChinese},
+		{text: "データベースの移行", expected: LanguageJapanese},
+		{text: "데이터베이스 마이그레이션", expected: LanguageKorean},
+		{text: "Привет мир", expected: LanguageOther},
+		{text: "12345", expected: LanguageOther},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, DetectLanguage(tt.text), tt.text)
+	}
+}
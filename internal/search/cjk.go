@@ -0,0 +1,117 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// isCJK reports whether r belongs to a script written without spaces
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// Dictionary is a word list used for forward maximum matching of CJK text
+type Dictionary struct {
+	words  map[string]bool
+	maxLen int // longest word in runes
+}
+
+// NewDictionary builds a dictionary from a word list
+func NewDictionary(words []string) *Dictionary {
+	d := &Dictionary{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		d.Add(word)
+	}
+	return d
+}
+
+// LoadDictionary reads a dictionary file with one word per line. Anything
+// after the first whitespace on a line (e.g. a frequency column) is ignored.
+func LoadDictionary(path string) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dictionary: %w", err)
+	}
+	defer f.Close()
+
+	d := NewDictionary(nil)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			d.Add(fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	return d, nil
+}
+
+// Add inserts a word; single characters are ignored since they never
+// improve on the bigram fallback.
+func (d *Dictionary) Add(word string) {
+	n := utf8.RuneCountInString(word)
+	if n < 2 {
+		return
+	}
+	d.words[word] = true
+	if n > d.maxLen {
+		d.maxLen = n
+	}
+}
+
+// segmentCJK splits a run of CJK characters into terms. Dictionary words are
+// matched greedily from the left; characters not covered by any word are
+// emitted as overlapping bigrams, or as a single character when isolated.
+func segmentCJK(run []rune, dict *Dictionary) []string {
+	var terms []string
+	var pending []rune
+
+	flush := func() {
+		switch len(pending) {
+		case 0:
+		case 1:
+			terms = append(terms, string(pending))
+		default:
+			for i := 0; i+1 < len(pending); i++ {
+				terms = append(terms, string(pending[i:i+2]))
+			}
+		}
+		pending = pending[:0]
+	}
+
+	for i := 0; i < len(run); {
+		if word := dict.longestMatch(run[i:]); word > 0 {
+			flush()
+			terms = append(terms, string(run[i:i+word]))
+			i += word
+			continue
+		}
+		pending = append(pending, run[i])
+		i++
+	}
+	flush()
+
+	return terms
+}
+
+// longestMatch returns the length of the longest dictionary word prefixing
+// text, or 0 if there is none
+func (d *Dictionary) longestMatch(text []rune) int {
+	if d == nil {
+		return 0
+	}
+	for n := min(d.maxLen, len(text)); n >= 2; n-- {
+		if d.words[string(text[:n])] {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+)
+
+// Hit is a matching document with its relevance score
+type Hit struct {
+	ID    uint    `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// SearchEngine indexes documents and answers free-text queries. Queries
+// support plain terms (all must match), "quoted phrases", prefix* terms and
+// -excluded terms.
+type SearchEngine interface {
+	Index(ctx context.Context, doc *models.Document) error
+	Remove(ctx context.Context, id uint) error
+	// Search returns at most limit hits ordered by descending score
+	Search(ctx context.Context, query string, limit int) ([]Hit, error)
+	// Flush persists pending changes
+	Flush() error
+	Close() error
+}
+
+// CorpusStats is implemented by engines that keep term statistics over the
+// indexed documents, e.g. to weight the terms of a document by TF-IDF
+type CorpusStats interface {
+	// Analyzer returns the analyzer used for documents in language
+	Analyzer(language string) *Analyzer
+	// DocumentFrequency returns the number of documents containing term
+	DocumentFrequency(term string) int
+	// Len returns the number of indexed documents
+	Len() int
+}
@@ -0,0 +1,499 @@
+package search
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+)
+
+// BM25 parameters and field weights
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2.0
+
+	indexFormatVersion = 2
+	indexFileName      = "index.gob"
+)
+
+// Posting holds the positions of a term inside one document
+type Posting struct {
+	Title   []int
+	Content []int
+}
+
+// DocStats holds per-document field lengths, the detected language and the
+// indexed terms
+type DocStats struct {
+	TitleLen   int
+	ContentLen int
+	Language   string
+	Terms      []string
+}
+
+// snapshot is the on-disk representation of the index
+type snapshot struct {
+	Version  int
+	Docs     map[uint]*DocStats
+	Postings map[string]map[uint]*Posting
+}
+
+// Index is an in-process inverted index with BM25 scoring. It is safe for
+// concurrent use and persists itself to a single file under its directory.
+type Index struct {
+	mu        sync.RWMutex
+	path      string
+	analyzers *Analyzers
+	docs      map[uint]*DocStats
+	postings  map[string]map[uint]*Posting
+	dirty     bool
+
+	// sorted is the term dictionary for prefix queries, nil when stale.
+	// Writers reset it under mu; readers rebuild it under dictMu.
+	dictMu sync.Mutex
+	sorted []string
+
+	totalTitleLen   int
+	totalContentLen int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Options configures an Index
+type Options struct {
+	// FlushInterval enables background writes of pending changes when positive
+	FlushInterval time.Duration
+	// Dictionary is used to segment CJK text; nil indexes it as bigrams
+	Dictionary *Dictionary
+}
+
+// NewIndex opens the index persisted in dir, or starts an empty one. An
+// empty dir keeps the index in memory only.
+func NewIndex(dir string, opts Options) (*Index, error) {
+	idx := &Index{
+		analyzers: NewAnalyzers(opts.Dictionary),
+		docs:      make(map[uint]*DocStats),
+		postings:  make(map[string]map[uint]*Posting),
+	}
+
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create index directory: %w", err)
+		}
+		idx.path = filepath.Join(dir, indexFileName)
+		if err := idx.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.FlushInterval > 0 && idx.path != "" {
+		idx.stop = make(chan struct{})
+		idx.done = make(chan struct{})
+		go idx.flushLoop(opts.FlushInterval)
+	}
+
+	return idx, nil
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+func (idx *Index) Analyzer(language string) *Analyzer {
+	return idx.analyzers.For(language)
+}
+
+func (idx *Index) DocumentFrequency(term string) int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.postings[term])
+}
+
+func (idx *Index) Index(ctx context.Context, doc *models.Document) error {
+	language := DetectLanguage(doc.Title + "\n" + doc.Content)
+	analyzer := idx.analyzers.For(language)
+	title := analyzer.Analyze(doc.Title)
+	content := analyzer.Analyze(doc.Content)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(doc.ID)
+
+	postings := make(map[string]*Posting)
+	for _, token := range title {
+		p := postings[token.Term]
+		if p == nil {
+			p = &Posting{}
+			postings[token.Term] = p
+		}
+		p.Title = append(p.Title, token.Position)
+	}
+	for _, token := range content {
+		p := postings[token.Term]
+		if p == nil {
+			p = &Posting{}
+			postings[token.Term] = p
+		}
+		p.Content = append(p.Content, token.Position)
+	}
+
+	stats := &DocStats{
+		TitleLen:   len(title),
+		ContentLen: len(content),
+		Language:   language,
+		Terms:      make([]string, 0, len(postings)),
+	}
+	for term, p := range postings {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[uint]*Posting)
+			idx.sorted = nil
+		}
+		idx.postings[term][doc.ID] = p
+		stats.Terms = append(stats.Terms, term)
+	}
+
+	idx.docs[doc.ID] = stats
+	idx.totalTitleLen += stats.TitleLen
+	idx.totalContentLen += stats.ContentLen
+	idx.dirty = true
+	return nil
+}
+
+func (idx *Index) Remove(ctx context.Context, id uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.removeLocked(id) {
+		idx.dirty = true
+	}
+	return nil
+}
+
+func (idx *Index) removeLocked(id uint) bool {
+	stats, ok := idx.docs[id]
+	if !ok {
+		return false
+	}
+
+	for _, term := range stats.Terms {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+			idx.sorted = nil
+		}
+	}
+
+	idx.totalTitleLen -= stats.TitleLen
+	idx.totalContentLen -= stats.ContentLen
+	delete(idx.docs, id)
+	return true
+}
+
+// Search analyzes the query once per analyzer in use, since terms of an
+// English document are stemmed while others are not, and matches each
+// analysis against the documents indexed with the same analyzer.
+func (idx *Index) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	byAnalyzer := make(map[*Analyzer]map[uint]bool)
+	for id, stats := range idx.docs {
+		analyzer := idx.analyzers.For(stats.Language)
+		if byAnalyzer[analyzer] == nil {
+			byAnalyzer[analyzer] = make(map[uint]bool)
+		}
+		byAnalyzer[analyzer][id] = true
+	}
+
+	var hits []Hit
+	for analyzer, docs := range byAnalyzer {
+		clauses := parseQuery(query, analyzer.Analyze)
+		for id, score := range idx.match(clauses) {
+			if docs[id] {
+				hits = append(hits, Hit{ID: id, Score: score})
+			}
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// match returns the documents matching all non-excluded clauses and none of
+// the excluded ones, with their summed scores
+func (idx *Index) match(clauses []clause) map[uint]float64 {
+	var scores map[uint]float64
+	excluded := make(map[uint]bool)
+
+	for _, c := range clauses {
+		matches := idx.evaluate(c)
+		if c.exclude {
+			for id := range matches {
+				excluded[id] = true
+			}
+			continue
+		}
+
+		// 所有非排除条件都必须命中
+		if scores == nil {
+			scores = matches
+			continue
+		}
+		for id, score := range scores {
+			if extra, ok := matches[id]; ok {
+				scores[id] = score + extra
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	for id := range excluded {
+		delete(scores, id)
+	}
+	return scores
+}
+
+// evaluate returns the documents matching a clause with their scores
+func (idx *Index) evaluate(c clause) map[uint]float64 {
+	matches := make(map[uint]float64)
+
+	switch c.kind {
+	case termClause:
+		for id, p := range idx.postings[c.terms[0]] {
+			matches[id] = idx.score(c.terms[0], id, p)
+		}
+
+	case prefixClause:
+		prefix := c.terms[0]
+		dict := idx.dictionary()
+		for i := sort.SearchStrings(dict, prefix); i < len(dict); i++ {
+			term := dict[i]
+			if !strings.HasPrefix(term, prefix) {
+				break
+			}
+			// 前缀展开的多个词只取最高分，避免常见前缀过度加权
+			for id, p := range idx.postings[term] {
+				matches[id] = math.Max(matches[id], idx.score(term, id, p))
+			}
+		}
+
+	case phraseClause:
+		first := idx.postings[c.terms[0]]
+		for id := range first {
+			postings := make([]*Posting, len(c.terms))
+			complete := true
+			for i, term := range c.terms {
+				if postings[i] = idx.postings[term][id]; postings[i] == nil {
+					complete = false
+					break
+				}
+			}
+			if !complete {
+				continue
+			}
+
+			titleMatch := phraseMatch(postings, func(p *Posting) []int { return p.Title })
+			contentMatch := phraseMatch(postings, func(p *Posting) []int { return p.Content })
+			if !titleMatch && !contentMatch {
+				continue
+			}
+
+			var score float64
+			for i, term := range c.terms {
+				score += idx.score(term, id, postings[i])
+			}
+			matches[id] = score
+		}
+	}
+
+	return matches
+}
+
+// phraseMatch reports whether the terms occur at consecutive positions
+func phraseMatch(postings []*Posting, field func(*Posting) []int) bool {
+	next := make(map[int]bool)
+	for _, pos := range field(postings[0]) {
+		next[pos+1] = true
+	}
+	for _, p := range postings[1:] {
+		current := make(map[int]bool)
+		for _, pos := range field(p) {
+			if next[pos] {
+				current[pos+1] = true
+			}
+		}
+		if len(current) == 0 {
+			return false
+		}
+		next = current
+	}
+	return len(next) > 0
+}
+
+// score computes the BM25 score of a term for a document, summing the title
+// and content fields with the title weighted higher.
+func (idx *Index) score(term string, id uint, p *Posting) float64 {
+	n := float64(len(idx.docs))
+	df := float64(len(idx.postings[term]))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	stats := idx.docs[id]
+	avgTitle := float64(idx.totalTitleLen) / n
+	avgContent := float64(idx.totalContentLen) / n
+
+	return idf * (titleWeight*bm25(len(p.Title), stats.TitleLen, avgTitle) +
+		bm25(len(p.Content), stats.ContentLen, avgContent))
+}
+
+func bm25(tf, length int, avgLength float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	norm := 1.0
+	if avgLength > 0 {
+		norm = 1 - bm25B + bm25B*float64(length)/avgLength
+	}
+	f := float64(tf)
+	return f * (bm25K1 + 1) / (f + bm25K1*norm)
+}
+
+// dictionary returns the sorted term list, rebuilding it if a write made it
+// stale. Callers must hold at least the read lock.
+func (idx *Index) dictionary() []string {
+	idx.dictMu.Lock()
+	defer idx.dictMu.Unlock()
+
+	if idx.sorted == nil {
+		idx.sorted = make([]string, 0, len(idx.postings))
+		for term := range idx.postings {
+			idx.sorted = append(idx.sorted, term)
+		}
+		sort.Strings(idx.sorted)
+	}
+	return idx.sorted
+}
+
+// Flush writes the index to disk if it changed since the last flush. The
+// file is replaced atomically.
+func (idx *Index) Flush() error {
+	if idx.path == "" {
+		return nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), indexFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create index file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = gob.NewEncoder(tmp).Encode(&snapshot{
+		Version:  indexFormatVersion,
+		Docs:     idx.docs,
+		Postings: idx.postings,
+	})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), idx.path); err != nil {
+		return fmt.Errorf("failed to replace index file: %w", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
+
+func (idx *Index) load() error {
+	f, err := os.Open(idx.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open index: %w", err)
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	// 格式不兼容时丢弃旧索引，由调用方重建
+	if snap.Version != indexFormatVersion {
+		return nil
+	}
+
+	if snap.Docs != nil {
+		idx.docs = snap.Docs
+	}
+	if snap.Postings != nil {
+		idx.postings = snap.Postings
+	}
+	for _, stats := range idx.docs {
+		idx.totalTitleLen += stats.TitleLen
+		idx.totalContentLen += stats.ContentLen
+	}
+	return nil
+}
+
+func (idx *Index) flushLoop(interval time.Duration) {
+	defer close(idx.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// 失败时保持 dirty 状态，下一轮重试
+			_ = idx.Flush()
+		case <-idx.stop:
+			return
+		}
+	}
+}
+
+// Close stops background flushing and writes pending changes
+func (idx *Index) Close() error {
+	if idx.stop != nil {
+		close(idx.stop)
+		<-idx.done
+		idx.stop = nil
+	}
+	return idx.Flush()
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex(t *testing.T, dir string) *Index {
+	idx, err := NewIndex(dir, Options{})
+	require.NoError(t, err)
+
+	docs := []models.Document{
+		{ID: 1, Title: "Oncall runbook", Content: "How to handle database failover during an incident."},
+		{ID: 2, Title: "Release process", Content: "Tag the release and notify oncall engineers."},
+		{ID: 3, Title: "Database migrations", Content: "Migrations run before the release is deployed."},
+	}
+	for i := range docs {
+		require.NoError(t, idx.Index(context.Background(), &docs[i]))
+	}
+	return idx
+}
+
+func hitIDs(hits []Hit) []uint {
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := newTestIndex(t, "")
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []uint
+	}{
+		{name: "title match ranks first", query: "oncall", expected: []uint{1, 2}},
+		{name: "all terms must match", query: "release database", expected: []uint{3}},
+		{name: "phrase", query: `"database failover"`, expected: []uint{1}},
+		{name: "phrase out of order", query: `"failover database"`, expected: []uint{}},
+		{name: "prefix", query: "migrat*", expected: []uint{3}},
+		{name: "exclusion", query: "release -migrations", expected: []uint{2}},
+		{name: "case insensitive", query: "DATABASE", expected: []uint{3, 1}},
+		{name: "no match", query: "kubernetes", expected: []uint{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := idx.Search(ctx, tt.query, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hitIDs(hits))
+		})
+	}
+}
+
+func TestIndexSearchMixedLanguages(t *testing.T) {
+	idx, err := NewIndex("", Options{Dictionary: NewDictionary([]string{"数据库"})})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	docs := []models.Document{
+		{ID: 1, Title: "数据库迁移指南", Content: "上线前先在预发环境运行 migrations 脚本。"},
+		{ID: 2, Title: "Database migrations", Content: "Migrations run before the release is deployed."},
+		{ID: 3, Title: "故障复盘", Content: "数据同步延迟导致告警。"},
+	}
+	for i := range docs {
+		require.NoError(t, idx.Index(ctx, &docs[i]))
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []uint
+	}{
+		{name: "dictionary word", query: "数据库", expected: []uint{1}},
+		{name: "bigram", query: "迁移", expected: []uint{1}},
+		{name: "multi character phrase", query: "同步延迟", expected: []uint{3}},
+		{name: "stemmed english", query: "migration", expected: []uint{2}},
+		{name: "exact english term in chinese document", query: "migrations", expected: []uint{2, 1}},
+		{name: "mixed query", query: "数据库 migrations", expected: []uint{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := idx.Search(ctx, tt.query, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hitIDs(hits))
+		})
+	}
+}
+
+func TestIndexUpdateAndRemove(t *testing.T) {
+	idx := newTestIndex(t, "")
+	ctx := context.Background()
+
+	require.NoError(t, idx.Index(ctx, &models.Document{ID: 2, Title: "Release process", Content: "Ship it."}))
+	hits, err := idx.Search(ctx, "oncall", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, hitIDs(hits))
+
+	require.NoError(t, idx.Remove(ctx, 1))
+	hits, err = idx.Search(ctx, "oncall", 10)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.Equal(t, 2, idx.Len())
+}
+
+func TestIndexPersistence(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	idx := newTestIndex(t, dir)
+	require.NoError(t, idx.Close())
+
+	reopened, err := NewIndex(dir, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.Len())
+
+	hits, err := reopened.Search(ctx, `"database failover"`, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, hitIDs(hits))
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+type clauseKind int
+
+const (
+	termClause clauseKind = iota
+	phraseClause
+	prefixClause
+)
+
+// clause is one element of a parsed query
+type clause struct {
+	kind    clauseKind
+	terms   []string
+	exclude bool
+}
+
+// parseQuery splits a query into clauses. Words become term clauses,
+// "quoted text" becomes a phrase, a trailing * marks a prefix and a leading
+// - excludes documents matching the clause.
+func parseQuery(query string, analyze func(string) []Token) []clause {
+	var clauses []clause
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		exclude := false
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			exclude = true
+			i++
+		}
+
+		var text string
+		quoted := false
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i+1 : end])
+			quoted = true
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			text = string(runes[i:end])
+			i = end
+		}
+
+		prefix := !quoted && strings.HasSuffix(text, "*")
+		terms := termsOf(analyze(strings.TrimSuffix(text, "*")))
+
+		switch {
+		case len(terms) == 0:
+			continue
+		case len(terms) > 1:
+			clauses = append(clauses, clause{kind: phraseClause, terms: terms, exclude: exclude})
+		case prefix:
+			clauses = append(clauses, clause{kind: prefixClause, terms: terms, exclude: exclude})
+		default:
+			clauses = append(clauses, clause{kind: termClause, terms: terms, exclude: exclude})
+		}
+	}
+
+	return clauses
+}
+
+func termsOf(tokens []Token) []string {
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		terms = append(terms, token.Term)
+	}
+	return terms
+}
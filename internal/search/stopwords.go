@@ -0,0 +1,22 @@
+package search
+
+// IsStopWord reports whether an analyzed term carries too little meaning to
+// characterize a text, such as function words and single characters. English
+// terms are stemmed, so the list holds their stemmed forms.
+func IsStopWord(term string) bool {
+	if len([]rune(term)) == 1 {
+		return true
+	}
+	return stopWords[term]
+}
+
+var stopWords = map[string]bool{
+	"an": true, "and": true, "ar": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "can": true, "do": true, "for": true, "from": true, "ha": true, "have": true,
+	"he": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"not": true, "of": true, "on": true, "or": true, "she": true, "so": true, "that": true,
+	"the": true, "their": true, "then": true, "there": true, "these": true, "thei": true,
+	"thi": true, "to": true, "wa": true, "we": true, "were": true, "what": true, "when": true,
+	"which": true, "while": true, "who": true, "will": true, "with": true, "you": true, "your": true,
+	"的": true, "了": true, "是": true, "在": true, "和": true, "也": true, "就": true,
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+)
+
+const (
+	// DefaultAskSources is the number of passages given to the model
+	DefaultAskSources = 5
+	maxQuestionLength = 2000
+)
+
+var (
+	ErrEmptyQuestion   = errors.New("question is required")
+	ErrQuestionTooLong = errors.New("question is too long")
+)
+
+// noAnswer is returned without calling the model when nothing relevant is found
+const noAnswer = "I couldn't find any documents that answer this question."
+
+const askSystemPrompt = `You answer questions about a team's documents.
+Use only the numbered sources provided by the user. Cite every statement with the number of its source in square brackets, e.g. [1].
+If the sources do not contain the answer, say that you don't know. Answer in the language of the question.`
+
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+type AskService interface {
+	// Ask answers question from the documents userID can read. onToken
+	// receives the answer as it is generated.
+	Ask(ctx context.Context, userID uint, question string, onToken func(token string) error) (*models.Answer, error)
+}
+
+type askService struct {
+	retriever *sourceRetriever
+	model     ai.ChatModel
+}
+
+func NewAskService(semantic SemanticSearchService, repo repository.DocumentRepository, model ai.ChatModel, sources int) AskService {
+	return &askService{
+		retriever: newSourceRetriever(semantic, repo, sources),
+		model:     model,
+	}
+}
+
+func (s *askService) Ask(ctx context.Context, userID uint, question string, onToken func(token string) error) (*models.Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, ErrEmptyQuestion
+	}
+	if len([]rune(question)) > maxQuestionLength {
+		return nil, ErrQuestionTooLong
+	}
+
+	sources, err := s.retriever.retrieve(ctx, userID, question, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &models.Answer{Question: question, Citations: []models.Citation{}}
+	if len(sources) == 0 {
+		answer.Answer = noAnswer
+		if err := onToken(noAnswer); err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}
+
+	messages := []ai.Message{
+		{Role: ai.RoleSystem, Content: askSystemPrompt},
+		{Role: ai.RoleUser, Content: buildAskPrompt(question, sources)},
+	}
+	answer.Answer, err = s.model.Stream(ctx, messages, onToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+
+	answer.Citations = citedSources(answer.Answer, sources)
+	return answer, nil
+}
+
+// sourceRetriever finds the passages given to a chat model as sources
+type sourceRetriever struct {
+	semantic SemanticSearchService
+	repo     repository.DocumentRepository
+	sources  int
+}
+
+func newSourceRetriever(semantic SemanticSearchService, repo repository.DocumentRepository, sources int) *sourceRetriever {
+	if sources <= 0 {
+		sources = DefaultAskSources
+	}
+	return &sourceRetriever{semantic: semantic, repo: repo, sources: sources}
+}
+
+// retrieve returns the passages most relevant to query from documents
+// userID can read, numbered from 1 in relevance order. When scope is not
+// nil only those documents are searched.
+func (s *sourceRetriever) retrieve(ctx context.Context, userID uint, query string, scope []uint) ([]models.Citation, error) {
+	if scope != nil && len(scope) == 0 {
+		return nil, nil
+	}
+	// 只在可读文档中检索，不可读的段落不会占用名额
+	readable, err := s.repo.FilterIDs(ctx, repository.DocumentListParams{IDs: scope, ReadableBy: &userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(readable) == 0 {
+		return nil, nil
+	}
+
+	passages, err := s.semantic.SearchDocuments(ctx, query, s.sources, readable)
+	if err != nil {
+		return nil, err
+	}
+	if len(passages) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(passages))
+	for _, p := range passages {
+		if !slices.Contains(ids, p.DocumentID) {
+			ids = append(ids, p.DocumentID)
+		}
+	}
+	docs, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Document, len(docs))
+	for i := range docs {
+		byID[docs[i].ID] = &docs[i]
+	}
+
+	sources := make([]models.Citation, 0, s.sources)
+	for _, p := range passages {
+		doc, ok := byID[p.DocumentID]
+		if !ok {
+			continue
+		}
+		sources = append(sources, models.Citation{
+			Index:      len(sources) + 1,
+			DocumentID: doc.ID,
+			Version:    doc.Version,
+			Title:      doc.Title,
+			ChunkIndex: p.ChunkIndex,
+			Text:       p.Text,
+		})
+		if len(sources) == s.sources {
+			break
+		}
+	}
+	return sources, nil
+}
+
+// buildAskPrompt lists the numbered sources followed by the question
+func buildAskPrompt(question string, sources []models.Citation) string {
+	var b strings.Builder
+	b.WriteString("Sources:\n\n")
+	for _, src := range sources {
+		fmt.Fprintf(&b, "[%d] %s (document %d, version %d)\n%s\n\n", src.Index, src.Title, src.DocumentID, src.Version, src.Text)
+	}
+	b.WriteString("Question: ")
+	b.WriteString(question)
+	return b.String()
+}
+
+// citedSources returns the sources the answer refers to, in order of first
+// reference. An answer without references is attributed to all sources.
+func citedSources(answer string, sources []models.Citation) []models.Citation {
+	var cited []models.Citation
+	seen := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 || n > len(sources) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		cited = append(cited, sources[n-1])
+	}
+	if len(cited) == 0 {
+		return sources
+	}
+	return cited
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// stubDocumentRepository serves documents from memory; methods the tests
+// don't need panic through the nil embedded interface
+type stubDocumentRepository struct {
+	repository.DocumentRepository
+	docs    map[uint]models.Document
+	members map[[2]uint]bool // workspace ID and user ID of the workspace members
+}
+
+func (r *stubDocumentRepository) FilterIDs(ctx context.Context, params repository.DocumentListParams) ([]uint, error) {
+	candidates := params.IDs
+	if candidates == nil {
+		for id := range r.docs {
+			candidates = append(candidates, id)
+		}
+		slices.Sort(candidates)
+	}
+
+	var ids []uint
+	for _, id := range candidates {
+		doc, ok := r.docs[id]
+		if !ok {
+			continue
+		}
+		if params.ReadableBy != nil && !r.readable(doc, *params.ReadableBy) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *stubDocumentRepository) readable(doc models.Document, userID uint) bool {
+	return doc.CreatorID == userID || doc.Status == models.DocumentStatusPublished ||
+		(doc.WorkspaceID != nil && r.members[[2]uint{*doc.WorkspaceID, userID}])
+}
+
+func (r *stubDocumentRepository) GetByID(ctx context.Context, id uint) (*models.Document, error) {
+	doc, ok := r.docs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &doc, nil
+}
+
+func (r *stubDocumentRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Document, error) {
+	var docs []models.Document
+	for _, id := range ids {
+		docs = append(docs, r.docs[id])
+	}
+	return docs, nil
+}
+
+type stubSemanticSearch struct {
+	passages []models.Passage
+}
+
+func (s *stubSemanticSearch) Search(ctx context.Context, query string, limit int) ([]models.Passage, error) {
+	return s.passages[:min(limit, len(s.passages))], nil
+}
+
+func (s *stubSemanticSearch) SearchDocuments(ctx context.Context, query string, limit int, documentIDs []uint) ([]models.Passage, error) {
+	var passages []models.Passage
+	for _, p := range s.passages {
+		if slices.Contains(documentIDs, p.DocumentID) && len(passages) < limit {
+			passages = append(passages, p)
+		}
+	}
+	return passages, nil
+}
+
+func (s *stubSemanticSearch) SearchReadable(ctx context.Context, query string, limit int, userID uint) ([]models.Passage, error) {
+	panic("not used by the tests")
+}
+
+func TestAsk(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Version: 3, Status: models.DocumentStatusPublished, CreatorID: 9},
+		2: {ID: 2, Title: "Private notes", Version: 1, Status: models.DocumentStatusDraft, CreatorID: 9},
+		3: {ID: 3, Title: "Failover checklist", Version: 2, Status: models.DocumentStatusDraft, CreatorID: 5},
+	}}
+	semantic := &stubSemanticSearch{passages: []models.Passage{
+		{DocumentID: 2, ChunkIndex: 0, Text: "Secret failover notes."},
+		{DocumentID: 1, ChunkIndex: 4, Text: "Promote the replica first. Then update DNS."},
+		{DocumentID: 3, ChunkIndex: 1, Text: "Check replication lag before failover."},
+	}}
+	svc := NewAskService(semantic, repo, ai.NewFakeChatModel(""), 5)
+
+	var streamed strings.Builder
+	answer, err := svc.Ask(ctx, 5, "  How do I fail over?  ", func(token string) error {
+		streamed.WriteString(token)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "How do I fail over?", answer.Question)
+	assert.Equal(t, "Promote the replica first [1]. Check replication lag before failover [2].", answer.Answer)
+	assert.Equal(t, answer.Answer, streamed.String())
+	assert.Equal(t, []models.Citation{
+		{Index: 1, DocumentID: 1, Version: 3, Title: "Oncall runbook", ChunkIndex: 4, Text: "Promote the replica first. Then update DNS."},
+		{Index: 2, DocumentID: 3, Version: 2, Title: "Failover checklist", ChunkIndex: 1, Text: "Check replication lag before failover."},
+	}, answer.Citations)
+}
+
+func TestAskSkipsUnreadablePassages(t *testing.T) {
+	docs := map[uint]models.Document{1: {ID: 1, Title: "Runbook", Status: models.DocumentStatusPublished}}
+	var passages []models.Passage
+	for id := uint(10); id < 20; id++ {
+		docs[id] = models.Document{ID: id, Status: models.DocumentStatusDraft, CreatorID: 9}
+		passages = append(passages, models.Passage{DocumentID: id, Text: "Secret."})
+	}
+	passages = append(passages, models.Passage{DocumentID: 1, Text: "Promote the replica."})
+	svc := NewAskService(&stubSemanticSearch{passages: passages}, &stubDocumentRepository{docs: docs}, ai.NewFakeChatModel(""), 2)
+
+	// 不可读文档的段落再多也不会挤掉可读的段落
+	answer, err := svc.Ask(context.Background(), 5, "How do I fail over?", func(string) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, answer.Citations, 1)
+	assert.Equal(t, uint(1), answer.Citations[0].DocumentID)
+}
+
+func TestAskWithoutReadableSources(t *testing.T) {
+	repo := &stubDocumentRepository{docs: map[uint]models.Document{
+		2: {ID: 2, Title: "Private notes", Status: models.DocumentStatusDraft, CreatorID: 9},
+	}}
+	semantic := &stubSemanticSearch{passages: []models.Passage{{DocumentID: 2, Text: "Secret."}}}
+	svc := NewAskService(semantic, repo, ai.NewFakeChatModel("should not be called"), 0)
+
+	answer, err := svc.Ask(context.Background(), 5, "What is secret?", func(string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, noAnswer, answer.Answer)
+	assert.Empty(t, answer.Citations)
+
+	_, err = svc.Ask(context.Background(), 5, " ", func(string) error { return nil })
+	assert.ErrorIs(t, err, ErrEmptyQuestion)
+}
+
+func TestCitedSources(t *testing.T) {
+	sources := []models.Citation{{Index: 1, DocumentID: 10}, {Index: 2, DocumentID: 20}, {Index: 3, DocumentID: 30}}
+
+	tests := []struct {
+		name     string
+		answer   string
+		expected []uint
+	}{
+		{name: "order of first reference", answer: "Yes [3]. Also [1] and [3].", expected: []uint{30, 10}},
+		{name: "out of range ignored", answer: "See [7] and [2].", expected: []uint{20}},
+		{name: "no references", answer: "I don't know.", expected: []uint{10, 20, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []uint
+			for _, c := range citedSources(tt.answer, sources) {
+				ids = append(ids, c.DocumentID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// chatHistoryMessages is the number of previous messages sent to the model
+	chatHistoryMessages = 10
+	maxChatTitleLength  = 80
+)
+
+var (
+	ErrChatSessionNotFound = errors.New("chat session not found")
+	ErrChatMessageNotFound = errors.New("chat message not found")
+	ErrInvalidChatScope    = errors.New("invalid chat scope")
+	ErrChatScopeNotFound   = errors.New("chat scope not found")
+	ErrEmptyMessage        = errors.New("message is required")
+	ErrMessageTooLong      = errors.New("message is too long")
+	ErrInvalidFeedback     = errors.New("feedback can only be given on answers")
+)
+
+const chatSystemPrompt = `You are having a conversation about a team's documents.
+Use only the numbered sources provided with the latest message and the earlier conversation. Cite every statement with the number of its source in square brackets, e.g. [1].
+If the sources do not contain the answer, say that you don't know. Answer in the language of the question.`
+
+// ChatService is also a DocumentListener: sessions are removed when a
+// document they draw on is deleted or its permissions change.
+type ChatService interface {
+	DocumentListener
+	// CreateSession starts a conversation about a document, a folder (a
+	// document and its descendants) or a tag
+	CreateSession(ctx context.Context, userID uint, scopeType string, scopeID uint, title string) (*models.ChatSession, error)
+	ListSessions(ctx context.Context, userID uint, page, pageSize int) ([]models.ChatSession, int64, error)
+	// GetSession returns a session of userID with its messages
+	GetSession(ctx context.Context, userID, sessionID uint) (*models.ChatSession, error)
+	DeleteSession(ctx context.Context, userID, sessionID uint) error
+	// SendMessage answers content within the session from the documents in
+	// its scope that userID can read. onToken receives the answer as it is
+	// generated. The stored assistant message is returned.
+	SendMessage(ctx context.Context, userID, sessionID uint, content string, onToken func(token string) error) (*models.ChatMessage, error)
+	// SetFeedback rates an answer with models.FeedbackUp, FeedbackDown or
+	// FeedbackNone
+	SetFeedback(ctx context.Context, userID, messageID uint, feedback int) (*models.ChatMessage, error)
+}
+
+type chatService struct {
+	repo       repository.ChatRepository
+	docRepo    repository.DocumentRepository
+	tagService TagService
+	retriever  *sourceRetriever
+	model      ai.ChatModel
+}
+
+func NewChatService(repo repository.ChatRepository, docRepo repository.DocumentRepository, tagService TagService, semantic SemanticSearchService, model ai.ChatModel, sources int) ChatService {
+	return &chatService{
+		repo:       repo,
+		docRepo:    docRepo,
+		tagService: tagService,
+		retriever:  newSourceRetriever(semantic, docRepo, sources),
+		model:      model,
+	}
+}
+
+func (s *chatService) CreateSession(ctx context.Context, userID uint, scopeType string, scopeID uint, title string) (*models.ChatSession, error) {
+	switch scopeType {
+	case models.ChatScopeDocument, models.ChatScopeFolder:
+		ids, err := s.docRepo.FilterIDs(ctx, repository.DocumentListParams{IDs: []uint{scopeID}, ReadableBy: &userID})
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, ErrChatScopeNotFound
+		}
+	case models.ChatScopeTag:
+		if _, err := s.tagService.GetTag(ctx, scopeID); err != nil {
+			if errors.Is(err, ErrTagNotFound) {
+				return nil, ErrChatScopeNotFound
+			}
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidChatScope
+	}
+
+	session := &models.ChatSession{
+		UserID:    userID,
+		Title:     truncateTitle(strings.TrimSpace(title)),
+		ScopeType: scopeType,
+		ScopeID:   scopeID,
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *chatService) ListSessions(ctx context.Context, userID uint, page, pageSize int) ([]models.ChatSession, int64, error) {
+	return s.repo.ListSessions(ctx, userID, page, pageSize)
+}
+
+func (s *chatService) GetSession(ctx context.Context, userID, sessionID uint) (*models.ChatSession, error) {
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChatSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 其他用户的会话按不存在处理
+	if session.UserID != userID {
+		return nil, ErrChatSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *chatService) DeleteSession(ctx context.Context, userID, sessionID uint) error {
+	if _, err := s.GetSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	return s.repo.DeleteSession(ctx, sessionID)
+}
+
+func (s *chatService) SendMessage(ctx context.Context, userID, sessionID uint, content string, onToken func(token string) error) (*models.ChatMessage, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	if len([]rune(content)) > maxQuestionLength {
+		return nil, ErrMessageTooLong
+	}
+
+	session, err := s.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.scopeDocumentIDs(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// 追问常省略主语，检索时带上上一个问题
+	query := content
+	if prev := lastUserMessage(session.Messages); prev != "" {
+		query = prev + "\n" + content
+	}
+	sources, err := s.retriever.retrieve(ctx, userID, query, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &models.ChatMessage{Role: ai.RoleAssistant, Citations: []models.Citation{}}
+	if len(sources) == 0 {
+		reply.Content = noAnswer
+		if err := onToken(noAnswer); err != nil {
+			return nil, err
+		}
+	} else {
+		messages := buildChatMessages(session.Messages, content, sources)
+		reply.Content, err = s.model.Stream(ctx, messages, onToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate answer: %w", err)
+		}
+		reply.Citations = citedSources(reply.Content, sources)
+	}
+
+	documentIDs := make([]uint, 0, len(sources))
+	seen := make(map[uint]bool, len(sources))
+	for _, src := range sources {
+		if !seen[src.DocumentID] {
+			seen[src.DocumentID] = true
+			documentIDs = append(documentIDs, src.DocumentID)
+		}
+	}
+
+	question := &models.ChatMessage{Role: ai.RoleUser, Content: content}
+	if err := s.repo.AddMessages(ctx, session.ID, []*models.ChatMessage{question, reply}, documentIDs); err != nil {
+		return nil, err
+	}
+
+	if session.Title == "" {
+		session.Title = truncateTitle(content)
+		if err := s.repo.UpdateSession(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
+}
+
+func (s *chatService) SetFeedback(ctx context.Context, userID, messageID uint, feedback int) (*models.ChatMessage, error) {
+	msg, err := s.repo.GetMessage(ctx, messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChatMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetSession(ctx, userID, msg.SessionID); err != nil {
+		if errors.Is(err, ErrChatSessionNotFound) {
+			return nil, ErrChatMessageNotFound
+		}
+		return nil, err
+	}
+	if msg.Role != ai.RoleAssistant {
+		return nil, ErrInvalidFeedback
+	}
+
+	if err := s.repo.SetFeedback(ctx, messageID, feedback); err != nil {
+		return nil, err
+	}
+	msg.Feedback = feedback
+	return msg, nil
+}
+
+// HandleDocumentEvent deletes the sessions that may draw on a document when
+// it is deleted or when an update changes who may read it, so a conversation
+// never keeps content its user has lost access to.
+func (s *chatService) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	switch {
+	case event.Type == DocumentDeleted:
+	case event.Type == DocumentUpdated && permissionsChanged(event.Previous, event.Document):
+	default:
+		return nil
+	}
+
+	// 文件夹会话覆盖所有子文档，祖先文档的会话同样受影响
+	ancestors, err := s.docRepo.AncestorIDs(ctx, event.DocumentID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	folderIDs := append([]uint{event.DocumentID}, ancestors...)
+
+	tagIDs, err := s.scopeTagIDs(ctx, event.Previous, event.Document)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.DeleteSessionsFor(ctx, event.DocumentID, folderIDs, tagIDs)
+	return err
+}
+
+// scopeDocumentIDs returns the documents a session may draw on
+func (s *chatService) scopeDocumentIDs(ctx context.Context, session *models.ChatSession) ([]uint, error) {
+	switch session.ScopeType {
+	case models.ChatScopeDocument:
+		return []uint{session.ScopeID}, nil
+	case models.ChatScopeFolder:
+		return s.docRepo.SubtreeIDs(ctx, session.ScopeID)
+	case models.ChatScopeTag:
+		tag, err := s.tagService.GetTag(ctx, session.ScopeID)
+		if errors.Is(err, ErrTagNotFound) {
+			return nil, ErrChatScopeNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids, err := s.docRepo.FilterIDs(ctx, repository.DocumentListParams{Tags: []string{tag.Name}})
+		if err != nil {
+			return nil, err
+		}
+		if ids == nil {
+			ids = []uint{}
+		}
+		return ids, nil
+	default:
+		return nil, ErrInvalidChatScope
+	}
+}
+
+// scopeTagIDs returns the tags whose sessions cover a document: its own tags
+// and their parents, since a parent tag scope includes nested tags
+func (s *chatService) scopeTagIDs(ctx context.Context, docs ...*models.Document) ([]uint, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, tag := range doc.Tags {
+			for name := tag.Name; name != "" && !seen[name]; name = parentTagName(name) {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	tags, err := s.tagService.GetTagsByName(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids, nil
+}
+
+// buildChatMessages assembles the prompt from the system prompt, the recent
+// history of the session and the new message with its sources
+func buildChatMessages(history []models.ChatMessage, content string, sources []models.Citation) []ai.Message {
+	if len(history) > chatHistoryMessages {
+		history = history[len(history)-chatHistoryMessages:]
+	}
+	messages := make([]ai.Message, 0, len(history)+2)
+	messages = append(messages, ai.Message{Role: ai.RoleSystem, Content: chatSystemPrompt})
+	for _, msg := range history {
+		messages = append(messages, ai.Message{Role: msg.Role, Content: msg.Content})
+	}
+	return append(messages, ai.Message{Role: ai.RoleUser, Content: buildAskPrompt(content, sources)})
+}
+
+func lastUserMessage(messages []models.ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == ai.RoleUser {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func truncateTitle(s string) string {
+	if r := []rune(s); len(r) > maxChatTitleLength {
+		return string(r[:maxChatTitleLength-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// stubChatRepository keeps sessions in memory and records cleanup calls
+type stubChatRepository struct {
+	repository.ChatRepository
+	sessions map[uint]*models.ChatSession
+	links    map[uint][]uint
+	cleanup  [][]uint // documentID, folderIDs..., then tagIDs per call
+}
+
+func newStubChatRepository() *stubChatRepository {
+	return &stubChatRepository{sessions: map[uint]*models.ChatSession{}, links: map[uint][]uint{}}
+}
+
+func (r *stubChatRepository) CreateSession(ctx context.Context, session *models.ChatSession) error {
+	session.ID = uint(len(r.sessions) + 1)
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *stubChatRepository) UpdateSession(ctx context.Context, session *models.ChatSession) error {
+	r.sessions[session.ID].Title = session.Title
+	return nil
+}
+
+func (r *stubChatRepository) GetSession(ctx context.Context, id uint) (*models.ChatSession, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *stubChatRepository) AddMessages(ctx context.Context, sessionID uint, messages []*models.ChatMessage, documentIDs []uint) error {
+	for _, msg := range messages {
+		msg.SessionID = sessionID
+		r.sessions[sessionID].Messages = append(r.sessions[sessionID].Messages, *msg)
+	}
+	r.links[sessionID] = append(r.links[sessionID], documentIDs...)
+	return nil
+}
+
+func (r *stubChatRepository) DeleteSessionsFor(ctx context.Context, documentID uint, folderIDs, tagIDs []uint) (int64, error) {
+	call := append([]uint{documentID}, folderIDs...)
+	r.cleanup = append(r.cleanup, append(call, tagIDs...))
+	return 0, nil
+}
+
+// stubTreeRepository adds the document tree to stubDocumentRepository
+type stubTreeRepository struct {
+	*stubDocumentRepository
+}
+
+func (r *stubTreeRepository) SubtreeIDs(ctx context.Context, rootID uint) ([]uint, error) {
+	ids := []uint{rootID}
+	for i := 0; i < len(ids); i++ {
+		for id, doc := range r.docs {
+			if doc.ParentID != nil && *doc.ParentID == ids[i] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *stubTreeRepository) AncestorIDs(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	for doc := r.docs[id]; doc.ParentID != nil; doc = r.docs[*doc.ParentID] {
+		ids = append(ids, *doc.ParentID)
+	}
+	return ids, nil
+}
+
+type stubTagService struct {
+	TagService
+	tags []models.Tag
+}
+
+func (s *stubTagService) GetTagsByName(ctx context.Context, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	for _, tag := range s.tags {
+		for _, name := range names {
+			if tag.Name == name {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+func chatFixture() (*stubChatRepository, *stubTreeRepository, ChatService) {
+	root, child := uint(1), uint(2)
+	docs := &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Operations", Status: models.DocumentStatusPublished, CreatorID: 9},
+		2: {ID: 2, Title: "Oncall runbook", Status: models.DocumentStatusPublished, CreatorID: 9, ParentID: &root},
+		3: {ID: 3, Title: "Database failover", Status: models.DocumentStatusPublished, CreatorID: 9, ParentID: &child},
+		4: {ID: 4, Title: "Unrelated failover", Status: models.DocumentStatusPublished, CreatorID: 9},
+	}}}
+	semantic := &stubSemanticSearch{passages: []models.Passage{
+		{DocumentID: 4, Text: "Failover of the legacy system."},
+		{DocumentID: 3, Text: "Promote the replica first."},
+	}}
+	tags := &stubTagService{tags: []models.Tag{{ID: 20, Name: "team"}, {ID: 21, Name: "team/db"}}}
+
+	chats := newStubChatRepository()
+	return chats, docs, NewChatService(chats, docs, tags, semantic, ai.NewFakeChatModel(""), 5)
+}
+
+func TestChatSendMessage(t *testing.T) {
+	ctx := context.Background()
+	chats, _, svc := chatFixture()
+
+	session, err := svc.CreateSession(ctx, 5, models.ChatScopeFolder, 1, "")
+	require.NoError(t, err)
+
+	reply, err := svc.SendMessage(ctx, 5, session.ID, "How do I fail over?", func(string) error { return nil })
+	require.NoError(t, err)
+
+	// 只检索文件夹子树中的文档
+	require.Len(t, reply.Citations, 1)
+	assert.Equal(t, uint(3), reply.Citations[0].DocumentID)
+	assert.Equal(t, []uint{3}, chats.links[session.ID])
+
+	stored := chats.sessions[session.ID]
+	require.Len(t, stored.Messages, 2)
+	assert.Equal(t, ai.RoleUser, stored.Messages[0].Role)
+	assert.Equal(t, ai.RoleAssistant, stored.Messages[1].Role)
+	assert.Equal(t, "How do I fail over?", stored.Title)
+
+	_, err = svc.SendMessage(ctx, 6, session.ID, "And then?", func(string) error { return nil })
+	assert.ErrorIs(t, err, ErrChatSessionNotFound)
+
+	_, err = svc.SendMessage(ctx, 5, session.ID, "   ", func(string) error { return nil })
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+}
+
+func TestChatCreateSession(t *testing.T) {
+	ctx := context.Background()
+	_, docs, svc := chatFixture()
+	docs.docs[5] = models.Document{ID: 5, Title: "Draft", Status: models.DocumentStatusDraft, CreatorID: 9}
+
+	_, err := svc.CreateSession(ctx, 5, "space", 1, "")
+	assert.ErrorIs(t, err, ErrInvalidChatScope)
+
+	_, err = svc.CreateSession(ctx, 5, models.ChatScopeDocument, 5, "")
+	assert.ErrorIs(t, err, ErrChatScopeNotFound)
+
+	session, err := svc.CreateSession(ctx, 9, models.ChatScopeDocument, 5, "Draft review")
+	require.NoError(t, err)
+	assert.Equal(t, "Draft review", session.Title)
+}
+
+func TestChatPermissionCleanup(t *testing.T) {
+	ctx := context.Background()
+	chats, docs, svc := chatFixture()
+
+	published := docs.docs[3]
+	published.Tags = []models.Tag{{ID: 21, Name: "team/db"}}
+	draft := published
+	draft.Status = models.DocumentStatusDraft
+
+	// 内容修改不影响权限，会话保留
+	edited := published
+	edited.Content = "Promote the replica first, then update DNS."
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: 3, Document: &edited, Previous: &published}))
+	assert.Empty(t, chats.cleanup)
+
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: 3, Document: &draft, Previous: &published}))
+	require.Len(t, chats.cleanup, 1)
+	// document, folders (itself and ancestors), then tags with their parents
+	assert.Equal(t, []uint{3, 3, 2, 1, 20, 21}, chats.cleanup[0])
+
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: 4, Previous: new(models.Document)}))
+	require.Len(t, chats.cleanup, 2)
+	assert.Equal(t, []uint{4, 4}, chats.cleanup[1])
+}
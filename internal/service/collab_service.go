@@ -0,0 +1,495 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ot"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// collabHistory is the number of operations kept to transform edits made
+	// on older revisions
+	collabHistory = 500
+	// collabSendBuffer is the number of messages queued for a client; clients
+	// falling further behind are disconnected
+	collabSendBuffer = 256
+)
+
+var (
+	ErrCollabReadOnly = errors.New("document is read-only for this user")
+	ErrCollabRevision = errors.New("operation is based on an unknown revision")
+	ErrCollabClosed   = errors.New("editing session is closed")
+)
+
+// Types of the messages sent to the clients of an editing session
+const (
+	// CollabMessageInit is sent to a client when it joins, with the content
+	// at the current revision
+	CollabMessageInit = "init"
+	// CollabMessageAck confirms an operation of the client as revision
+	CollabMessageAck = "ack"
+	// CollabMessageOperation is an operation of another client, or an
+	// update of the document made outside the session
+	CollabMessageOperation = "operation"
+	// CollabMessagePresence lists the clients of the session
+	CollabMessagePresence = "presence"
+	// CollabMessageClosed is sent when the document is deleted
+	CollabMessageClosed = "closed"
+)
+
+// CollabCursor is the selection of a client as character offsets into the
+// content; Start equals End for a caret
+type CollabCursor struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// CollabPresence is a client of an editing session
+type CollabPresence struct {
+	ClientID uint64        `json:"client_id"`
+	UserID   uint          `json:"user_id"`
+	Username string        `json:"username"`
+	ReadOnly bool          `json:"read_only,omitempty"`
+	Cursor   *CollabCursor `json:"cursor,omitempty"`
+}
+
+// CollabMessage is sent to the clients of an editing session. Revision is
+// the revision of the content after the message.
+type CollabMessage struct {
+	Type      string           `json:"type"`
+	Revision  int              `json:"revision"`
+	ClientID  uint64           `json:"client_id,omitempty"` // the receiving client for init, the author of an operation
+	UserID    uint             `json:"user_id,omitempty"`
+	Content   string           `json:"content,omitempty"`
+	ReadOnly  bool             `json:"read_only,omitempty"`
+	Operation ot.Operation     `json:"operation,omitempty"`
+	Presence  []CollabPresence `json:"presence,omitempty"`
+}
+
+// CollabService runs real-time editing sessions. The clients of a document
+// send operations made on a revision of its content; the session transforms
+// them against the operations accepted since (see package ot), applies them
+// and relays them to the other clients, so concurrent edits are merged
+// rather than overwritten. The merged content is saved periodically and when
+// the last client leaves, through DocumentService.UpdateDocument and a new
+// version.
+//
+// Users who can read a document may join its session; its creator and the
+// members of its workspace may edit it, unless another user checked it out.
+// The merged content is saved on behalf of the last client who edited it.
+// CollabService is also a
+// DocumentListener: updates made outside the session are merged into it.
+type CollabService interface {
+	DocumentListener
+	// Join adds userID to the editing session of a document, starting the
+	// session if needed
+	Join(ctx context.Context, userID, documentID uint) (*CollabClient, error)
+}
+
+type collabService struct {
+	mu              sync.Mutex
+	sessions        map[uint]*collabSession
+	docRepo         repository.DocumentRepository
+	docService      DocumentService
+	workspaces      WorkspaceService
+	userRepo        repository.UserRepository
+	locks           repository.DocumentLockRepository
+	persistInterval time.Duration
+}
+
+// NewCollabService creates the collaboration service, saving the content of
+// sessions every persistInterval. When workspaces is nil, only creators edit
+// their documents; when locks is nil, check-out locks are not enforced.
+func NewCollabService(docRepo repository.DocumentRepository, docService DocumentService, workspaces WorkspaceService, userRepo repository.UserRepository, locks repository.DocumentLockRepository, persistInterval time.Duration) CollabService {
+	return &collabService{
+		sessions:        make(map[uint]*collabSession),
+		docRepo:         docRepo,
+		docService:      docService,
+		workspaces:      workspaces,
+		userRepo:        userRepo,
+		locks:           locks,
+		persistInterval: persistInterval,
+	}
+}
+
+// collabSession is the editing session of one document
+type collabSession struct {
+	mu         sync.Mutex
+	documentID uint
+	content    string
+	revision   int
+	history    []ot.Operation // the operations up to revision
+	clients    map[uint64]*CollabClient
+	lastID     uint64
+	lastEditor uint
+	// saved is the content last saved or updated outside the session, the
+	// content at savedRevision, or -1 when no revision has it
+	saved         string
+	savedRevision int
+	closed        bool
+	deleted       bool
+	done          chan struct{}
+}
+
+// CollabClient is a connection to an editing session. Messages for the
+// client are read from Messages, which is closed when the client is removed.
+type CollabClient struct {
+	ID       uint64
+	UserID   uint
+	ReadOnly bool
+
+	username string
+	cursor   *CollabCursor
+	messages chan CollabMessage
+	removed  bool
+	session  *collabSession
+	service  *collabService
+}
+
+func (s *collabService) Join(ctx context.Context, userID, documentID uint) (*CollabClient, error) {
+	doc, err := readableDocument(ctx, s.docRepo, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	readOnly := !canEditDocument(ctx, s.workspaces, userID, doc)
+	username := fmt.Sprintf("user %d", userID)
+	if user, err := s.userRepo.GetByID(ctx, userID); err == nil {
+		username = user.Username
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := s.sessions[documentID]
+	if session == nil {
+		session = &collabSession{
+			documentID: documentID,
+			content:    doc.Content,
+			clients:    make(map[uint64]*CollabClient),
+			saved:      doc.Content,
+			done:       make(chan struct{}),
+		}
+		s.sessions[documentID] = session
+		go s.persistLoop(session)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.lastID++
+	client := &CollabClient{
+		ID:       session.lastID,
+		UserID:   userID,
+		ReadOnly: readOnly,
+		username: username,
+		messages: make(chan CollabMessage, collabSendBuffer),
+		session:  session,
+		service:  s,
+	}
+	session.clients[client.ID] = client
+	client.messages <- CollabMessage{
+		Type:     CollabMessageInit,
+		Revision: session.revision,
+		ClientID: client.ID,
+		UserID:   userID,
+		Content:  session.content,
+		ReadOnly: readOnly,
+	}
+	session.broadcastPresence()
+	return client, nil
+}
+
+func (c *CollabClient) Messages() <-chan CollabMessage {
+	return c.messages
+}
+
+// Submit submits an operation made on revision of the content. It fails
+// with ErrDocumentLocked while another user has the document checked out.
+func (c *CollabClient) Submit(revision int, op ot.Operation) error {
+	if c.ReadOnly {
+		return ErrCollabReadOnly
+	}
+	// 锁在会话之外检查，避免持有会话锁时访问数据库
+	if c.service.locks != nil {
+		if err := checkLock(context.Background(), c.service.locks, c.UserID, c.session.documentID); err != nil {
+			return err
+		}
+	}
+
+	session := c.session
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if c.removed {
+		return ErrCollabClosed
+	}
+	if revision > session.revision || revision < session.revision-len(session.history) {
+		return ErrCollabRevision
+	}
+
+	// 将操作依次变换到当前修订之上
+	var err error
+	for _, concurrent := range session.history[len(session.history)-(session.revision-revision):] {
+		if op, _, err = ot.Transform(op, concurrent); err != nil {
+			return err
+		}
+	}
+	if err := session.apply(op); err != nil {
+		return err
+	}
+	session.lastEditor = c.UserID
+
+	c.send(CollabMessage{Type: CollabMessageAck, Revision: session.revision})
+	session.broadcast(CollabMessage{
+		Type:      CollabMessageOperation,
+		Revision:  session.revision,
+		ClientID:  c.ID,
+		UserID:    c.UserID,
+		Operation: op,
+	}, c.ID)
+	return nil
+}
+
+// MoveCursor sets the selection of the client in the current content
+func (c *CollabClient) MoveCursor(cursor CollabCursor) error {
+	session := c.session
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if c.removed {
+		return ErrCollabClosed
+	}
+
+	length := len([]rune(session.content))
+	cursor.Start = min(max(cursor.Start, 0), length)
+	cursor.End = min(max(cursor.End, cursor.Start), length)
+	c.cursor = &cursor
+	session.broadcastPresence()
+	return nil
+}
+
+// Leave removes the client from the session. The session ends, saving its
+// content, when its last client leaves.
+func (c *CollabClient) Leave() {
+	s, session := c.service, c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// 过慢被移除的客户端也会调用 Leave，由它结束空会话
+	joined := !c.removed
+	if joined {
+		session.remove(c)
+	}
+	if len(session.clients) == 0 {
+		if !session.closed {
+			session.closed = true
+			delete(s.sessions, session.documentID)
+			close(session.done)
+		}
+		return
+	}
+	if joined {
+		session.broadcastPresence()
+	}
+}
+
+func (c *CollabClient) send(msg CollabMessage) bool {
+	select {
+	case c.messages <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// apply applies an operation on the current revision. Callers hold mu.
+func (session *collabSession) apply(op ot.Operation) error {
+	content, err := op.Apply(session.content)
+	if err != nil {
+		return err
+	}
+	session.content = content
+	session.revision++
+	session.history = append(session.history, op)
+	if len(session.history) > collabHistory {
+		session.history = session.history[len(session.history)-collabHistory:]
+	}
+	for _, client := range session.clients {
+		if client.cursor != nil {
+			client.cursor.Start = ot.TransformIndex(client.cursor.Start, op)
+			client.cursor.End = ot.TransformIndex(client.cursor.End, op)
+		}
+	}
+	return nil
+}
+
+// broadcast sends msg to every client but except. Clients too slow to take
+// it are removed. Callers hold mu.
+func (session *collabSession) broadcast(msg CollabMessage, except uint64) {
+	var slow []*CollabClient
+	for id, client := range session.clients {
+		if id != except && !client.send(msg) {
+			slow = append(slow, client)
+		}
+	}
+	if len(slow) == 0 {
+		return
+	}
+	for _, client := range slow {
+		utils.Logger.Warn("Disconnecting slow collaboration client",
+			zap.Uint("document_id", session.documentID), zap.Uint64("client_id", client.ID))
+		session.remove(client)
+	}
+	// 会话至少保留一个客户端时才通知在线状态，空会话由 Leave 结束
+	if len(session.clients) > 0 {
+		session.broadcastPresence()
+	}
+}
+
+func (session *collabSession) broadcastPresence() {
+	presence := make([]CollabPresence, 0, len(session.clients))
+	for id := uint64(1); id <= session.lastID; id++ {
+		client, ok := session.clients[id]
+		if !ok {
+			continue
+		}
+		p := CollabPresence{ClientID: id, UserID: client.UserID, Username: client.username, ReadOnly: client.ReadOnly}
+		if client.cursor != nil {
+			cursor := *client.cursor
+			p.Cursor = &cursor
+		}
+		presence = append(presence, p)
+	}
+	session.broadcast(CollabMessage{Type: CollabMessagePresence, Revision: session.revision, Presence: presence}, 0)
+}
+
+func (session *collabSession) remove(client *CollabClient) {
+	client.removed = true
+	delete(session.clients, client.ID)
+	close(client.messages)
+}
+
+func (s *collabService) persistLoop(session *collabSession) {
+	ticker := time.NewTicker(s.persistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.persist(session)
+		case <-session.done:
+			s.persist(session)
+			return
+		}
+	}
+}
+
+// persist saves the content of session if it changed since it was last
+// saved, as a new version of the document
+func (s *collabService) persist(session *collabSession) {
+	session.mu.Lock()
+	if session.deleted || session.revision == session.savedRevision {
+		session.mu.Unlock()
+		return
+	}
+	content, revision, editor := session.content, session.revision, session.lastEditor
+	previous, previousRevision := session.saved, session.savedRevision
+	// 先记下要保存的内容，保存触发的文档事件据此识别为本会话的修改
+	session.saved, session.savedRevision = content, revision
+	session.mu.Unlock()
+
+	ctx := context.Background()
+	err := func() error {
+		doc, err := s.docRepo.GetByID(ctx, session.documentID)
+		if err != nil {
+			return err
+		}
+		if doc.Content == content {
+			return nil
+		}
+		doc.Content = content
+		doc.Version++
+		if err := s.docService.UpdateDocument(ctx, doc, editor); err != nil {
+			return err
+		}
+		return s.docService.CreateVersion(ctx, doc.ID, editor)
+	}()
+	if err != nil {
+		utils.Logger.Error("Failed to save collaborative edits",
+			zap.Uint("document_id", session.documentID), zap.Int("revision", revision), zap.Error(err))
+		session.mu.Lock()
+		if session.savedRevision == revision {
+			session.saved, session.savedRevision = previous, previousRevision
+		}
+		session.mu.Unlock()
+	}
+}
+
+func (s *collabService) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	switch event.Type {
+	case DocumentUpdated:
+		s.mu.Lock()
+		session := s.sessions[event.DocumentID]
+		s.mu.Unlock()
+		if session == nil || event.Document == nil {
+			return nil
+		}
+		return session.merge(event.Document.Content)
+	case DocumentDeleted:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		session := s.sessions[event.DocumentID]
+		if session == nil {
+			return nil
+		}
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		session.deleted = true
+		session.closed = true
+		session.broadcast(CollabMessage{Type: CollabMessageClosed, Revision: session.revision}, 0)
+		for _, client := range session.clients {
+			session.remove(client)
+		}
+		delete(s.sessions, event.DocumentID)
+		close(session.done)
+	}
+	return nil
+}
+
+// merge merges content saved outside the session into it as an operation
+// of no client
+func (session *collabSession) merge(content string) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.closed || content == session.saved {
+		return nil
+	}
+
+	// 外部修改基于最后保存的内容，变换到当前修订后合并
+	clean := session.revision == session.savedRevision
+	op := ot.Diff(session.saved, content)
+	base := session.savedRevision
+	if base < 0 || base < session.revision-len(session.history) {
+		op, base = ot.Diff(session.content, content), session.revision
+	}
+	var err error
+	for _, concurrent := range session.history[len(session.history)-(session.revision-base):] {
+		if _, op, err = ot.Transform(concurrent, op); err != nil {
+			return err
+		}
+	}
+	if err := session.apply(op); err != nil {
+		return err
+	}
+
+	session.saved, session.savedRevision = content, -1
+	if clean {
+		session.savedRevision = session.revision
+	}
+	session.broadcast(CollabMessage{Type: CollabMessageOperation, Revision: session.revision, Operation: op}, 0)
+	return nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/ot"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubCollabDocumentService saves documents into the stub repository and
+// publishes their updates like the document service
+type stubCollabDocumentService struct {
+	DocumentService
+	mu       sync.Mutex
+	repo     *stubTreeRepository
+	listener DocumentListener
+	versions []models.DocumentVersion
+}
+
+func (s *stubCollabDocumentService) UpdateDocument(ctx context.Context, doc *models.Document, userID uint) error {
+	s.mu.Lock()
+	previous := s.repo.docs[doc.ID]
+	s.repo.docs[doc.ID] = *doc
+	s.mu.Unlock()
+	return s.listener.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: doc.ID, Document: doc, Previous: &previous})
+}
+
+func (s *stubCollabDocumentService) CreateVersion(ctx context.Context, docID uint, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc := s.repo.docs[docID]
+	s.versions = append(s.versions, models.DocumentVersion{DocumentID: docID, Version: doc.Version, Content: doc.Content, CreatedBy: userID})
+	return nil
+}
+
+func (s *stubCollabDocumentService) savedVersions() []models.DocumentVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.DocumentVersion(nil), s.versions...)
+}
+
+// drain returns the messages queued for client
+func drain(client *CollabClient) []CollabMessage {
+	var messages []CollabMessage
+	for {
+		select {
+		case msg, ok := <-client.Messages():
+			if !ok {
+				return messages
+			}
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestCollabSession(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	workspaceID := uint(1)
+	docs := &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Content: "Check lag. Promote the replica.", Version: 3, Status: models.DocumentStatusPublished, CreatorID: 1, WorkspaceID: &workspaceID},
+		2: {ID: 2, Title: "Private notes", Status: models.DocumentStatusDraft, CreatorID: 1},
+	}}}
+	users := &stubUserRepository{users: []models.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"}}}
+	workspaceRepo := newStubWorkspaceRepository()
+	workspaceRepo.members[[2]uint{1, 2}] = models.WorkspaceMember{WorkspaceID: 1, UserID: 2, Role: models.WorkspaceRoleMember}
+	docService := &stubCollabDocumentService{repo: docs}
+	locks := &stubLockRepository{locks: map[uint]models.DocumentLock{}}
+	svc := NewCollabService(docs, docService, NewWorkspaceService(workspaceRepo, users), users, locks, time.Hour)
+	docService.listener = svc
+
+	_, err := svc.Join(ctx, 3, 2)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	alice, err := svc.Join(ctx, 1, 1)
+	require.NoError(t, err)
+	bob, err := svc.Join(ctx, 2, 1)
+	require.NoError(t, err)
+	carol, err := svc.Join(ctx, 3, 1)
+	require.NoError(t, err)
+	assert.False(t, bob.ReadOnly, "workspace members edit")
+	assert.True(t, carol.ReadOnly, "other readers only watch")
+
+	init := drain(carol)
+	require.NotEmpty(t, init)
+	assert.Equal(t, CollabMessage{Type: CollabMessageInit, ClientID: carol.ID, UserID: 3, Content: "Check lag. Promote the replica.", ReadOnly: true}, init[0])
+	presence := init[len(init)-1]
+	assert.Equal(t, CollabMessagePresence, presence.Type)
+	require.Len(t, presence.Presence, 3)
+	assert.Equal(t, "bob", presence.Presence[1].Username)
+	drain(alice)
+	drain(bob)
+
+	require.NoError(t, bob.MoveCursor(CollabCursor{Start: 11, End: 18}))
+
+	// alice and bob edit revision 0 at the same time
+	require.NoError(t, alice.Submit(0, ot.Operation{}.Insert("1. ").Retain(31)))
+	require.NoError(t, bob.Submit(0, ot.Operation{}.Retain(23).Insert("new ").Retain(8)))
+	assert.ErrorIs(t, carol.Submit(2, ot.Operation{}.Retain(38)), ErrCollabReadOnly)
+	assert.ErrorIs(t, bob.Submit(5, ot.Operation{}.Retain(38)), ErrCollabRevision)
+	assert.ErrorIs(t, bob.Submit(2, ot.Operation{}.Retain(3)), ot.ErrInvalidOperation)
+
+	session := alice.session
+	assert.Equal(t, "1. Check lag. Promote the new replica.", session.content)
+	assert.Equal(t, 2, session.revision)
+	assert.Equal(t, &CollabCursor{Start: 14, End: 21}, bob.cursor, "cursors follow the edits")
+
+	messages := drain(bob)
+	require.Len(t, messages, 3)
+	assert.Equal(t, CollabMessageOperation, messages[1].Type)
+	assert.Equal(t, alice.ID, messages[1].ClientID)
+	assert.Equal(t, CollabMessage{Type: CollabMessageAck, Revision: 2}, messages[2])
+	messages = drain(alice)
+	require.Len(t, messages, 3)
+	assert.Equal(t, ot.Operation{}.Retain(26).Insert("new ").Retain(8), messages[2].Operation, "operations are relayed transformed")
+
+	// the session is saved as a new version
+	drain(carol)
+	svc.(*collabService).persist(session)
+	require.Len(t, docService.savedVersions(), 1)
+	assert.Equal(t, models.DocumentVersion{DocumentID: 1, Version: 4, Content: "1. Check lag. Promote the new replica.", CreatedBy: 2}, docService.savedVersions()[0])
+	assert.Empty(t, drain(carol), "saving doesn't echo into the session")
+
+	// an update made elsewhere is merged with the edits not saved yet
+	require.NoError(t, alice.Submit(2, ot.Operation{}.Retain(38).Insert(" Page the DBA.")))
+	updated := docs.docs[1]
+	updated.Content = "1. Check replication lag. Promote the new replica."
+	require.NoError(t, docService.UpdateDocument(ctx, &updated, 1))
+	assert.Equal(t, "1. Check replication lag. Promote the new replica. Page the DBA.", session.content)
+	messages = drain(carol)
+	require.NotEmpty(t, messages)
+	assert.Equal(t, CollabMessage{Type: CollabMessageOperation, Revision: 4, Operation: ot.Operation{}.Retain(9).Insert("replication ").Retain(43)}, messages[len(messages)-1])
+
+	// while bob has the document checked out, only he edits it
+	locks.locks[1] = models.DocumentLock{DocumentID: 1, OwnerID: 2, ExpiresAt: time.Now().Add(time.Minute)}
+	assert.ErrorIs(t, alice.Submit(4, ot.Operation{}.Retain(64)), ErrDocumentLocked)
+	require.NoError(t, bob.Submit(4, ot.Operation{}.Retain(64)))
+	delete(locks.locks, 1)
+	drain(carol)
+
+	// the last one to leave saves the session
+	alice.Leave()
+	bob.Leave()
+	carol.Leave()
+	drain(carol)
+	_, ok := <-carol.Messages()
+	assert.False(t, ok)
+	require.Eventually(t, func() bool { return len(docService.savedVersions()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "1. Check replication lag. Promote the new replica. Page the DBA.", docService.savedVersions()[1].Content)
+	assert.Equal(t, 5, docService.savedVersions()[1].Version)
+
+	// deleting the document closes its session
+	dave, err := svc.Join(ctx, 1, 1)
+	require.NoError(t, err)
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: 1}))
+	messages = drain(dave)
+	assert.Equal(t, CollabMessageClosed, messages[len(messages)-1].Type)
+	assert.ErrorIs(t, dave.Submit(0, ot.Operation{}.Retain(1)), ErrCollabClosed)
+	dave.Leave()
+}
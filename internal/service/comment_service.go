@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/anchor"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCommentLength = 10000
+	reanchorTimeout  = time.Minute
+)
+
+var (
+	ErrCommentNotFound        = errors.New("comment not found")
+	ErrEmptyComment           = errors.New("comment is required")
+	ErrCommentTooLong         = errors.New("comment is too long")
+	ErrCommentVersionNotFound = errors.New("document version not found")
+	ErrNotCommentAuthor       = errors.New("only the author can edit a comment")
+	ErrCommentNotDeletable    = errors.New("only the author or the document's creator can delete a comment")
+	ErrThreadNotResolvable    = errors.New("only the thread's author or the document's creator can resolve it")
+	ErrInvalidAnchor          = errors.New("invalid comment anchor")
+)
+
+// mentionPattern matches @username not preceded by a word character, so
+// that e-mail addresses aren't taken for mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9_](?:[A-Za-z0-9_.-]*[A-Za-z0-9_])?)`)
+
+// AddCommentRequest adds a comment to a document. ParentID replies to a
+// thread; Version is the document version the comment is written against,
+// the current one when zero. Anchor attaches a new thread to a range of that
+// version's content; its Quote, when given, must be the text of the range.
+type AddCommentRequest struct {
+	UserID     uint
+	DocumentID uint
+	ParentID   *uint
+	Version    int
+	Content    string
+	Anchor     *models.CommentAnchor
+}
+
+// CommentService manages threaded comments on documents. Users comment on
+// the documents they can read, and users mentioned with @username are
+// notified if they can read the document too.
+//
+// CommentService is also a DocumentListener: when a document's content
+// changes, anchored threads are moved to where their text now is, or marked
+// orphaned when it is gone. Threads are moved on a background worker, once
+// for any number of saves made while the document waits in its queue.
+type CommentService interface {
+	DocumentListener
+	AddComment(ctx context.Context, req AddCommentRequest) (*models.Comment, error)
+	// ListComments returns the threads of a document with their replies,
+	// only unresolved threads when unresolvedOnly is set
+	ListComments(ctx context.Context, userID, documentID uint, unresolvedOnly bool, page, pageSize int) ([]models.Comment, int64, error)
+	// EditComment changes the content of a comment of userID
+	EditComment(ctx context.Context, userID, commentID uint, content string) (*models.Comment, error)
+	// DeleteComment deletes a comment, and a thread's replies with it
+	DeleteComment(ctx context.Context, userID, commentID uint) error
+	// ResolveThread resolves or reopens the thread of a comment
+	ResolveThread(ctx context.Context, userID, commentID uint, resolved bool) (*models.Comment, error)
+	// Close moves the threads of the queued documents and stops the worker
+	Close()
+}
+
+type commentService struct {
+	repo          repository.CommentRepository
+	docRepo       repository.DocumentRepository
+	userRepo      repository.UserRepository
+	notifications NotificationService
+	queue         *documentQueue
+}
+
+func NewCommentService(repo repository.CommentRepository, docRepo repository.DocumentRepository, userRepo repository.UserRepository, notifications NotificationService) CommentService {
+	s := &commentService{
+		repo:          repo,
+		docRepo:       docRepo,
+		userRepo:      userRepo,
+		notifications: notifications,
+	}
+	s.queue = newDocumentQueue("move comment anchors", reanchorTimeout, s.reanchor)
+	return s
+}
+
+func (s *commentService) AddComment(ctx context.Context, req AddCommentRequest) (*models.Comment, error) {
+	content, err := validateComment(req.Content)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := readableDocument(ctx, s.docRepo, req.UserID, req.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Anchor != nil && req.ParentID != nil {
+		return nil, ErrInvalidAnchor
+	}
+
+	var parentID *uint
+	if req.ParentID != nil {
+		parent, err := s.repo.GetByID(ctx, *req.ParentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && parent.DocumentID != doc.ID) {
+			return nil, ErrCommentNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 回复的回复挂到线程的第一条评论下
+		parentID = &parent.ID
+		if parent.ParentID != nil {
+			parentID = parent.ParentID
+		}
+	}
+
+	version, versionContent, err := s.commentedContent(ctx, doc, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var threadAnchor *models.CommentAnchor
+	var anchorStatus string
+	if req.Anchor != nil {
+		if threadAnchor, anchorStatus, err = anchorComment(doc, versionContent, req.Anchor); err != nil {
+			return nil, err
+		}
+	}
+
+	mentions, err := s.mentionedUsers(ctx, doc, req.UserID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		DocumentID:   doc.ID,
+		Version:      version,
+		ContentHash:  contentHash(versionContent),
+		ParentID:     parentID,
+		AuthorID:     req.UserID,
+		Content:      content,
+		Anchor:       threadAnchor,
+		AnchorStatus: anchorStatus,
+		Mentions:     mentions,
+	}
+	if err := s.repo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.notifyMentions(ctx, doc, comment, mentions)
+	return s.repo.GetByID(ctx, comment.ID)
+}
+
+func (s *commentService) ListComments(ctx context.Context, userID, documentID uint, unresolvedOnly bool, page, pageSize int) ([]models.Comment, int64, error) {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListThreads(ctx, documentID, unresolvedOnly, page, pageSize)
+}
+
+func (s *commentService) EditComment(ctx context.Context, userID, commentID uint, content string) (*models.Comment, error) {
+	content, err := validateComment(content)
+	if err != nil {
+		return nil, err
+	}
+	comment, doc, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, ErrNotCommentAuthor
+	}
+
+	mentions, err := s.mentionedUsers(ctx, doc, userID, content)
+	if err != nil {
+		return nil, err
+	}
+	var added []uint
+	for _, id := range mentions {
+		if !slices.Contains(comment.Mentions, id) {
+			added = append(added, id)
+		}
+	}
+
+	now := time.Now()
+	comment.Content = content
+	comment.Mentions = mentions
+	comment.EditedAt = &now
+	if err := s.repo.Update(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.notifyMentions(ctx, doc, comment, added)
+	return comment, nil
+}
+
+func (s *commentService) DeleteComment(ctx context.Context, userID, commentID uint) error {
+	comment, doc, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID && doc.CreatorID != userID {
+		return ErrCommentNotDeletable
+	}
+	return s.repo.Delete(ctx, comment.ID)
+}
+
+func (s *commentService) ResolveThread(ctx context.Context, userID, commentID uint, resolved bool) (*models.Comment, error) {
+	comment, doc, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != nil {
+		if comment, err = s.repo.GetByID(ctx, *comment.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if comment.AuthorID != userID && doc.CreatorID != userID {
+		return nil, ErrThreadNotResolvable
+	}
+
+	if resolved == (comment.ResolvedAt != nil) {
+		return comment, nil
+	}
+	if resolved {
+		now := time.Now()
+		comment.ResolvedAt = &now
+		comment.ResolvedByID = &userID
+	} else {
+		comment.ResolvedAt = nil
+		comment.ResolvedByID = nil
+	}
+	if err := s.repo.Update(ctx, comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *commentService) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	if event.Type != DocumentUpdated || event.Document == nil || event.Previous == nil ||
+		event.Document.Content == event.Previous.Content {
+		return nil
+	}
+	return s.queue.enqueue(event.DocumentID)
+}
+
+func (s *commentService) Close() {
+	s.queue.close()
+}
+
+// reanchor moves the anchored threads of a document to its current content
+func (s *commentService) reanchor(ctx context.Context, id uint) error {
+	doc, err := s.docRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	threads, err := s.repo.ListAnnotations(ctx, id, false)
+	if err != nil {
+		return err
+	}
+	for _, thread := range threads {
+		if thread.Anchor == nil {
+			continue
+		}
+		// 找不到原文时保留旧锚点，标记为孤立
+		moved, status := thread.Anchor, models.AnchorStatusOrphaned
+		if located, ok := anchor.Locate(doc.Content, selector(thread.Anchor)); ok {
+			moved, status = commentAnchor(located), models.AnchorStatusAnchored
+		}
+		if status == thread.AnchorStatus && *moved == *thread.Anchor {
+			continue
+		}
+		if err := s.repo.UpdateAnchor(ctx, thread.ID, moved, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getComment returns a comment on a document userID can read, with the
+// document
+func (s *commentService) getComment(ctx context.Context, userID, commentID uint) (*models.Comment, *models.Document, error) {
+	comment, err := s.repo.GetByID(ctx, commentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := readableDocument(ctx, s.docRepo, userID, comment.DocumentID)
+	if errors.Is(err, ErrDocumentNotFound) {
+		return nil, nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return comment, doc, nil
+}
+
+// commentedContent returns the number and content of the version a comment
+// is written against: the current document when number is zero or the
+// current version, otherwise the recorded version
+func (s *commentService) commentedContent(ctx context.Context, doc *models.Document, number int) (int, string, error) {
+	if number == 0 || number == doc.Version {
+		return doc.Version, doc.Content, nil
+	}
+
+	versions, err := s.docRepo.GetVersions(ctx, doc.ID)
+	if err != nil {
+		return 0, "", err
+	}
+	for _, version := range versions {
+		if version.Version == number {
+			return version.Version, version.Content, nil
+		}
+	}
+	return 0, "", ErrCommentVersionNotFound
+}
+
+// contentHash identifies the content a comment was written against
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// mentionedUsers returns the IDs of the users mentioned in content who can
+// read doc, other than the author
+func (s *commentService) mentionedUsers(ctx context.Context, doc *models.Document, authorID uint, content string) ([]uint, error) {
+	var names []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.userRepo.FindByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for _, user := range users {
+		if user.ID == authorID || slices.Contains(ids, user.ID) ||
+			!slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, user.Username) }) {
+			continue
+		}
+		readable, err := s.docRepo.FilterIDs(ctx, repository.DocumentListParams{IDs: []uint{doc.ID}, ReadableBy: &user.ID})
+		if err != nil {
+			return nil, err
+		}
+		if len(readable) > 0 {
+			ids = append(ids, user.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// notifyMentions notifies mentioned users. Notifications are not essential
+// to the comment, so failures are logged rather than returned.
+func (s *commentService) notifyMentions(ctx context.Context, doc *models.Document, comment *models.Comment, userIDs []uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	actor := fmt.Sprintf("user %d", comment.AuthorID)
+	if author, err := s.userRepo.GetByID(ctx, comment.AuthorID); err == nil {
+		actor = author.Username
+	}
+	notifications := make([]*models.Notification, 0, len(userIDs))
+	for _, id := range userIDs {
+		notifications = append(notifications, &models.Notification{
+			UserID:     id,
+			Type:       models.NotificationMention,
+			ActorID:    &comment.AuthorID,
+			DocumentID: &doc.ID,
+			CommentID:  &comment.ID,
+			Message:    truncateRunes(fmt.Sprintf("%s mentioned you in a comment on %q", actor, doc.Title), 512),
+		})
+	}
+	if err := s.notifications.Notify(ctx, notifications...); err != nil {
+		utils.Logger.Warn("Failed to notify mentioned users", zap.Uint("comment_id", comment.ID), zap.Error(err))
+	}
+}
+
+// anchorComment checks that requested is a range of the content commented
+// on and returns the anchor on the current content of doc
+func anchorComment(doc *models.Document, content string, requested *models.CommentAnchor) (*models.CommentAnchor, string, error) {
+	sel, err := anchor.Select(content, requested.Start, requested.End)
+	if err != nil || (requested.Quote != "" && requested.Quote != sel.Quote) {
+		return nil, "", ErrInvalidAnchor
+	}
+	if content == doc.Content {
+		return commentAnchor(sel), models.AnchorStatusAnchored, nil
+	}
+
+	// 针对旧版本的评论，定位到当前内容中
+	located, ok := anchor.Locate(doc.Content, sel)
+	if !ok {
+		return commentAnchor(sel), models.AnchorStatusOrphaned, nil
+	}
+	return commentAnchor(located), models.AnchorStatusAnchored, nil
+}
+
+func selector(a *models.CommentAnchor) anchor.Selector {
+	return anchor.Selector{Start: a.Start, End: a.End, Quote: a.Quote, Prefix: a.Prefix, Suffix: a.Suffix}
+}
+
+func commentAnchor(sel anchor.Selector) *models.CommentAnchor {
+	return &models.CommentAnchor{Start: sel.Start, End: sel.End, Quote: sel.Quote, Prefix: sel.Prefix, Suffix: sel.Suffix}
+}
+
+func validateComment(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", ErrEmptyComment
+	}
+	if len([]rune(content)) > maxCommentLength {
+		return "", ErrCommentTooLong
+	}
+	return content, nil
+}
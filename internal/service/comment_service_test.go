@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// stubCommentRepository keeps comments in memory
+type stubCommentRepository struct {
+	repository.CommentRepository
+	comments map[uint]*models.Comment
+}
+
+func (r *stubCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	comment.ID = uint(len(r.comments) + 1)
+	copied := *comment
+	r.comments[comment.ID] = &copied
+	return nil
+}
+
+func (r *stubCommentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	copied := *comment
+	r.comments[comment.ID] = &copied
+	return nil
+}
+
+func (r *stubCommentRepository) GetByID(ctx context.Context, id uint) (*models.Comment, error) {
+	comment, ok := r.comments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *comment
+	return &copied, nil
+}
+
+func (r *stubCommentRepository) ListThreads(ctx context.Context, documentID uint, unresolvedOnly bool, page, pageSize int) ([]models.Comment, int64, error) {
+	var threads []models.Comment
+	for id := uint(1); id <= uint(len(r.comments)+10); id++ {
+		comment, ok := r.comments[id]
+		if !ok || comment.DocumentID != documentID || comment.ParentID != nil || (unresolvedOnly && comment.ResolvedAt != nil) {
+			continue
+		}
+		thread := *comment
+		for replyID := id + 1; replyID <= uint(len(r.comments)+10); replyID++ {
+			if reply, ok := r.comments[replyID]; ok && reply.ParentID != nil && *reply.ParentID == id {
+				thread.Replies = append(thread.Replies, *reply)
+			}
+		}
+		threads = append(threads, thread)
+	}
+	total := int64(len(threads))
+	start := min((page-1)*pageSize, len(threads))
+	return threads[start:min(start+pageSize, len(threads))], total, nil
+}
+
+func (r *stubCommentRepository) ListAnnotations(ctx context.Context, documentID uint, unresolvedOnly bool) ([]models.Comment, error) {
+	threads, _, err := r.ListThreads(ctx, documentID, unresolvedOnly, 1, len(r.comments)+1)
+	var annotations []models.Comment
+	for _, thread := range threads {
+		if thread.AnchorStatus != "" {
+			annotations = append(annotations, thread)
+		}
+	}
+	return annotations, err
+}
+
+func (r *stubCommentRepository) UpdateAnchor(ctx context.Context, id uint, anchor *models.CommentAnchor, status string) error {
+	r.comments[id].Anchor = anchor
+	r.comments[id].AnchorStatus = status
+	return nil
+}
+
+func (r *stubCommentRepository) Delete(ctx context.Context, id uint) error {
+	for commentID, comment := range r.comments {
+		if commentID == id || (comment.ParentID != nil && *comment.ParentID == id) {
+			delete(r.comments, commentID)
+		}
+	}
+	return nil
+}
+
+// stubVersionedRepository adds document versions to stubTreeRepository
+type stubVersionedRepository struct {
+	*stubTreeRepository
+	versions []models.DocumentVersion
+}
+
+func (r *stubVersionedRepository) GetVersions(ctx context.Context, documentID uint) ([]models.DocumentVersion, error) {
+	var versions []models.DocumentVersion
+	for _, version := range r.versions {
+		if version.DocumentID == documentID {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+// stubNotificationService records the notifications sent
+type stubNotificationService struct {
+	NotificationService
+	sent []models.Notification
+}
+
+func (s *stubNotificationService) Notify(ctx context.Context, notifications ...*models.Notification) error {
+	for _, n := range notifications {
+		s.sent = append(s.sent, *n)
+	}
+	return nil
+}
+
+func TestComments(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	docs := &stubVersionedRepository{stubTreeRepository: &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Content: "Promote the replica.", Version: 3, Status: models.DocumentStatusPublished, CreatorID: 1},
+		2: {ID: 2, Title: "Private notes", Version: 1, Status: models.DocumentStatusDraft, CreatorID: 1},
+	}}}}
+	docs.versions = []models.DocumentVersion{{ID: 1, DocumentID: 1, Version: 2, Title: "Oncall runbook", Content: "Promote."}}
+	users := &stubUserRepository{users: []models.User{
+		{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"}, {ID: 4, Username: "dave"},
+	}}
+	notifications := &stubNotificationService{}
+	repo := &stubCommentRepository{comments: map[uint]*models.Comment{}}
+	svc := NewCommentService(repo, docs, users, notifications)
+
+	_, err := svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, Content: "  "})
+	assert.ErrorIs(t, err, ErrEmptyComment)
+	_, err = svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 2, Content: "Nice"})
+	assert.ErrorIs(t, err, ErrDocumentNotFound, "drafts of others can't be commented on")
+	_, err = svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, Version: 1, Content: "Old"})
+	assert.ErrorIs(t, err, ErrCommentVersionNotFound)
+
+	thread, err := svc.AddComment(ctx, AddCommentRequest{
+		UserID:     2,
+		DocumentID: 1,
+		Content:    "@Carol @alice should we check lag first? cc bob@example.com @bob @nobody",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, thread.Version)
+	assert.Equal(t, contentHash("Promote the replica."), thread.ContentHash)
+	assert.Len(t, docs.versions, 1, "commenting doesn't add versions")
+	assert.Equal(t, []uint{1, 3}, thread.Mentions, "authors and unknown users aren't mentioned")
+	require.Len(t, notifications.sent, 2)
+	assert.Equal(t, models.NotificationMention, notifications.sent[0].Type)
+	assert.Equal(t, `bob mentioned you in a comment on "Oncall runbook"`, notifications.sent[0].Message)
+	assert.Equal(t, thread.ID, *notifications.sent[0].CommentID)
+
+	old, err := svc.AddComment(ctx, AddCommentRequest{UserID: 3, DocumentID: 1, Version: 2, Content: "This was clearer"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, old.Version)
+	assert.Equal(t, contentHash("Promote."), old.ContentHash)
+
+	reply, err := svc.AddComment(ctx, AddCommentRequest{UserID: 1, DocumentID: 1, ParentID: &thread.ID, Content: "Yes"})
+	require.NoError(t, err)
+	nested, err := svc.AddComment(ctx, AddCommentRequest{UserID: 3, DocumentID: 1, ParentID: &reply.ID, Content: "Agreed"})
+	require.NoError(t, err)
+	assert.Equal(t, thread.ID, *nested.ParentID, "replies to replies join the thread")
+	assert.Equal(t, thread.ContentHash, nested.ContentHash)
+
+	_, err = svc.EditComment(ctx, 3, thread.ID, "Hijacked")
+	assert.ErrorIs(t, err, ErrNotCommentAuthor)
+	edited, err := svc.EditComment(ctx, 2, thread.ID, "@carol @dave should we check lag first?")
+	require.NoError(t, err)
+	assert.NotNil(t, edited.EditedAt)
+	assert.Equal(t, []uint{3, 4}, edited.Mentions)
+	require.Len(t, notifications.sent, 3, "only new mentions are notified")
+	assert.Equal(t, uint(4), notifications.sent[2].UserID)
+
+	_, err = svc.ResolveThread(ctx, 3, nested.ID, true)
+	assert.ErrorIs(t, err, ErrThreadNotResolvable)
+	resolved, err := svc.ResolveThread(ctx, 1, nested.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, thread.ID, resolved.ID, "replies resolve their thread")
+	assert.Equal(t, uint(1), *resolved.ResolvedByID)
+
+	threads, total, err := svc.ListComments(ctx, 4, 1, false, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, threads, 2)
+	assert.Len(t, threads[0].Replies, 2)
+
+	threads, total, err = svc.ListComments(ctx, 4, 1, true, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, old.ID, threads[0].ID)
+
+	reopened, err := svc.ResolveThread(ctx, 2, thread.ID, false)
+	require.NoError(t, err)
+	assert.Nil(t, reopened.ResolvedAt)
+
+	assert.ErrorIs(t, svc.DeleteComment(ctx, 4, old.ID), ErrCommentNotDeletable)
+	require.NoError(t, svc.DeleteComment(ctx, 1, thread.ID), "document creators delete any comment")
+	_, total, err = svc.ListComments(ctx, 1, 1, false, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, repo.comments, 1, "replies are deleted with their thread")
+}
+
+func TestCommentAnchors(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	content := "## Failover\n\nPromote the replica. Update DNS.\n\n## Rollback\n\nPromote the replica. Restore the primary.\n"
+	doc := models.Document{ID: 1, Title: "Oncall runbook", Content: content, Version: 3, Status: models.DocumentStatusPublished, CreatorID: 1}
+	docs := &stubVersionedRepository{stubTreeRepository: &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{1: doc}}}}
+	docs.versions = []models.DocumentVersion{{ID: 1, DocumentID: 1, Version: 2, Title: "Oncall runbook", Content: "Promote the replica. Restore the primary.\n"}}
+	repo := &stubCommentRepository{comments: map[uint]*models.Comment{}}
+	svc := NewCommentService(repo, docs, &stubUserRepository{}, &stubNotificationService{})
+
+	rollback := strings.LastIndex(content, "Promote")
+	_, err := svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, Content: "Which one?",
+		Anchor: &models.CommentAnchor{Start: rollback, End: rollback + 20, Quote: "Restore the primary."}})
+	assert.ErrorIs(t, err, ErrInvalidAnchor, "the quote must match the range")
+	_, err = svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, Content: "Which one?",
+		Anchor: &models.CommentAnchor{Start: rollback, End: rollback + 500}})
+	assert.ErrorIs(t, err, ErrInvalidAnchor)
+
+	thread, err := svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, Content: "Which one?",
+		Anchor: &models.CommentAnchor{Start: rollback, End: rollback + 20, Quote: "Promote the replica."}})
+	require.NoError(t, err)
+	assert.Equal(t, models.AnchorStatusAnchored, thread.AnchorStatus)
+	assert.Equal(t, "\n\n## Rollback\n\n", thread.Anchor.Prefix[len(thread.Anchor.Prefix)-15:])
+
+	_, err = svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, ParentID: &thread.ID, Content: "This one",
+		Anchor: &models.CommentAnchor{Start: 0, End: 2}})
+	assert.ErrorIs(t, err, ErrInvalidAnchor, "replies aren't anchored")
+
+	old, err := svc.AddComment(ctx, AddCommentRequest{UserID: 2, DocumentID: 1, Version: 2, Content: "Restore how?",
+		Anchor: &models.CommentAnchor{Start: 21, End: 40}})
+	require.NoError(t, err)
+	assert.Equal(t, "Restore the primary", old.Anchor.Quote)
+	assert.Equal(t, strings.Index(content, "Restore"), old.Anchor.Start, "anchors on older versions are moved to the current content")
+
+	updated := doc
+	updated.Content = "# Runbook\n\n" + strings.Replace(content, "Promote the replica. Restore the primary.", "Promote the new replica. Rebuild the old primary.", 1)
+	docs.docs[1] = updated
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: 1, Document: &updated, Previous: &doc}))
+	svc.Close()
+
+	annotations, err := repo.ListAnnotations(ctx, 1, true)
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	moved := annotations[0].Anchor
+	assert.Equal(t, models.AnchorStatusAnchored, annotations[0].AnchorStatus)
+	assert.Equal(t, "Promote the new replica.", moved.Quote)
+	assert.Equal(t, moved.Quote, string([]rune(updated.Content)[moved.Start:moved.End]))
+	assert.Equal(t, models.AnchorStatusOrphaned, annotations[1].AnchorStatus)
+	assert.Equal(t, "Restore the primary", annotations[1].Anchor.Quote, "orphans keep their last anchor")
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+)
+
+type DocumentEventType string
+
+const (
+	DocumentCreated DocumentEventType = "created"
+	DocumentUpdated DocumentEventType = "updated"
+	DocumentDeleted DocumentEventType = "deleted"
+)
+
+// DocumentEvent describes a change to a document made by UserID. Document is
+// the saved state and is nil for deletions; Previous is the state before an
+// update or deletion.
+type DocumentEvent struct {
+	Type       DocumentEventType
+	DocumentID uint
+	UserID     uint
+	Document   *models.Document
+	Previous   *models.Document
+}
+
+// DocumentListener is notified after a document change has been persisted.
+// Errors are logged and do not fail the change itself.
+type DocumentListener interface {
+	HandleDocumentEvent(ctx context.Context, event DocumentEvent) error
+}
+
+// permissionsChanged reports whether an update changed who may read the
+// document
+func permissionsChanged(prev, cur *models.Document) bool {
+	if prev == nil || cur == nil {
+		return false
+	}
+	return prev.CreatorID != cur.CreatorID || !equalIDs(prev.WorkspaceID, cur.WorkspaceID) ||
+		(prev.Status == models.DocumentStatusPublished) != (cur.Status == models.DocumentStatusPublished)
+}
+
+func (s *documentService) publish(ctx context.Context, event DocumentEvent) {
+	for _, listener := range s.listeners {
+		if err := listener.HandleDocumentEvent(ctx, event); err != nil {
+			utils.Logger.Warn("Document listener failed",
+				zap.Error(err),
+				zap.String("event", string(event.Type)),
+				zap.Uint("document_id", event.DocumentID),
+			)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const documentQueueSize = 256
+
+var ErrDocumentQueueFull = errors.New("document queue is full")
+
+// documentQueue processes document IDs on a background worker, for derived
+// data that may take a model call to compute. An ID that is already waiting
+// is not queued twice.
+type documentQueue struct {
+	name    string
+	timeout time.Duration
+	process func(ctx context.Context, id uint) error
+
+	queue   chan uint
+	mu      sync.Mutex
+	pending map[uint]bool
+	wg      sync.WaitGroup
+}
+
+// newDocumentQueue starts the worker. name describes the work in log
+// messages, e.g. "summarize document".
+func newDocumentQueue(name string, timeout time.Duration, process func(ctx context.Context, id uint) error) *documentQueue {
+	q := &documentQueue{
+		name:    name,
+		timeout: timeout,
+		process: process,
+		queue:   make(chan uint, documentQueueSize),
+		pending: make(map[uint]bool),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+func (q *documentQueue) enqueue(id uint) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending[id] {
+		return nil
+	}
+	select {
+	case q.queue <- id:
+		q.pending[id] = true
+		return nil
+	default:
+		return ErrDocumentQueueFull
+	}
+}
+
+// close processes the queued documents and stops the worker
+func (q *documentQueue) close() {
+	close(q.queue)
+	q.wg.Wait()
+}
+
+func (q *documentQueue) run() {
+	defer q.wg.Done()
+
+	for id := range q.queue {
+		// 处理前移出待办集合，处理期间的修改会重新入队
+		q.mu.Lock()
+		delete(q.pending, id)
+		q.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		err := q.process(ctx, id)
+		cancel()
+
+		if err != nil {
+			utils.Logger.Warn("Failed to "+q.name,
+				zap.Error(err),
+				zap.Uint("document_id", id),
+			)
+		}
+	}
+}
@@ -4,28 +4,97 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/Zhaoyikaiii/docmind/internal/models"
 	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+	"github.com/Zhaoyikaiii/docmind/internal/slug"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type DocumentService interface {
 	CreateDocument(ctx context.Context, doc *models.Document) error
-	UpdateDocument(ctx context.Context, doc *models.Document) error
+	// UpdateDocument saves doc on behalf of userID, who must be able to edit
+	// it. The creator of a document never changes.
+	UpdateDocument(ctx context.Context, doc *models.Document, userID uint) error
 	DeleteDocument(ctx context.Context, id uint, userID uint) error
-	GetDocument(ctx context.Context, id uint) (*models.Document, error)
+	// GetDocument returns a document userID can read, with its summary,
+	// annotations and lock
+	GetDocument(ctx context.Context, userID, id uint) (*models.Document, error)
 	ListDocuments(ctx context.Context, params repository.DocumentListParams) ([]models.Document, int64, error)
+	// GetDocumentFacets counts facet values over every document ListDocuments
+	// would return for params, across all pages
+	GetDocumentFacets(ctx context.Context, params repository.DocumentListParams) (*models.Facets, error)
 	CreateVersion(ctx context.Context, docID uint, userID uint) error
 	GetVersions(ctx context.Context, docID uint) ([]models.DocumentVersion, error)
-	ManageTags(ctx context.Context, docID uint, addTags []uint, removeTags []uint) error
+	// ManageTags adds and removes tags of a document on behalf of userID,
+	// who must be able to edit it
+	ManageTags(ctx context.Context, userID, docID uint, addTags []uint, removeTags []uint) error
+	ManageTagsByName(ctx context.Context, userID, docID uint, addTags []string, removeTags []string) error
+	// GetDocumentByPath returns the document userID can read at a slug path
+	// such as "handbook/oncall", looking only in a workspace when
+	// workspaceID is set. When the path is outdated because the document or
+	// a parent was renamed or moved, no document is returned and redirect
+	// is the current slug path of the document instead.
+	GetDocumentByPath(ctx context.Context, userID uint, path string, workspaceID *uint) (doc *models.Document, redirect string, err error)
+	// Subscribe adds a listener of document changes. It is meant for
+	// listeners that depend on the document service themselves, and must be
+	// called before the service is used.
+	Subscribe(listener DocumentListener)
 }
 
+var (
+	ErrDocumentTitleExists   = errors.New("document with this title already exists")
+	ErrDocumentEditForbidden = errors.New("unauthorized to update this document")
+	ErrWorkspaceForbidden    = errors.New("only members of a workspace can put documents in it")
+)
+
+// maxSearchHits caps how many engine hits are considered for one listing
+const maxSearchHits = 1000
+
+const (
+	// maxSlugAttempts bounds how often a slug is chosen again when
+	// concurrent saves keep taking it
+	maxSlugAttempts = 3
+	// slugMigrationBatch is the number of documents given a slug at a time
+	// by MigrateDocumentSlugs
+	slugMigrationBatch = 500
+)
+
 type documentService struct {
-	repo repository.DocumentRepository
+	repo       repository.DocumentRepository
+	tagService TagService
+	engine     search.SearchEngine
+	summaries  *SummaryService
+	comments   repository.CommentRepository
+	locks      repository.DocumentLockRepository
+	slugs      repository.DocumentSlugRepository
+	workspaces WorkspaceService
+	listeners  []DocumentListener
 }
 
-func NewDocumentService(repo repository.DocumentRepository) DocumentService {
-	return &documentService{repo: repo}
+// NewDocumentService creates the document service. When engine is nil,
+// listings fall back to the repository's LIKE search; when summaries is nil,
+// documents are returned without summaries; when comments is nil, without
+// annotations. When locks is nil, check-out locks are not enforced; when
+// slugs is nil, documents are not given slugs; when workspaces is nil, only
+// creators edit their documents.
+func NewDocumentService(repo repository.DocumentRepository, tagService TagService, engine search.SearchEngine, summaries *SummaryService, comments repository.CommentRepository, locks repository.DocumentLockRepository, slugs repository.DocumentSlugRepository, workspaces WorkspaceService, listeners ...DocumentListener) DocumentService {
+	return &documentService{
+		repo:       repo,
+		tagService: tagService,
+		engine:     engine,
+		summaries:  summaries,
+		comments:   comments,
+		locks:      locks,
+		slugs:      slugs,
+		workspaces: workspaces,
+		listeners:  listeners,
+	}
 }
 
 func (s *documentService) CreateDocument(ctx context.Context, doc *models.Document) error {
@@ -35,23 +104,70 @@ func (s *documentService) CreateDocument(ctx context.Context, doc *models.Docume
 		return fmt.Errorf("failed to check document existence: %w", err)
 	}
 	if exists {
-		return fmt.Errorf("document with this title already exists")
+		return ErrDocumentTitleExists
+	}
+
+	if err := s.checkWorkspace(ctx, doc.CreatorID, doc.WorkspaceID); err != nil {
+		return err
+	}
+
+	if err := s.resolveTags(ctx, doc); err != nil {
+		return err
+	}
+
+	if err := saveWithSlug(ctx, s.repo, doc, s.slugs != nil, s.repo.Create); err != nil {
+		return err
 	}
-	
-	return s.repo.Create(ctx, doc)
+
+	s.publish(ctx, DocumentEvent{Type: DocumentCreated, DocumentID: doc.ID, UserID: doc.CreatorID, Document: doc})
+	return nil
 }
 
-func (s *documentService) UpdateDocument(ctx context.Context, doc *models.Document) error {
+func (s *documentService) UpdateDocument(ctx context.Context, doc *models.Document, userID uint) error {
 	existing, err := s.repo.GetByID(ctx, doc.ID)
 	if err != nil {
 		return err
 	}
 
-	if existing.CreatorID != doc.CreatorID {
-		return errors.New("unauthorized to update this document")
+	if !canEditDocument(ctx, s.workspaces, userID, existing) {
+		return ErrDocumentEditForbidden
+	}
+	doc.CreatorID = existing.CreatorID
+
+	// 移入工作区需要是其成员，移出工作区只有创建者可以
+	if !equalIDs(existing.WorkspaceID, doc.WorkspaceID) {
+		if doc.WorkspaceID == nil && userID != existing.CreatorID {
+			return ErrDocumentEditForbidden
+		}
+		if err := s.checkWorkspace(ctx, userID, doc.WorkspaceID); err != nil {
+			return err
+		}
+	}
+
+	// 文档被签出时只有持锁人可以写入
+	if s.locks != nil {
+		if err := checkLock(ctx, s.locks, userID, doc.ID); err != nil {
+			return err
+		}
 	}
 
-	return s.repo.Update(ctx, doc)
+	if err := s.resolveTags(ctx, doc); err != nil {
+		return err
+	}
+
+	// slug 由标题生成，标题和范围不变时保持不变
+	newSlug := s.slugs != nil
+	if newSlug && existing.Slug != "" && existing.Title == doc.Title && sameDocumentScope(existing, doc) {
+		doc.Slug = existing.Slug
+		newSlug = false
+	}
+	if err := saveWithSlug(ctx, s.repo, doc, newSlug, s.repo.Update); err != nil {
+		return err
+	}
+	s.recordSlug(ctx, existing, doc)
+
+	s.publish(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: doc.ID, UserID: userID, Document: doc, Previous: existing})
+	return nil
 }
 
 func (s *documentService) DeleteDocument(ctx context.Context, id uint, userID uint) error {
@@ -64,15 +180,350 @@ func (s *documentService) DeleteDocument(ctx context.Context, id uint, userID ui
 		return errors.New("unauthorized to delete this document")
 	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.publish(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: id, UserID: userID, Previous: doc})
+	return nil
 }
 
-func (s *documentService) GetDocument(ctx context.Context, id uint) (*models.Document, error) {
-	return s.repo.GetByID(ctx, id)
+func (s *documentService) GetDocument(ctx context.Context, userID, id uint) (*models.Document, error) {
+	doc, err := readableDocument(ctx, s.repo, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.attachSummaries(ctx, doc)
+	s.attachAnnotations(ctx, doc)
+	s.attachLock(ctx, doc)
+	s.attachSlugPath(ctx, doc)
+	return doc, nil
+}
+
+func (s *documentService) GetDocumentByPath(ctx context.Context, userID uint, path string, workspaceID *uint) (*models.Document, string, error) {
+	segments := slug.Split(path)
+	if len(segments) == 0 {
+		return nil, "", ErrDocumentNotFound
+	}
+	requested := strings.Join(segments, "/")
+
+	ids, err := s.repo.FilterIDs(ctx, repository.DocumentListParams{
+		Slug:        segments[len(segments)-1],
+		WorkspaceID: workspaceID,
+		ReadableBy:  &userID,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(ids) == 0 {
+		redirect, err := s.formerSlugPath(ctx, userID, segments[len(segments)-1], workspaceID)
+		return nil, redirect, err
+	}
+
+	// 同一 slug 可能出现在多个范围，优先完整路径匹配的文档
+	slices.Sort(ids)
+	var redirect string
+	for _, id := range ids {
+		doc, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		current, err := s.slugPath(ctx, doc)
+		if err != nil {
+			return nil, "", err
+		}
+		if current == requested {
+			doc, err := s.GetDocument(ctx, userID, id)
+			return doc, "", err
+		}
+		if redirect == "" {
+			redirect = current
+		}
+	}
+	return nil, redirect, nil
+}
+
+// formerSlugPath returns the current slug path of the most recently renamed
+// document userID can read that had a slug
+func (s *documentService) formerSlugPath(ctx context.Context, userID uint, former string, workspaceID *uint) (string, error) {
+	if s.slugs == nil {
+		return "", ErrDocumentNotFound
+	}
+	records, err := s.slugs.ListBySlug(ctx, former, workspaceID)
+	if err != nil {
+		return "", err
+	}
+	for _, record := range records {
+		doc, err := readableDocument(ctx, s.repo, userID, record.DocumentID)
+		if errors.Is(err, ErrDocumentNotFound) || (err == nil && doc.Slug == "") {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return s.slugPath(ctx, doc)
+	}
+	return "", ErrDocumentNotFound
+}
+
+// slugPath joins the slugs of the parents of doc and its own. Parents
+// without a slug, created before slugs were introduced, are left out.
+func (s *documentService) slugPath(ctx context.Context, doc *models.Document) (string, error) {
+	ancestors, err := s.repo.AncestorIDs(ctx, doc.ID)
+	if err != nil {
+		return "", err
+	}
+	var parents []models.Document
+	if len(ancestors) > 0 {
+		if parents, err = s.repo.GetByIDs(ctx, ancestors); err != nil {
+			return "", err
+		}
+	}
+	slugs := make(map[uint]string, len(parents))
+	for _, parent := range parents {
+		slugs[parent.ID] = parent.Slug
+	}
+
+	segments := make([]string, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if parent := slugs[ancestors[i]]; parent != "" {
+			segments = append(segments, parent)
+		}
+	}
+	return strings.Join(append(segments, doc.Slug), "/"), nil
+}
+
+// attachSlugPath adds the slug path to doc. Failures are logged.
+func (s *documentService) attachSlugPath(ctx context.Context, doc *models.Document) {
+	if doc.Slug == "" {
+		return
+	}
+	path, err := s.slugPath(ctx, doc)
+	if err != nil {
+		utils.Logger.Warn("Failed to build document slug path", zap.Uint("document_id", doc.ID), zap.Error(err))
+		return
+	}
+	doc.SlugPath = path
+}
+
+// assignSlug gives doc a slug made from its title that no other document of
+// its scope has, numbering it when it is taken
+func assignSlug(ctx context.Context, repo repository.DocumentRepository, doc *models.Document) error {
+	base := slug.Make(doc.Title)
+	taken, err := repo.ListSlugs(ctx, doc.WorkspaceID, doc.CreatorID, base, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check document slugs: %w", err)
+	}
+
+	doc.Slug = base
+	for n := 2; slices.Contains(taken, doc.Slug); n++ {
+		doc.Slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return nil
+}
+
+// saveWithSlug saves doc, first giving it a new slug when newSlug is set.
+// The slug is chosen again when a concurrent save took it.
+func saveWithSlug(ctx context.Context, repo repository.DocumentRepository, doc *models.Document, newSlug bool, save func(context.Context, *models.Document) error) error {
+	for attempt := 1; ; attempt++ {
+		if newSlug {
+			if err := assignSlug(ctx, repo, doc); err != nil {
+				return err
+			}
+		}
+		err := save(ctx, doc)
+		if !newSlug || !errors.Is(err, repository.ErrSlugTaken) || attempt == maxSlugAttempts {
+			return err
+		}
+	}
+}
+
+// MigrateDocumentSlugs gives a slug to every document that has none, such
+// as those created before slugs, and creates the indexes keeping slugs
+// unique. Slugs duplicated before the indexes existed are assigned again.
+// It can be run repeatedly.
+func MigrateDocumentSlugs(ctx context.Context, repo repository.DocumentRepository) error {
+	if err := repo.ClearDuplicateSlugs(ctx); err != nil {
+		return fmt.Errorf("failed to clear duplicate slugs: %w", err)
+	}
+
+	var afterID uint
+	for {
+		docs, err := repo.ListWithoutSlug(ctx, afterID, slugMigrationBatch)
+		if err != nil {
+			return err
+		}
+		for i := range docs {
+			doc := &docs[i]
+			save := func(ctx context.Context, doc *models.Document) error {
+				return repo.UpdateSlug(ctx, doc.ID, doc.Slug)
+			}
+			if err := saveWithSlug(ctx, repo, doc, true, save); err != nil {
+				return fmt.Errorf("failed to assign slug to document %d: %w", doc.ID, err)
+			}
+			afterID = doc.ID
+		}
+		if len(docs) < slugMigrationBatch {
+			break
+		}
+	}
+
+	return repo.MigrateSlugs(ctx)
+}
+
+// recordSlug keeps the former slug of a renamed or moved document so that
+// its old paths redirect to it. Failures are logged.
+func (s *documentService) recordSlug(ctx context.Context, prev, doc *models.Document) {
+	if s.slugs == nil || prev.Slug == "" || (prev.Slug == doc.Slug && sameDocumentScope(prev, doc)) {
+		return
+	}
+	err := s.slugs.Record(ctx, &models.DocumentSlug{
+		DocumentID:  doc.ID,
+		WorkspaceID: prev.WorkspaceID,
+		CreatorID:   prev.CreatorID,
+		Slug:        prev.Slug,
+	})
+	if err != nil {
+		utils.Logger.Warn("Failed to record former document slug", zap.Uint("document_id", doc.ID), zap.Error(err))
+	}
 }
 
 func (s *documentService) ListDocuments(ctx context.Context, params repository.DocumentListParams) ([]models.Document, int64, error) {
-	return s.repo.List(ctx, params)
+	var docs []models.Document
+	var total int64
+	var err error
+	if params.Search == "" || s.engine == nil {
+		docs, total, err = s.repo.List(ctx, params)
+	} else {
+		docs, total, err = s.searchDocuments(ctx, params)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ptrs := make([]*models.Document, len(docs))
+	for i := range docs {
+		ptrs[i] = &docs[i]
+	}
+	s.attachSummaries(ctx, ptrs...)
+	return docs, total, nil
+}
+
+// attachSummaries adds the generated summaries to docs. Summaries are
+// optional, so failures are logged rather than failing the read.
+func (s *documentService) attachSummaries(ctx context.Context, docs ...*models.Document) {
+	if s.summaries == nil {
+		return
+	}
+	if err := s.summaries.Attach(ctx, docs...); err != nil {
+		utils.Logger.Warn("Failed to load document summaries", zap.Error(err))
+	}
+}
+
+// attachAnnotations adds the unresolved anchored threads to doc so editors
+// can highlight them. Like summaries they are optional, so failures are
+// logged.
+func (s *documentService) attachAnnotations(ctx context.Context, doc *models.Document) {
+	if s.comments == nil {
+		return
+	}
+	annotations, err := s.comments.ListAnnotations(ctx, doc.ID, true)
+	if err != nil {
+		utils.Logger.Warn("Failed to load document annotations", zap.Uint("document_id", doc.ID), zap.Error(err))
+		return
+	}
+	doc.Annotations = annotations
+}
+
+// attachLock adds the active check-out lock to doc. Failures are logged.
+func (s *documentService) attachLock(ctx context.Context, doc *models.Document) {
+	if s.locks == nil {
+		return
+	}
+	lock, err := s.locks.GetActive(ctx, doc.ID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Logger.Warn("Failed to load document lock", zap.Uint("document_id", doc.ID), zap.Error(err))
+		}
+		return
+	}
+	doc.Lock = lock
+}
+
+// searchDocuments answers a listing with a search term through the search
+// engine: hits are narrowed by the remaining filters and kept in relevance order.
+func (s *documentService) searchDocuments(ctx context.Context, params repository.DocumentListParams) ([]models.Document, int64, error) {
+	matched, err := s.searchMatches(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(len(matched))
+	start := (params.Page - 1) * params.PageSize
+	if start < 0 || start >= len(matched) {
+		return []models.Document{}, total, nil
+	}
+	end := min(start+params.PageSize, len(matched))
+	pageIDs := matched[start:end]
+
+	docs, err := s.repo.GetByIDs(ctx, pageIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rankDocuments(docs, pageIDs), total, nil
+}
+
+func (s *documentService) GetDocumentFacets(ctx context.Context, params repository.DocumentListParams) (*models.Facets, error) {
+	if params.Search != "" && s.engine != nil {
+		matched, err := s.searchMatches(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		params.Search = ""
+		params.IDs = matched
+	}
+	return s.repo.Facets(ctx, params)
+}
+
+// searchMatches returns the IDs of the engine hits for params.Search that
+// pass the remaining filters, in relevance order
+func (s *documentService) searchMatches(ctx context.Context, params repository.DocumentListParams) ([]uint, error) {
+	hits, err := s.engine.Search(ctx, params.Search, maxSearchHits)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	filter := params
+	filter.Search = ""
+	filter.IDs = ids
+	allowed, err := s.repo.FilterIDs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	isAllowed := make(map[uint]bool, len(allowed))
+	for _, id := range allowed {
+		isAllowed[id] = true
+	}
+	matched := ids[:0]
+	for _, id := range ids {
+		if isAllowed[id] {
+			matched = append(matched, id)
+		}
+	}
+	return matched, nil
+}
+
+func (s *documentService) Subscribe(listener DocumentListener) {
+	s.listeners = append(s.listeners, listener)
 }
 
 func (s *documentService) CreateVersion(ctx context.Context, docID uint, userID uint) error {
@@ -96,7 +547,11 @@ func (s *documentService) GetVersions(ctx context.Context, docID uint) ([]models
 	return s.repo.GetVersions(ctx, docID)
 }
 
-func (s *documentService) ManageTags(ctx context.Context, docID uint, addTags []uint, removeTags []uint) error {
+func (s *documentService) ManageTags(ctx context.Context, userID, docID uint, addTags []uint, removeTags []uint) error {
+	if err := s.checkTagging(ctx, userID, docID); err != nil {
+		return err
+	}
+
 	if len(addTags) > 0 {
 		if err := s.repo.AddTags(ctx, docID, addTags); err != nil {
 			return err
@@ -111,3 +566,141 @@ func (s *documentService) ManageTags(ctx context.Context, docID uint, addTags []
 
 	return nil
 }
+
+func (s *documentService) ManageTagsByName(ctx context.Context, userID, docID uint, addTags []string, removeTags []string) error {
+	if err := s.checkTagging(ctx, userID, docID); err != nil {
+		return err
+	}
+
+	if len(addTags) > 0 {
+		tags, err := s.tagService.ResolveTags(ctx, addTags)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.AddTags(ctx, docID, tagIDs(tags)); err != nil {
+			return err
+		}
+	}
+
+	if len(removeTags) > 0 {
+		tags, err := s.tagService.GetTagsByName(ctx, removeTags)
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		if err := s.repo.RemoveTags(ctx, docID, tagIDs(tags)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkTagging applies the rules of UpdateDocument to changing the tags of a
+// document: userID must be able to edit it and no one else may hold its lock
+func (s *documentService) checkTagging(ctx context.Context, userID, docID uint) error {
+	doc, err := s.repo.GetByID(ctx, docID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDocumentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !canEditDocument(ctx, s.workspaces, userID, doc) {
+		return ErrDocumentEditForbidden
+	}
+	if s.locks != nil {
+		return checkLock(ctx, s.locks, userID, docID)
+	}
+	return nil
+}
+
+// resolveTags replaces tags given only by name with existing tags, creating
+// missing ones, so that clients don't need to look up tag IDs first.
+func (s *documentService) resolveTags(ctx context.Context, doc *models.Document) error {
+	var names []string
+	resolved := make([]models.Tag, 0, len(doc.Tags))
+	for _, tag := range doc.Tags {
+		if tag.ID != 0 {
+			resolved = append(resolved, tag)
+			continue
+		}
+		names = append(names, tag.Name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags, err := s.tagService.ResolveTags(ctx, names)
+	if err != nil {
+		return err
+	}
+	doc.Tags = append(resolved, tags...)
+	return nil
+}
+
+// readableDocument returns a document userID may read: their own, one of
+// their workspaces or a published one
+func readableDocument(ctx context.Context, repo repository.DocumentRepository, userID, documentID uint) (*models.Document, error) {
+	ids, err := repo.FilterIDs(ctx, repository.DocumentListParams{IDs: []uint{documentID}, ReadableBy: &userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+	doc, err := repo.GetByID(ctx, documentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, err
+}
+
+// checkWorkspace returns ErrWorkspaceForbidden unless userID is a member of
+// the workspace a document is put in. Documents outside of workspaces pass.
+func (s *documentService) checkWorkspace(ctx context.Context, userID uint, workspaceID *uint) error {
+	if workspaceID == nil {
+		return nil
+	}
+	if s.workspaces == nil {
+		return ErrWorkspaceForbidden
+	}
+	_, err := s.workspaces.Membership(ctx, userID, *workspaceID)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		return ErrWorkspaceForbidden
+	}
+	return err
+}
+
+// canEditDocument reports whether userID may edit doc: its creator and the
+// members of its workspace may. When workspaces is nil, only the creator.
+func canEditDocument(ctx context.Context, workspaces WorkspaceService, userID uint, doc *models.Document) bool {
+	if doc.CreatorID == userID {
+		return true
+	}
+	if doc.WorkspaceID == nil || workspaces == nil {
+		return false
+	}
+	_, err := workspaces.Membership(ctx, userID, *doc.WorkspaceID)
+	return err == nil
+}
+
+// sameDocumentScope reports whether a and b belong to the same scope: the
+// same workspace, or for documents outside of workspaces, the same creator.
+// Slugs are unique and wiki links resolve within a scope.
+func sameDocumentScope(a, b *models.Document) bool {
+	if a.WorkspaceID != nil || b.WorkspaceID != nil {
+		return a.WorkspaceID != nil && b.WorkspaceID != nil && *a.WorkspaceID == *b.WorkspaceID
+	}
+	return a.CreatorID == b.CreatorID
+}
+
+func tagIDs(tags []models.Tag) []uint {
+	ids := make([]uint, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
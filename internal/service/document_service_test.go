@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubSluggedRepository creates documents and looks them up by slug. Like
+// the unique slug indexes, it refuses to save a slug taken in its scope.
+type stubSluggedRepository struct {
+	*stubWritableRepository
+	nextID uint
+	// concurrent is created right before the next document, as if by a
+	// concurrent request
+	concurrent *models.Document
+	migrated   bool
+}
+
+func (r *stubSluggedRepository) ExistsByTitleAndCreator(ctx context.Context, title string, creatorID uint) (bool, error) {
+	for _, doc := range r.docs {
+		if doc.Title == title && doc.CreatorID == creatorID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *stubSluggedRepository) Create(ctx context.Context, doc *models.Document) error {
+	if r.concurrent != nil {
+		concurrent := r.concurrent
+		r.concurrent = nil
+		require.NoError(nil, r.Create(ctx, concurrent))
+	}
+	if r.slugTaken(doc.ID, doc.Slug, doc.WorkspaceID, doc.CreatorID) {
+		return repository.ErrSlugTaken
+	}
+	r.nextID++
+	doc.ID = r.nextID
+	r.docs[doc.ID] = *doc
+	return nil
+}
+
+func (r *stubSluggedRepository) slugTaken(id uint, slug string, workspaceID *uint, creatorID uint) bool {
+	for otherID, other := range r.docs {
+		if otherID != id && slug != "" && other.Slug == slug && inLinkScope(other, workspaceID, creatorID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *stubSluggedRepository) ListWithoutSlug(ctx context.Context, afterID uint, limit int) ([]models.Document, error) {
+	var found []models.Document
+	for _, id := range sortedIDs(r.docs) {
+		if id > afterID && r.docs[id].Slug == "" && len(found) < limit {
+			found = append(found, r.docs[id])
+		}
+	}
+	return found, nil
+}
+
+func (r *stubSluggedRepository) UpdateSlug(ctx context.Context, id uint, slug string) error {
+	doc := r.docs[id]
+	if r.slugTaken(id, slug, doc.WorkspaceID, doc.CreatorID) {
+		return repository.ErrSlugTaken
+	}
+	doc.Slug = slug
+	r.docs[id] = doc
+	return nil
+}
+
+func (r *stubSluggedRepository) ClearDuplicateSlugs(ctx context.Context) error {
+	for _, id := range sortedIDs(r.docs) {
+		doc := r.docs[id]
+		for _, olderID := range sortedIDs(r.docs) {
+			older := r.docs[olderID]
+			if olderID < id && doc.Slug != "" && older.Slug == doc.Slug && inLinkScope(older, doc.WorkspaceID, doc.CreatorID) {
+				doc.Slug = ""
+				r.docs[id] = doc
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (r *stubSluggedRepository) MigrateSlugs(ctx context.Context) error {
+	r.migrated = true
+	return nil
+}
+
+func (r *stubSluggedRepository) ListSlugs(ctx context.Context, workspaceID *uint, creatorID uint, base string, excludeID uint) ([]string, error) {
+	var slugs []string
+	for id, doc := range r.docs {
+		if id != excludeID && inLinkScope(doc, workspaceID, creatorID) &&
+			(doc.Slug == base || strings.HasPrefix(doc.Slug, base+"-")) {
+			slugs = append(slugs, doc.Slug)
+		}
+	}
+	return slugs, nil
+}
+
+func (r *stubSluggedRepository) FilterIDs(ctx context.Context, params repository.DocumentListParams) ([]uint, error) {
+	if params.IDs == nil {
+		params.IDs = sortedIDs(r.docs)
+	}
+	ids, _ := r.stubWritableRepository.FilterIDs(ctx, params)
+	return slices.DeleteFunc(ids, func(id uint) bool {
+		doc := r.docs[id]
+		return (params.Slug != "" && doc.Slug != params.Slug) ||
+			(params.WorkspaceID != nil && (doc.WorkspaceID == nil || *doc.WorkspaceID != *params.WorkspaceID))
+	}), nil
+}
+
+// stubSlugRepository keeps former slugs in memory
+type stubSlugRepository struct {
+	slugs []models.DocumentSlug
+}
+
+func (r *stubSlugRepository) Record(ctx context.Context, slug *models.DocumentSlug) error {
+	r.slugs = append(r.slugs, *slug)
+	return nil
+}
+
+func (r *stubSlugRepository) ListBySlug(ctx context.Context, slug string, workspaceID *uint) ([]models.DocumentSlug, error) {
+	var found []models.DocumentSlug
+	for i := len(r.slugs) - 1; i >= 0; i-- {
+		record := r.slugs[i]
+		if record.Slug == slug && (workspaceID == nil || (record.WorkspaceID != nil && *record.WorkspaceID == *workspaceID)) {
+			found = append(found, record)
+		}
+	}
+	return found, nil
+}
+
+func TestDocumentSlugs(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	platform, infra := uint(1), uint(2)
+	docs := &stubSluggedRepository{stubWritableRepository: &stubWritableRepository{&stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{}}}}}
+	slugs := &stubSlugRepository{}
+	users := &stubUserRepository{users: []models.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"}}}
+	workspaceRepo := newStubWorkspaceRepository()
+	for _, member := range [][2]uint{{platform, 1}, {platform, 2}, {infra, 2}} {
+		workspaceRepo.members[member] = models.WorkspaceMember{WorkspaceID: member[0], UserID: member[1], Role: models.WorkspaceRoleMember}
+	}
+	svc := NewDocumentService(docs, nil, nil, nil, nil, nil, slugs, NewWorkspaceService(workspaceRepo, users))
+
+	create := func(doc models.Document) *models.Document {
+		if doc.Status == "" {
+			doc.Status = models.DocumentStatusPublished
+		}
+		require.NoError(t, svc.CreateDocument(ctx, &doc))
+		return &doc
+	}
+	handbook := create(models.Document{Title: "Handbook", CreatorID: 1, WorkspaceID: &platform})
+	oncall := create(models.Document{Title: "On-call: Runbook", CreatorID: 1, WorkspaceID: &platform, ParentID: &handbook.ID})
+	second := create(models.Document{Title: "On-call runbook!", CreatorID: 2, WorkspaceID: &platform})
+	other := create(models.Document{Title: "On-call runbook", CreatorID: 2, WorkspaceID: &infra})
+	personal := create(models.Document{Title: "On-call runbook", CreatorID: 1, Status: models.DocumentStatusDraft})
+
+	assert.Equal(t, "on-call-runbook", oncall.Slug)
+	assert.Equal(t, "on-call-runbook-2", second.Slug, "slugs are unique per workspace")
+	assert.Equal(t, "on-call-runbook", other.Slug)
+	assert.Equal(t, "on-call-runbook", personal.Slug)
+
+	// a slug taken by a concurrent save is chosen again
+	docs.concurrent = &models.Document{Title: "Release", Slug: "release", CreatorID: 1, WorkspaceID: &platform}
+	release := create(models.Document{Title: "Release", CreatorID: 2, WorkspaceID: &platform})
+	assert.Equal(t, "release-2", release.Slug)
+
+	// only members put documents in a workspace
+	planted := models.Document{Title: "Planted", CreatorID: 3, WorkspaceID: &platform}
+	assert.ErrorIs(t, svc.CreateDocument(ctx, &planted), ErrWorkspaceForbidden)
+	smuggled := *personal
+	smuggled.WorkspaceID = &infra
+	assert.ErrorIs(t, svc.UpdateDocument(ctx, &smuggled, 1), ErrWorkspaceForbidden)
+	assert.Nil(t, docs.docs[personal.ID].WorkspaceID)
+
+	doc, redirect, err := svc.GetDocumentByPath(ctx, 2, "handbook/on-call-runbook", &platform)
+	require.NoError(t, err)
+	assert.Empty(t, redirect)
+	assert.Equal(t, oncall.ID, doc.ID)
+	assert.Equal(t, "handbook/on-call-runbook", doc.SlugPath)
+	doc, _, err = svc.GetDocumentByPath(ctx, 2, "/on-call-runbook/", &infra)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, doc.ID)
+
+	// the full path tells documents with the same slug apart
+	doc, _, err = svc.GetDocumentByPath(ctx, 2, "handbook/on-call-runbook", nil)
+	require.NoError(t, err)
+	assert.Equal(t, oncall.ID, doc.ID)
+	_, redirect, err = svc.GetDocumentByPath(ctx, 2, "on-call-runbook", &platform)
+	require.NoError(t, err)
+	assert.Equal(t, "handbook/on-call-runbook", redirect, "a path without the parents redirects")
+
+	// saving without renaming keeps the slug, whatever the client sends
+	update := *oncall
+	update.Slug = "custom"
+	update.Content = "Promote the replica."
+	require.NoError(t, svc.UpdateDocument(ctx, &update, 1))
+	assert.Equal(t, "on-call-runbook", docs.docs[oncall.ID].Slug)
+	assert.Empty(t, slugs.slugs)
+
+	// renaming the parent and the document redirects their old paths
+	renamed := *handbook
+	renamed.Title = "Team handbook"
+	require.NoError(t, svc.UpdateDocument(ctx, &renamed, 1))
+	update.Title = "Oncall"
+	require.NoError(t, svc.UpdateDocument(ctx, &update, 1))
+	assert.Equal(t, "oncall", update.Slug)
+
+	_, redirect, err = svc.GetDocumentByPath(ctx, 2, "handbook/on-call-runbook", &platform)
+	require.NoError(t, err)
+	assert.Equal(t, "team-handbook/oncall", redirect)
+	_, redirect, err = svc.GetDocumentByPath(ctx, 2, "handbook", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "team-handbook", redirect)
+
+	// the former slug of a moved document is looked up in its old workspace
+	moved := *second
+	moved.WorkspaceID = &infra
+	require.NoError(t, svc.UpdateDocument(ctx, &moved, 2))
+	assert.Equal(t, "on-call-runbook-2", moved.Slug)
+	require.Len(t, slugs.slugs, 3)
+	assert.Equal(t, &platform, slugs.slugs[2].WorkspaceID)
+	_, redirect, err = svc.GetDocumentByPath(ctx, 2, "on-call-runbook-2", &platform)
+	require.NoError(t, err)
+	assert.Equal(t, "on-call-runbook-2", redirect)
+
+	_, _, err = svc.GetDocumentByPath(ctx, 2, "nowhere", nil)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, _, err = svc.GetDocumentByPath(ctx, 2, "/", nil)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func TestMigrateDocumentSlugs(t *testing.T) {
+	ctx := context.Background()
+	platform := uint(1)
+	docs := &stubSluggedRepository{stubWritableRepository: &stubWritableRepository{&stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall", CreatorID: 1, WorkspaceID: &platform},
+		2: {ID: 2, Title: "Oncall", Slug: "oncall", CreatorID: 2, WorkspaceID: &platform},
+		3: {ID: 3, Title: "Oncall!", Slug: "oncall", CreatorID: 3, WorkspaceID: &platform},
+		4: {ID: 4, Title: "Oncall", CreatorID: 1},
+	}}}}}
+
+	require.NoError(t, MigrateDocumentSlugs(ctx, docs))
+	assert.Equal(t, "oncall-2", docs.docs[1].Slug)
+	assert.Equal(t, "oncall", docs.docs[2].Slug, "the oldest document keeps a shared slug")
+	assert.Equal(t, "oncall-3", docs.docs[3].Slug)
+	assert.Equal(t, "oncall", docs.docs[4].Slug)
+	assert.True(t, docs.migrated)
+
+	require.NoError(t, MigrateDocumentSlugs(ctx, docs))
+	assert.Equal(t, "oncall-2", docs.docs[1].Slug, "migrating again changes nothing")
+}
+
+func TestGetDocumentAccess(t *testing.T) {
+	ctx := context.Background()
+	docs := &stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Draft", Status: models.DocumentStatusDraft, CreatorID: 1},
+	}}
+	comments := &stubCommentRepository{comments: map[uint]*models.Comment{
+		1: {ID: 1, DocumentID: 1, AuthorID: 1, Content: "Private note", AnchorStatus: models.AnchorStatusAnchored,
+			Anchor: &models.CommentAnchor{Start: 0, End: 5, Quote: "Draft"}},
+	}}
+	svc := NewDocumentService(docs, nil, nil, nil, comments, nil, nil, nil)
+
+	doc, err := svc.GetDocument(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Len(t, doc.Annotations, 1)
+
+	// 无权读取的文档与不存在的文档一样返回 404，不泄露批注
+	_, err = svc.GetDocument(ctx, 2, 1)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, err = svc.GetDocument(ctx, 1, 9)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func TestManageTagsAccess(t *testing.T) {
+	ctx := context.Background()
+	docs := &stubTaggingRepository{added: map[uint][]uint{}, stubDocumentRepository: &stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Runbook", Status: models.DocumentStatusPublished, CreatorID: 1},
+	}}}
+	locks := &stubLockRepository{locks: map[uint]models.DocumentLock{}}
+	svc := NewDocumentService(docs, nil, nil, nil, nil, locks, nil, nil)
+
+	require.NoError(t, svc.ManageTags(ctx, 1, 1, []uint{4}, nil))
+	assert.Equal(t, []uint{4}, docs.added[1])
+
+	// 可读但不可编辑的文档不能改标签
+	assert.ErrorIs(t, svc.ManageTags(ctx, 2, 1, []uint{5}, nil), ErrDocumentEditForbidden)
+	assert.ErrorIs(t, svc.ManageTagsByName(ctx, 2, 1, []string{"spam"}, nil), ErrDocumentEditForbidden)
+	assert.ErrorIs(t, svc.ManageTags(ctx, 1, 9, []uint{5}, nil), ErrDocumentNotFound)
+
+	locks.locks[1] = models.DocumentLock{DocumentID: 1, OwnerID: 3, ExpiresAt: time.Now().Add(time.Hour)}
+	assert.ErrorIs(t, svc.ManageTags(ctx, 1, 1, []uint{5}, nil), ErrDocumentLocked)
+	assert.Equal(t, []uint{4}, docs.added[1])
+}
@@ -0,0 +1,467 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Zhaoyikaiii/docmind/internal/export"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/storage"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	exportTimeout = 10 * time.Minute
+	// exportDownloadURL is the URL downloading the file of a finished job
+	exportDownloadURL = "/api/v1/exports/%d/download"
+
+	// DefaultMaxSyncExportDocuments and DefaultMaxSyncExportBytes bound the
+	// exports returned directly when no limits are configured
+	DefaultMaxSyncExportDocuments = 20
+	DefaultMaxSyncExportBytes     = 10 << 20
+)
+
+var (
+	ErrUnsupportedExportFormat = errors.New("unsupported export format")
+	ErrExportJobNotFound       = errors.New("export job not found")
+	ErrExportNotReady          = errors.New("export is not finished")
+)
+
+// ExportRequest selects what to export. With Subtree set, the document and
+// its descendants are exported as a ZIP archive. Async asks for a job even
+// when the export is small.
+type ExportRequest struct {
+	DocumentID uint
+	UserID     uint
+	Format     string
+	Subtree    bool
+	Async      bool
+}
+
+// ExportResult is either an exported file or, for large exports, the job
+// producing it
+type ExportResult struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+	Job         *models.ExportJob
+}
+
+type ExportService interface {
+	// Export exports a document the user may read. Exports of more documents
+	// or bytes than the configured limits run as background jobs.
+	Export(ctx context.Context, req ExportRequest) (*ExportResult, error)
+	// GetJob returns an export job of the user
+	GetJob(ctx context.Context, id uint, userID uint) (*models.ExportJob, error)
+	// OpenJob returns a finished export job of the user with a reader of the
+	// exported file; the caller closes the reader
+	OpenJob(ctx context.Context, id uint, userID uint) (*models.ExportJob, io.ReadCloser, error)
+}
+
+// ExportOptions bounds the exports returned directly
+type ExportOptions struct {
+	MaxSyncDocuments int
+	MaxSyncBytes     int64
+}
+
+// DocumentExporter exports documents with the exporter registered for the
+// requested format. Files attached to the documents are passed to the
+// exporter, which inlines or bundles them. Subtree exports are ZIP archives
+// in which the children of a document are in a folder named after it.
+type DocumentExporter struct {
+	repo         repository.DocumentRepository
+	fileRepo     repository.FileRepository
+	jobs         repository.ExportJobRepository
+	fileOperator storage.FileOperator
+	renderer     RenderService
+	exporters    *export.Registry
+	opts         ExportOptions
+	queue        *documentQueue
+}
+
+func NewDocumentExporter(repo repository.DocumentRepository, fileRepo repository.FileRepository, jobs repository.ExportJobRepository,
+	fileOperator storage.FileOperator, renderer RenderService, exporters *export.Registry, opts ExportOptions) *DocumentExporter {
+	if opts.MaxSyncDocuments <= 0 {
+		opts.MaxSyncDocuments = DefaultMaxSyncExportDocuments
+	}
+	if opts.MaxSyncBytes <= 0 {
+		opts.MaxSyncBytes = DefaultMaxSyncExportBytes
+	}
+	s := &DocumentExporter{
+		repo:         repo,
+		fileRepo:     fileRepo,
+		jobs:         jobs,
+		fileOperator: fileOperator,
+		renderer:     renderer,
+		exporters:    exporters,
+		opts:         opts,
+	}
+	s.queue = newDocumentQueue("export document", exportTimeout, s.run)
+	return s
+}
+
+// Close runs the queued jobs and stops the worker
+func (s *DocumentExporter) Close() {
+	s.queue.close()
+}
+
+func (s *DocumentExporter) Export(ctx context.Context, req ExportRequest) (*ExportResult, error) {
+	if _, err := s.exporters.Get(req.Format); err != nil {
+		return nil, ErrUnsupportedExportFormat
+	}
+	req.Format = strings.ToLower(req.Format)
+
+	docs, files, err := s.collect(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(0)
+	for _, doc := range docs {
+		size += int64(len(doc.Content))
+	}
+	for _, f := range files {
+		size += f.Size
+	}
+
+	if req.Async || len(docs) > s.opts.MaxSyncDocuments || size > s.opts.MaxSyncBytes {
+		job := &models.ExportJob{
+			UserID:     req.UserID,
+			DocumentID: req.DocumentID,
+			Format:     req.Format,
+			Subtree:    req.Subtree,
+			Status:     models.ExportStatusPending,
+		}
+		if err := s.jobs.Create(ctx, job); err != nil {
+			return nil, err
+		}
+		if err := s.queue.enqueue(job.ID); err != nil {
+			job.Status = models.ExportStatusFailed
+			job.Error = err.Error()
+			s.jobs.Update(ctx, job)
+			return nil, err
+		}
+		return &ExportResult{Job: job}, nil
+	}
+
+	return s.build(ctx, req, docs, files)
+}
+
+func (s *DocumentExporter) GetJob(ctx context.Context, id uint, userID uint) (*models.ExportJob, error) {
+	job, err := s.jobs.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 其他用户的任务按不存在处理
+	if job.UserID != userID {
+		return nil, ErrExportJobNotFound
+	}
+	if job.Status == models.ExportStatusDone {
+		job.DownloadURL = fmt.Sprintf(exportDownloadURL, job.ID)
+	}
+	return job, nil
+}
+
+func (s *DocumentExporter) OpenJob(ctx context.Context, id uint, userID uint) (*models.ExportJob, io.ReadCloser, error) {
+	job, err := s.GetJob(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.ExportStatusDone {
+		return nil, nil, ErrExportNotReady
+	}
+	rc, err := s.fileOperator.GetFile(job.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	return job, rc, nil
+}
+
+// run performs a queued export job and stores the exported file
+func (s *DocumentExporter) run(ctx context.Context, id uint) error {
+	job, err := s.jobs.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job.Status = models.ExportStatusRunning
+	if err := s.jobs.Update(ctx, job); err != nil {
+		return err
+	}
+
+	result, err := s.runJob(ctx, job)
+	now := time.Now()
+	job.CompletedAt = &now
+	if err != nil {
+		job.Status = models.ExportStatusFailed
+		job.Error = truncateError(err)
+		if updateErr := s.jobs.Update(context.WithoutCancel(ctx), job); updateErr != nil {
+			utils.Logger.Error("Failed to record export failure", zap.Uint("job_id", job.ID), zap.Error(updateErr))
+		}
+		return err
+	}
+
+	storagePath, err := s.fileOperator.SaveFile(memoryFile{bytes.NewReader(result.Data)}, result.FileName)
+	if err != nil {
+		job.Status = models.ExportStatusFailed
+		job.Error = "failed to store export"
+		s.jobs.Update(context.WithoutCancel(ctx), job)
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+	job.Status = models.ExportStatusDone
+	job.FileName = result.FileName
+	job.ContentType = result.ContentType
+	job.Path = storagePath
+	job.Size = int64(len(result.Data))
+	return s.jobs.Update(ctx, job)
+}
+
+func (s *DocumentExporter) runJob(ctx context.Context, job *models.ExportJob) (*ExportResult, error) {
+	req := ExportRequest{DocumentID: job.DocumentID, UserID: job.UserID, Format: job.Format, Subtree: job.Subtree}
+	docs, files, err := s.collect(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, req, docs, files)
+}
+
+// collect loads the documents to export, parents before children, and the
+// files attached to them. Descendants the user may not read are left out
+// together with their own descendants.
+func (s *DocumentExporter) collect(ctx context.Context, req ExportRequest) ([]models.Document, []models.File, error) {
+	ids := []uint{req.DocumentID}
+	if req.Subtree {
+		subtree, err := s.repo.SubtreeIDs(ctx, req.DocumentID)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = subtree
+	}
+
+	readable, err := s.repo.FilterIDs(ctx, repository.DocumentListParams{IDs: ids, ReadableBy: &req.UserID})
+	if err != nil {
+		return nil, nil, err
+	}
+	allowed := make(map[uint]bool, len(readable))
+	for _, id := range readable {
+		allowed[id] = true
+	}
+	if !allowed[req.DocumentID] {
+		return nil, nil, ErrDocumentNotFound
+	}
+
+	loaded, err := s.repo.GetByIDs(ctx, readable)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uint]models.Document, len(loaded))
+	for _, doc := range loaded {
+		byID[doc.ID] = doc
+	}
+
+	children := make(map[uint][]uint)
+	for id, doc := range byID {
+		if id != req.DocumentID && doc.ParentID != nil {
+			children[*doc.ParentID] = append(children[*doc.ParentID], id)
+		}
+	}
+
+	// 从根文档深度优先遍历，不可读文档的子文档不会被访问到
+	var docs []models.Document
+	var visit func(id uint)
+	visit = func(id uint) {
+		docs = append(docs, byID[id])
+		kids := children[id]
+		slices.Sort(kids)
+		for _, child := range kids {
+			visit(child)
+		}
+	}
+	visit(req.DocumentID)
+
+	docIDs := make([]uint, 0, len(docs))
+	for _, doc := range docs {
+		docIDs = append(docIDs, doc.ID)
+	}
+	files, err := s.fileRepo.ListByDocuments(ctx, docIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return docs, files, nil
+}
+
+// build exports a single document, or the documents as a ZIP archive for
+// subtree exports
+func (s *DocumentExporter) build(ctx context.Context, req ExportRequest, docs []models.Document, files []models.File) (*ExportResult, error) {
+	exporter, err := s.exporters.Get(req.Format)
+	if err != nil {
+		return nil, ErrUnsupportedExportFormat
+	}
+	attached := make(map[uint][]models.File)
+	for _, f := range files {
+		if f.DocumentID != nil {
+			attached[*f.DocumentID] = append(attached[*f.DocumentID], f)
+		}
+	}
+
+	if !req.Subtree {
+		var buf bytes.Buffer
+		if err := s.exportDocument(ctx, req.UserID, &buf, exporter, &docs[0], attached[docs[0].ID]); err != nil {
+			return nil, err
+		}
+		return &ExportResult{
+			FileName:    exportFileName(&docs[0]) + exporter.Extension(),
+			ContentType: exporter.ContentType(),
+			Data:        buf.Bytes(),
+		}, nil
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	// 每个文档的子文档放在以其标题命名的目录中
+	folders := make(map[uint]string, len(docs))
+	taken := make(map[string]bool)
+	for i := range docs {
+		doc := &docs[i]
+		dir := ""
+		if doc.ParentID != nil && doc.ID != req.DocumentID {
+			dir = folders[*doc.ParentID]
+		}
+		name := uniqueName(taken, dir, exportFileName(doc), exporter.Extension())
+		folders[doc.ID] = path.Join(dir, name) + "/"
+
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     path.Join(dir, name+exporter.Extension()),
+			Method:   zip.Deflate,
+			Modified: doc.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.exportDocument(ctx, req.UserID, w, exporter, doc, attached[doc.ID]); err != nil {
+			return nil, fmt.Errorf("failed to export document %d: %w", doc.ID, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return &ExportResult{
+		FileName:    exportFileName(&docs[0]) + ".zip",
+		ContentType: "application/zip",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+func (s *DocumentExporter) exportDocument(ctx context.Context, userID uint, w io.Writer, exporter export.Exporter, doc *models.Document, files []models.File) error {
+	rendered, err := s.renderer.RenderDocument(ctx, userID, doc.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	exported := &export.Document{
+		ID:        doc.ID,
+		Title:     doc.Title,
+		Markdown:  doc.Content,
+		HTML:      rendered.HTML,
+		TOC:       rendered.TOC,
+		Status:    doc.Status,
+		CreatedAt: doc.CreatedAt,
+		UpdatedAt: doc.UpdatedAt,
+	}
+	author := &doc.Creator
+	if doc.Author != nil {
+		author = doc.Author
+	}
+	exported.Author = author.FullName
+	if exported.Author == "" {
+		exported.Author = author.Username
+	}
+	for _, tag := range doc.Tags {
+		exported.Tags = append(exported.Tags, tag.Name)
+	}
+
+	for _, f := range files {
+		data, err := s.readFile(&f)
+		if err != nil {
+			return err
+		}
+		exported.Attachments = append(exported.Attachments, export.Attachment{
+			ID:          f.ID,
+			Name:        f.OriginalName,
+			ContentType: f.ContentType,
+			URL:         fmt.Sprintf(fileContentURL, f.ID),
+			Data:        data,
+		})
+	}
+
+	return exporter.Export(ctx, w, exported)
+}
+
+func (s *DocumentExporter) readFile(file *models.File) ([]byte, error) {
+	rc, err := s.fileOperator.GetFile(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %d: %w", file.ID, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// maxExportNameLength caps the length of file names derived from titles
+const maxExportNameLength = 100
+
+// exportFileName derives a file name without extension from the title of a
+// document, safe on common file systems
+func exportFileName(doc *models.Document) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, doc.Title)
+	name = strings.Trim(name, " .")
+	if utf8.RuneCountInString(name) > maxExportNameLength {
+		name = strings.TrimRight(string([]rune(name)[:maxExportNameLength]), " .")
+	}
+	if name == "" {
+		name = fmt.Sprintf("document-%d", doc.ID)
+	}
+	return name
+}
+
+// uniqueName returns name, numbered when a document with the same name is
+// already in dir
+func uniqueName(taken map[string]bool, dir, name, ext string) string {
+	candidate := name
+	for i := 2; taken[strings.ToLower(path.Join(dir, candidate+ext))]; i++ {
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+	taken[strings.ToLower(path.Join(dir, candidate+ext))] = true
+	return candidate
+}
+
+// truncateError returns the message of err cut to fit ExportJob.Error
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) <= 255 {
+		return msg
+	}
+	return strings.ToValidUTF8(msg[:255], "")
+}
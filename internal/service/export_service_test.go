@@ -0,0 +1,161 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/export"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/render"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func (r *stubTreeRepository) GetByID(ctx context.Context, id uint) (*models.Document, error) {
+	doc, ok := r.docs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &doc, nil
+}
+
+// stubExportJobRepository keeps export jobs in memory
+type stubExportJobRepository struct {
+	jobs map[uint]models.ExportJob
+}
+
+func (r *stubExportJobRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	job.ID = uint(len(r.jobs) + 1)
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *stubExportJobRepository) Update(ctx context.Context, job *models.ExportJob) error {
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *stubExportJobRepository) GetByID(ctx context.Context, id uint) (*models.ExportJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &job, nil
+}
+
+func newTestExporter(t *testing.T, opts ExportOptions) (*DocumentExporter, *stubExportJobRepository) {
+	t.Helper()
+	utils.Logger = zap.NewNop()
+
+	parent := func(id uint) *uint { return &id }
+	repo := &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Guide", Content: "# Guide\n\n![logo](/api/v1/files/1/content)\n", CreatorID: 1, Status: models.DocumentStatusDraft,
+			Creator: models.User{Username: "alice"}, Tags: []models.Tag{{Name: "ops"}}},
+		2: {ID: 2, Title: "Setup", Content: "Install it.", CreatorID: 2, Status: models.DocumentStatusPublished, ParentID: parent(1)},
+		3: {ID: 3, Title: "setup", Content: "Configure it.", CreatorID: 1, ParentID: parent(1)},
+		4: {ID: 4, Title: "Linux/macOS", Content: "Use the shell.", CreatorID: 1, ParentID: parent(2)},
+		5: {ID: 5, Title: "Private", Content: "Hidden.", CreatorID: 2, Status: models.DocumentStatusDraft, ParentID: parent(1)},
+		6: {ID: 6, Title: "Under private", Content: "Hidden too.", CreatorID: 2, Status: models.DocumentStatusPublished, ParentID: parent(5)},
+	}}}
+	files := &stubFileRepository{files: map[uint]*models.File{
+		1: {ID: 1, OriginalName: "logo.png", ContentType: "image/png", Path: "uploads/logo.png", Size: 4, DocumentID: parent(1)},
+	}}
+	operator := &stubFileOperator{contents: map[string][]byte{"uploads/logo.png": []byte("logo")}}
+	jobs := &stubExportJobRepository{jobs: make(map[uint]models.ExportJob)}
+	renderer := NewRenderService(repo, render.NewMarkdownRenderer(), 0)
+
+	return NewDocumentExporter(repo, files, jobs, operator, renderer, export.NewRegistry(), opts), jobs
+}
+
+func zipEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	entries := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		entries[f.Name] = string(content)
+	}
+	return entries
+}
+
+func TestExportDocument(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestExporter(t, ExportOptions{})
+	defer svc.Close()
+
+	result, err := svc.Export(ctx, ExportRequest{DocumentID: 1, UserID: 1, Format: "MD"})
+	require.NoError(t, err)
+	require.Nil(t, result.Job)
+	assert.Equal(t, "Guide.md", result.FileName)
+	assert.Equal(t, "text/markdown; charset=utf-8", result.ContentType)
+	assert.Contains(t, string(result.Data), "![logo](data:image/png;base64,bG9nbw==)")
+	assert.Contains(t, string(result.Data), "author: alice")
+
+	_, err = svc.Export(ctx, ExportRequest{DocumentID: 1, UserID: 2, Format: "md"})
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	_, err = svc.Export(ctx, ExportRequest{DocumentID: 1, UserID: 1, Format: "docx"})
+	assert.ErrorIs(t, err, ErrUnsupportedExportFormat)
+}
+
+func TestExportSubtree(t *testing.T) {
+	svc, _ := newTestExporter(t, ExportOptions{})
+	defer svc.Close()
+
+	result, err := svc.Export(context.Background(), ExportRequest{DocumentID: 1, UserID: 1, Format: "html", Subtree: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Guide.zip", result.FileName)
+	assert.Equal(t, "application/zip", result.ContentType)
+
+	entries := zipEntries(t, result.Data)
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	// 无权读取的文档连同其子文档一起跳过
+	assert.Equal(t, []string{"Guide.html", "Guide/Setup.html", "Guide/Setup/Linux_macOS.html", "Guide/setup (2).html"}, names)
+	assert.Contains(t, entries["Guide/Setup/Linux_macOS.html"], "Use the shell.")
+}
+
+func TestExportJob(t *testing.T) {
+	ctx := context.Background()
+	svc, jobs := newTestExporter(t, ExportOptions{MaxSyncDocuments: 2})
+
+	result, err := svc.Export(ctx, ExportRequest{DocumentID: 1, UserID: 1, Format: "md", Subtree: true})
+	require.NoError(t, err)
+	require.NotNil(t, result.Job)
+	assert.Equal(t, models.ExportStatusPending, result.Job.Status)
+	assert.Nil(t, result.Data)
+
+	// 关闭时执行完队列中的任务
+	svc.Close()
+	assert.Equal(t, models.ExportStatusDone, jobs.jobs[result.Job.ID].Status)
+
+	_, _, err = svc.OpenJob(ctx, result.Job.ID, 2)
+	assert.ErrorIs(t, err, ErrExportJobNotFound)
+
+	job, err := svc.GetJob(ctx, result.Job.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Guide.zip", job.FileName)
+	assert.Equal(t, "/api/v1/exports/1/download", job.DownloadURL)
+
+	job, rc, err := svc.OpenJob(ctx, job.ID, 1)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, job.Size, int64(len(data)))
+	assert.Contains(t, zipEntries(t, data), "Guide/Setup/Linux_macOS.md")
+}
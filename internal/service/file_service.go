@@ -1,50 +1,163 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
+	"path/filepath"
+	"strings"
 
+	"github.com/Zhaoyikaiii/docmind/internal/extract"
 	"github.com/Zhaoyikaiii/docmind/internal/models"
 	"github.com/Zhaoyikaiii/docmind/internal/repository"
 	"github.com/Zhaoyikaiii/docmind/internal/storage"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fileContentURL is the URL serving the content of a file, used to
+// reference images of imported documents
+const fileContentURL = "/api/v1/files/%d/content"
+
+// maxImportSize caps the size of an original file read for import
+const maxImportSize = 64 << 20
+
+var (
+	ErrFileNotFound    = errors.New("file not found")
+	ErrFileForbidden   = errors.New("not allowed to import this file")
+	ErrNoExtractedText = errors.New("file has no extracted text")
 )
 
 type FileService interface {
 	CreateFile(ctx context.Context, file *models.File, uploadedFile multipart.File) error
 	GetFile(ctx context.Context, id uint) (*models.File, error)
+	// OpenFile returns a file with a reader of its content; the caller closes
+	// the reader. Only the uploader and users who can read the document the
+	// file is attached to may open it; others get ErrFileNotFound.
+	OpenFile(ctx context.Context, id uint, userID uint) (*models.File, io.ReadCloser, error)
+	// GetFileText returns a file with its extracted text under the same
+	// access rules as OpenFile
+	GetFileText(ctx context.Context, id uint, userID uint) (*models.File, error)
 	DeleteFile(ctx context.Context, id uint, userID uint) error
 	ListFiles(ctx context.Context, params models.FileListParams) ([]models.File, int64, error)
 	UpdateFile(ctx context.Context, file *models.File) error
+	// ImportAsDocument creates a document of the user from one of their files
+	// and associates the file with it. Word documents are converted to
+	// Markdown with their images saved as files attached to the document;
+	// other files use their extracted text. The title defaults to the file
+	// name without its extension.
+	ImportAsDocument(ctx context.Context, fileID uint, userID uint, title string) (*models.Document, error)
 }
 
 type fileService struct {
 	repo         repository.FileRepository
 	fileOperator storage.FileOperator
+	extractors   *extract.Registry
+	docService   DocumentService
+	docRepo      repository.DocumentRepository
 }
 
-func NewFileService(repo repository.FileRepository, fileOperator storage.FileOperator) FileService {
+// NewFileService creates the file service. When extractors is nil, no text
+// is extracted from uploaded files.
+func NewFileService(repo repository.FileRepository, fileOperator storage.FileOperator, extractors *extract.Registry, docService DocumentService, docRepo repository.DocumentRepository) FileService {
 	return &fileService{
 		repo:         repo,
 		fileOperator: fileOperator,
+		extractors:   extractors,
+		docService:   docService,
+		docRepo:      docRepo,
 	}
 }
 
 func (s *fileService) CreateFile(ctx context.Context, file *models.File, uploadedFile multipart.File) error {
+	// 调用方可能已读取过上传内容
+	if _, err := uploadedFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
 	storagePath, err := s.fileOperator.SaveFile(uploadedFile, file.OriginalName)
 	if err != nil {
 		return fmt.Errorf("failed to save file: %w", err)
 	}
 
 	file.Path = storagePath
+	s.extractText(ctx, file, uploadedFile)
 	return s.repo.Create(ctx, file)
 }
 
+// extractText stores the text of r in file. Extraction failures are recorded
+// on the file instead of failing the upload.
+func (s *fileService) extractText(ctx context.Context, file *models.File, r io.ReaderAt) {
+	if s.extractors == nil {
+		return
+	}
+
+	text, err := s.extractors.Extract(ctx, file.OriginalName, r, file.Size)
+	switch {
+	case errors.Is(err, extract.ErrUnsupportedFormat):
+		file.TextStatus = models.TextStatusUnsupported
+	case err != nil:
+		utils.Logger.Warn("Failed to extract file text",
+			zap.Error(err),
+			zap.String("filename", file.OriginalName))
+		file.TextStatus = models.TextStatusFailed
+		file.TextError = truncateRunes(err.Error(), 255)
+	default:
+		file.TextStatus = models.TextStatusExtracted
+		file.Text = text
+	}
+}
+
 func (s *fileService) GetFile(ctx context.Context, id uint) (*models.File, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
+func (s *fileService) OpenFile(ctx context.Context, id uint, userID uint) (*models.File, io.ReadCloser, error) {
+	file, err := s.readableFile(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, err := s.fileOperator.GetFile(file.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, rc, nil
+}
+
+func (s *fileService) GetFileText(ctx context.Context, id uint, userID uint) (*models.File, error) {
+	return s.readableFile(ctx, id, userID)
+}
+
+// readableFile returns the file if userID uploaded it or can read the
+// document it is attached to, and ErrFileNotFound otherwise so that
+// inaccessible files are indistinguishable from missing ones
+func (s *fileService) readableFile(ctx context.Context, id uint, userID uint) (*models.File, error) {
+	file, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file.UploaderID == userID {
+		return file, nil
+	}
+	if file.DocumentID == nil {
+		return nil, ErrFileNotFound
+	}
+	if _, err := readableDocument(ctx, s.docRepo, userID, *file.DocumentID); err != nil {
+		if errors.Is(err, ErrDocumentNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
 func (s *fileService) DeleteFile(ctx context.Context, id uint, userID uint) error {
 	file, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -88,3 +201,146 @@ func (s *fileService) UpdateFile(ctx context.Context, file *models.File) error {
 
 	return s.repo.Update(ctx, existing)
 }
+
+func (s *fileService) ImportAsDocument(ctx context.Context, fileID uint, userID uint, title string) (*models.Document, error) {
+	file, err := s.repo.GetByID(ctx, fileID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if file.UploaderID != userID {
+		return nil, ErrFileForbidden
+	}
+
+	content, images, err := s.importContent(ctx, file, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if title = strings.TrimSpace(title); title == "" {
+		title = strings.TrimSuffix(file.OriginalName, filepath.Ext(file.OriginalName))
+	}
+	doc := &models.Document{
+		Title:     truncateRunes(title, 255),
+		Content:   content,
+		CreatorID: userID,
+	}
+	if err := s.docService.CreateDocument(ctx, doc); err != nil {
+		s.discardFiles(ctx, images)
+		return nil, err
+	}
+
+	for _, f := range append(images, file) {
+		f.DocumentID = &doc.ID
+		if err := s.repo.Update(ctx, f); err != nil {
+			return nil, fmt.Errorf("failed to associate file with document: %w", err)
+		}
+	}
+	return doc, nil
+}
+
+// importContent returns the Markdown content of a document imported from
+// file and the image files it references. Word documents that cannot be
+// converted fall back to their extracted text.
+func (s *fileService) importContent(ctx context.Context, file *models.File, userID uint) (string, []*models.File, error) {
+	if strings.EqualFold(filepath.Ext(file.OriginalName), ".docx") {
+		markdown, images, err := s.convertDOCX(ctx, file, userID)
+		if err == nil && strings.TrimSpace(markdown) != "" {
+			return markdown, images, nil
+		}
+		if err != nil {
+			utils.Logger.Warn("Failed to convert DOCX to Markdown",
+				zap.Error(err),
+				zap.Uint("file_id", file.ID))
+		}
+		s.discardFiles(ctx, images)
+	}
+
+	if file.TextStatus != models.TextStatusExtracted || strings.TrimSpace(file.Text) == "" {
+		return "", nil, ErrNoExtractedText
+	}
+	return file.Text, nil, nil
+}
+
+func (s *fileService) convertDOCX(ctx context.Context, file *models.File, userID uint) (string, []*models.File, error) {
+	rc, err := s.fileOperator.GetFile(file.Path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxImportSize {
+		return "", nil, errors.New("file too large to import")
+	}
+
+	var images []*models.File
+	saveImage := func(ctx context.Context, image extract.Image) (string, error) {
+		img, err := s.saveImage(ctx, image, userID)
+		if err != nil {
+			return "", err
+		}
+		images = append(images, img)
+		return fmt.Sprintf(fileContentURL, img.ID), nil
+	}
+
+	markdown, err := extract.DOCXToMarkdown(ctx, bytes.NewReader(data), int64(len(data)), saveImage)
+	return markdown, images, err
+}
+
+// saveImage stores an image embedded in an imported document as a file of
+// the user
+func (s *fileService) saveImage(ctx context.Context, image extract.Image, userID uint) (*models.File, error) {
+	return storeFile(ctx, s.repo, s.fileOperator, image.Name, image.ContentType, image.Data, userID)
+}
+
+// discardFiles removes files saved by an import that did not complete
+func (s *fileService) discardFiles(ctx context.Context, files []*models.File) {
+	discardFiles(ctx, s.repo, s.fileOperator, files)
+}
+
+// storeFile saves data as a new file of the user
+func storeFile(ctx context.Context, repo repository.FileRepository, fileOperator storage.FileOperator, name, contentType string, data []byte, userID uint) (*models.File, error) {
+	storagePath, err := fileOperator.SaveFile(memoryFile{bytes.NewReader(data)}, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+
+	file := &models.File{
+		OriginalName: name,
+		StorageName:  filepath.Base(storagePath),
+		Path:         storagePath,
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		UploaderID:   userID,
+	}
+	if err := repo.Create(ctx, file); err != nil {
+		fileOperator.DeleteFile(storagePath)
+		return nil, err
+	}
+	return file, nil
+}
+
+func discardFiles(ctx context.Context, repo repository.FileRepository, fileOperator storage.FileOperator, files []*models.File) {
+	for _, f := range files {
+		if err := fileOperator.DeleteFile(f.Path); err != nil {
+			utils.Logger.Warn("Failed to delete file", zap.Error(err), zap.String("path", f.Path))
+		}
+		if err := repo.Delete(ctx, f.ID); err != nil {
+			utils.Logger.Warn("Failed to delete file record", zap.Error(err), zap.Uint("file_id", f.ID))
+		}
+	}
+}
+
+// memoryFile adapts in-memory content to multipart.File for storage
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"slices"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/extract"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/storage"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// stubFileRepository keeps files in memory
+type stubFileRepository struct {
+	repository.FileRepository
+	files map[uint]*models.File
+}
+
+func (r *stubFileRepository) Create(ctx context.Context, file *models.File) error {
+	file.ID = uint(len(r.files) + 1)
+	r.files[file.ID] = file
+	return nil
+}
+
+func (r *stubFileRepository) Update(ctx context.Context, file *models.File) error {
+	r.files[file.ID] = file
+	return nil
+}
+
+func (r *stubFileRepository) GetByID(ctx context.Context, id uint) (*models.File, error) {
+	file, ok := r.files[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *file
+	return &copied, nil
+}
+
+func (r *stubFileRepository) Delete(ctx context.Context, id uint) error {
+	delete(r.files, id)
+	return nil
+}
+
+func (r *stubFileRepository) ListByDocuments(ctx context.Context, documentIDs []uint) ([]models.File, error) {
+	var files []models.File
+	for id := uint(1); id <= uint(len(r.files)); id++ {
+		file, ok := r.files[id]
+		if ok && file.DocumentID != nil && slices.Contains(documentIDs, *file.DocumentID) {
+			files = append(files, *file)
+		}
+	}
+	return files, nil
+}
+
+// stubFileOperator stores file contents in memory
+type stubFileOperator struct {
+	storage.FileOperator
+	contents map[string][]byte
+}
+
+func (o *stubFileOperator) SaveFile(file multipart.File, filename string) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("uploads/%d-%s", len(o.contents), filename)
+	o.contents[path] = data
+	return path, nil
+}
+
+func (o *stubFileOperator) GetFile(path string) (io.ReadCloser, error) {
+	data, ok := o.contents[path]
+	if !ok {
+		return nil, errors.New("no such file")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (o *stubFileOperator) DeleteFile(path string) error {
+	delete(o.contents, path)
+	return nil
+}
+
+// stubDocumentCreator records the documents created through it
+type stubDocumentCreator struct {
+	DocumentService
+	created []*models.Document
+}
+
+func (s *stubDocumentCreator) CreateDocument(ctx context.Context, doc *models.Document) error {
+	for _, existing := range s.created {
+		if existing.Title == doc.Title && existing.CreatorID == doc.CreatorID {
+			return ErrDocumentTitleExists
+		}
+	}
+	doc.ID = uint(len(s.created) + 10)
+	s.created = append(s.created, doc)
+	return nil
+}
+
+func TestFileServiceExtractAndImport(t *testing.T) {
+	utils.Logger = zap.NewNop()
+	ctx := context.Background()
+	repo := &stubFileRepository{files: make(map[uint]*models.File)}
+	docs := &stubDocumentCreator{}
+	svc := NewFileService(repo, &stubFileOperator{contents: make(map[string][]byte)}, extract.NewRegistry(), docs, &stubDocumentRepository{})
+
+	upload := func(name, content string) *models.File {
+		file := &models.File{OriginalName: name, Size: int64(len(content)), UploaderID: 1}
+		require.NoError(t, svc.CreateFile(ctx, file, memoryFile{bytes.NewReader([]byte(content))}))
+		return file
+	}
+
+	notes := upload("release-notes.md", "# Release 2.0\n\nAdds text extraction.\n")
+	assert.Equal(t, models.TextStatusExtracted, notes.TextStatus)
+	assert.Equal(t, "# Release 2.0\n\nAdds text extraction.", notes.Text)
+
+	legacy := upload("legacy.doc", "\xD0\xCF\x11\xE0")
+	assert.Equal(t, models.TextStatusUnsupported, legacy.TextStatus)
+
+	broken := upload("broken.docx", "not a zip archive")
+	assert.Equal(t, models.TextStatusFailed, broken.TextStatus)
+	assert.NotEmpty(t, broken.TextError)
+
+	doc, err := svc.ImportAsDocument(ctx, notes.ID, 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, "release-notes", doc.Title)
+	assert.Equal(t, notes.Text, doc.Content)
+	assert.Equal(t, doc.ID, *repo.files[notes.ID].DocumentID)
+
+	_, err = svc.ImportAsDocument(ctx, notes.ID, 1, "")
+	assert.ErrorIs(t, err, ErrDocumentTitleExists)
+
+	doc, err = svc.ImportAsDocument(ctx, notes.ID, 1, "  Release 2.0  ")
+	require.NoError(t, err)
+	assert.Equal(t, "Release 2.0", doc.Title)
+
+	_, err = svc.ImportAsDocument(ctx, notes.ID, 2, "")
+	assert.ErrorIs(t, err, ErrFileForbidden)
+
+	_, err = svc.ImportAsDocument(ctx, legacy.ID, 1, "")
+	assert.ErrorIs(t, err, ErrNoExtractedText)
+
+	_, err = svc.ImportAsDocument(ctx, 99, 1, "")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestFileServiceAccess(t *testing.T) {
+	ctx := context.Background()
+	draft, published := uint(1), uint(2)
+	repo := &stubFileRepository{files: map[uint]*models.File{
+		1: {ID: 1, Path: "uploads/plan.png", UploaderID: 5, DocumentID: &draft},
+		2: {ID: 2, Path: "uploads/diagram.png", UploaderID: 5, DocumentID: &published},
+		3: {ID: 3, Path: "uploads/loose.pdf", UploaderID: 5},
+	}}
+	operator := &stubFileOperator{contents: map[string][]byte{
+		"uploads/plan.png":    []byte("plan"),
+		"uploads/diagram.png": []byte("diagram"),
+		"uploads/loose.pdf":   []byte("loose"),
+	}}
+	docRepo := &stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Status: models.DocumentStatusDraft, CreatorID: 5},
+		2: {ID: 2, Status: models.DocumentStatusPublished, CreatorID: 5},
+	}}
+	svc := NewFileService(repo, operator, nil, &stubDocumentCreator{}, docRepo)
+
+	tests := []struct {
+		name    string
+		fileID  uint
+		userID  uint
+		wantErr error
+	}{
+		{name: "uploader opens unattached file", fileID: 3, userID: 5},
+		{name: "uploader opens file of draft", fileID: 1, userID: 5},
+		{name: "reader opens file of published document", fileID: 2, userID: 7},
+		{name: "file of unreadable draft is hidden", fileID: 1, userID: 7, wantErr: ErrFileNotFound},
+		{name: "unattached file of another user is hidden", fileID: 3, userID: 7, wantErr: ErrFileNotFound},
+		{name: "missing file", fileID: 9, userID: 5, wantErr: ErrFileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, rc, err := svc.OpenFile(ctx, tt.fileID, tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer rc.Close()
+			data, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Equal(t, operator.contents[file.Path], data)
+		})
+		t.Run(tt.name+" text", func(t *testing.T) {
+			file, err := svc.GetFileText(ctx, tt.fileID, tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.fileID, file.ID)
+		})
+	}
+}
+
+func buildTestDOCX(t *testing.T, parts map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	return buf.String()
+}
+
+func TestFileServiceImportDOCX(t *testing.T) {
+	utils.Logger = zap.NewNop()
+	ctx := context.Background()
+	repo := &stubFileRepository{files: make(map[uint]*models.File)}
+	operator := &stubFileOperator{contents: make(map[string][]byte)}
+	docs := &stubDocumentCreator{}
+	svc := NewFileService(repo, operator, extract.NewRegistry(), docs, &stubDocumentRepository{})
+
+	content := buildTestDOCX(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w" xmlns:r="r" xmlns:a="a"><w:body>
+<w:p><w:pPr><w:outlineLvl w:val="0"/></w:pPr><w:r><w:t>Topology</w:t></w:r></w:p>
+<w:p><w:r><w:drawing><a:blip r:embed="rId1"/></w:drawing></w:r></w:p></w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships><Relationship Id="rId1" Target="media/image1.png"/></Relationships>`,
+		"word/media/image1.png":        "\x89PNG",
+	})
+	file := &models.File{OriginalName: "topology.docx", Size: int64(len(content)), UploaderID: 1}
+	require.NoError(t, svc.CreateFile(ctx, file, memoryFile{bytes.NewReader([]byte(content))}))
+
+	doc, err := svc.ImportAsDocument(ctx, file.ID, 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, "# Topology\n\n![](/api/v1/files/2/content)", doc.Content)
+
+	image := repo.files[2]
+	assert.Equal(t, "image1.png", image.OriginalName)
+	assert.Equal(t, "image/png", image.ContentType)
+	assert.Equal(t, doc.ID, *image.DocumentID)
+	assert.Equal(t, []byte("\x89PNG"), operator.contents[image.Path])
+
+	// 标题冲突时删除已保存的图片
+	_, err = svc.ImportAsDocument(ctx, file.ID, 1, "")
+	assert.ErrorIs(t, err, ErrDocumentTitleExists)
+	assert.Len(t, repo.files, 2)
+	assert.Len(t, operator.contents, 2)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+)
+
+// homeSectionSize is the number of documents in each section of the home page
+const homeSectionSize = 10
+
+// HomeService keeps track of the documents users view and star, and builds
+// their personal lists of documents. The lists only hold documents the user
+// may read.
+type HomeService interface {
+	// RecordView stores that userID viewed a document they can read now
+	RecordView(ctx context.Context, userID, documentID uint) error
+	// Star adds a document userID can read to their favorites
+	Star(ctx context.Context, userID, documentID uint) error
+	// Unstar removes a document from the favorites of userID
+	Unstar(ctx context.Context, userID, documentID uint) error
+	// Favorites returns the favorites of userID, last starred first
+	Favorites(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+	// RecentlyViewed returns the documents userID viewed, last viewed first
+	RecentlyViewed(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+	// RecentlyUpdated returns the documents of the workspaces of userID,
+	// last updated first
+	RecentlyUpdated(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+	// AwaitingReview returns the documents userID was asked to review and
+	// hasn't yet, oldest request first
+	AwaitingReview(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error)
+	// Home returns the first documents of each list
+	Home(ctx context.Context, userID uint) (*models.Home, error)
+}
+
+type homeService struct {
+	repo    repository.HomeRepository
+	reviews repository.ReviewRequestRepository
+	docRepo repository.DocumentRepository
+}
+
+func NewHomeService(repo repository.HomeRepository, reviews repository.ReviewRequestRepository, docRepo repository.DocumentRepository) HomeService {
+	return &homeService{
+		repo:    repo,
+		reviews: reviews,
+		docRepo: docRepo,
+	}
+}
+
+func (s *homeService) RecordView(ctx context.Context, userID, documentID uint) error {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return err
+	}
+	return s.repo.RecordView(ctx, userID, documentID, time.Now())
+}
+
+func (s *homeService) Star(ctx context.Context, userID, documentID uint) error {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return err
+	}
+	return s.repo.Star(ctx, userID, documentID)
+}
+
+func (s *homeService) Unstar(ctx context.Context, userID, documentID uint) error {
+	return s.repo.Unstar(ctx, userID, documentID)
+}
+
+func (s *homeService) Favorites(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	return s.repo.ListStarred(ctx, userID, limit)
+}
+
+func (s *homeService) RecentlyViewed(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	return s.repo.ListViewed(ctx, userID, limit)
+}
+
+func (s *homeService) RecentlyUpdated(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	return s.repo.ListUpdated(ctx, userID, limit)
+}
+
+func (s *homeService) AwaitingReview(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	return s.reviews.ListPending(ctx, userID, limit)
+}
+
+func (s *homeService) Home(ctx context.Context, userID uint) (*models.Home, error) {
+	var home models.Home
+	sections := []struct {
+		items *[]models.FeedItem
+		list  func(context.Context, uint, int) ([]models.FeedItem, error)
+	}{
+		{&home.Favorites, s.Favorites},
+		{&home.RecentlyViewed, s.RecentlyViewed},
+		{&home.RecentlyUpdated, s.RecentlyUpdated},
+		{&home.AwaitingReview, s.AwaitingReview},
+	}
+	for _, section := range sections {
+		items, err := section.list(ctx, userID, homeSectionSize)
+		if err != nil {
+			return nil, err
+		}
+		if items == nil {
+			items = []models.FeedItem{}
+		}
+		*section.items = items
+	}
+	return &home, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubHomeRepository keeps views and stars in memory, in the order they
+// were made
+type stubHomeRepository struct {
+	repository.HomeRepository
+	views []models.DocumentView
+	stars []models.DocumentStar
+}
+
+func (r *stubHomeRepository) RecordView(ctx context.Context, userID, documentID uint, viewedAt time.Time) error {
+	count := 1
+	r.views = slices.DeleteFunc(r.views, func(view models.DocumentView) bool {
+		if view.UserID == userID && view.DocumentID == documentID {
+			count += view.ViewCount
+			return true
+		}
+		return false
+	})
+	r.views = append(r.views, models.DocumentView{UserID: userID, DocumentID: documentID, ViewedAt: viewedAt, ViewCount: count})
+	return nil
+}
+
+func (r *stubHomeRepository) Star(ctx context.Context, userID, documentID uint) error {
+	r.stars = append(r.stars, models.DocumentStar{UserID: userID, DocumentID: documentID, CreatedAt: time.Now()})
+	return nil
+}
+
+func (r *stubHomeRepository) Unstar(ctx context.Context, userID, documentID uint) error {
+	r.stars = slices.DeleteFunc(r.stars, func(star models.DocumentStar) bool {
+		return star.UserID == userID && star.DocumentID == documentID
+	})
+	return nil
+}
+
+func (r *stubHomeRepository) ListStarred(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	for i := len(r.stars) - 1; i >= 0 && len(items) < limit; i-- {
+		if r.stars[i].UserID == userID {
+			items = append(items, models.FeedItem{DocumentNode: models.DocumentNode{ID: r.stars[i].DocumentID}, StarredAt: &r.stars[i].CreatedAt})
+		}
+	}
+	return items, nil
+}
+
+func (r *stubHomeRepository) ListViewed(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	for i := len(r.views) - 1; i >= 0 && len(items) < limit; i-- {
+		if r.views[i].UserID == userID {
+			items = append(items, models.FeedItem{DocumentNode: models.DocumentNode{ID: r.views[i].DocumentID}, ViewedAt: &r.views[i].ViewedAt, ViewCount: r.views[i].ViewCount})
+		}
+	}
+	return items, nil
+}
+
+func (r *stubHomeRepository) ListUpdated(ctx context.Context, userID uint, limit int) ([]models.FeedItem, error) {
+	return nil, nil
+}
+
+func TestHome(t *testing.T) {
+	ctx := context.Background()
+
+	docs := &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Status: models.DocumentStatusPublished, CreatorID: 1},
+		2: {ID: 2, Title: "Failover", Status: models.DocumentStatusPublished, CreatorID: 2},
+		3: {ID: 3, Title: "Postmortem", Status: models.DocumentStatusDraft, CreatorID: 1},
+	}}}
+	repo := &stubHomeRepository{}
+	reviews := &stubReviewRepository{requests: map[[2]uint]models.ReviewRequest{
+		{2, 1}: {DocumentID: 2, ReviewerID: 1, RequesterID: 2, Status: models.ReviewStatusPending},
+	}}
+	svc := NewHomeService(repo, reviews, docs)
+
+	require.NoError(t, svc.Star(ctx, 1, 1))
+	require.NoError(t, svc.Star(ctx, 1, 3))
+	assert.ErrorIs(t, svc.Star(ctx, 2, 3), ErrDocumentNotFound, "only readable documents are starred")
+	require.NoError(t, svc.Unstar(ctx, 1, 1))
+
+	for _, id := range []uint{1, 2, 1} {
+		require.NoError(t, svc.RecordView(ctx, 1, id))
+	}
+	assert.ErrorIs(t, svc.RecordView(ctx, 2, 3), ErrDocumentNotFound, "views of unreadable documents aren't recorded")
+	assert.ErrorIs(t, svc.RecordView(ctx, 1, 9), ErrDocumentNotFound)
+	assert.Len(t, repo.views, 2)
+
+	home, err := svc.Home(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{3}, feedIDs(home.Favorites))
+	assert.Equal(t, []uint{1, 2}, feedIDs(home.RecentlyViewed), "last viewed first")
+	assert.Equal(t, 2, home.RecentlyViewed[0].ViewCount)
+	assert.NotNil(t, home.RecentlyUpdated, "empty sections are empty lists")
+	assert.Empty(t, home.RecentlyUpdated)
+	assert.Equal(t, []uint{2}, feedIDs(home.AwaitingReview))
+
+	viewed, err := svc.RecentlyViewed(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, feedIDs(viewed))
+}
+
+func feedIDs(items []models.FeedItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
@@ -0,0 +1,570 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/importer"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/storage"
+)
+
+const (
+	// maxDocumentPathLength is the size of the path column of documents
+	maxDocumentPathLength = 255
+	// documentURL is where links between imported pages point to
+	documentURL = "/api/v1/documents/%d"
+)
+
+var errParentNotImported = errors.New("parent page could not be imported")
+
+// ImportRequest describes an import. Format names the format of the
+// archive, such as "confluence" or "notion"; it is detected when empty.
+type ImportRequest struct {
+	FS     fs.FS
+	UserID uint
+	Format string
+	DryRun bool
+}
+
+type ImportService interface {
+	// ImportTree imports the pages of an archive as documents of the user,
+	// keeping their hierarchy. Files the pages show or link to are attached
+	// to the documents and links between pages point to the documents.
+	// Pages already imported to the same path are updated when they changed,
+	// so importing an archive again is safe. With DryRun set, nothing is
+	// changed and the report tells what would be done.
+	ImportTree(ctx context.Context, req ImportRequest) (*models.ImportReport, error)
+}
+
+type importService struct {
+	repo         repository.DocumentRepository
+	docService   DocumentService
+	userRepo     repository.UserRepository
+	fileRepo     repository.FileRepository
+	fileOperator storage.FileOperator
+	formats      *importer.Registry
+}
+
+func NewImportService(repo repository.DocumentRepository, docService DocumentService, userRepo repository.UserRepository, fileRepo repository.FileRepository, fileOperator storage.FileOperator, formats *importer.Registry) ImportService {
+	return &importService{
+		repo:         repo,
+		docService:   docService,
+		userRepo:     userRepo,
+		fileRepo:     fileRepo,
+		fileOperator: fileOperator,
+		formats:      formats,
+	}
+}
+
+// importRun holds the state of one import
+type importRun struct {
+	fsys   fs.FS
+	userID uint
+	dryRun bool
+
+	existing    map[string]*models.Document
+	attachments map[uint][]models.File
+	// documentIDs maps page paths to the documents they were imported as;
+	// failed maps the paths of pages that could not be imported
+	documentIDs map[string]uint
+	failed      map[string]bool
+	titles      map[string]bool
+	// authors maps author names to users; names mapping to no single user
+	// map to nil
+	authors map[string]*uint
+	// linking are the documents with links to pages imported after them
+	linking []pendingLinks
+}
+
+// pendingLinks is a document whose links are rewritten once all pages are
+// imported. Content is the content of the page before links are rewritten.
+type pendingLinks struct {
+	entry   *models.ImportedDocument
+	page    *importer.Page
+	doc     *models.Document
+	content string
+}
+
+func (s *importService) ImportTree(ctx context.Context, req ImportRequest) (*models.ImportReport, error) {
+	format, pages, err := s.formats.Scan(req.FS, req.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(pages))
+	for _, page := range pages {
+		paths = append(paths, page.Path)
+	}
+	existing, err := s.repo.GetByPaths(ctx, req.UserID, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &importRun{
+		fsys:        req.FS,
+		userID:      req.UserID,
+		dryRun:      req.DryRun,
+		existing:    make(map[string]*models.Document, len(existing)),
+		attachments: make(map[uint][]models.File),
+		documentIDs: make(map[string]uint, len(pages)),
+		failed:      make(map[string]bool),
+		titles:      make(map[string]bool),
+	}
+	docIDs := make([]uint, 0, len(existing))
+	for i := range existing {
+		run.existing[existing[i].Path] = &existing[i]
+		run.titles[existing[i].Title] = true
+		docIDs = append(docIDs, existing[i].ID)
+	}
+	files, err := s.fileRepo.ListByDocuments(ctx, docIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		run.attachments[*f.DocumentID] = append(run.attachments[*f.DocumentID], f)
+	}
+	if run.authors, err = s.resolveAuthors(ctx, pages); err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{Format: format, DryRun: req.DryRun, Documents: make([]models.ImportedDocument, len(pages))}
+	for i, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry := &report.Documents[i]
+		if err := s.importPage(ctx, run, page, entry); err != nil {
+			run.failed[page.Path] = true
+			entry.Action = models.ImportActionFailed
+			entry.Error = err.Error()
+		}
+	}
+
+	// 链接到后导入页面的文档在全部导入后更新链接
+	for _, pending := range run.linking {
+		content, _ := s.rewriteLinks(run, pending.page, pending.content, pending.entry)
+		if content == pending.doc.Content {
+			continue
+		}
+		pending.doc.Content = content
+		if err := s.docService.UpdateDocument(ctx, pending.doc, req.UserID); err != nil {
+			pending.entry.Warnings = append(pending.entry.Warnings, fmt.Sprintf("failed to link pages: %v", err))
+		}
+	}
+
+	for _, entry := range report.Documents {
+		switch entry.Action {
+		case models.ImportActionCreate:
+			report.Created++
+		case models.ImportActionUpdate:
+			report.Updated++
+		case models.ImportActionUnchanged:
+			report.Unchanged++
+		case models.ImportActionFailed:
+			report.Failed++
+		}
+		report.Images += entry.Images
+		report.Attachments += entry.Attachments
+	}
+	return report, nil
+}
+
+// resolveAuthors maps the authors named by pages to users. A name matches
+// the username or email of a user, or else the full name of a single user.
+func (s *importService) resolveAuthors(ctx context.Context, pages []*importer.Page) (map[string]*uint, error) {
+	var names []string
+	for _, page := range pages {
+		if page.Author != "" && !slices.Contains(names, page.Author) {
+			names = append(names, page.Author)
+		}
+	}
+	users, err := s.userRepo.FindByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[string]*uint, len(names))
+	for _, name := range names {
+		var byFullName []uint
+		for _, user := range users {
+			if strings.EqualFold(user.Username, name) || strings.EqualFold(user.Email, name) {
+				id := user.ID
+				authors[name] = &id
+				break
+			}
+			if strings.EqualFold(user.FullName, name) {
+				byFullName = append(byFullName, user.ID)
+			}
+		}
+		if authors[name] == nil && len(byFullName) == 1 {
+			authors[name] = &byFullName[0]
+		}
+	}
+	return authors, nil
+}
+
+// importPage creates or updates the document of a page, filling in entry
+func (s *importService) importPage(ctx context.Context, run *importRun, page *importer.Page, entry *models.ImportedDocument) error {
+	*entry = models.ImportedDocument{
+		Path:       page.Path,
+		ParentPath: page.ParentPath,
+		Title:      truncateRunes(page.Title, 255),
+		Warnings:   page.Warnings,
+	}
+	if len(page.Path) > maxDocumentPathLength {
+		return fmt.Errorf("path longer than %d bytes", maxDocumentPathLength)
+	}
+
+	var parentID *uint
+	if page.ParentPath != "" {
+		if run.failed[page.ParentPath] {
+			return errParentNotImported
+		}
+		if id, ok := run.documentIDs[page.ParentPath]; ok {
+			parentID = &id
+		}
+	}
+
+	var tags []string
+	for _, name := range page.Tags {
+		normalized, err := normalizeTagName(name)
+		if err != nil {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("invalid tag %q", name))
+			continue
+		}
+		if !slices.Contains(tags, normalized) {
+			tags = append(tags, normalized)
+		}
+	}
+
+	existing := run.existing[page.Path]
+	status := page.Status
+	switch status {
+	case models.DocumentStatusDraft, models.DocumentStatusPublished, models.DocumentStatusArchived:
+	default:
+		if status != "" {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("unknown status %q", status))
+		}
+		status = models.DocumentStatusDraft
+		if existing != nil {
+			status = existing.Status
+		}
+	}
+
+	if page.Author != "" {
+		entry.AuthorID = run.authors[page.Author]
+		if entry.AuthorID == nil {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("author %q matches no single user", page.Author))
+		}
+	}
+	if entry.AuthorID == nil && existing != nil {
+		entry.AuthorID = existing.AuthorID
+	}
+
+	current := ""
+	if existing != nil {
+		current = existing.Title
+	}
+	title, err := s.uniqueTitle(ctx, run, entry.Title, current)
+	if err != nil {
+		return err
+	}
+	if title != entry.Title {
+		entry.Warnings = append(entry.Warnings, fmt.Sprintf("renamed to %q, the title is taken", title))
+		entry.Title = title
+	}
+	run.titles[entry.Title] = true
+
+	withFiles, uploaded, changed, err := s.attachFiles(ctx, run, page, existing)
+	if err != nil {
+		return err
+	}
+	entry.Images, entry.Attachments = uploaded.counts()
+	content, pending := s.rewriteLinks(run, page, withFiles, entry)
+
+	if existing == nil {
+		entry.Action = models.ImportActionCreate
+		if run.dryRun {
+			return nil
+		}
+		doc := &models.Document{
+			Title:     entry.Title,
+			Content:   content,
+			Status:    status,
+			CreatorID: run.userID,
+			AuthorID:  entry.AuthorID,
+			ParentID:  parentID,
+			Path:      page.Path,
+			Tags:      tagsByName(tags),
+		}
+		if err := s.docService.CreateDocument(ctx, doc); err != nil {
+			discardFiles(ctx, s.fileRepo, s.fileOperator, uploaded.all())
+			entry.Images, entry.Attachments = 0, 0
+			return err
+		}
+		entry.DocumentID = doc.ID
+		run.documentIDs[page.Path] = doc.ID
+		if pending {
+			run.linking = append(run.linking, pendingLinks{entry: entry, page: page, doc: doc, content: withFiles})
+		}
+		return s.associate(ctx, uploaded.all(), doc.ID)
+	}
+
+	entry.DocumentID = existing.ID
+	run.documentIDs[page.Path] = existing.ID
+	removed := removedTags(existing.Tags, tags)
+	if !changed && existing.Title == entry.Title && existing.Content == content && existing.Status == status &&
+		equalIDs(existing.ParentID, parentID) && equalIDs(existing.AuthorID, entry.AuthorID) &&
+		len(removed) == 0 && len(addedTags(existing.Tags, tags)) == 0 {
+		entry.Action = models.ImportActionUnchanged
+		return nil
+	}
+
+	entry.Action = models.ImportActionUpdate
+	if run.dryRun {
+		return nil
+	}
+	doc := *existing
+	doc.Title = entry.Title
+	doc.Content = content
+	doc.Status = status
+	doc.AuthorID = entry.AuthorID
+	doc.Author = nil
+	doc.ParentID = parentID
+	doc.Tags = tagsByName(tags)
+	if err := s.docService.UpdateDocument(ctx, &doc, run.userID); err != nil {
+		discardFiles(ctx, s.fileRepo, s.fileOperator, uploaded.all())
+		entry.Images, entry.Attachments = 0, 0
+		return err
+	}
+	if len(removed) > 0 {
+		if err := s.docService.ManageTagsByName(ctx, run.userID, doc.ID, nil, removed); err != nil {
+			return err
+		}
+	}
+	if pending {
+		run.linking = append(run.linking, pendingLinks{entry: entry, page: page, doc: &doc, content: withFiles})
+	}
+	return s.associate(ctx, uploaded.all(), doc.ID)
+}
+
+// pageFiles are the files uploaded for the images and attachments of a page
+type pageFiles struct {
+	images      []*models.File
+	attachments []*models.File
+}
+
+func (f *pageFiles) counts() (int, int) {
+	return len(f.images), len(f.attachments)
+}
+
+func (f *pageFiles) all() []*models.File {
+	return append(slices.Clip(f.images), f.attachments...)
+}
+
+// attachFiles rewrites the links of a page to the files it shows or links
+// to as links to attachments of its document. Files already attached to the
+// document by an earlier import are reused; others are uploaded, except on
+// dry runs, where changed reports that the content will change instead.
+func (s *importService) attachFiles(ctx context.Context, run *importRun, page *importer.Page, existing *models.Document) (content string, uploaded *pageFiles, changed bool, err error) {
+	var attached []models.File
+	if existing != nil {
+		attached = run.attachments[existing.ID]
+	}
+
+	uploaded = &pageFiles{}
+	byPath := make(map[string]string)
+	attach := func(refs []importer.File, files *[]*models.File) (map[string]string, error) {
+		urls := make(map[string]string, len(refs))
+		for _, ref := range refs {
+			if url, ok := byPath[ref.Path]; ok {
+				if url != "" {
+					urls[ref.Ref] = url
+				}
+				continue
+			}
+			info, err := fs.Stat(run.fsys, ref.Path)
+			if err != nil {
+				return nil, err
+			}
+			name := path.Base(ref.Path)
+			i := slices.IndexFunc(attached, func(f models.File) bool {
+				return f.OriginalName == name && f.Size == info.Size()
+			})
+			if i >= 0 {
+				byPath[ref.Path] = fmt.Sprintf(fileContentURL, attached[i].ID)
+				urls[ref.Ref] = byPath[ref.Path]
+				continue
+			}
+
+			if run.dryRun {
+				changed = true
+				byPath[ref.Path] = ""
+				*files = append(*files, &models.File{OriginalName: name})
+				continue
+			}
+			file, err := s.uploadFile(ctx, run, ref.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to upload %s: %w", ref.Ref, err)
+			}
+			*files = append(*files, file)
+			byPath[ref.Path] = fmt.Sprintf(fileContentURL, file.ID)
+			urls[ref.Ref] = byPath[ref.Path]
+		}
+		return urls, nil
+	}
+	replace := func(urls map[string]string) func(string) (string, bool) {
+		return func(ref string) (string, bool) {
+			url, ok := urls[ref]
+			return url, ok
+		}
+	}
+
+	images, err := attach(page.Images, &uploaded.images)
+	var attachments map[string]string
+	if err == nil {
+		attachments, err = attach(page.Attachments, &uploaded.attachments)
+	}
+	if err != nil {
+		if !run.dryRun {
+			discardFiles(ctx, s.fileRepo, s.fileOperator, uploaded.all())
+		}
+		return "", nil, false, err
+	}
+
+	content = importer.RewriteImages(page.Content, replace(images))
+	content = importer.RewriteLinks(content, replace(attachments))
+	return content, uploaded, changed, nil
+}
+
+// rewriteLinks points the links of a page to other pages at their
+// documents. pending reports links to pages not imported yet, which are
+// left as they are; links to pages that failed are left with a warning.
+func (s *importService) rewriteLinks(run *importRun, page *importer.Page, content string, entry *models.ImportedDocument) (string, bool) {
+	pending := false
+	urls := make(map[string]string, len(page.Links))
+	for _, link := range page.Links {
+		id, ok := run.documentIDs[link.Path]
+		if !ok && run.existing[link.Path] != nil {
+			id, ok = run.existing[link.Path].ID, true
+		}
+		switch {
+		case ok:
+			url := fmt.Sprintf(documentURL, id)
+			if i := strings.Index(link.Ref, "#"); i >= 0 {
+				url += link.Ref[i:]
+			}
+			urls[link.Ref] = url
+		case run.failed[link.Path]:
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("link %s points to a page that was not imported", link.Ref))
+		case !run.dryRun:
+			pending = true
+		}
+	}
+	return importer.RewriteLinks(content, func(ref string) (string, bool) {
+		url, ok := urls[ref]
+		return url, ok
+	}), pending
+}
+
+func (s *importService) uploadFile(ctx context.Context, run *importRun, name string) (*models.File, error) {
+	f, err := run.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportSize {
+		return nil, errors.New("file too large to import")
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return storeFile(ctx, s.fileRepo, s.fileOperator, path.Base(name), contentType, data, run.userID)
+}
+
+// associate attaches files uploaded for a page to its document
+func (s *importService) associate(ctx context.Context, files []*models.File, docID uint) error {
+	for _, f := range files {
+		f.DocumentID = &docID
+		if err := s.fileRepo.Update(ctx, f); err != nil {
+			return fmt.Errorf("failed to associate file with document: %w", err)
+		}
+	}
+	return nil
+}
+
+// uniqueTitle returns title, numbered when the user already has another
+// document with that title or another page of the import takes it. current
+// is the title of the document the page was imported as before, if any.
+func (s *importService) uniqueTitle(ctx context.Context, run *importRun, title, current string) (string, error) {
+	candidate := title
+	for i := 2; ; i++ {
+		if candidate == current {
+			return candidate, nil
+		}
+		if !run.titles[candidate] {
+			exists, err := s.repo.ExistsByTitleAndCreator(ctx, candidate, run.userID)
+			if err != nil {
+				return "", err
+			}
+			if !exists {
+				return candidate, nil
+			}
+		}
+		candidate = fmt.Sprintf("%s (%d)", title, i)
+	}
+}
+
+func tagsByName(names []string) []models.Tag {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+	return tags
+}
+
+// removedTags returns the names of tags that are no longer listed
+func removedTags(tags []models.Tag, names []string) []string {
+	var removed []string
+	for _, tag := range tags {
+		if !slices.Contains(names, tag.Name) {
+			removed = append(removed, tag.Name)
+		}
+	}
+	return removed
+}
+
+// addedTags returns the listed names that are not among the tags
+func addedTags(tags []models.Tag, names []string) []string {
+	var added []string
+	for _, name := range names {
+		if !slices.ContainsFunc(tags, func(tag models.Tag) bool { return tag.Name == name }) {
+			added = append(added, name)
+		}
+	}
+	return added
+}
+
+func equalIDs(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Zhaoyikaiii/docmind/internal/importer"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// importedDocuments stores the documents of an import test, read through
+// the repository and written through the document service
+type importedDocuments struct {
+	docs map[uint]*models.Document
+}
+
+type stubImportRepository struct {
+	repository.DocumentRepository
+	*importedDocuments
+}
+
+func (r *stubImportRepository) GetByPaths(ctx context.Context, creatorID uint, paths []string) ([]models.Document, error) {
+	var docs []models.Document
+	for id := uint(1); id <= uint(len(r.docs)); id++ {
+		doc := r.docs[id]
+		for _, p := range paths {
+			if doc.CreatorID == creatorID && doc.Path == p {
+				docs = append(docs, *doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (r *stubImportRepository) ExistsByTitleAndCreator(ctx context.Context, title string, creatorID uint) (bool, error) {
+	for _, doc := range r.docs {
+		if doc.Title == title && doc.CreatorID == creatorID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type stubUserRepository struct {
+	repository.UserRepository
+	users []models.User
+}
+
+func (r *stubUserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			return &r.users[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *stubUserRepository) FindByNames(ctx context.Context, names []string) ([]models.User, error) {
+	var users []models.User
+	for _, user := range r.users {
+		for _, name := range names {
+			if strings.EqualFold(user.Username, name) || strings.EqualFold(user.Email, name) || strings.EqualFold(user.FullName, name) {
+				users = append(users, user)
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+type stubImportDocumentService struct {
+	DocumentService
+	*importedDocuments
+	updates int
+}
+
+func (s *stubImportDocumentService) CreateDocument(ctx context.Context, doc *models.Document) error {
+	doc.ID = uint(len(s.docs) + 1)
+	copied := *doc
+	s.docs[doc.ID] = &copied
+	return nil
+}
+
+func (s *stubImportDocumentService) UpdateDocument(ctx context.Context, doc *models.Document, userID uint) error {
+	s.updates++
+	copied := *doc
+	s.docs[doc.ID] = &copied
+	return nil
+}
+
+func (s *stubImportDocumentService) ManageTagsByName(ctx context.Context, userID, docID uint, addTags []string, removeTags []string) error {
+	doc := s.docs[docID]
+	var tags []models.Tag
+	for _, tag := range doc.Tags {
+		if !slices.Contains(removeTags, tag.Name) {
+			tags = append(tags, tag)
+		}
+	}
+	doc.Tags = tags
+	return nil
+}
+
+func TestImportTree(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	store := &importedDocuments{docs: map[uint]*models.Document{
+		1: {ID: 1, Title: "setup", Content: "Written by hand.", CreatorID: 1},
+	}}
+	docs := &stubImportDocumentService{importedDocuments: store}
+	files := &stubFileRepository{files: make(map[uint]*models.File)}
+	operator := &stubFileOperator{contents: make(map[string][]byte)}
+	svc := NewImportService(&stubImportRepository{importedDocuments: store}, docs, &stubUserRepository{}, files, operator, importer.NewRegistry())
+
+	fsys := fstest.MapFS{
+		"guides/README.md":       {Data: []byte("---\ntitle: Guides\ntags: [ docs ]\nstatus: published\n---\nAll guides.\n")},
+		"guides/setup.md":        {Data: []byte("---\nstatus: ready\n---\n![Flow](img/flow.png)\n")},
+		"guides/img/flow.png":    {Data: []byte("\x89PNG")},
+		"guides/linux/shell.md":  {Data: []byte("Use the shell.")},
+		"guides/linux/notes.txt": {Data: []byte("not imported")},
+	}
+
+	report, err := svc.ImportTree(ctx, ImportRequest{FS: fsys, UserID: 1, DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, "markdown", report.Format)
+	assert.Equal(t, 4, report.Created)
+	assert.Equal(t, 1, report.Images)
+	assert.Len(t, store.docs, 1, "dry run must not create documents")
+	assert.Empty(t, operator.contents)
+
+	report, err = svc.ImportTree(ctx, ImportRequest{FS: fsys, UserID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Created)
+	assert.Equal(t, 0, report.Failed)
+	assert.Equal(t, 1, report.Images)
+
+	byPath := make(map[string]models.ImportedDocument)
+	for _, entry := range report.Documents {
+		byPath[entry.Path] = entry
+	}
+	guides := store.docs[byPath["guides/"].DocumentID]
+	assert.Equal(t, "Guides", guides.Title)
+	assert.Equal(t, models.DocumentStatusPublished, guides.Status)
+	assert.Equal(t, []models.Tag{{Name: "docs"}}, guides.Tags)
+
+	setup := store.docs[byPath["guides/setup.md"].DocumentID]
+	assert.Equal(t, "setup (2)", setup.Title)
+	assert.Equal(t, guides.ID, *setup.ParentID)
+	assert.Equal(t, models.DocumentStatusDraft, setup.Status)
+	assert.Equal(t, "![Flow](/api/v1/files/1/content)\n", setup.Content)
+	assert.Equal(t, setup.ID, *files.files[1].DocumentID)
+	assert.Equal(t, "image/png", files.files[1].ContentType)
+	assert.Contains(t, byPath["guides/setup.md"].Warnings, `unknown status "ready"`)
+
+	linux := store.docs[byPath["guides/linux/"].DocumentID]
+	shell := store.docs[byPath["guides/linux/shell.md"].DocumentID]
+	assert.Equal(t, guides.ID, *linux.ParentID)
+	assert.Equal(t, linux.ID, *shell.ParentID)
+	assert.Equal(t, "guides/linux/shell.md", shell.Path)
+
+	// 再次导入相同内容不会产生变更
+	report, err = svc.ImportTree(ctx, ImportRequest{FS: fsys, UserID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Unchanged)
+	assert.Equal(t, 0, report.Images)
+	assert.Equal(t, 0, docs.updates)
+	assert.Len(t, files.files, 1)
+
+	fsys["guides/README.md"] = &fstest.MapFile{Data: []byte("---\ntitle: Guides\nstatus: published\n---\nAll guides, updated.\n")}
+	report, err = svc.ImportTree(ctx, ImportRequest{FS: fsys, UserID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 3, report.Unchanged)
+	assert.Equal(t, "All guides, updated.\n", store.docs[guides.ID].Content)
+	assert.Empty(t, store.docs[guides.ID].Tags)
+}
+
+func TestImportTreeLinksAndAuthors(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	store := &importedDocuments{docs: make(map[uint]*models.Document)}
+	docs := &stubImportDocumentService{importedDocuments: store}
+	files := &stubFileRepository{files: make(map[uint]*models.File)}
+	operator := &stubFileOperator{contents: make(map[string][]byte)}
+	users := &stubUserRepository{users: []models.User{
+		{ID: 7, Username: "alice", Email: "alice@example.com", FullName: "Alice Liddell"},
+		{ID: 8, Username: "bob", FullName: "Robert Smith"},
+		{ID: 9, Username: "rob", FullName: "Robert Smith"},
+	}}
+	svc := NewImportService(&stubImportRepository{importedDocuments: store}, docs, users, files, operator, importer.NewRegistry())
+
+	fsys := fstest.MapFS{
+		"a.md":       {Data: []byte("---\nauthor: Alice Liddell\n---\nSee [b](b.md#usage) and [the spec](spec.pdf), twice: ![spec](spec.pdf)\n")},
+		"b.md":       {Data: []byte("---\nauthor: Robert Smith\n---\nBack to [a](a.md).\n")},
+		"spec.pdf":   {Data: []byte("%PDF")},
+		"c/index.md": {Data: []byte("---\nauthor: carol\n---\nNo links.\n")},
+	}
+
+	report, err := svc.ImportTree(ctx, ImportRequest{FS: fsys, UserID: 1, Format: "markdown"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Created)
+	assert.Equal(t, 1, report.Images)
+	assert.Equal(t, 0, report.Attachments, "the linked file is the shown image")
+	assert.Len(t, files.files, 1)
+
+	byPath := make(map[string]models.ImportedDocument)
+	for _, entry := range report.Documents {
+		byPath[entry.Path] = entry
+	}
+	a := store.docs[byPath["a.md"].DocumentID]
+	b := store.docs[byPath["b.md"].DocumentID]
+	assert.Equal(t, "See [b](/api/v1/documents/2#usage) and [the spec](/api/v1/files/1/content), twice: ![spec](/api/v1/files/1/content)\n", a.Content)
+	assert.Equal(t, "Back to [a](/api/v1/documents/1).\n", b.Content)
+	assert.Equal(t, uint(7), *a.AuthorID)
+	assert.Nil(t, b.AuthorID)
+	assert.Contains(t, byPath["b.md"].Warnings, `author "Robert Smith" matches no single user`)
+	assert.Contains(t, byPath["c/"].Warnings, `author "carol" matches no single user`)
+	assert.Equal(t, 1, docs.updates, "a is linked to b once b is imported")
+
+	report, err = svc.ImportTree(ctx, ImportRequest{FS: fsys, UserID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Unchanged)
+	assert.Equal(t, 1, docs.updates)
+}
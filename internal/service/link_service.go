@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/links"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxLinkTarget is the size of the target column of document links
+const maxLinkTarget = 255
+
+// LinkService keeps track of the links between documents. Documents link to
+// each other with [[Title]] wiki links and /documents/:id URLs. A wiki link
+// names a document of the same workspace, or for documents outside of
+// workspaces, another document of the same creator.
+//
+// LinkService is a DocumentListener: the links of a document are stored
+// when it is saved, and when a document is renamed or moved to another
+// workspace, the links to it are rewritten so they keep pointing to it.
+type LinkService interface {
+	DocumentListener
+	// Backlinks returns the documents userID can read that link to a document
+	Backlinks(ctx context.Context, userID, documentID uint) ([]models.DocumentNode, error)
+	// BrokenLinks returns a page of the broken links of the documents
+	// selected by params
+	BrokenLinks(ctx context.Context, params repository.LinkListParams) ([]models.BrokenLink, error)
+	// Graph returns a page of the documents selected by params and the links
+	// between them. Links to documents on other pages are left out, so
+	// large knowledge bases are best viewed a workspace at a time.
+	Graph(ctx context.Context, params repository.LinkListParams) (*models.DocumentGraph, error)
+}
+
+const (
+	DefaultLinkPageSize = 200
+	MaxLinkPageSize     = 1000
+)
+
+type linkService struct {
+	repo       repository.DocumentLinkRepository
+	docRepo    repository.DocumentRepository
+	docService DocumentService
+}
+
+// NewLinkService creates the link service. Links to renamed or moved
+// documents are rewritten through docService, so that the rewrites respect
+// check-out locks and are published like any other update.
+func NewLinkService(repo repository.DocumentLinkRepository, docRepo repository.DocumentRepository, docService DocumentService) LinkService {
+	return &linkService{
+		repo:       repo,
+		docRepo:    docRepo,
+		docService: docService,
+	}
+}
+
+func (s *linkService) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	if event.Type == DocumentDeleted {
+		if err := s.repo.Replace(ctx, event.DocumentID, nil); err != nil {
+			return err
+		}
+		return s.repo.Unlink(ctx, event.DocumentID)
+	}
+
+	doc, prev := event.Document, event.Previous
+	if doc == nil {
+		return nil
+	}
+	created := event.Type == DocumentCreated || prev == nil
+	moved := !created && !sameDocumentScope(prev, doc)
+	renamed := !created && prev.Title != doc.Title
+
+	if created || moved || prev.Content != doc.Content {
+		if err := s.storeLinks(ctx, doc); err != nil {
+			return err
+		}
+	}
+	if renamed || moved {
+		if err := s.rewriteLinks(ctx, prev, doc, event.UserID); err != nil {
+			return err
+		}
+	}
+	if created || renamed || moved {
+		// 新标题可能修复了同一范围内的断链
+		return s.repo.ResolveWiki(ctx, doc.WorkspaceID, doc.CreatorID, doc.Title, doc.ID)
+	}
+	return nil
+}
+
+// storeLinks parses the links of doc and stores them with their targets
+func (s *linkService) storeLinks(ctx context.Context, doc *models.Document) error {
+	parsed := links.Parse(doc.Content)
+
+	var titles []string
+	var ids []uint
+	for _, link := range parsed {
+		if link.Kind == links.Wiki {
+			titles = append(titles, link.Title)
+		} else {
+			ids = append(ids, link.ID)
+		}
+	}
+
+	byTitle := make(map[string]uint)
+	named, err := s.repo.FindByTitles(ctx, doc.WorkspaceID, doc.CreatorID, titles)
+	if err != nil {
+		return err
+	}
+	for _, node := range named {
+		// 同名文档取最早创建的一个
+		if _, ok := byTitle[strings.ToLower(node.Title)]; !ok {
+			byTitle[strings.ToLower(node.Title)] = node.ID
+		}
+	}
+	existing := make(map[uint]bool)
+	found, err := s.repo.Nodes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, node := range found {
+		existing[node.ID] = true
+	}
+
+	var edges []models.DocumentLink
+	seen := make(map[string]bool)
+	for _, link := range parsed {
+		edge := models.DocumentLink{SourceID: doc.ID, Kind: link.Kind, Target: truncateRunes(link.Target(), maxLinkTarget)}
+		if link.Kind == links.Wiki {
+			if id, ok := byTitle[strings.ToLower(link.Title)]; ok {
+				edge.TargetID = &id
+			}
+		} else if existing[link.ID] {
+			edge.TargetID = &link.ID
+		}
+
+		key := edge.Kind + ":" + strings.ToLower(edge.Target)
+		if seen[key] || (edge.TargetID != nil && *edge.TargetID == doc.ID) {
+			continue
+		}
+		seen[key] = true
+		edges = append(edges, edge)
+	}
+	return s.repo.Replace(ctx, doc.ID, edges)
+}
+
+// rewriteLinks updates the documents linking to doc after userID renamed or
+// moved it from prev. Wiki links are renamed, or turned into URL links when
+// the title no longer resolves from the linking document; Markdown links
+// showing the old title show the new one. Only the documents userID may edit
+// are rewritten.
+func (s *linkService) rewriteLinks(ctx context.Context, prev, doc *models.Document, userID uint) error {
+	backlinks, err := s.repo.ListByTarget(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+
+	wikiSources := make(map[uint]bool)
+	var sources []uint
+	for _, link := range backlinks {
+		if len(sources) == 0 || sources[len(sources)-1] != link.SourceID {
+			sources = append(sources, link.SourceID)
+		}
+		if link.Kind == models.DocumentLinkWiki && strings.EqualFold(link.Target, truncateRunes(prev.Title, maxLinkTarget)) {
+			wikiSources[link.SourceID] = true
+		}
+	}
+
+	for _, sourceID := range sources {
+		source, err := s.docRepo.GetByID(ctx, sourceID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		wikiLinked := sameDocumentScope(source, doc) && links.CanWikiLink(doc.Title)
+		content := links.Rewrite(source.Content, func(link links.Link) (string, bool) {
+			switch {
+			case link.Kind == links.Wiki && wikiSources[sourceID] && strings.EqualFold(link.Title, prev.Title):
+				if wikiLinked {
+					return links.WikiLink(doc.Title, link.Label), prev.Title != doc.Title
+				}
+				label := link.Label
+				if label == "" {
+					label = doc.Title
+				}
+				return links.URLLink(doc.ID, label), true
+			case link.Markdown && link.ID == doc.ID && link.Label == prev.Title:
+				return link.Relabel(doc.Title), prev.Title != doc.Title
+			}
+			return "", false
+		})
+		if content == source.Content {
+			continue
+		}
+
+		source.Content = content
+		// 改写失败（例如无权编辑或文档被他人签出）不影响重命名本身
+		if err := s.docService.UpdateDocument(ctx, source, userID); err != nil {
+			utils.Logger.Warn("Failed to rewrite document links",
+				zap.Uint("document_id", sourceID),
+				zap.Uint("target_id", doc.ID),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+func (s *linkService) Backlinks(ctx context.Context, userID, documentID uint) ([]models.DocumentNode, error) {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return nil, err
+	}
+	backlinks, err := s.repo.ListByTarget(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(backlinks))
+	for _, link := range backlinks {
+		ids = append(ids, link.SourceID)
+	}
+	readable, err := s.readableIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Nodes(ctx, readable)
+}
+
+func (s *linkService) BrokenLinks(ctx context.Context, params repository.LinkListParams) ([]models.BrokenLink, error) {
+	broken, err := s.repo.ListBroken(ctx, normalizeLinkListParams(params))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(broken))
+	for _, link := range broken {
+		ids = append(ids, link.SourceID)
+	}
+	nodes, err := s.repo.Nodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.DocumentNode, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	result := make([]models.BrokenLink, 0, len(broken))
+	for _, link := range broken {
+		if node, ok := byID[link.SourceID]; ok {
+			result = append(result, models.BrokenLink{Document: node, Kind: link.Kind, Target: link.Target})
+		}
+	}
+	return result, nil
+}
+
+func (s *linkService) Graph(ctx context.Context, params repository.LinkListParams) (*models.DocumentGraph, error) {
+	nodes, err := s.repo.ListNodes(ctx, normalizeLinkListParams(params))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	edges, err := s.repo.ListEdges(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DocumentGraph{Nodes: nodes, Edges: edges}, nil
+}
+
+// normalizeLinkListParams applies the default and maximum page size
+func normalizeLinkListParams(params repository.LinkListParams) repository.LinkListParams {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize <= 0 {
+		params.PageSize = DefaultLinkPageSize
+	}
+	params.PageSize = min(params.PageSize, MaxLinkPageSize)
+	return params
+}
+
+// readableIDs returns the documents among ids userID can read
+func (s *linkService) readableIDs(ctx context.Context, userID uint, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	return s.docRepo.FilterIDs(ctx, repository.DocumentListParams{IDs: ids, ReadableBy: &userID})
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubLinkRepository keeps links in memory and looks documents up in docs
+type stubLinkRepository struct {
+	repository.DocumentLinkRepository
+	docs     map[uint]models.Document
+	links    []models.DocumentLink
+	readable func(doc models.Document, userID uint) bool
+}
+
+func (r *stubLinkRepository) Replace(ctx context.Context, sourceID uint, links []models.DocumentLink) error {
+	r.links = slices.DeleteFunc(r.links, func(link models.DocumentLink) bool { return link.SourceID == sourceID })
+	r.links = append(r.links, links...)
+	return nil
+}
+
+func (r *stubLinkRepository) ListByTarget(ctx context.Context, targetID uint) ([]models.DocumentLink, error) {
+	var found []models.DocumentLink
+	for _, link := range r.sorted() {
+		if link.TargetID != nil && *link.TargetID == targetID {
+			found = append(found, link)
+		}
+	}
+	return found, nil
+}
+
+func (r *stubLinkRepository) ListBroken(ctx context.Context, params repository.LinkListParams) ([]models.DocumentLink, error) {
+	var found []models.DocumentLink
+	for _, link := range r.sorted() {
+		if source, ok := r.docs[link.SourceID]; ok && link.TargetID == nil && r.listed(source, params) {
+			found = append(found, link)
+		}
+	}
+	return page(found, params), nil
+}
+
+func (r *stubLinkRepository) ListNodes(ctx context.Context, params repository.LinkListParams) ([]models.DocumentNode, error) {
+	var nodes []models.DocumentNode
+	for _, id := range sortedIDs(r.docs) {
+		if r.listed(r.docs[id], params) {
+			nodes = append(nodes, documentNode(r.docs[id]))
+		}
+	}
+	return page(nodes, params), nil
+}
+
+func (r *stubLinkRepository) listed(doc models.Document, params repository.LinkListParams) bool {
+	return r.readable(doc, params.ReadableBy) &&
+		(params.WorkspaceID == nil || (doc.WorkspaceID != nil && *doc.WorkspaceID == *params.WorkspaceID))
+}
+
+func page[T any](items []T, params repository.LinkListParams) []T {
+	start := min((params.Page-1)*params.PageSize, len(items))
+	return items[start:min(start+params.PageSize, len(items))]
+}
+
+func (r *stubLinkRepository) ListEdges(ctx context.Context, ids []uint) ([]models.DocumentEdge, error) {
+	var edges []models.DocumentEdge
+	for _, link := range r.sorted() {
+		if link.TargetID == nil || !slices.Contains(ids, link.SourceID) || !slices.Contains(ids, *link.TargetID) {
+			continue
+		}
+		edge := models.DocumentEdge{Source: link.SourceID, Target: *link.TargetID}
+		if !slices.Contains(edges, edge) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges, nil
+}
+
+func (r *stubLinkRepository) Nodes(ctx context.Context, ids []uint) ([]models.DocumentNode, error) {
+	var nodes []models.DocumentNode
+	for _, id := range sortedIDs(r.docs) {
+		if slices.Contains(ids, id) {
+			nodes = append(nodes, documentNode(r.docs[id]))
+		}
+	}
+	return nodes, nil
+}
+
+func (r *stubLinkRepository) FindByTitles(ctx context.Context, workspaceID *uint, creatorID uint, titles []string) ([]models.DocumentNode, error) {
+	var nodes []models.DocumentNode
+	for _, id := range sortedIDs(r.docs) {
+		doc := r.docs[id]
+		if inLinkScope(doc, workspaceID, creatorID) && slices.ContainsFunc(titles, func(title string) bool { return strings.EqualFold(title, doc.Title) }) {
+			nodes = append(nodes, documentNode(doc))
+		}
+	}
+	return nodes, nil
+}
+
+func (r *stubLinkRepository) ResolveWiki(ctx context.Context, workspaceID *uint, creatorID uint, title string, targetID uint) error {
+	for i, link := range r.links {
+		source, ok := r.docs[link.SourceID]
+		if link.TargetID == nil && link.Kind == models.DocumentLinkWiki && strings.EqualFold(link.Target, title) &&
+			ok && inLinkScope(source, workspaceID, creatorID) && link.SourceID != targetID {
+			r.links[i].TargetID = &targetID
+		}
+	}
+	return nil
+}
+
+func (r *stubLinkRepository) Unlink(ctx context.Context, targetID uint) error {
+	for i, link := range r.links {
+		if link.TargetID != nil && *link.TargetID == targetID {
+			r.links[i].TargetID = nil
+		}
+	}
+	return nil
+}
+
+func (r *stubLinkRepository) sorted() []models.DocumentLink {
+	sorted := slices.Clone(r.links)
+	slices.SortStableFunc(sorted, func(a, b models.DocumentLink) int { return int(a.SourceID) - int(b.SourceID) })
+	return sorted
+}
+
+func inLinkScope(doc models.Document, workspaceID *uint, creatorID uint) bool {
+	if workspaceID != nil {
+		return doc.WorkspaceID != nil && *doc.WorkspaceID == *workspaceID
+	}
+	return doc.WorkspaceID == nil && doc.CreatorID == creatorID
+}
+
+func documentNode(doc models.Document) models.DocumentNode {
+	return models.DocumentNode{ID: doc.ID, Title: doc.Title, Slug: doc.Slug, Status: doc.Status, WorkspaceID: doc.WorkspaceID, UpdatedAt: doc.UpdatedAt}
+}
+
+func sortedIDs(docs map[uint]models.Document) []uint {
+	ids := make([]uint, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestDocumentLinks(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	platform, infra := uint(1), uint(2)
+	docs := &stubWritableRepository{&stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Status: models.DocumentStatusPublished, CreatorID: 1, WorkspaceID: &platform,
+			Content: "Start with [[Failover]] and [[Missing page]]. See /documents/2 and [Failover](/documents/2)."},
+		2: {ID: 2, Title: "Failover", Status: models.DocumentStatusPublished, CreatorID: 2, WorkspaceID: &platform,
+			Content: "Back to [[oncall runbook|the runbook]]."},
+		3: {ID: 3, Title: "Postmortem", Status: models.DocumentStatusDraft, CreatorID: 1,
+			Content: "Follow [[Failover]] and /documents/2."},
+	}}}}
+	linkRepo := &stubLinkRepository{docs: docs.docs, readable: docs.readable}
+	users := &stubUserRepository{users: []models.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}}}
+	workspaceRepo := newStubWorkspaceRepository()
+	for _, member := range [][2]uint{{platform, 1}, {platform, 2}, {infra, 2}} {
+		workspaceRepo.members[member] = models.WorkspaceMember{WorkspaceID: member[0], UserID: member[1], Role: models.WorkspaceRoleMember}
+	}
+	docService := NewDocumentService(docs, nil, nil, nil, nil, nil, nil, NewWorkspaceService(workspaceRepo, users))
+	svc := NewLinkService(linkRepo, docs, docService)
+	docService.Subscribe(svc)
+
+	for _, id := range []uint{1, 2, 3} {
+		doc := docs.docs[id]
+		require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentCreated, DocumentID: id, Document: &doc}))
+	}
+
+	backlinks, err := svc.Backlinks(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 3}, nodeIDs(backlinks))
+	backlinks, err = svc.Backlinks(ctx, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, nodeIDs(backlinks), "drafts of others are left out")
+	_, err = svc.Backlinks(ctx, 2, 3)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	broken, err := svc.BrokenLinks(ctx, repository.LinkListParams{ReadableBy: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []models.BrokenLink{
+		{Document: documentNode(docs.docs[1]), Kind: models.DocumentLinkWiki, Target: "Missing page"},
+		{Document: documentNode(docs.docs[3]), Kind: models.DocumentLinkWiki, Target: "Failover"},
+	}, broken, "wiki links of personal documents only name personal documents")
+
+	graph, err := svc.Graph(ctx, repository.LinkListParams{ReadableBy: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3}, nodeIDs(graph.Nodes))
+	assert.Equal(t, []models.DocumentEdge{{Source: 1, Target: 2}, {Source: 2, Target: 1}, {Source: 3, Target: 2}}, graph.Edges)
+
+	graph, err = svc.Graph(ctx, repository.LinkListParams{ReadableBy: 1, WorkspaceID: &platform})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2}, nodeIDs(graph.Nodes))
+	assert.Equal(t, []models.DocumentEdge{{Source: 1, Target: 2}, {Source: 2, Target: 1}}, graph.Edges)
+
+	// 只返回同一页节点之间的链接
+	graph, err = svc.Graph(ctx, repository.LinkListParams{ReadableBy: 1, Page: 2, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{3}, nodeIDs(graph.Nodes))
+	assert.Empty(t, graph.Edges)
+
+	// renaming rewrites the links to the document
+	failover := docs.docs[2]
+	failover.Title = "Failover steps"
+	require.NoError(t, docService.UpdateDocument(ctx, &failover, 2))
+	assert.Equal(t, "Start with [[Failover steps]] and [[Missing page]]. See /documents/2 and [Failover steps](/documents/2).", docs.docs[1].Content)
+	assert.Equal(t, "Follow [[Failover]] and /documents/2.", docs.docs[3].Content)
+	backlinks, err = svc.Backlinks(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 3}, nodeIDs(backlinks))
+
+	// moving it to another workspace turns wiki links into URL links
+	failover.WorkspaceID = &infra
+	require.NoError(t, docService.UpdateDocument(ctx, &failover, 2))
+	assert.Equal(t, "Start with [Failover steps](/documents/2) and [[Missing page]]. See /documents/2 and [Failover steps](/documents/2).", docs.docs[1].Content)
+	backlinks, err = svc.Backlinks(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 3}, nodeIDs(backlinks))
+	broken, err = svc.BrokenLinks(ctx, repository.LinkListParams{ReadableBy: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []models.BrokenLink{
+		{Document: documentNode(docs.docs[1]), Kind: models.DocumentLinkWiki, Target: "Missing page"},
+		{Document: documentNode(docs.docs[2]), Kind: models.DocumentLinkWiki, Target: "oncall runbook"},
+	}, broken)
+
+	// creating the missing page fixes the links naming it
+	docs.docs[4] = models.Document{ID: 4, Title: "missing page", Status: models.DocumentStatusPublished, CreatorID: 2, WorkspaceID: &platform}
+	missing := docs.docs[4]
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentCreated, DocumentID: 4, Document: &missing}))
+	backlinks, err = svc.Backlinks(ctx, 1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, nodeIDs(backlinks))
+
+	// deleting a document breaks the links to it
+	delete(docs.docs, 4)
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: 4, Previous: &missing}))
+	broken, err = svc.BrokenLinks(ctx, repository.LinkListParams{ReadableBy: 1})
+	require.NoError(t, err)
+	require.Len(t, broken, 3)
+	assert.Equal(t, "Missing page", broken[0].Target)
+}
+
+func nodeIDs(nodes []models.DocumentNode) []uint {
+	ids := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DefaultLockTTL is how long a lock lasts without a heartbeat
+const DefaultLockTTL = 5 * time.Minute
+
+var (
+	ErrDocumentLocked     = errors.New("document is locked by another user")
+	ErrDocumentNotLocked  = errors.New("document is not locked")
+	ErrLockNotHeld        = errors.New("lock is not held by this user")
+	ErrNotDocumentEditor  = errors.New("only editors of the document can lock it")
+	ErrLockBreakForbidden = errors.New("only admins can break locks")
+)
+
+// LockService manages check-out locks, for teams that edit documents one
+// at a time rather than together. The creator of a document and the members
+// of its workspace may check it out; while the lock is held, updates by
+// anyone else are rejected with ErrDocumentLocked.
+type LockService interface {
+	// CheckOut locks a document for userID, or renews the lock userID
+	// already holds. When another user holds it, the error is
+	// ErrDocumentLocked and the returned lock is theirs.
+	CheckOut(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error)
+	// Heartbeat extends the lock of userID by the lock TTL
+	Heartbeat(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error)
+	// CheckIn releases the lock of userID
+	CheckIn(ctx context.Context, userID, documentID uint) error
+	// GetLock returns the active lock of a document, or ErrDocumentNotLocked
+	GetLock(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error)
+	// BreakLock removes the lock of a document and notifies its holder.
+	// Only admins break locks.
+	BreakLock(ctx context.Context, userID, documentID uint) error
+}
+
+type lockService struct {
+	repo          repository.DocumentLockRepository
+	docRepo       repository.DocumentRepository
+	workspaces    WorkspaceService
+	userRepo      repository.UserRepository
+	notifications NotificationService
+	ttl           time.Duration
+}
+
+// NewLockService creates the lock service. Locks last ttl after being
+// checked out or renewed, DefaultLockTTL when ttl is zero.
+func NewLockService(repo repository.DocumentLockRepository, docRepo repository.DocumentRepository, workspaces WorkspaceService, userRepo repository.UserRepository, notifications NotificationService, ttl time.Duration) LockService {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+	return &lockService{
+		repo:          repo,
+		docRepo:       docRepo,
+		workspaces:    workspaces,
+		userRepo:      userRepo,
+		notifications: notifications,
+		ttl:           ttl,
+	}
+}
+
+func (s *lockService) CheckOut(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error) {
+	doc, err := readableDocument(ctx, s.docRepo, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditDocument(ctx, s.workspaces, userID, doc) {
+		return nil, ErrNotDocumentEditor
+	}
+
+	now := time.Now()
+	acquired, err := s.repo.Acquire(ctx, &models.DocumentLock{
+		DocumentID: documentID,
+		OwnerID:    userID,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := s.repo.GetActive(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return lock, ErrDocumentLocked
+	}
+	return lock, nil
+}
+
+func (s *lockService) Heartbeat(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error) {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return nil, err
+	}
+	renewed, err := s.repo.Renew(ctx, documentID, userID, time.Now().Add(s.ttl))
+	if err != nil {
+		return nil, err
+	}
+	if !renewed {
+		return nil, ErrLockNotHeld
+	}
+	return s.repo.GetActive(ctx, documentID)
+}
+
+func (s *lockService) CheckIn(ctx context.Context, userID, documentID uint) error {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return err
+	}
+	released, err := s.repo.Release(ctx, documentID, userID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (s *lockService) GetLock(ctx context.Context, userID, documentID uint) (*models.DocumentLock, error) {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return nil, err
+	}
+	lock, err := s.repo.GetActive(ctx, documentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDocumentNotLocked
+	}
+	return lock, err
+}
+
+func (s *lockService) BreakLock(ctx context.Context, userID, documentID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role != models.UserRoleAdmin {
+		return ErrLockBreakForbidden
+	}
+
+	doc, err := s.docRepo.GetByID(ctx, documentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDocumentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	lock, err := s.repo.GetActive(ctx, documentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDocumentNotLocked
+	}
+	if err != nil {
+		return err
+	}
+	// 只删除读到的那把锁，期间被他人重新签出的锁不受影响
+	deleted, err := s.repo.Delete(ctx, lock)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDocumentNotLocked
+	}
+
+	if lock.OwnerID == userID {
+		return nil
+	}
+	// 通知失败不影响解锁
+	err = s.notifications.Notify(ctx, &models.Notification{
+		UserID:     lock.OwnerID,
+		Type:       models.NotificationLockBroken,
+		ActorID:    &userID,
+		DocumentID: &doc.ID,
+		Message:    truncateRunes(fmt.Sprintf("%s broke your lock on %q; unsaved changes may conflict", user.Username, doc.Title), 512),
+	})
+	if err != nil {
+		utils.Logger.Warn("Failed to notify lock holder", zap.Uint("document_id", documentID), zap.Error(err))
+	}
+	return nil
+}
+
+// checkLock returns ErrDocumentLocked when a user other than userID holds
+// the lock of a document
+func checkLock(ctx context.Context, repo repository.DocumentLockRepository, userID, documentID uint) error {
+	lock, err := repo.GetActive(ctx, documentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lock.OwnerID != userID {
+		return ErrDocumentLocked
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// stubLockRepository keeps locks in memory
+type stubLockRepository struct {
+	repository.DocumentLockRepository
+	locks map[uint]models.DocumentLock
+}
+
+func (r *stubLockRepository) Acquire(ctx context.Context, lock *models.DocumentLock) (bool, error) {
+	if existing, ok := r.locks[lock.DocumentID]; ok && existing.OwnerID != lock.OwnerID && !existing.ExpiresAt.Before(lock.AcquiredAt) {
+		return false, nil
+	}
+	r.locks[lock.DocumentID] = *lock
+	return true, nil
+}
+
+func (r *stubLockRepository) Renew(ctx context.Context, documentID, ownerID uint, expiresAt time.Time) (bool, error) {
+	lock, ok := r.locks[documentID]
+	if !ok || lock.OwnerID != ownerID || lock.ExpiresAt.Before(time.Now()) {
+		return false, nil
+	}
+	lock.ExpiresAt = expiresAt
+	r.locks[documentID] = lock
+	return true, nil
+}
+
+func (r *stubLockRepository) GetActive(ctx context.Context, documentID uint) (*models.DocumentLock, error) {
+	lock, ok := r.locks[documentID]
+	if !ok || lock.ExpiresAt.Before(time.Now()) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &lock, nil
+}
+
+func (r *stubLockRepository) Release(ctx context.Context, documentID, ownerID uint) (bool, error) {
+	lock, ok := r.locks[documentID]
+	if !ok || lock.OwnerID != ownerID {
+		return false, nil
+	}
+	delete(r.locks, documentID)
+	return true, nil
+}
+
+func (r *stubLockRepository) Delete(ctx context.Context, lock *models.DocumentLock) (bool, error) {
+	current, ok := r.locks[lock.DocumentID]
+	if !ok || current.OwnerID != lock.OwnerID || !current.AcquiredAt.Equal(lock.AcquiredAt) {
+		return false, nil
+	}
+	delete(r.locks, lock.DocumentID)
+	return true, nil
+}
+
+// stubWritableRepository adds updates to stubTreeRepository
+type stubWritableRepository struct {
+	*stubTreeRepository
+}
+
+func (r *stubWritableRepository) Update(ctx context.Context, doc *models.Document) error {
+	r.docs[doc.ID] = *doc
+	return nil
+}
+
+func TestDocumentLocks(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	workspaceID := uint(1)
+	docs := &stubWritableRepository{&stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Content: "Promote the replica.", Status: models.DocumentStatusPublished, CreatorID: 1, WorkspaceID: &workspaceID},
+	}}}}
+	users := &stubUserRepository{users: []models.User{
+		{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"}, {ID: 4, Username: "root", Role: models.UserRoleAdmin},
+	}}
+	workspaceRepo := newStubWorkspaceRepository()
+	workspaceRepo.members[[2]uint{1, 2}] = models.WorkspaceMember{WorkspaceID: 1, UserID: 2, Role: models.WorkspaceRoleMember}
+	locks := &stubLockRepository{locks: map[uint]models.DocumentLock{}}
+	notifications := &stubNotificationService{}
+	workspaces := NewWorkspaceService(workspaceRepo, users)
+	svc := NewLockService(locks, docs, workspaces, users, notifications, time.Minute)
+	docService := NewDocumentService(docs, nil, nil, nil, nil, locks, nil, workspaces)
+
+	_, err := svc.CheckOut(ctx, 3, 1)
+	assert.ErrorIs(t, err, ErrNotDocumentEditor, "readers can't check documents out")
+	_, err = svc.GetLock(ctx, 2, 1)
+	assert.ErrorIs(t, err, ErrDocumentNotLocked)
+
+	lock, err := svc.CheckOut(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), lock.OwnerID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lock.ExpiresAt, time.Second)
+
+	held, err := svc.CheckOut(ctx, 1, 1)
+	assert.ErrorIs(t, err, ErrDocumentLocked)
+	assert.Equal(t, uint(2), held.OwnerID, "the lock of the holder is returned")
+	assert.ErrorIs(t, svc.CheckIn(ctx, 1, 1), ErrLockNotHeld)
+	_, err = svc.Heartbeat(ctx, 1, 1)
+	assert.ErrorIs(t, err, ErrLockNotHeld)
+
+	update := docs.docs[1]
+	update.Content = "Promote the new replica."
+	assert.ErrorIs(t, docService.UpdateDocument(ctx, &update, 1), ErrDocumentLocked, "only the holder writes")
+	assert.ErrorIs(t, docService.UpdateDocument(ctx, &update, 3), ErrDocumentEditForbidden)
+	require.NoError(t, docService.UpdateDocument(ctx, &update, 2), "members holding the lock write")
+	assert.Equal(t, uint(1), docs.docs[1].CreatorID, "the creator stays")
+
+	// the lock lapses without heartbeats
+	expired := locks.locks[1]
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	locks.locks[1] = expired
+	_, err = svc.Heartbeat(ctx, 2, 1)
+	assert.ErrorIs(t, err, ErrLockNotHeld)
+	require.NoError(t, docService.UpdateDocument(ctx, &update, 1))
+
+	lock, err = svc.CheckOut(ctx, 1, 1)
+	require.NoError(t, err, "expired locks are taken over")
+	locks.locks[1] = models.DocumentLock{DocumentID: 1, OwnerID: 1, AcquiredAt: lock.AcquiredAt, ExpiresAt: time.Now().Add(time.Second)}
+	renewed, err := svc.Heartbeat(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), renewed.ExpiresAt, time.Second)
+	require.NoError(t, docService.UpdateDocument(ctx, &update, 1))
+
+	assert.ErrorIs(t, svc.BreakLock(ctx, 2, 1), ErrLockBreakForbidden)
+	require.NoError(t, svc.BreakLock(ctx, 4, 1))
+	require.Len(t, notifications.sent, 1)
+	assert.Equal(t, uint(1), notifications.sent[0].UserID)
+	assert.Equal(t, models.NotificationLockBroken, notifications.sent[0].Type)
+	assert.Equal(t, `root broke your lock on "Oncall runbook"; unsaved changes may conflict`, notifications.sent[0].Message)
+	assert.ErrorIs(t, svc.BreakLock(ctx, 4, 1), ErrDocumentNotLocked)
+
+	_, err = svc.CheckOut(ctx, 2, 1)
+	require.NoError(t, err)
+	require.NoError(t, svc.CheckIn(ctx, 2, 1))
+	_, err = svc.GetLock(ctx, 1, 1)
+	assert.ErrorIs(t, err, ErrDocumentNotLocked)
+
+	// 读到的锁在删除前被重新签出时，新锁保留
+	_, err = svc.CheckOut(ctx, 2, 1)
+	require.NoError(t, err)
+	stale := NewLockService(&staleLockRepository{locks}, docs, workspaces, users, notifications, time.Minute)
+	assert.ErrorIs(t, stale.BreakLock(ctx, 4, 1), ErrDocumentNotLocked)
+	assert.Equal(t, uint(2), locks.locks[1].OwnerID)
+	assert.Len(t, notifications.sent, 1)
+}
+
+// staleLockRepository returns locks as they were before they were acquired
+// again, as if they were read just before
+type staleLockRepository struct {
+	*stubLockRepository
+}
+
+func (r *staleLockRepository) GetActive(ctx context.Context, documentID uint) (*models.DocumentLock, error) {
+	lock, err := r.stubLockRepository.GetActive(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	lock.AcquiredAt = lock.AcquiredAt.Add(-time.Minute)
+	return lock, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+)
+
+// NotificationService keeps the in-app notifications of users
+type NotificationService interface {
+	// Notify stores notifications for their users
+	Notify(ctx context.Context, notifications ...*models.Notification) error
+	// ListNotifications returns the notifications of a user, newest first
+	ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, error)
+	// MarkRead marks notifications of userID as read, all of them when ids
+	// is empty, and returns how many were unread
+	MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error)
+}
+
+type notificationService struct {
+	repo repository.NotificationRepository
+}
+
+func NewNotificationService(repo repository.NotificationRepository) NotificationService {
+	return &notificationService{repo: repo}
+}
+
+func (s *notificationService) Notify(ctx context.Context, notifications ...*models.Notification) error {
+	return s.repo.Create(ctx, notifications)
+}
+
+func (s *notificationService) ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, error) {
+	return s.repo.List(ctx, userID, unreadOnly, page, pageSize)
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	return s.repo.MarkRead(ctx, userID, ids)
+}
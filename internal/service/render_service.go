@@ -0,0 +1,167 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/render"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+)
+
+// DefaultRenderCacheSize is the number of rendered documents kept in memory
+// when no cache size is configured
+const DefaultRenderCacheSize = 500
+
+var ErrVersionNotFound = errors.New("document version not found")
+
+type RenderService interface {
+	DocumentListener
+	// RenderDocument renders the current content of a document userID can
+	// read, or the content saved with version when version is positive
+	RenderDocument(ctx context.Context, userID, docID uint, version int) (*models.RenderedDocument, error)
+}
+
+// renderService caches rendered documents by document, version and content
+// hash, so edits that do not create a version still render fresh output
+type renderService struct {
+	repo     repository.DocumentRepository
+	renderer render.Renderer
+
+	mu      sync.Mutex
+	size    int
+	entries map[renderKey]*list.Element
+	lru     *list.List
+}
+
+type renderKey struct {
+	documentID uint
+	version    int
+	hash       [sha256.Size]byte
+}
+
+type renderEntry struct {
+	key      renderKey
+	rendered *models.RenderedDocument
+}
+
+func NewRenderService(repo repository.DocumentRepository, renderer render.Renderer, cacheSize int) RenderService {
+	if cacheSize <= 0 {
+		cacheSize = DefaultRenderCacheSize
+	}
+	return &renderService{
+		repo:     repo,
+		renderer: renderer,
+		size:     cacheSize,
+		entries:  make(map[renderKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (s *renderService) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	if event.Type == DocumentDeleted {
+		s.evict(event.DocumentID)
+	}
+	return nil
+}
+
+func (s *renderService) RenderDocument(ctx context.Context, userID, docID uint, version int) (*models.RenderedDocument, error) {
+	// 先检查权限再查缓存，缓存的结果不会给无权读取的用户
+	doc, err := readableDocument(ctx, s.repo, userID, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	content := doc.Content
+	if version > 0 && version != doc.Version {
+		v, err := s.findVersion(ctx, docID, version)
+		if err != nil {
+			return nil, err
+		}
+		content = v.Content
+	} else {
+		version = doc.Version
+	}
+
+	key := renderKey{documentID: docID, version: version, hash: sha256.Sum256([]byte(content))}
+	if rendered := s.get(key); rendered != nil {
+		return rendered, nil
+	}
+
+	result, err := s.renderer.Render(content)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &models.RenderedDocument{
+		DocumentID: docID,
+		Version:    version,
+		HTML:       result.HTML,
+		TOC:        result.TOC,
+		RenderedAt: time.Now(),
+	}
+	if rendered.TOC == nil {
+		rendered.TOC = []models.TOCEntry{}
+	}
+	s.put(key, rendered)
+	return rendered, nil
+}
+
+// findVersion returns the latest saved row of a document version
+func (s *renderService) findVersion(ctx context.Context, docID uint, version int) (*models.DocumentVersion, error) {
+	versions, err := s.repo.GetVersions(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	var found *models.DocumentVersion
+	for i := range versions {
+		if versions[i].Version == version && (found == nil || versions[i].ID > found.ID) {
+			found = &versions[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrVersionNotFound
+	}
+	return found, nil
+}
+
+func (s *renderService) get(key renderKey) *models.RenderedDocument {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.lru.MoveToFront(el)
+		return el.Value.(*renderEntry).rendered
+	}
+	return nil
+}
+
+func (s *renderService) put(key renderKey, rendered *models.RenderedDocument) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*renderEntry).rendered = rendered
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(&renderEntry{key: key, rendered: rendered})
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*renderEntry).key)
+	}
+}
+
+// evict drops every cached rendering of a document
+func (s *renderService) evict(docID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, el := range s.entries {
+		if key.documentID == docID {
+			s.lru.Remove(el)
+			delete(s.entries, key)
+		}
+	}
+}
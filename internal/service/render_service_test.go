@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/render"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// stubVersionRepository serves documents and their saved versions
+type stubVersionRepository struct {
+	repository.DocumentRepository
+	docs     map[uint]*models.Document
+	versions []models.DocumentVersion
+}
+
+func (r *stubVersionRepository) GetByID(ctx context.Context, id uint) (*models.Document, error) {
+	doc, ok := r.docs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *doc
+	return &copied, nil
+}
+
+func (r *stubVersionRepository) FilterIDs(ctx context.Context, params repository.DocumentListParams) ([]uint, error) {
+	var ids []uint
+	for _, id := range params.IDs {
+		doc, ok := r.docs[id]
+		if ok && (params.ReadableBy == nil || doc.CreatorID == *params.ReadableBy || doc.Status == models.DocumentStatusPublished) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *stubVersionRepository) GetVersions(ctx context.Context, documentID uint) ([]models.DocumentVersion, error) {
+	var versions []models.DocumentVersion
+	for _, v := range r.versions {
+		if v.DocumentID == documentID {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// countingRenderer counts the documents it renders
+type countingRenderer struct {
+	render.Renderer
+	calls int
+}
+
+func (r *countingRenderer) Render(source string) (*render.Result, error) {
+	r.calls++
+	return r.Renderer.Render(source)
+}
+
+func TestRenderService(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubVersionRepository{
+		docs: map[uint]*models.Document{1: {ID: 1, Version: 2, Content: "# Current", CreatorID: 1}},
+		versions: []models.DocumentVersion{
+			{ID: 1, DocumentID: 1, Version: 1, Content: "# First draft"},
+			{ID: 2, DocumentID: 1, Version: 1, Content: "# First"},
+		},
+	}
+	renderer := &countingRenderer{Renderer: render.NewMarkdownRenderer()}
+	svc := NewRenderService(repo, renderer, 2)
+
+	rendered, err := svc.RenderDocument(ctx, 1, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rendered.Version)
+	assert.Equal(t, `<h1 id="current">Current</h1>`+"\n", rendered.HTML)
+	assert.Equal(t, []models.TOCEntry{{Level: 1, Text: "Current", ID: "current"}}, rendered.TOC)
+
+	// 相同版本和内容命中缓存
+	_, err = svc.RenderDocument(ctx, 1, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renderer.calls)
+
+	// 缓存的结果也不会给无权读取草稿的用户
+	_, err = svc.RenderDocument(ctx, 2, 1, 0)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, err = svc.RenderDocument(ctx, 2, 1, 1)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	// 未创建版本的编辑也会重新渲染
+	repo.docs[1].Content = "# Edited"
+	rendered, err = svc.RenderDocument(ctx, 1, 1, 0)
+	require.NoError(t, err)
+	assert.Contains(t, rendered.HTML, "Edited")
+	assert.Equal(t, 2, renderer.calls)
+
+	rendered, err = svc.RenderDocument(ctx, 1, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rendered.Version)
+	assert.Contains(t, rendered.HTML, `<h1 id="first">First</h1>`)
+
+	_, err = svc.RenderDocument(ctx, 1, 1, 5)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = svc.RenderDocument(ctx, 1, 7, 0)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	// 缓存容量为 2，最早的渲染结果已被淘汰
+	_, err = svc.RenderDocument(ctx, 1, 1, 0)
+	require.NoError(t, err)
+	calls := renderer.calls
+	repo.docs[1].Content = "# Current"
+	_, err = svc.RenderDocument(ctx, 1, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, calls+1, renderer.calls)
+
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: 1}))
+	_, err = svc.RenderDocument(ctx, 1, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, calls+2, renderer.calls)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReviewForbidden    = errors.New("only editors of the document can request reviews")
+	ErrInvalidReviewer    = errors.New("reviewers must be other users who can read the document")
+	ErrReviewNotRequested = errors.New("no pending review of this document")
+)
+
+// ReviewService asks users to review documents. The documents a user was
+// asked to review show up on their home page until they complete the review.
+type ReviewService interface {
+	// RequestReview asks reviewerIDs to review a document and notifies them.
+	// Only editors of the document request reviews, from other users who can
+	// read it.
+	RequestReview(ctx context.Context, userID, documentID uint, reviewerIDs []uint) ([]*models.ReviewRequest, error)
+	// CompleteReview marks the pending review of userID as done
+	CompleteReview(ctx context.Context, userID, documentID uint) error
+}
+
+type reviewService struct {
+	repo          repository.ReviewRequestRepository
+	docRepo       repository.DocumentRepository
+	workspaces    WorkspaceService
+	userRepo      repository.UserRepository
+	notifications NotificationService
+}
+
+func NewReviewService(repo repository.ReviewRequestRepository, docRepo repository.DocumentRepository, workspaces WorkspaceService, userRepo repository.UserRepository, notifications NotificationService) ReviewService {
+	return &reviewService{
+		repo:          repo,
+		docRepo:       docRepo,
+		workspaces:    workspaces,
+		userRepo:      userRepo,
+		notifications: notifications,
+	}
+}
+
+func (s *reviewService) RequestReview(ctx context.Context, userID, documentID uint, reviewerIDs []uint) ([]*models.ReviewRequest, error) {
+	doc, err := readableDocument(ctx, s.docRepo, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditDocument(ctx, s.workspaces, userID, doc) {
+		return nil, ErrReviewForbidden
+	}
+	if len(reviewerIDs) == 0 {
+		return nil, ErrInvalidReviewer
+	}
+	requester, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var requests []*models.ReviewRequest
+	seen := make(map[uint]bool)
+	for _, reviewerID := range reviewerIDs {
+		if seen[reviewerID] {
+			continue
+		}
+		seen[reviewerID] = true
+		if reviewerID == userID {
+			return nil, ErrInvalidReviewer
+		}
+
+		reviewer, err := s.userRepo.GetByID(ctx, reviewerID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidReviewer
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := readableDocument(ctx, s.docRepo, reviewerID, documentID); errors.Is(err, ErrDocumentNotFound) {
+			return nil, ErrInvalidReviewer
+		} else if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, &models.ReviewRequest{
+			DocumentID:  documentID,
+			ReviewerID:  reviewerID,
+			Reviewer:    reviewer,
+			RequesterID: userID,
+			Status:      models.ReviewStatusPending,
+			RequestedAt: now,
+		})
+	}
+	if err := s.repo.Request(ctx, requests); err != nil {
+		return nil, err
+	}
+
+	notifications := make([]*models.Notification, 0, len(requests))
+	for _, request := range requests {
+		notifications = append(notifications, &models.Notification{
+			UserID:     request.ReviewerID,
+			Type:       models.NotificationReview,
+			ActorID:    &userID,
+			DocumentID: &doc.ID,
+			Message:    truncateRunes(fmt.Sprintf("%s asked you to review %q", requester.Username, doc.Title), 512),
+		})
+	}
+	// 通知失败不影响评审请求
+	if err := s.notifications.Notify(ctx, notifications...); err != nil {
+		utils.Logger.Warn("Failed to notify reviewers", zap.Uint("document_id", documentID), zap.Error(err))
+	}
+	return requests, nil
+}
+
+func (s *reviewService) CompleteReview(ctx context.Context, userID, documentID uint) error {
+	if _, err := readableDocument(ctx, s.docRepo, userID, documentID); err != nil {
+		return err
+	}
+	completed, err := s.repo.Complete(ctx, documentID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return ErrReviewNotRequested
+	}
+	return nil
+}
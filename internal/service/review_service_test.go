@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubReviewRepository keeps review requests in memory, by document and
+// reviewer
+type stubReviewRepository struct {
+	repository.ReviewRequestRepository
+	requests map[[2]uint]models.ReviewRequest
+}
+
+func (r *stubReviewRepository) Request(ctx context.Context, requests []*models.ReviewRequest) error {
+	for _, request := range requests {
+		r.requests[[2]uint{request.DocumentID, request.ReviewerID}] = *request
+	}
+	return nil
+}
+
+func (r *stubReviewRepository) Complete(ctx context.Context, documentID, reviewerID uint, reviewedAt time.Time) (bool, error) {
+	request, ok := r.requests[[2]uint{documentID, reviewerID}]
+	if !ok || request.Status != models.ReviewStatusPending {
+		return false, nil
+	}
+	request.Status = models.ReviewStatusReviewed
+	request.ReviewedAt = &reviewedAt
+	r.requests[[2]uint{documentID, reviewerID}] = request
+	return true, nil
+}
+
+func (r *stubReviewRepository) ListPending(ctx context.Context, reviewerID uint, limit int) ([]models.FeedItem, error) {
+	var items []models.FeedItem
+	for _, request := range r.requests {
+		if request.ReviewerID == reviewerID && request.Status == models.ReviewStatusPending {
+			items = append(items, models.FeedItem{DocumentNode: models.DocumentNode{ID: request.DocumentID}, RequesterID: &request.RequesterID})
+		}
+	}
+	return items, nil
+}
+
+func TestReviews(t *testing.T) {
+	ctx := context.Background()
+	utils.Logger = zap.NewNop()
+
+	workspaceID := uint(1)
+	docs := &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Oncall runbook", Status: models.DocumentStatusPublished, CreatorID: 1, WorkspaceID: &workspaceID},
+		2: {ID: 2, Title: "Postmortem", Status: models.DocumentStatusDraft, CreatorID: 1},
+		3: {ID: 3, Title: "Capacity plan", Status: models.DocumentStatusDraft, CreatorID: 1, WorkspaceID: &workspaceID},
+	}, members: map[[2]uint]bool{{1, 2}: true}}}
+	users := &stubUserRepository{users: []models.User{
+		{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"},
+	}}
+	workspaceRepo := newStubWorkspaceRepository()
+	workspaceRepo.members[[2]uint{1, 2}] = models.WorkspaceMember{WorkspaceID: 1, UserID: 2, Role: models.WorkspaceRoleMember}
+	reviews := &stubReviewRepository{requests: map[[2]uint]models.ReviewRequest{}}
+	notifications := &stubNotificationService{}
+	svc := NewReviewService(reviews, docs, NewWorkspaceService(workspaceRepo, users), users, notifications)
+
+	_, err := svc.RequestReview(ctx, 3, 1, []uint{2})
+	assert.ErrorIs(t, err, ErrReviewForbidden, "readers can't request reviews")
+	_, err = svc.RequestReview(ctx, 1, 2, []uint{3})
+	assert.ErrorIs(t, err, ErrInvalidReviewer, "reviewers must be able to read the document")
+	_, err = svc.RequestReview(ctx, 2, 1, []uint{2})
+	assert.ErrorIs(t, err, ErrInvalidReviewer, "nobody reviews their own request")
+	_, err = svc.RequestReview(ctx, 2, 1, []uint{9})
+	assert.ErrorIs(t, err, ErrInvalidReviewer)
+	assert.Empty(t, reviews.requests)
+
+	requests, err := svc.RequestReview(ctx, 2, 1, []uint{3, 1, 3})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "carol", requests[0].Reviewer.Username)
+	assert.Equal(t, uint(2), requests[0].RequesterID)
+	require.Len(t, notifications.sent, 2)
+	assert.Equal(t, uint(3), notifications.sent[0].UserID)
+	assert.Equal(t, models.NotificationReview, notifications.sent[0].Type)
+	assert.Equal(t, `bob asked you to review "Oncall runbook"`, notifications.sent[0].Message)
+
+	require.NoError(t, svc.CompleteReview(ctx, 3, 1))
+	assert.Equal(t, models.ReviewStatusReviewed, reviews.requests[[2]uint{1, 3}].Status)
+	assert.ErrorIs(t, svc.CompleteReview(ctx, 3, 1), ErrReviewNotRequested)
+	assert.ErrorIs(t, svc.CompleteReview(ctx, 3, 2), ErrDocumentNotFound)
+
+	// members of the workspace review its drafts
+	_, err = svc.RequestReview(ctx, 1, 3, []uint{2})
+	require.NoError(t, err)
+	_, err = svc.RequestReview(ctx, 1, 3, []uint{3})
+	assert.ErrorIs(t, err, ErrInvalidReviewer)
+
+	// asking again reopens the review
+	_, err = svc.RequestReview(ctx, 1, 1, []uint{3})
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewStatusPending, reviews.requests[[2]uint{1, 3}].Status)
+	assert.Nil(t, reviews.requests[[2]uint{1, 3}].ReviewedAt)
+}
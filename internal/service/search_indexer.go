@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+)
+
+const reindexBatchSize = 200
+
+// searchIndexer keeps a SearchEngine in sync with document changes
+type searchIndexer struct {
+	engine search.SearchEngine
+}
+
+func NewSearchIndexer(engine search.SearchEngine) DocumentListener {
+	return &searchIndexer{engine: engine}
+}
+
+func (i *searchIndexer) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	if event.Type == DocumentDeleted {
+		return i.engine.Remove(ctx, event.DocumentID)
+	}
+	return i.engine.Index(ctx, event.Document)
+}
+
+// RebuildSearchIndex indexes every stored document, e.g. when the engine
+// starts without a persisted index.
+func RebuildSearchIndex(ctx context.Context, repo repository.DocumentRepository, engine search.SearchEngine) error {
+	params := repository.DocumentListParams{Page: 1, PageSize: reindexBatchSize}
+	for {
+		docs, _, err := repo.List(ctx, params)
+		if err != nil {
+			return err
+		}
+		for i := range docs {
+			if err := engine.Index(ctx, &docs[i]); err != nil {
+				return err
+			}
+		}
+		if len(docs) < params.PageSize {
+			return engine.Flush()
+		}
+		// 按 ID 翻页，重建期间新增或删除的文档不会导致跳过或重复
+		params.AfterID = docs[len(docs)-1].ID
+	}
+}
+
+// rankDocuments orders docs by the position of their ID in ids
+func rankDocuments(docs []models.Document, ids []uint) []models.Document {
+	position := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+
+	ranked := make([]models.Document, len(ids))
+	for _, doc := range docs {
+		if i, ok := position[doc.ID]; ok {
+			ranked[i] = doc
+		}
+	}
+
+	// 去掉查询期间已被删除的文档留下的空位
+	result := ranked[:0]
+	for _, doc := range ranked {
+		if doc.ID != 0 {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
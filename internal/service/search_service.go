@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+)
+
+var (
+	ErrEmptySearchQuery  = errors.New("search query is required")
+	ErrInvalidSearchType = errors.New("invalid search type")
+)
+
+type SearchService interface {
+	Search(ctx context.Context, params repository.SearchParams) ([]models.SearchHit, int64, error)
+	Facets(ctx context.Context, params repository.SearchParams) (*models.Facets, error)
+}
+
+type searchService struct {
+	repo repository.SearchRepository
+}
+
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{repo: repo}
+}
+
+func (s *searchService) Search(ctx context.Context, params repository.SearchParams) ([]models.SearchHit, int64, error) {
+	params, err := normalizeSearchParams(params)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.Search(ctx, params)
+}
+
+func (s *searchService) Facets(ctx context.Context, params repository.SearchParams) (*models.Facets, error) {
+	params, err := normalizeSearchParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Facets(ctx, params)
+}
+
+// normalizeSearchParams validates the query and types, searching all types
+// when none are given
+func normalizeSearchParams(params repository.SearchParams) (repository.SearchParams, error) {
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Query == "" {
+		return params, ErrEmptySearchQuery
+	}
+
+	if len(params.Types) == 0 {
+		params.Types = []string{models.SearchHitDocument, models.SearchHitFile}
+	}
+	for _, t := range params.Types {
+		if t != models.SearchHitDocument && t != models.SearchHitFile {
+			return params, ErrInvalidSearchType
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/vector"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultSemanticLimit = 10
+	MaxSemanticLimit     = 50
+
+	semanticQueueSize    = 256
+	semanticIndexTimeout = 2 * time.Minute
+)
+
+var ErrSemanticQueueFull = errors.New("semantic index queue is full")
+
+type SemanticSearchService interface {
+	// Search returns the passages closest in meaning to query
+	Search(ctx context.Context, query string, limit int) ([]models.Passage, error)
+	// SearchDocuments is Search restricted to the given documents
+	SearchDocuments(ctx context.Context, query string, limit int, documentIDs []uint) ([]models.Passage, error)
+	// SearchReadable is Search restricted to the documents userID can read
+	SearchReadable(ctx context.Context, query string, limit int, userID uint) ([]models.Passage, error)
+}
+
+type semanticSearchService struct {
+	embedder ai.Embedder
+	store    vector.Store
+	repo     repository.DocumentRepository
+}
+
+func NewSemanticSearchService(embedder ai.Embedder, store vector.Store, repo repository.DocumentRepository) SemanticSearchService {
+	return &semanticSearchService{embedder: embedder, store: store, repo: repo}
+}
+
+func (s *semanticSearchService) Search(ctx context.Context, query string, limit int) ([]models.Passage, error) {
+	return s.search(ctx, query, semanticLimit(limit), nil)
+}
+
+// SearchReadable passes the readable documents to the store so that the
+// limit is not used up by passages of documents userID cannot read
+func (s *semanticSearchService) SearchReadable(ctx context.Context, query string, limit int, userID uint) ([]models.Passage, error) {
+	readable, err := s.repo.FilterIDs(ctx, repository.DocumentListParams{ReadableBy: &userID})
+	if err != nil {
+		return nil, err
+	}
+	return s.SearchDocuments(ctx, query, limit, readable)
+}
+
+func (s *semanticSearchService) SearchDocuments(ctx context.Context, query string, limit int, documentIDs []uint) ([]models.Passage, error) {
+	allowed := make(map[uint]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		allowed[id] = true
+	}
+	return s.search(ctx, query, semanticLimit(limit), func(id uint) bool { return allowed[id] })
+}
+
+// semanticLimit applies the default and maximum number of passages
+func semanticLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultSemanticLimit
+	}
+	return min(limit, MaxSemanticLimit)
+}
+
+func (s *semanticSearchService) search(ctx context.Context, query string, limit int, filter func(uint) bool) ([]models.Passage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	matches, err := s.store.Search(ctx, vectors[0], limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	passages := make([]models.Passage, 0, len(matches))
+	for _, m := range matches {
+		passages = append(passages, models.Passage{
+			DocumentID: m.DocumentID,
+			ChunkIndex: m.ChunkIndex,
+			Title:      m.Title,
+			Text:       m.Text,
+			Score:      m.Score,
+		})
+	}
+	return passages, nil
+}
+
+// SemanticIndexer keeps a vector store in sync with document changes.
+// Embedding may call a remote model, so events are queued and processed by a
+// background worker instead of delaying the write that caused them.
+type SemanticIndexer struct {
+	embedder ai.Embedder
+	store    vector.Store
+	chunker  *ai.Chunker
+
+	queue chan DocumentEvent
+	wg    sync.WaitGroup
+}
+
+func NewSemanticIndexer(embedder ai.Embedder, store vector.Store, chunker *ai.Chunker) *SemanticIndexer {
+	i := &SemanticIndexer{
+		embedder: embedder,
+		store:    store,
+		chunker:  chunker,
+		queue:    make(chan DocumentEvent, semanticQueueSize),
+	}
+	i.wg.Add(1)
+	go i.run()
+	return i
+}
+
+func (i *SemanticIndexer) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	select {
+	case i.queue <- event:
+		return nil
+	default:
+		return ErrSemanticQueueFull
+	}
+}
+
+// Close processes the queued events and stops the worker. The store itself
+// is left open.
+func (i *SemanticIndexer) Close() {
+	close(i.queue)
+	i.wg.Wait()
+}
+
+func (i *SemanticIndexer) run() {
+	defer i.wg.Done()
+
+	for event := range i.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), semanticIndexTimeout)
+		var err error
+		if event.Type == DocumentDeleted {
+			err = i.store.Delete(ctx, event.DocumentID)
+		} else {
+			err = i.index(ctx, event.Document)
+		}
+		cancel()
+
+		if err != nil {
+			utils.Logger.Warn("Failed to update semantic index",
+				zap.Error(err),
+				zap.Uint("document_id", event.DocumentID),
+			)
+		}
+	}
+}
+
+// index embeds the passages of a document and replaces its stored vectors.
+// The title is prepended to each passage so that passages keep the context
+// of the document they come from.
+func (i *SemanticIndexer) index(ctx context.Context, doc *models.Document) error {
+	chunks := i.chunker.Split(doc.Content)
+	if len(chunks) == 0 {
+		return i.store.Delete(ctx, doc.ID)
+	}
+
+	texts := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		texts = append(texts, doc.Title+"\n\n"+chunk.Text)
+	}
+	vectors, err := i.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed document: %w", err)
+	}
+
+	entries := make([]vector.Entry, 0, len(chunks))
+	for j, chunk := range chunks {
+		entries = append(entries, vector.Entry{
+			ChunkIndex: chunk.Index,
+			Title:      doc.Title,
+			Text:       chunk.Text,
+			Vector:     vectors[j],
+		})
+	}
+	return i.store.Upsert(ctx, doc.ID, entries)
+}
+
+// Rebuild indexes every stored document synchronously, e.g. when the store
+// starts empty or the embedding model changed.
+func (i *SemanticIndexer) Rebuild(ctx context.Context, repo repository.DocumentRepository) error {
+	params := repository.DocumentListParams{Page: 1, PageSize: reindexBatchSize}
+	for {
+		docs, _, err := repo.List(ctx, params)
+		if err != nil {
+			return err
+		}
+		for j := range docs {
+			if err := i.index(ctx, &docs[j]); err != nil {
+				return err
+			}
+		}
+		if len(docs) < params.PageSize {
+			return i.store.Flush()
+		}
+		params.AfterID = docs[len(docs)-1].ID
+	}
+}
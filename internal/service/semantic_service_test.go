@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/vector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemanticSearch(t *testing.T) {
+	ctx := context.Background()
+	embedder := ai.NewHashEmbedder(128)
+	store, err := vector.NewHNSW("", vector.Options{Dimensions: embedder.Dimensions()})
+	require.NoError(t, err)
+
+	indexer := NewSemanticIndexer(embedder, store, ai.NewChunker(60, 10))
+	docs := []*models.Document{
+		{ID: 1, Title: "Oncall runbook", Content: "Promote the database replica during failover.\n\nPage the database owner."},
+		{ID: 2, Title: "Release process", Content: "Tag the release and notify the team."},
+		{ID: 3, Title: "Old notes", Content: "Database failover notes that were deleted."},
+	}
+	for _, doc := range docs {
+		require.NoError(t, indexer.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentCreated, DocumentID: doc.ID, Document: doc}))
+	}
+	require.NoError(t, indexer.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: 3}))
+	indexer.Close()
+
+	assert.Equal(t, 3, store.Len())
+
+	repo := &stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Status: models.DocumentStatusDraft, CreatorID: 9},
+		2: {ID: 2, Status: models.DocumentStatusPublished, CreatorID: 9},
+	}}
+	svc := NewSemanticSearchService(embedder, store, repo)
+	passages, err := svc.Search(ctx, "database failover", 2)
+	require.NoError(t, err)
+	require.Len(t, passages, 2)
+	assert.Equal(t, uint(1), passages[0].DocumentID)
+	assert.Equal(t, "Oncall runbook", passages[0].Title)
+	assert.Equal(t, "Promote the database replica during failover.", passages[0].Text)
+	assert.GreaterOrEqual(t, passages[0].Score, passages[1].Score)
+
+	// 草稿只对作者可见
+	passages, err = svc.SearchReadable(ctx, "database failover", 2, 5)
+	require.NoError(t, err)
+	require.Len(t, passages, 1)
+	assert.Equal(t, uint(2), passages[0].DocumentID)
+
+	passages, err = svc.SearchReadable(ctx, "database failover", 2, 9)
+	require.NoError(t, err)
+	assert.Len(t, passages, 2)
+
+	_, err = svc.Search(ctx, "  ", 5)
+	assert.ErrorIs(t, err, ErrEmptySearchQuery)
+}
+
+// stubPagedRepository lists documents in ID order like the database does
+type stubPagedRepository struct {
+	*stubDocumentRepository
+}
+
+func (r *stubPagedRepository) List(ctx context.Context, params repository.DocumentListParams) ([]models.Document, int64, error) {
+	ids := slices.Sorted(maps.Keys(r.docs))
+	var docs []models.Document
+	for _, id := range ids {
+		if id > params.AfterID && len(docs) < params.PageSize {
+			docs = append(docs, r.docs[id])
+		}
+	}
+	return docs, int64(len(ids)), nil
+}
+
+func TestSemanticRebuild(t *testing.T) {
+	embedder := ai.NewHashEmbedder(32)
+	store, err := vector.NewHNSW("", vector.Options{Dimensions: embedder.Dimensions()})
+	require.NoError(t, err)
+	indexer := NewSemanticIndexer(embedder, store, ai.NewChunker(60, 10))
+	defer indexer.Close()
+
+	docs := map[uint]models.Document{}
+	for id := uint(1); id <= reindexBatchSize+50; id++ {
+		docs[id*2] = models.Document{ID: id * 2, Title: "Note", Content: "Short note."}
+	}
+	require.NoError(t, indexer.Rebuild(context.Background(), &stubPagedRepository{&stubDocumentRepository{docs: docs}}))
+	assert.Equal(t, len(docs), store.Len())
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"gorm.io/gorm"
+)
+
+const summaryTimeout = 2 * time.Minute
+
+// SummaryService keeps a summary and key points for every document. They
+// are generated in the background when a published document is saved, and
+// for other documents when they are first read. Each summary is stored with
+// the hash of the content it was computed from; reads return the latest one,
+// marked stale and queued for regeneration when the document has changed.
+type SummaryService struct {
+	repo       repository.DocumentRepository
+	summaries  repository.DocumentSummaryRepository
+	summarizer ai.Summarizer
+	queue      *documentQueue
+}
+
+func NewSummaryService(repo repository.DocumentRepository, summaries repository.DocumentSummaryRepository, summarizer ai.Summarizer) *SummaryService {
+	s := &SummaryService{
+		repo:       repo,
+		summaries:  summaries,
+		summarizer: summarizer,
+	}
+	s.queue = newDocumentQueue("summarize document", summaryTimeout, s.generate)
+	return s
+}
+
+func (s *SummaryService) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	if event.Type == DocumentDeleted || event.Document == nil {
+		return nil
+	}
+	if event.Document.Status != models.DocumentStatusPublished {
+		return nil
+	}
+	return s.queue.enqueue(event.DocumentID)
+}
+
+// Attach sets the Summary of each document and queues the documents whose
+// summary is missing or stale
+func (s *SummaryService) Attach(ctx context.Context, docs ...*models.Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	summaries, err := s.summaries.Latest(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byDocument := make(map[uint]*models.DocumentSummary, len(summaries))
+	for i := range summaries {
+		byDocument[summaries[i].DocumentID] = &summaries[i]
+	}
+
+	for _, doc := range docs {
+		summary, ok := byDocument[doc.ID]
+		if !ok {
+			// 队列已满时跳过，下次读取会再次尝试
+			_ = s.queue.enqueue(doc.ID)
+			continue
+		}
+		doc.Summary = &models.Summary{
+			Text:        summary.Summary,
+			KeyPoints:   summary.KeyPoints,
+			Source:      summary.Source,
+			Version:     summary.Version,
+			GeneratedAt: summary.UpdatedAt,
+			Stale:       summary.Version != doc.Version || summary.ContentHash != summaryHash(doc),
+		}
+		if doc.Summary.Stale {
+			_ = s.queue.enqueue(doc.ID)
+		}
+	}
+	return nil
+}
+
+// Close processes the queued documents and stops the worker
+func (s *SummaryService) Close() {
+	s.queue.close()
+}
+
+// generate summarizes the current content of a document unless its latest
+// summary is still up to date
+func (s *SummaryService) generate(ctx context.Context, id uint) error {
+	doc, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hash := summaryHash(doc)
+	latest, err := s.summaries.Latest(ctx, []uint{id})
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 && latest[0].Version == doc.Version && latest[0].ContentHash == hash {
+		return nil
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, doc.Title, doc.Content)
+	if err != nil {
+		return err
+	}
+
+	return s.summaries.Save(ctx, &models.DocumentSummary{
+		DocumentID:  doc.ID,
+		ContentHash: hash,
+		Version:     doc.Version,
+		Summary:     summary.Text,
+		KeyPoints:   summary.KeyPoints,
+		Source:      summary.Source,
+	})
+}
+
+// summaryHash identifies the content a summary was generated from
+func summaryHash(doc *models.Document) string {
+	sum := sha256.Sum256([]byte(doc.Title + "\x00" + doc.Content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSummaryRepository keeps documents and their summaries in memory
+type stubSummaryRepository struct {
+	repository.DocumentRepository
+	mu        sync.Mutex
+	docs      map[uint]models.Document
+	summaries []models.DocumentSummary
+}
+
+func (r *stubSummaryRepository) GetByID(ctx context.Context, id uint) (*models.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc := r.docs[id]
+	return &doc, nil
+}
+
+func (r *stubSummaryRepository) Latest(ctx context.Context, documentIDs []uint) ([]models.DocumentSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest := make(map[uint]models.DocumentSummary)
+	for _, s := range r.summaries {
+		latest[s.DocumentID] = s
+	}
+	var summaries []models.DocumentSummary
+	for _, id := range documentIDs {
+		if s, ok := latest[id]; ok {
+			summaries = append(summaries, s)
+		}
+	}
+	return summaries, nil
+}
+
+func (r *stubSummaryRepository) Save(ctx context.Context, summary *models.DocumentSummary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summaries = append(r.summaries, *summary)
+	return nil
+}
+
+func TestSummaryService(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubSummaryRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Failover", Version: 2, Status: models.DocumentStatusPublished,
+			Content: "Promote the replica when the primary fails. Then update the DNS record of the replica."},
+		2: {ID: 2, Title: "Notes", Version: 1, Status: models.DocumentStatusDraft, Content: "Draft notes."},
+	}}
+	summarizer := ai.NewExtractiveSummarizer(1, 2)
+
+	svc := NewSummaryService(repo, repo, summarizer)
+	published, draft := repo.docs[1], repo.docs[2]
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: 1, Document: &published}))
+	require.NoError(t, svc.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentCreated, DocumentID: 2, Document: &draft}))
+	svc.Close()
+
+	// 只有已发布的文档在保存时生成摘要
+	require.Len(t, repo.summaries, 1)
+	assert.Equal(t, uint(1), repo.summaries[0].DocumentID)
+	assert.Equal(t, 2, repo.summaries[0].Version)
+	assert.Equal(t, "Promote the replica when the primary fails.", repo.summaries[0].Summary)
+	assert.Equal(t, ai.SummarySourceExtractive, repo.summaries[0].Source)
+
+	// 内容变化后摘要标记为过期，读取时重新生成
+	svc = NewSummaryService(repo, repo, summarizer)
+	edited := published
+	edited.Content = "Never promote the replica by hand. Ask the oncall engineer."
+	repo.docs[1] = edited
+	require.NoError(t, svc.Attach(ctx, &published, &edited, &draft))
+	svc.Close()
+
+	require.NotNil(t, published.Summary)
+	assert.False(t, published.Summary.Stale)
+	require.NotNil(t, edited.Summary)
+	assert.True(t, edited.Summary.Stale)
+	assert.Nil(t, draft.Summary)
+
+	require.Len(t, repo.summaries, 3)
+	byDocument := map[uint]string{}
+	for _, s := range repo.summaries[1:] {
+		byDocument[s.DocumentID] = s.Summary
+	}
+	assert.Equal(t, "Never promote the replica by hand.", byDocument[1])
+	assert.Equal(t, "Draft notes.", byDocument[2])
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTagNameLength      = 255
+	maxTagNamespaceLength = 50
+	tagPathSeparator      = "/"
+	tagNamespaceSeparator = ":"
+)
+
+var (
+	ErrTagNotFound    = errors.New("tag not found")
+	ErrTagExists      = errors.New("tag with this name already exists")
+	ErrInvalidTagName = errors.New("invalid tag name")
+	ErrMergeSameTag   = errors.New("cannot merge a tag into itself")
+	ErrTagHasChildren = errors.New("tag has nested tags")
+	ErrTagAdminOnly   = errors.New("only admins can rename, delete or merge tags")
+)
+
+type TagService interface {
+	CreateTag(ctx context.Context, name string) (*models.Tag, error)
+	// RenameTag, DeleteTag and MergeTags change the tags of every document
+	// and may only be used by admins
+	RenameTag(ctx context.Context, userID, id uint, name string) (*models.Tag, error)
+	DeleteTag(ctx context.Context, userID, id uint) error
+	GetTag(ctx context.Context, id uint) (*models.Tag, error)
+	GetTagsByName(ctx context.Context, names []string) ([]models.Tag, error)
+	ListTags(ctx context.Context, params repository.TagListParams) ([]models.TagWithCount, int64, error)
+	GetTagTree(ctx context.Context) ([]*models.TagNode, error)
+	MergeTags(ctx context.Context, userID, sourceID, targetID uint) (*models.Tag, error)
+	// ResolveTags returns the tags with the given names, creating the ones
+	// that do not exist yet.
+	ResolveTags(ctx context.Context, names []string) ([]models.Tag, error)
+}
+
+type tagService struct {
+	repo     repository.TagRepository
+	userRepo repository.UserRepository
+}
+
+func NewTagService(repo repository.TagRepository, userRepo repository.UserRepository) TagService {
+	return &tagService{repo: repo, userRepo: userRepo}
+}
+
+// normalizeTagName trims whitespace around every level of a hierarchical
+// name and validates the result
+func normalizeTagName(name string) (string, error) {
+	segments := strings.Split(name, tagPathSeparator)
+	for i, segment := range segments {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			return "", ErrInvalidTagName
+		}
+		segments[i] = segment
+	}
+
+	name = strings.Join(segments, tagPathSeparator)
+	if len([]rune(name)) > maxTagNameLength {
+		return "", ErrInvalidTagName
+	}
+
+	if namespace, value, ok := strings.Cut(segments[0], tagNamespaceSeparator); ok {
+		namespace, value = strings.TrimSpace(namespace), strings.TrimSpace(value)
+		if namespace == "" || value == "" || len([]rune(namespace)) > maxTagNamespaceLength {
+			return "", ErrInvalidTagName
+		}
+		segments[0] = namespace + tagNamespaceSeparator + value
+		name = strings.Join(segments, tagPathSeparator)
+	}
+
+	return name, nil
+}
+
+// tagNamespace returns the namespace of a normalized tag name, e.g. "env"
+// for "env:prod". Nested tags inherit the namespace of their root.
+func tagNamespace(name string) string {
+	root, _, _ := strings.Cut(name, tagPathSeparator)
+	namespace, _, ok := strings.Cut(root, tagNamespaceSeparator)
+	if !ok {
+		return ""
+	}
+	return namespace
+}
+
+// parentTagName returns the name of the enclosing tag, or "" for a root tag
+func parentTagName(name string) string {
+	i := strings.LastIndex(name, tagPathSeparator)
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+func (s *tagService) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByName(ctx, name); err == nil {
+		return nil, ErrTagExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check tag existence: %w", err)
+	}
+
+	return s.ensureTag(ctx, name)
+}
+
+func (s *tagService) RenameTag(ctx context.Context, userID, id uint, name string) (*models.Tag, error) {
+	if err := s.checkAdmin(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.GetTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name == name {
+		return tag, nil
+	}
+
+	// 不允许把标签移动到它自己的子标签下
+	if strings.HasPrefix(name, tag.Name+tagPathSeparator) {
+		return nil, ErrInvalidTagName
+	}
+
+	descendants, err := s.repo.GetDescendants(ctx, tag.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// 子标签的名称随父标签一起更新，先检查所有新名称是否冲突
+	oldName := tag.Name
+	names := []string{name}
+	for i := range descendants {
+		child := &descendants[i]
+		child.Name = name + strings.TrimPrefix(child.Name, oldName)
+		child.Namespace = tagNamespace(name)
+		names = append(names, child.Name)
+	}
+	existing, err := s.repo.GetByNames(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tag existence: %w", err)
+	}
+	if len(existing) > 0 {
+		return nil, ErrTagExists
+	}
+
+	parentID, err := s.ensureParent(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Name = name
+	tag.Namespace = tagNamespace(name)
+	tag.ParentID = parentID
+	if err := s.repo.Rename(ctx, tag, descendants); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, userID, id uint) error {
+	if err := s.checkAdmin(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.GetTag(ctx, id); err != nil {
+		return err
+	}
+	if err := s.checkNoChildren(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *tagService) GetTag(ctx context.Context, id uint) (*models.Tag, error) {
+	tag, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTagNotFound
+	}
+	return tag, err
+}
+
+func (s *tagService) GetTagsByName(ctx context.Context, names []string) ([]models.Tag, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		if name, err := normalizeTagName(name); err == nil {
+			normalized = append(normalized, name)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return s.repo.GetByNames(ctx, normalized)
+}
+
+func (s *tagService) ListTags(ctx context.Context, params repository.TagListParams) ([]models.TagWithCount, int64, error) {
+	return s.repo.List(ctx, params)
+}
+
+func (s *tagService) MergeTags(ctx context.Context, userID, sourceID, targetID uint) (*models.Tag, error) {
+	if err := s.checkAdmin(ctx, userID); err != nil {
+		return nil, err
+	}
+	if sourceID == targetID {
+		return nil, ErrMergeSameTag
+	}
+
+	if _, err := s.GetTag(ctx, sourceID); err != nil {
+		return nil, err
+	}
+	if err := s.checkNoChildren(ctx, sourceID); err != nil {
+		return nil, err
+	}
+	target, err := s.GetTag(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Merge(ctx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (s *tagService) ResolveTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool, len(names))
+	wanted := make([]string, 0, len(names))
+	for _, name := range names {
+		name, err := normalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			wanted = append(wanted, name)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+
+	existing, err := s.repo.GetByNames(ctx, wanted)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]models.Tag, len(existing))
+	for _, tag := range existing {
+		byName[tag.Name] = tag
+	}
+
+	tags := make([]models.Tag, 0, len(wanted))
+	for _, name := range wanted {
+		if tag, ok := byName[name]; ok {
+			tags = append(tags, tag)
+			continue
+		}
+
+		tag, err := s.ensureTag(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+
+	return tags, nil
+}
+
+func (s *tagService) GetTagTree(ctx context.Context) ([]*models.TagNode, error) {
+	tags, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := s.repo.ListDocumentPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTagTree(tags, pairs), nil
+}
+
+// ensureTag returns the tag with the given normalized name, creating it and
+// any missing ancestors.
+func (s *tagService) ensureTag(ctx context.Context, name string) (*models.Tag, error) {
+	if tag, err := s.repo.GetByName(ctx, name); err == nil {
+		return tag, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	parentID, err := s.ensureParent(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{
+		Name:      name,
+		Namespace: tagNamespace(name),
+		ParentID:  parentID,
+	}
+	if err := s.repo.Create(ctx, tag); err != nil {
+		// 并发创建时可能已被其他请求创建，重新查询一次
+		created, getErr := s.repo.GetByName(ctx, name)
+		if getErr != nil {
+			return nil, fmt.Errorf("failed to create tag %q: %w", name, err)
+		}
+		return created, nil
+	}
+	return tag, nil
+}
+
+// ensureParent makes sure the enclosing tag of name exists and returns its ID
+func (s *tagService) ensureParent(ctx context.Context, name string) (*uint, error) {
+	parentName := parentTagName(name)
+	if parentName == "" {
+		return nil, nil
+	}
+
+	parent, err := s.ensureTag(ctx, parentName)
+	if err != nil {
+		return nil, err
+	}
+	return &parent.ID, nil
+}
+
+// checkAdmin returns ErrTagAdminOnly unless userID is an admin
+func (s *tagService) checkAdmin(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTagAdminOnly
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role != models.UserRoleAdmin {
+		return ErrTagAdminOnly
+	}
+	return nil
+}
+
+func (s *tagService) checkNoChildren(ctx context.Context, id uint) error {
+	children, err := s.repo.CountChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrTagHasChildren
+	}
+	return nil
+}
+
+// buildTagTree arranges tags by their ParentID links. Root tags of the same
+// namespace are grouped below a synthetic "namespace:" node.
+func buildTagTree(tags []models.Tag, pairs []repository.TagDocumentPair) []*models.TagNode {
+	nodes := make(map[uint]*models.TagNode, len(tags))
+	parents := make(map[*models.TagNode]*models.TagNode, len(tags))
+	namespaces := make(map[string]*models.TagNode)
+	var roots []*models.TagNode
+
+	for _, tag := range tags {
+		label := tag.Name
+		if i := strings.LastIndex(label, tagPathSeparator); i >= 0 {
+			label = label[i+1:]
+		}
+		nodes[tag.ID] = &models.TagNode{ID: tag.ID, Name: label, FullName: tag.Name}
+	}
+
+	for _, tag := range tags {
+		node := nodes[tag.ID]
+		if tag.ParentID != nil {
+			if parent, ok := nodes[*tag.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				parents[node] = parent
+				continue
+			}
+		}
+
+		if tag.Namespace != "" {
+			group, ok := namespaces[tag.Namespace]
+			if !ok {
+				group = &models.TagNode{
+					Name:     tag.Namespace,
+					FullName: tag.Namespace + tagNamespaceSeparator,
+				}
+				namespaces[tag.Namespace] = group
+				roots = append(roots, group)
+			}
+			node.Name = strings.TrimPrefix(node.Name, group.FullName)
+			group.Children = append(group.Children, node)
+			parents[node] = group
+			continue
+		}
+
+		roots = append(roots, node)
+	}
+
+	// 每个节点统计自身及所有子孙标签下的去重文档数
+	docs := make(map[*models.TagNode]map[uint]struct{})
+	for _, pair := range pairs {
+		for node := nodes[pair.TagID]; node != nil; node = parents[node] {
+			if docs[node] == nil {
+				docs[node] = make(map[uint]struct{})
+			}
+			docs[node][pair.DocumentID] = struct{}{}
+		}
+	}
+	for node, ids := range docs {
+		node.DocumentCount = int64(len(ids))
+	}
+
+	sortTagNodes(roots)
+	return roots
+}
+
+func sortTagNodes(nodes []*models.TagNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].FullName < nodes[j].FullName
+	})
+	for _, node := range nodes {
+		sortTagNodes(node.Children)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		namespace string
+		parent    string
+		wantErr   bool
+	}{
+		{input: " runbook ", expected: "runbook"},
+		{input: "team / platform /infra", expected: "team/platform/infra", parent: "team/platform"},
+		{input: "env : prod", expected: "env:prod", namespace: "env"},
+		{input: "team:platform/infra", expected: "team:platform/infra", namespace: "team", parent: "team:platform"},
+		{input: "team//infra", wantErr: true},
+		{input: ":prod", wantErr: true},
+		{input: "   ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			name, err := normalizeTagName(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidTagName)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, name)
+			assert.Equal(t, tt.namespace, tagNamespace(name))
+			assert.Equal(t, tt.parent, parentTagName(name))
+		})
+	}
+}
+
+func TestBuildTagTree(t *testing.T) {
+	team, platform := uint(1), uint(2)
+	tags := []models.Tag{
+		{ID: 1, Name: "team"},
+		{ID: 2, Name: "team/platform", ParentID: &team},
+		{ID: 3, Name: "team/platform/infra", ParentID: &platform},
+		{ID: 4, Name: "env:prod", Namespace: "env"},
+		{ID: 5, Name: "env:staging", Namespace: "env"},
+	}
+	pairs := []repository.TagDocumentPair{
+		{TagID: 3, DocumentID: 10},
+		{TagID: 2, DocumentID: 10},
+		{TagID: 1, DocumentID: 11},
+		{TagID: 4, DocumentID: 10},
+		{TagID: 5, DocumentID: 12},
+	}
+
+	roots := buildTagTree(tags, pairs)
+
+	assert.Len(t, roots, 2)
+
+	env := roots[0]
+	assert.Equal(t, "env:", env.FullName)
+	assert.Equal(t, int64(2), env.DocumentCount)
+	assert.Equal(t, "prod", env.Children[0].Name)
+
+	root := roots[1]
+	assert.Equal(t, "team", root.Name)
+	assert.Equal(t, int64(2), root.DocumentCount)
+
+	assert.Equal(t, "platform", root.Children[0].Name)
+	assert.Equal(t, int64(1), root.Children[0].DocumentCount)
+	assert.Equal(t, "team/platform/infra", root.Children[0].Children[0].FullName)
+}
+
+func (r *stubTagRepository) GetByID(ctx context.Context, id uint) (*models.Tag, error) {
+	for _, tag := range r.tags {
+		if tag.ID == id {
+			return &tag, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *stubTagRepository) GetByName(ctx context.Context, name string) (*models.Tag, error) {
+	for _, tag := range r.tags {
+		if tag.Name == name {
+			return &tag, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *stubTagRepository) GetByNames(ctx context.Context, names []string) ([]models.Tag, error) {
+	var found []models.Tag
+	for _, tag := range r.tags {
+		if slices.Contains(names, tag.Name) {
+			found = append(found, tag)
+		}
+	}
+	return found, nil
+}
+
+func (r *stubTagRepository) GetDescendants(ctx context.Context, name string) ([]models.Tag, error) {
+	var found []models.Tag
+	for _, tag := range r.tags {
+		if strings.HasPrefix(tag.Name, name+tagPathSeparator) {
+			found = append(found, tag)
+		}
+	}
+	return found, nil
+}
+
+func (r *stubTagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	tag.ID = uint(len(r.tags) + 1)
+	r.tags = append(r.tags, *tag)
+	return nil
+}
+
+func (r *stubTagRepository) Rename(ctx context.Context, tag *models.Tag, descendants []models.Tag) error {
+	for _, renamed := range append([]models.Tag{*tag}, descendants...) {
+		i := slices.IndexFunc(r.tags, func(t models.Tag) bool { return t.ID == renamed.ID })
+		r.tags[i] = renamed
+	}
+	return nil
+}
+
+func TestRenameTag(t *testing.T) {
+	ctx := context.Background()
+	team, platform := uint(1), uint(2)
+	repo := &stubTagRepository{tags: []models.Tag{
+		{ID: 1, Name: "team"},
+		{ID: 2, Name: "team/platform", ParentID: &team},
+		{ID: 3, Name: "team/platform/infra", ParentID: &platform},
+		{ID: 4, Name: "squad/infra"},
+	}}
+	users := &stubUserRepository{users: []models.User{{ID: 1, Role: models.UserRoleAdmin}, {ID: 2}}}
+	svc := NewTagService(repo, users)
+
+	// 改名影响所有文档，只有管理员可以
+	before := slices.Clone(repo.tags)
+	_, err := svc.RenameTag(ctx, 2, 2, "platform")
+	assert.ErrorIs(t, err, ErrTagAdminOnly)
+	assert.ErrorIs(t, svc.DeleteTag(ctx, 2, 4), ErrTagAdminOnly)
+	_, err = svc.MergeTags(ctx, 2, 4, 1)
+	assert.ErrorIs(t, err, ErrTagAdminOnly)
+	assert.Equal(t, before, repo.tags)
+
+	// 子标签的新名称冲突时不做任何修改
+	_, err = svc.RenameTag(ctx, 1, 2, "squad")
+	assert.ErrorIs(t, err, ErrTagExists)
+	assert.Equal(t, before, repo.tags)
+
+	_, err = svc.RenameTag(ctx, 1, 2, "team")
+	assert.ErrorIs(t, err, ErrTagExists)
+
+	tag, err := svc.RenameTag(ctx, 1, 2, "org:platform")
+	require.NoError(t, err)
+	assert.Equal(t, "org:platform", tag.Name)
+	assert.Nil(t, tag.ParentID)
+	infra, err := repo.GetByID(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "org:platform/infra", infra.Name)
+	assert.Equal(t, "org", infra.Namespace)
+}
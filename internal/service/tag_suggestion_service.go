@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+	"github.com/Zhaoyikaiii/docmind/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultTagSuggestions     = 10
+	DefaultAutoApplyThreshold = 0.8
+
+	// minSuggestionConfidence drops matches on marginal terms
+	minSuggestionConfidence = 0.15
+	// maxNewTagSuggestions caps the suggestions that are not existing tags
+	maxNewTagSuggestions = 5
+	tagSuggestionTimeout = time.Minute
+
+	// modelSuggestionConfidence is given to tags only the model proposed
+	modelSuggestionConfidence = 0.6
+	maxModelVocabulary        = 200
+	maxModelContent           = 4000
+)
+
+var ErrDocumentNotFound = errors.New("document not found")
+
+const tagSuggestionPrompt = `You suggest tags for documents. Reply with a JSON array of at most %d tag names only, without code fences.
+Prefer the existing tags listed by the user; propose a new tag only when none of them fits.`
+
+type TagSuggestionService interface {
+	// Suggest returns the tags suggested for a document userID can read that
+	// it doesn't carry yet, computing them if they haven't been
+	Suggest(ctx context.Context, userID, documentID uint) ([]models.TagSuggestion, error)
+}
+
+// TagSuggestOptions configures a TagSuggester
+type TagSuggestOptions struct {
+	// Limit is the number of suggestions kept per document
+	Limit int
+	// AutoApply adds existing tags suggested with at least Threshold
+	// confidence to the document when suggestions are recomputed
+	AutoApply bool
+	Threshold float64
+}
+
+// TagSuggester suggests tags from the content of documents. Terms are
+// weighted by TF-IDF against the search index, matched against the names of
+// existing tags, and optionally reviewed by a chat model. Suggestions are
+// recomputed in the background after every change of a document.
+type TagSuggester struct {
+	repo    repository.TagSuggestionRepository
+	docRepo repository.DocumentRepository
+	tagRepo repository.TagRepository
+	stats   search.CorpusStats
+	model   ai.ChatModel
+	opts    TagSuggestOptions
+	queue   *documentQueue
+}
+
+// NewTagSuggester creates the suggester. model may be nil to rely on
+// keyword extraction alone.
+func NewTagSuggester(repo repository.TagSuggestionRepository, docRepo repository.DocumentRepository, tagRepo repository.TagRepository, stats search.CorpusStats, model ai.ChatModel, opts TagSuggestOptions) *TagSuggester {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultTagSuggestions
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultAutoApplyThreshold
+	}
+	s := &TagSuggester{
+		repo:    repo,
+		docRepo: docRepo,
+		tagRepo: tagRepo,
+		stats:   stats,
+		model:   model,
+		opts:    opts,
+	}
+	s.queue = newDocumentQueue("suggest tags", tagSuggestionTimeout, s.refresh)
+	return s
+}
+
+func (s *TagSuggester) HandleDocumentEvent(ctx context.Context, event DocumentEvent) error {
+	return s.queue.enqueue(event.DocumentID)
+}
+
+// Close processes the queued documents and stops the worker
+func (s *TagSuggester) Close() {
+	s.queue.close()
+}
+
+func (s *TagSuggester) Suggest(ctx context.Context, userID, documentID uint) ([]models.TagSuggestion, error) {
+	doc, err := readableDocument(ctx, s.docRepo, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions, computed, err := s.repo.ListByDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if !computed {
+		if suggestions, err = s.compute(ctx, doc); err != nil {
+			return nil, err
+		}
+		if err := s.repo.Replace(ctx, documentID, suggestions); err != nil {
+			return nil, err
+		}
+	}
+
+	// 标签可能在计算后被手动添加
+	applied := appliedTags(doc)
+	result := make([]models.TagSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if !applied[strings.ToLower(suggestion.Name)] {
+			result = append(result, suggestion)
+		}
+	}
+	return result, nil
+}
+
+// refresh recomputes the suggestions of a document and auto-applies the
+// confident ones when enabled
+func (s *TagSuggester) refresh(ctx context.Context, id uint) error {
+	doc, err := s.docRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.repo.DeleteByDocument(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := s.compute(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	if s.opts.AutoApply {
+		var apply []uint
+		kept := suggestions[:0]
+		for _, suggestion := range suggestions {
+			if suggestion.TagID != nil && suggestion.Confidence >= s.opts.Threshold {
+				apply = append(apply, *suggestion.TagID)
+				continue
+			}
+			kept = append(kept, suggestion)
+		}
+		if len(apply) > 0 {
+			if err := s.docRepo.AddTags(ctx, id, apply); err != nil {
+				return err
+			}
+			suggestions = kept
+		}
+	}
+
+	return s.repo.Replace(ctx, id, suggestions)
+}
+
+// compute ranks existing tags and distinctive terms of the document
+func (s *TagSuggester) compute(ctx context.Context, doc *models.Document) ([]models.TagSuggestion, error) {
+	tags, err := s.tagRepo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer := s.stats.Analyzer(search.DetectLanguage(doc.Title + "\n" + doc.Content))
+	weights, surfaces := s.termWeights(analyzer, doc)
+	applied := appliedTags(doc)
+
+	var suggestions []models.TagSuggestion
+	covered := make(map[string]bool)
+	for i := range tags {
+		tag := &tags[i]
+		terms := tagTerms(analyzer, tag.Name)
+		if len(terms) == 0 {
+			continue
+		}
+		if applied[strings.ToLower(tag.Name)] {
+			for _, term := range terms {
+				covered[term] = true
+			}
+			continue
+		}
+
+		// 标签的每个词都须出现在文档中
+		var sum float64
+		for _, term := range terms {
+			if weights[term] == 0 {
+				sum = 0
+				break
+			}
+			sum += weights[term]
+		}
+		confidence := sum / float64(len(terms))
+		if confidence < minSuggestionConfidence {
+			continue
+		}
+		for _, term := range terms {
+			covered[term] = true
+		}
+		suggestions = append(suggestions, models.TagSuggestion{
+			DocumentID: doc.ID,
+			TagID:      &tag.ID,
+			Name:       tag.Name,
+			Confidence: confidence,
+			Source:     models.SuggestionSourceVocabulary,
+		})
+	}
+
+	// 现有标签未覆盖的高权重词作为新标签建议
+	terms := make([]string, 0, len(weights))
+	for term := range weights {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if weights[terms[i]] != weights[terms[j]] {
+			return weights[terms[i]] > weights[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	added := 0
+	for _, term := range terms {
+		if added == maxNewTagSuggestions || weights[term] < minSuggestionConfidence {
+			break
+		}
+		name, err := normalizeTagName(surfaces[term])
+		if covered[term] || err != nil || applied[name] {
+			continue
+		}
+		suggestions = append(suggestions, models.TagSuggestion{
+			DocumentID: doc.ID,
+			Name:       name,
+			Confidence: weights[term],
+			Source:     models.SuggestionSourceKeyword,
+		})
+		added++
+	}
+
+	if s.model != nil {
+		if suggestions, err = s.review(ctx, doc, tags, applied, suggestions); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			utils.Logger.Warn("Failed to review tag suggestions", zap.Error(err), zap.Uint("document_id", doc.ID))
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+	if len(suggestions) > s.opts.Limit {
+		suggestions = suggestions[:s.opts.Limit]
+	}
+	return suggestions, nil
+}
+
+// termWeights returns the TF-IDF weight of every term of a document scaled
+// so that the highest is 1, and the most frequent original spelling of each
+// term for naming new tags
+func (s *TagSuggester) termWeights(analyzer *search.Analyzer, doc *models.Document) (map[string]float64, map[string]string) {
+	tf := make(map[string]float64)
+	spellings := make(map[string]map[string]int)
+	count := func(text string, weight float64) {
+		raw := analyzer.Tokenizer.Tokenize(text)
+		for _, token := range analyzer.Analyze(text) {
+			if search.IsStopWord(token.Term) || isNumber(token.Term) {
+				continue
+			}
+			tf[token.Term] += weight
+			if spellings[token.Term] == nil {
+				spellings[token.Term] = make(map[string]int)
+			}
+			spellings[token.Term][strings.ToLower(raw[token.Position].Term)]++
+		}
+	}
+	count(doc.Title, 2)
+	count(doc.Content, 1)
+
+	n := float64(s.stats.Len())
+	weights := make(map[string]float64, len(tf))
+	var top float64
+	for term, f := range tf {
+		idf := math.Log((n+1)/(float64(s.stats.DocumentFrequency(term))+1)) + 1
+		weights[term] = (1 + math.Log(f)) * idf
+		top = math.Max(top, weights[term])
+	}
+	surfaces := make(map[string]string, len(tf))
+	for term := range weights {
+		weights[term] /= top
+		surfaces[term] = mostFrequent(spellings[term])
+	}
+	return weights, surfaces
+}
+
+// review asks the model to pick tags among the existing vocabulary and the
+// candidates. Picked candidates gain confidence and tags only the model
+// proposes are added with a moderate confidence.
+func (s *TagSuggester) review(ctx context.Context, doc *models.Document, tags []models.Tag, applied map[string]bool, suggestions []models.TagSuggestion) ([]models.TagSuggestion, error) {
+	vocabulary := make([]string, 0, min(len(tags), maxModelVocabulary))
+	byName := make(map[string]*models.Tag, len(tags))
+	for i := range tags {
+		byName[strings.ToLower(tags[i].Name)] = &tags[i]
+		if len(vocabulary) < maxModelVocabulary {
+			vocabulary = append(vocabulary, tags[i].Name)
+		}
+	}
+	candidates := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		candidates = append(candidates, suggestion.Name)
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Existing tags: %s\n", strings.Join(vocabulary, ", "))
+	fmt.Fprintf(&prompt, "Candidate tags: %s\n\n", strings.Join(candidates, ", "))
+	fmt.Fprintf(&prompt, "# %s\n\n%s", doc.Title, truncateRunes(doc.Content, maxModelContent))
+
+	messages := []ai.Message{
+		{Role: ai.RoleSystem, Content: fmt.Sprintf(tagSuggestionPrompt, s.opts.Limit)},
+		{Role: ai.RoleUser, Content: prompt.String()},
+	}
+	reply, err := s.model.Stream(ctx, messages, func(string) error { return nil })
+	if err != nil {
+		return suggestions, err
+	}
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return suggestions, fmt.Errorf("tag reply is not a JSON array")
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &names); err != nil {
+		return suggestions, fmt.Errorf("failed to parse tag reply: %w", err)
+	}
+
+	for _, name := range names {
+		name, err := normalizeTagName(name)
+		if err != nil || applied[strings.ToLower(name)] {
+			continue
+		}
+		if i := suggestionIndex(suggestions, name); i >= 0 {
+			suggestions[i].Confidence = (suggestions[i].Confidence + 1) / 2
+			continue
+		}
+		suggestion := models.TagSuggestion{
+			DocumentID: doc.ID,
+			Name:       name,
+			Confidence: modelSuggestionConfidence,
+			Source:     models.SuggestionSourceModel,
+		}
+		if tag, ok := byName[strings.ToLower(name)]; ok {
+			suggestion.TagID = &tag.ID
+			suggestion.Name = tag.Name
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// suggestionIndex returns the index of the suggestion named name, ignoring
+// case, or -1
+func suggestionIndex(suggestions []models.TagSuggestion, name string) int {
+	for i := range suggestions {
+		if strings.EqualFold(suggestions[i].Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// tagTerms analyzes the last level of a tag name, without its namespace, so
+// "team/platform" and "env:prod" match on "platform" and "prod"
+func tagTerms(analyzer *search.Analyzer, name string) []string {
+	leaf := name[strings.LastIndex(name, tagPathSeparator)+1:]
+	if _, value, ok := strings.Cut(leaf, tagNamespaceSeparator); ok {
+		leaf = value
+	}
+	var terms []string
+	for _, token := range analyzer.Analyze(leaf) {
+		if !search.IsStopWord(token.Term) {
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+// appliedTags returns the lowercased names of the tags of a document
+func appliedTags(doc *models.Document) map[string]bool {
+	applied := make(map[string]bool, len(doc.Tags))
+	for _, tag := range doc.Tags {
+		applied[strings.ToLower(tag.Name)] = true
+	}
+	return applied
+}
+
+func isNumber(term string) bool {
+	for _, r := range term {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func mostFrequent(counts map[string]int) string {
+	var best string
+	for s, n := range counts {
+		if n > counts[best] || (n == counts[best] && s < best) {
+			best = s
+		}
+	}
+	return best
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
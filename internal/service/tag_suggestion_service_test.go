@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/ai"
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/Zhaoyikaiii/docmind/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSuggestionRepository struct {
+	repository.TagSuggestionRepository
+	stored   map[uint][]models.TagSuggestion
+	replaced int
+}
+
+func (r *stubSuggestionRepository) Replace(ctx context.Context, documentID uint, suggestions []models.TagSuggestion) error {
+	r.stored[documentID] = suggestions
+	r.replaced++
+	return nil
+}
+
+func (r *stubSuggestionRepository) ListByDocument(ctx context.Context, documentID uint) ([]models.TagSuggestion, bool, error) {
+	suggestions, ok := r.stored[documentID]
+	return suggestions, ok, nil
+}
+
+func (r *stubSuggestionRepository) DeleteByDocument(ctx context.Context, documentID uint) error {
+	delete(r.stored, documentID)
+	return nil
+}
+
+type stubTagRepository struct {
+	repository.TagRepository
+	tags []models.Tag
+}
+
+func (r *stubTagRepository) ListAll(ctx context.Context) ([]models.Tag, error) {
+	return r.tags, nil
+}
+
+// stubTaggingRepository serves documents and records added tags
+type stubTaggingRepository struct {
+	*stubDocumentRepository
+	added map[uint][]uint
+}
+
+func (r *stubTaggingRepository) AddTags(ctx context.Context, docID uint, tagIDs []uint) error {
+	r.added[docID] = append(r.added[docID], tagIDs...)
+	return nil
+}
+
+func tagSuggestionFixture(t *testing.T) (*stubSuggestionRepository, *stubTaggingRepository, *stubTagRepository, *search.Index) {
+	docs := &stubTaggingRepository{added: map[uint][]uint{}, stubDocumentRepository: &stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "Database failover", CreatorID: 1, Content: "Promote the replica when the primary database fails. " +
+			"The replica takes over and replication restarts from the new primary database."},
+		2: {ID: 2, Title: "Team lunch", CreatorID: 1, Content: "Lunch is served on Fridays for the whole team."},
+		3: {ID: 3, Title: "Hiring", CreatorID: 1, Content: "The team is hiring engineers for the platform."},
+	}}}
+	index, err := search.NewIndex("", search.Options{})
+	require.NoError(t, err)
+	for _, doc := range docs.docs {
+		require.NoError(t, index.Index(context.Background(), &doc))
+	}
+	tags := &stubTagRepository{tags: []models.Tag{
+		{ID: 10, Name: "databases"},
+		{ID: 11, Name: "team"},
+		{ID: 12, Name: "env:primary"},
+		{ID: 13, Name: "infra/replication"},
+	}}
+	return &stubSuggestionRepository{stored: map[uint][]models.TagSuggestion{}}, docs, tags, index
+}
+
+func suggestionNames(suggestions []models.TagSuggestion) []string {
+	names := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestTagSuggesterSuggest(t *testing.T) {
+	ctx := context.Background()
+	repo, docs, tags, index := tagSuggestionFixture(t)
+	doc := docs.docs[1]
+	doc.Tags = []models.Tag{{ID: 12, Name: "env:primary"}}
+	docs.docs[1] = doc
+
+	suggester := NewTagSuggester(repo, docs, tags, index, nil, TagSuggestOptions{})
+	defer suggester.Close()
+
+	suggestions, err := suggester.Suggest(ctx, 1, 1)
+	require.NoError(t, err)
+	names := suggestionNames(suggestions)
+
+	// 词干匹配现有标签，已有标签和无关标签不出现
+	assert.Contains(t, names, "databases")
+	assert.Contains(t, names, "infra/replication")
+	assert.NotContains(t, names, "team")
+	assert.NotContains(t, names, "env:primary")
+	// 新标签建议使用原文拼写，且不重复现有标签的词
+	assert.Contains(t, names, "replica")
+	assert.NotContains(t, names, "database")
+	assert.NotContains(t, names, "primary")
+
+	for _, s := range suggestions {
+		assert.LessOrEqual(t, s.Confidence, 1.0)
+		if s.Name == "databases" {
+			require.NotNil(t, s.TagID)
+			assert.Equal(t, uint(10), *s.TagID)
+			assert.Equal(t, models.SuggestionSourceVocabulary, s.Source)
+		}
+	}
+	assert.Len(t, repo.stored[1], len(suggestions))
+
+	_, err = suggester.Suggest(ctx, 1, 99)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, err = suggester.Suggest(ctx, 2, 1)
+	assert.ErrorIs(t, err, ErrDocumentNotFound, "drafts of other users are hidden")
+
+	// 没有建议的结果也会保存，不再重复计算
+	docs.docs[4] = models.Document{ID: 4, CreatorID: 1}
+	replaced := repo.replaced
+	for range 2 {
+		suggestions, err = suggester.Suggest(ctx, 1, 4)
+		require.NoError(t, err)
+		assert.Empty(t, suggestions)
+	}
+	assert.Equal(t, replaced+1, repo.replaced)
+}
+
+func TestTagSuggesterAutoApply(t *testing.T) {
+	ctx := context.Background()
+	repo, docs, tags, index := tagSuggestionFixture(t)
+
+	suggester := NewTagSuggester(repo, docs, tags, index, nil, TagSuggestOptions{AutoApply: true, Threshold: 0.9})
+	doc := docs.docs[1]
+	require.NoError(t, suggester.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentUpdated, DocumentID: 1, Document: &doc}))
+	require.NoError(t, suggester.HandleDocumentEvent(ctx, DocumentEvent{Type: DocumentDeleted, DocumentID: 7}))
+	suggester.Close()
+
+	assert.Equal(t, []uint{10}, docs.added[1])
+	assert.NotContains(t, suggestionNames(repo.stored[1]), "databases")
+	assert.NotEmpty(t, repo.stored[1])
+	assert.NotContains(t, repo.stored, uint(7))
+}
+
+func TestTagSuggesterModelReview(t *testing.T) {
+	ctx := context.Background()
+	repo, docs, tags, index := tagSuggestionFixture(t)
+
+	model := ai.NewFakeChatModel(`Suggested: ["Team", "replica", "high availability", " "]`)
+	suggester := NewTagSuggester(repo, docs, tags, index, model, TagSuggestOptions{})
+	defer suggester.Close()
+
+	suggestions, err := suggester.Suggest(ctx, 1, 1)
+	require.NoError(t, err)
+
+	byName := make(map[string]models.TagSuggestion)
+	for _, s := range suggestions {
+		byName[s.Name] = s
+	}
+	require.Contains(t, byName, "team")
+	assert.Equal(t, uint(11), *byName["team"].TagID)
+	assert.Equal(t, models.SuggestionSourceModel, byName["team"].Source)
+	assert.Contains(t, byName, "high availability")
+	// 模型认可的候选提高置信度
+	assert.Equal(t, models.SuggestionSourceKeyword, byName["replica"].Source)
+	assert.Greater(t, byName["replica"].Confidence, 0.5)
+}
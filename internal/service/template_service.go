@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrTemplateNameRequired    = errors.New("template name is required")
+	ErrTemplateNameExists      = errors.New("template with this name already exists in the workspace")
+	ErrInvalidTemplateVariable = errors.New("invalid template variable")
+	ErrInvalidTemplateStatus   = errors.New("status must be draft, published or archived")
+	ErrTemplateValuesMissing   = errors.New("missing values for required template variables")
+	ErrTemplateTitleRequired   = errors.New("a title is required for documents from this template")
+	ErrTemplateNotDeletable    = errors.New("only the creator of a template or a workspace owner can delete it")
+)
+
+// Built-in placeholders, filled in without being declared as variables
+const (
+	placeholderDate   = "date"   // 2006-01-02
+	placeholderTime   = "time"   // 15:04
+	placeholderAuthor = "author" // full name of the user creating the document
+	placeholderTitle  = "title"  // title of the new document
+)
+
+var builtinPlaceholders = []string{placeholderDate, placeholderTime, placeholderAuthor, placeholderTitle}
+
+var (
+	placeholderPattern  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// SaveTemplateRequest saves a document as a template of a workspace. Title
+// defaults to the document's title and DefaultTags, when nil, to its tags.
+type SaveTemplateRequest struct {
+	UserID        uint
+	WorkspaceID   uint
+	DocumentID    uint
+	Name          string
+	Description   string
+	Category      string
+	Title         string
+	Variables     []models.TemplateVariable
+	DefaultTags   []string
+	DefaultStatus string
+}
+
+// CreateFromTemplateRequest creates a document from a template. Values fill
+// the template's variables and may override the built-in placeholders, e.g.
+// to date a postmortem; Tags are added to the template's default tags.
+type CreateFromTemplateRequest struct {
+	UserID     uint
+	TemplateID uint
+	Title      string
+	Values     map[string]string
+	Tags       []string
+	ParentID   *uint
+}
+
+// TemplateService manages the document templates of workspaces. Only
+// members of a workspace see and use its templates.
+type TemplateService interface {
+	// SaveTemplate stores a readable document as a template. Placeholders
+	// of the document that are neither built in nor declared are added to
+	// the template's variables.
+	SaveTemplate(ctx context.Context, req SaveTemplateRequest) (*models.Template, error)
+	// ListTemplates returns the templates of a workspace, only those of
+	// category when it is not empty
+	ListTemplates(ctx context.Context, userID, workspaceID uint, category string) ([]models.Template, error)
+	GetTemplate(ctx context.Context, userID, templateID uint) (*models.Template, error)
+	DeleteTemplate(ctx context.Context, userID, templateID uint) error
+	// CreateDocument creates a document in the template's workspace with its
+	// placeholders filled in and the template's default tags and status
+	CreateDocument(ctx context.Context, req CreateFromTemplateRequest) (*models.Document, error)
+}
+
+type templateService struct {
+	repo       repository.TemplateRepository
+	workspaces WorkspaceService
+	docRepo    repository.DocumentRepository
+	docService DocumentService
+	userRepo   repository.UserRepository
+}
+
+func NewTemplateService(repo repository.TemplateRepository, workspaces WorkspaceService, docRepo repository.DocumentRepository, docService DocumentService, userRepo repository.UserRepository) TemplateService {
+	return &templateService{
+		repo:       repo,
+		workspaces: workspaces,
+		docRepo:    docRepo,
+		docService: docService,
+		userRepo:   userRepo,
+	}
+}
+
+func (s *templateService) SaveTemplate(ctx context.Context, req SaveTemplateRequest) (*models.Template, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrTemplateNameRequired
+	}
+	status := req.DefaultStatus
+	switch status {
+	case "":
+		status = models.DocumentStatusDraft
+	case models.DocumentStatusDraft, models.DocumentStatusPublished, models.DocumentStatusArchived:
+	default:
+		return nil, ErrInvalidTemplateStatus
+	}
+
+	if _, err := s.workspaces.Membership(ctx, req.UserID, req.WorkspaceID); err != nil {
+		return nil, err
+	}
+	doc, err := readableDocument(ctx, s.docRepo, req.UserID, req.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.ExistsByName(ctx, req.WorkspaceID, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrTemplateNameExists
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = doc.Title
+	}
+	variables, err := templateVariables(req.Variables, title, doc.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := req.DefaultTags
+	if tags == nil {
+		tags = make([]string, 0, len(doc.Tags))
+		for _, tag := range doc.Tags {
+			tags = append(tags, tag.Name)
+		}
+	}
+	defaultTags, err := uniqueTagNames(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.Template{
+		WorkspaceID:   req.WorkspaceID,
+		Name:          truncateRunes(name, 255),
+		Description:   truncateRunes(strings.TrimSpace(req.Description), 1024),
+		Category:      truncateRunes(strings.ToLower(strings.TrimSpace(req.Category)), 50),
+		Title:         truncateRunes(title, 255),
+		Content:       doc.Content,
+		Variables:     variables,
+		DefaultTags:   defaultTags,
+		DefaultStatus: status,
+		CreatorID:     req.UserID,
+		DocumentID:    &doc.ID,
+	}
+	if err := s.repo.Create(ctx, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *templateService) ListTemplates(ctx context.Context, userID, workspaceID uint, category string) ([]models.Template, error) {
+	if _, err := s.workspaces.Membership(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByWorkspace(ctx, workspaceID, strings.ToLower(strings.TrimSpace(category)))
+}
+
+func (s *templateService) GetTemplate(ctx context.Context, userID, templateID uint) (*models.Template, error) {
+	template, err := s.repo.GetByID(ctx, templateID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 其他工作区的模板按不存在处理
+	if _, err := s.workspaces.Membership(ctx, userID, template.WorkspaceID); err != nil {
+		if errors.Is(err, ErrWorkspaceNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *templateService) DeleteTemplate(ctx context.Context, userID, templateID uint) error {
+	template, err := s.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return err
+	}
+	if template.CreatorID != userID {
+		member, err := s.workspaces.Membership(ctx, userID, template.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		if member.Role != models.WorkspaceRoleOwner {
+			return ErrTemplateNotDeletable
+		}
+	}
+	return s.repo.Delete(ctx, templateID)
+}
+
+func (s *templateService) CreateDocument(ctx context.Context, req CreateFromTemplateRequest) (*models.Document, error) {
+	template, err := s.GetTemplate(ctx, req.UserID, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	author := user.FullName
+	if author == "" {
+		author = user.Username
+	}
+	now := time.Now()
+	values := map[string]string{
+		placeholderDate:   now.Format(time.DateOnly),
+		placeholderTime:   now.Format("15:04"),
+		placeholderAuthor: author,
+	}
+	for _, name := range builtinPlaceholders {
+		if value := strings.TrimSpace(req.Values[name]); value != "" {
+			values[name] = value
+		}
+	}
+
+	var missing []string
+	for _, variable := range template.Variables {
+		value := strings.TrimSpace(req.Values[variable.Name])
+		if value == "" {
+			value = variable.Default
+		}
+		if value == "" && variable.Required {
+			missing = append(missing, variable.Name)
+		}
+		values[variable.Name] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateValuesMissing, strings.Join(missing, ", "))
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = strings.TrimSpace(fillPlaceholders(template.Title, values))
+	}
+	if title == "" {
+		return nil, ErrTemplateTitleRequired
+	}
+	title = truncateRunes(title, 255)
+	values[placeholderTitle] = title
+
+	tagNames, err := uniqueTagNames(append(slices.Clone(template.DefaultTags), req.Tags...))
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]models.Tag, 0, len(tagNames))
+	for _, name := range tagNames {
+		tags = append(tags, models.Tag{Name: name})
+	}
+
+	status := template.DefaultStatus
+	if status == "" {
+		status = models.DocumentStatusDraft
+	}
+	workspaceID := template.WorkspaceID
+	doc := &models.Document{
+		Title:       title,
+		Content:     fillPlaceholders(template.Content, values),
+		Status:      status,
+		CreatorID:   req.UserID,
+		ParentID:    req.ParentID,
+		WorkspaceID: &workspaceID,
+		Tags:        tags,
+	}
+	if err := s.docService.CreateDocument(ctx, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// templateVariables validates the declared variables and adds the
+// undeclared placeholders of title and content, in order of appearance
+func templateVariables(declared []models.TemplateVariable, title, content string) ([]models.TemplateVariable, error) {
+	variables := make([]models.TemplateVariable, 0, len(declared))
+	seen := make(map[string]bool, len(declared))
+	for _, variable := range declared {
+		variable.Name = strings.TrimSpace(variable.Name)
+		if !variableNamePattern.MatchString(variable.Name) {
+			return nil, fmt.Errorf("%w: %q is not a valid name", ErrInvalidTemplateVariable, variable.Name)
+		}
+		if slices.Contains(builtinPlaceholders, variable.Name) {
+			return nil, fmt.Errorf("%w: %s is built in", ErrInvalidTemplateVariable, variable.Name)
+		}
+		if seen[variable.Name] {
+			return nil, fmt.Errorf("%w: %s is declared twice", ErrInvalidTemplateVariable, variable.Name)
+		}
+		seen[variable.Name] = true
+		variable.Description = truncateRunes(strings.TrimSpace(variable.Description), 255)
+		variables = append(variables, variable)
+	}
+
+	for _, text := range []string{title, content} {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			name := match[1]
+			if seen[name] || slices.Contains(builtinPlaceholders, name) {
+				continue
+			}
+			seen[name] = true
+			variables = append(variables, models.TemplateVariable{Name: name})
+		}
+	}
+	return variables, nil
+}
+
+// fillPlaceholders replaces the placeholders of text that have a value.
+// Others are kept, so literal braces in code samples survive.
+func fillPlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// uniqueTagNames normalizes tag names and drops duplicates
+func uniqueTagNames(names []string) ([]string, error) {
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized, err := normalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(unique, normalized) {
+			unique = append(unique, normalized)
+		}
+	}
+	return unique, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// stubTemplateRepository keeps templates in memory
+type stubTemplateRepository struct {
+	repository.TemplateRepository
+	templates map[uint]*models.Template
+}
+
+func (r *stubTemplateRepository) Create(ctx context.Context, template *models.Template) error {
+	template.ID = uint(len(r.templates) + 1)
+	r.templates[template.ID] = template
+	return nil
+}
+
+func (r *stubTemplateRepository) GetByID(ctx context.Context, id uint) (*models.Template, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *template
+	return &copied, nil
+}
+
+func (r *stubTemplateRepository) ListByWorkspace(ctx context.Context, workspaceID uint, category string) ([]models.Template, error) {
+	var templates []models.Template
+	for id := uint(1); id <= uint(len(r.templates)); id++ {
+		template, ok := r.templates[id]
+		if ok && template.WorkspaceID == workspaceID && (category == "" || template.Category == category) {
+			templates = append(templates, *template)
+		}
+	}
+	return templates, nil
+}
+
+func (r *stubTemplateRepository) ExistsByName(ctx context.Context, workspaceID uint, name string) (bool, error) {
+	for _, template := range r.templates {
+		if template.WorkspaceID == workspaceID && template.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *stubTemplateRepository) Delete(ctx context.Context, id uint) error {
+	delete(r.templates, id)
+	return nil
+}
+
+func TestTemplates(t *testing.T) {
+	ctx := context.Background()
+	users := &stubUserRepository{users: []models.User{
+		{ID: 1, Username: "alice", FullName: "Alice Liddell"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"},
+	}}
+	workspaces := NewWorkspaceService(newStubWorkspaceRepository(), users)
+	workspace, err := workspaces.CreateWorkspace(ctx, 1, "Platform", "")
+	require.NoError(t, err)
+	_, err = workspaces.AddMember(ctx, 1, workspace.ID, 2, "")
+	require.NoError(t, err)
+
+	docRepo := &stubTreeRepository{&stubDocumentRepository{docs: map[uint]models.Document{
+		1: {ID: 1, Title: "ADR-001: Use PostgreSQL", CreatorID: 1, Status: models.DocumentStatusPublished,
+			Tags: []models.Tag{{ID: 4, Name: "adr"}},
+			Content: "# {{title}}\n\nDate: {{ date }}\nDeciders: {{author}}\nStatus: {{ status }}\n\n## Context\n\n{{context}}\n\n" +
+				"```\n{{not a placeholder}}\n```\n"},
+		2: {ID: 2, Title: "Private draft", CreatorID: 3, Status: models.DocumentStatusDraft},
+	}}}
+	docs := &stubDocumentCreator{}
+	svc := NewTemplateService(&stubTemplateRepository{templates: map[uint]*models.Template{}}, workspaces, docRepo, docs, users)
+
+	save := SaveTemplateRequest{
+		UserID:      2,
+		WorkspaceID: workspace.ID,
+		DocumentID:  1,
+		Name:        "ADR",
+		Category:    " ADR ",
+		Title:       "ADR-{{number}}: {{decision}}",
+		Variables: []models.TemplateVariable{
+			{Name: "number", Required: true},
+			{Name: "status", Default: "Proposed"},
+		},
+	}
+	template, err := svc.SaveTemplate(ctx, save)
+	require.NoError(t, err)
+	assert.Equal(t, "adr", template.Category)
+	assert.Equal(t, models.DocumentStatusDraft, template.DefaultStatus)
+	assert.Equal(t, []string{"adr"}, template.DefaultTags, "tags default to the document's")
+	assert.Equal(t, []models.TemplateVariable{
+		{Name: "number", Required: true},
+		{Name: "status", Default: "Proposed"},
+		{Name: "decision"},
+		{Name: "context"},
+	}, template.Variables, "undeclared placeholders are added")
+
+	_, err = svc.SaveTemplate(ctx, save)
+	assert.ErrorIs(t, err, ErrTemplateNameExists)
+
+	invalid := save
+	invalid.Name = "Invalid"
+	invalid.Variables = []models.TemplateVariable{{Name: "date"}}
+	_, err = svc.SaveTemplate(ctx, invalid)
+	assert.ErrorIs(t, err, ErrInvalidTemplateVariable, "built-in placeholders can't be redeclared")
+
+	private := save
+	private.Name, private.DocumentID = "Private", 2
+	_, err = svc.SaveTemplate(ctx, private)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	outsider := save
+	outsider.UserID, outsider.Name = 3, "Outsider"
+	_, err = svc.SaveTemplate(ctx, outsider)
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+
+	postmortem := save
+	postmortem.Name, postmortem.Category, postmortem.Title, postmortem.Variables = "Postmortem", "postmortem", "", nil
+	postmortem.DefaultTags, postmortem.DefaultStatus = []string{"incident", " incident "}, models.DocumentStatusPublished
+	_, err = svc.SaveTemplate(ctx, postmortem)
+	require.NoError(t, err)
+
+	templates, err := svc.ListTemplates(ctx, 1, workspace.ID, "ADR")
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, "ADR", templates[0].Name)
+	_, err = svc.ListTemplates(ctx, 3, workspace.ID, "")
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+	_, err = svc.GetTemplate(ctx, 3, template.ID)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	_, err = svc.CreateDocument(ctx, CreateFromTemplateRequest{UserID: 1, TemplateID: template.ID})
+	assert.ErrorIs(t, err, ErrTemplateValuesMissing)
+
+	doc, err := svc.CreateDocument(ctx, CreateFromTemplateRequest{
+		UserID:     1,
+		TemplateID: template.ID,
+		Values:     map[string]string{"number": "002", "decision": "Use Kafka", "context": "Queues are growing."},
+		Tags:       []string{"messaging"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ADR-002: Use Kafka", doc.Title)
+	assert.Equal(t, "# ADR-002: Use Kafka\n\nDate: "+time.Now().Format(time.DateOnly)+"\nDeciders: Alice Liddell\nStatus: Proposed\n\n"+
+		"## Context\n\nQueues are growing.\n\n```\n{{not a placeholder}}\n```\n", doc.Content)
+	assert.Equal(t, []models.Tag{{Name: "adr"}, {Name: "messaging"}}, doc.Tags)
+	assert.Equal(t, models.DocumentStatusDraft, doc.Status)
+	assert.Equal(t, uint(1), doc.CreatorID)
+	require.NotNil(t, doc.WorkspaceID)
+	assert.Equal(t, workspace.ID, *doc.WorkspaceID)
+
+	_, err = svc.CreateDocument(ctx, CreateFromTemplateRequest{
+		UserID:     1,
+		TemplateID: template.ID,
+		Values:     map[string]string{"number": "002", "decision": "Use Kafka"},
+	})
+	assert.ErrorIs(t, err, ErrDocumentTitleExists)
+
+	incident, err := svc.CreateDocument(ctx, CreateFromTemplateRequest{
+		UserID:     2,
+		TemplateID: 2,
+		Title:      "Outage on 2024-05-01",
+		Values:     map[string]string{"date": "2024-05-01", "number": "x"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, incident.Content, "# Outage on 2024-05-01\n\nDate: 2024-05-01\nDeciders: bob\n")
+	assert.Equal(t, []models.Tag{{Name: "incident"}}, incident.Tags)
+	assert.Equal(t, models.DocumentStatusPublished, incident.Status)
+
+	assert.ErrorIs(t, svc.DeleteTemplate(ctx, 2, 99), ErrTemplateNotFound)
+	require.NoError(t, svc.DeleteTemplate(ctx, 1, template.ID), "owners delete any template")
+	_, err = svc.GetTemplate(ctx, 1, template.ID)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
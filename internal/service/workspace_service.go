@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWorkspaceNotFound     = errors.New("workspace not found")
+	ErrWorkspaceNameRequired = errors.New("workspace name is required")
+	ErrNotWorkspaceOwner     = errors.New("only workspace owners can do this")
+	ErrInvalidWorkspaceRole  = errors.New("role must be owner or member")
+	ErrWorkspaceUserNotFound = errors.New("user not found")
+	ErrWorkspaceCreator      = errors.New("the creator of a workspace cannot be removed")
+)
+
+type WorkspaceService interface {
+	// CreateWorkspace creates a workspace with userID as its owner
+	CreateWorkspace(ctx context.Context, userID uint, name, description string) (*models.Workspace, error)
+	// ListWorkspaces returns the workspaces userID is a member of
+	ListWorkspaces(ctx context.Context, userID uint) ([]models.Workspace, error)
+	// GetWorkspace returns a workspace of userID with its members
+	GetWorkspace(ctx context.Context, userID, workspaceID uint) (*models.Workspace, error)
+	// Membership returns the membership of userID, or ErrWorkspaceNotFound
+	// when userID is not a member
+	Membership(ctx context.Context, userID, workspaceID uint) (*models.WorkspaceMember, error)
+	// AddMember adds memberID with role, or changes the role of an existing
+	// member. Only owners manage members.
+	AddMember(ctx context.Context, userID, workspaceID, memberID uint, role string) (*models.WorkspaceMember, error)
+	// RemoveMember removes memberID. Owners remove anyone but the creator,
+	// members only themselves.
+	RemoveMember(ctx context.Context, userID, workspaceID, memberID uint) error
+}
+
+type workspaceService struct {
+	repo     repository.WorkspaceRepository
+	userRepo repository.UserRepository
+}
+
+func NewWorkspaceService(repo repository.WorkspaceRepository, userRepo repository.UserRepository) WorkspaceService {
+	return &workspaceService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *workspaceService) CreateWorkspace(ctx context.Context, userID uint, name, description string) (*models.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrWorkspaceNameRequired
+	}
+
+	workspace := &models.Workspace{
+		Name:        truncateRunes(name, 255),
+		Description: truncateRunes(strings.TrimSpace(description), 1024),
+		CreatorID:   userID,
+		Members:     []models.WorkspaceMember{{UserID: userID, Role: models.WorkspaceRoleOwner}},
+	}
+	if err := s.repo.Create(ctx, workspace); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+func (s *workspaceService) ListWorkspaces(ctx context.Context, userID uint) ([]models.Workspace, error) {
+	return s.repo.ListByMember(ctx, userID)
+}
+
+func (s *workspaceService) GetWorkspace(ctx context.Context, userID, workspaceID uint) (*models.Workspace, error) {
+	if _, err := s.Membership(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWorkspaceNotFound
+	}
+	return workspace, err
+}
+
+func (s *workspaceService) Membership(ctx context.Context, userID, workspaceID uint) (*models.WorkspaceMember, error) {
+	member, err := s.repo.GetMember(ctx, workspaceID, userID)
+	// 非成员按工作区不存在处理
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWorkspaceNotFound
+	}
+	return member, err
+}
+
+func (s *workspaceService) AddMember(ctx context.Context, userID, workspaceID, memberID uint, role string) (*models.WorkspaceMember, error) {
+	if role == "" {
+		role = models.WorkspaceRoleMember
+	}
+	if role != models.WorkspaceRoleOwner && role != models.WorkspaceRoleMember {
+		return nil, ErrInvalidWorkspaceRole
+	}
+	if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, memberID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWorkspaceUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	member := &models.WorkspaceMember{WorkspaceID: workspaceID, UserID: memberID, Role: role}
+	if err := s.repo.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	member.User = user
+	return member, nil
+}
+
+func (s *workspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID uint) error {
+	if memberID != userID {
+		if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	workspace, err := s.GetWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.CreatorID == memberID {
+		return ErrWorkspaceCreator
+	}
+	return s.repo.RemoveMember(ctx, workspaceID, memberID)
+}
+
+func (s *workspaceService) requireOwner(ctx context.Context, userID, workspaceID uint) error {
+	member, err := s.Membership(ctx, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if member.Role != models.WorkspaceRoleOwner {
+		return ErrNotWorkspaceOwner
+	}
+	return nil
+}
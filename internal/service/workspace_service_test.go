@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zhaoyikaiii/docmind/internal/models"
+	"github.com/Zhaoyikaiii/docmind/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// stubWorkspaceRepository keeps workspaces and their members in memory
+type stubWorkspaceRepository struct {
+	repository.WorkspaceRepository
+	workspaces map[uint]*models.Workspace
+	members    map[[2]uint]models.WorkspaceMember // workspace ID, user ID
+}
+
+func newStubWorkspaceRepository() *stubWorkspaceRepository {
+	return &stubWorkspaceRepository{workspaces: map[uint]*models.Workspace{}, members: map[[2]uint]models.WorkspaceMember{}}
+}
+
+func (r *stubWorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	workspace.ID = uint(len(r.workspaces) + 1)
+	r.workspaces[workspace.ID] = workspace
+	for _, member := range workspace.Members {
+		member.WorkspaceID = workspace.ID
+		r.members[[2]uint{workspace.ID, member.UserID}] = member
+	}
+	return nil
+}
+
+func (r *stubWorkspaceRepository) GetByID(ctx context.Context, id uint) (*models.Workspace, error) {
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *workspace
+	copied.Members = nil
+	for key, member := range r.members {
+		if key[0] == id {
+			copied.Members = append(copied.Members, member)
+		}
+	}
+	return &copied, nil
+}
+
+func (r *stubWorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID uint) (*models.WorkspaceMember, error) {
+	member, ok := r.members[[2]uint{workspaceID, userID}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &member, nil
+}
+
+func (r *stubWorkspaceRepository) SaveMember(ctx context.Context, member *models.WorkspaceMember) error {
+	r.members[[2]uint{member.WorkspaceID, member.UserID}] = *member
+	return nil
+}
+
+func (r *stubWorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uint) error {
+	delete(r.members, [2]uint{workspaceID, userID})
+	return nil
+}
+
+func TestWorkspaceMembers(t *testing.T) {
+	ctx := context.Background()
+	users := &stubUserRepository{users: []models.User{
+		{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"},
+	}}
+	svc := NewWorkspaceService(newStubWorkspaceRepository(), users)
+
+	_, err := svc.CreateWorkspace(ctx, 1, "  ", "")
+	assert.ErrorIs(t, err, ErrWorkspaceNameRequired)
+
+	workspace, err := svc.CreateWorkspace(ctx, 1, " Platform ", "Platform team")
+	require.NoError(t, err)
+	assert.Equal(t, "Platform", workspace.Name)
+
+	owner, err := svc.Membership(ctx, 1, workspace.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WorkspaceRoleOwner, owner.Role)
+
+	_, err = svc.GetWorkspace(ctx, 2, workspace.ID)
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound, "non-members don't see the workspace")
+
+	member, err := svc.AddMember(ctx, 1, workspace.ID, 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.WorkspaceRoleMember, member.Role)
+	assert.Equal(t, "bob", member.User.Username)
+
+	_, err = svc.AddMember(ctx, 2, workspace.ID, 3, models.WorkspaceRoleMember)
+	assert.ErrorIs(t, err, ErrNotWorkspaceOwner)
+	_, err = svc.AddMember(ctx, 1, workspace.ID, 3, "admin")
+	assert.ErrorIs(t, err, ErrInvalidWorkspaceRole)
+	_, err = svc.AddMember(ctx, 1, workspace.ID, 42, "")
+	assert.ErrorIs(t, err, ErrWorkspaceUserNotFound)
+
+	loaded, err := svc.GetWorkspace(ctx, 2, workspace.ID)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Members, 2)
+
+	assert.ErrorIs(t, svc.RemoveMember(ctx, 2, workspace.ID, 1), ErrNotWorkspaceOwner)
+	_, err = svc.AddMember(ctx, 1, workspace.ID, 2, models.WorkspaceRoleOwner)
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.RemoveMember(ctx, 2, workspace.ID, 1), ErrWorkspaceCreator)
+
+	require.NoError(t, svc.RemoveMember(ctx, 2, workspace.ID, 2), "members may leave")
+	_, err = svc.Membership(ctx, 2, workspace.ID)
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+}
@@ -0,0 +1,68 @@
+// Package slug turns titles into readable URL segments
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxLength is the longest slug Make returns, in characters
+	MaxLength = 80
+	// Fallback is the slug of titles without letters or digits
+	Fallback = "untitled"
+)
+
+// Make returns the slug of title: its letters and digits in lower case,
+// with accents removed and the runs of other characters replaced by a
+// hyphen. Letters of other scripts are kept as they are.
+func Make(title string) string {
+	plain, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), title)
+	if err != nil {
+		plain = title
+	}
+
+	var b strings.Builder
+	length := 0
+	hyphen := false
+	for _, r := range strings.ToLower(plain) {
+		if length >= MaxLength {
+			break
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			hyphen = b.Len() > 0
+			continue
+		}
+		if hyphen {
+			b.WriteByte('-')
+			length++
+			hyphen = false
+			if length >= MaxLength {
+				break
+			}
+		}
+		b.WriteRune(r)
+		length++
+	}
+
+	s := strings.TrimSuffix(b.String(), "-")
+	if s == "" {
+		return Fallback
+	}
+	return s
+}
+
+// Split returns the segments of a slug path such as "handbook/oncall"
+func Split(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
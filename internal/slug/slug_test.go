@@ -0,0 +1,35 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Oncall runbook", "oncall-runbook"},
+		{"  Q3 Planning: Goals & Risks!  ", "q3-planning-goals-risks"},
+		{"Café déjà vu", "cafe-deja-vu"},
+		{"运维 手册", "运维-手册"},
+		{"C++ / Go", "c-go"},
+		{"!!!", Fallback},
+		{"", Fallback},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Make(tt.title), tt.title)
+	}
+
+	long := Make(strings.Repeat("ab ", 60))
+	assert.LessOrEqual(t, len([]rune(long)), MaxLength)
+	assert.False(t, strings.HasSuffix(long, "-"))
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{"handbook", "oncall"}, Split("/handbook//oncall/"))
+	assert.Empty(t, Split("/"))
+}
@@ -0,0 +1,497 @@
+package vector
+
+import (
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HNSW parameters used when not configured
+const (
+	DefaultM              = 16
+	DefaultEfConstruction = 200
+	DefaultEfSearch       = 64
+
+	hnswFormatVersion = 1
+	hnswFileName      = "vectors.gob"
+)
+
+// Options configures an HNSW index
+type Options struct {
+	Dimensions     int
+	M              int // neighbors per node and layer; layer 0 keeps 2*M
+	EfConstruction int
+	EfSearch       int
+	// FlushInterval enables background writes of pending changes when positive
+	FlushInterval time.Duration
+}
+
+// node is a passage in the graph. Deleted nodes stay in the graph so that
+// searches can still route through them until the next compaction.
+type node struct {
+	Entry
+	Neighbors [][]int // per layer, from 0 to the node's level
+	Deleted   bool
+}
+
+// hnswSnapshot is the on-disk representation of the index
+type hnswSnapshot struct {
+	Version    int
+	Dimensions int
+	Nodes      []*node
+	EntryPoint int
+	MaxLevel   int
+}
+
+// HNSW is an in-process approximate nearest neighbor index using a
+// hierarchical navigable small world graph. It is safe for concurrent use
+// and persists itself to a single file under its directory.
+type HNSW struct {
+	mu   sync.RWMutex
+	path string
+	opts Options
+
+	nodes      []*node
+	entryPoint int // -1 when empty
+	maxLevel   int
+	byDocument map[uint][]int
+	deleted    int
+	levelMult  float64
+	rng        *rand.Rand
+	dirty      bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewHNSW opens the index persisted in dir, or starts an empty one. An empty
+// dir keeps the index in memory only. A persisted index built for other
+// dimensions is discarded and has to be rebuilt by the caller.
+func NewHNSW(dir string, opts Options) (*HNSW, error) {
+	if opts.Dimensions <= 0 {
+		return nil, fmt.Errorf("vector dimensions must be positive")
+	}
+	if opts.M <= 1 {
+		opts.M = DefaultM
+	}
+	if opts.EfConstruction <= 0 {
+		opts.EfConstruction = DefaultEfConstruction
+	}
+	if opts.EfSearch <= 0 {
+		opts.EfSearch = DefaultEfSearch
+	}
+
+	h := &HNSW{
+		opts:       opts,
+		entryPoint: -1,
+		byDocument: make(map[uint][]int),
+		levelMult:  1 / math.Log(float64(opts.M)),
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create vector index directory: %w", err)
+		}
+		h.path = filepath.Join(dir, hnswFileName)
+		if err := h.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.FlushInterval > 0 && h.path != "" {
+		h.stop = make(chan struct{})
+		h.done = make(chan struct{})
+		go h.flushLoop(opts.FlushInterval)
+	}
+
+	return h, nil
+}
+
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes) - h.deleted
+}
+
+func (h *HNSW) Upsert(ctx context.Context, documentID uint, entries []Entry) error {
+	for _, entry := range entries {
+		if len(entry.Vector) != h.opts.Dimensions {
+			return fmt.Errorf("vector has %d dimensions, expected %d", len(entry.Vector), h.opts.Dimensions)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.deleteLocked(documentID)
+	for _, entry := range entries {
+		entry.DocumentID = documentID
+		h.insert(entry)
+	}
+	h.compactIfNeeded()
+	h.dirty = true
+	return nil
+}
+
+func (h *HNSW) Delete(ctx context.Context, documentID uint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.deleteLocked(documentID) {
+		h.compactIfNeeded()
+		h.dirty = true
+	}
+	return nil
+}
+
+func (h *HNSW) deleteLocked(documentID uint) bool {
+	ids, ok := h.byDocument[documentID]
+	if !ok {
+		return false
+	}
+	for _, id := range ids {
+		h.nodes[id].Deleted = true
+	}
+	h.deleted += len(ids)
+	delete(h.byDocument, documentID)
+	return true
+}
+
+func (h *HNSW) Search(ctx context.Context, vector []float32, limit int, filter func(documentID uint) bool) ([]Match, error) {
+	if len(vector) != h.opts.Dimensions {
+		return nil, fmt.Errorf("query vector has %d dimensions, expected %d", len(vector), h.opts.Dimensions)
+	}
+	if limit <= 0 {
+		return []Match{}, nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entryPoint < 0 {
+		return []Match{}, nil
+	}
+
+	ep := h.entryPoint
+	for level := h.maxLevel; level > 0; level-- {
+		ep = h.greedy(vector, ep, level)
+	}
+
+	// 已删除节点仍会出现在候选中，按删除比例放大搜索宽度
+	ef := max(h.opts.EfSearch, limit)
+	if live := len(h.nodes) - h.deleted; live > 0 {
+		ef += ef * h.deleted / live
+	}
+
+	for {
+		matches := make([]Match, 0, limit)
+		for _, c := range h.searchLayer(vector, ep, ef, 0) {
+			n := h.nodes[c.id]
+			if n.Deleted || (filter != nil && !filter(n.DocumentID)) {
+				continue
+			}
+			matches = append(matches, Match{
+				DocumentID: n.DocumentID,
+				ChunkIndex: n.ChunkIndex,
+				Title:      n.Title,
+				Text:       n.Text,
+				Score:      1 - c.dist,
+			})
+			if len(matches) == limit {
+				break
+			}
+		}
+
+		// 过滤后结果不足时扩大搜索宽度重试，直到覆盖整个图
+		if len(matches) == limit || ef >= len(h.nodes) {
+			return matches, nil
+		}
+		ef *= 4
+	}
+}
+
+// insert adds an entry to the graph. Callers must hold the write lock.
+func (h *HNSW) insert(entry Entry) {
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	id := len(h.nodes)
+	n := &node{Entry: entry, Neighbors: make([][]int, level+1)}
+	h.nodes = append(h.nodes, n)
+	h.byDocument[entry.DocumentID] = append(h.byDocument[entry.DocumentID], id)
+
+	if h.entryPoint < 0 {
+		h.entryPoint = id
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entryPoint
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(entry.Vector, ep, l)
+	}
+
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(entry.Vector, ep, h.opts.EfConstruction, l)
+		neighbors := candidates[:min(h.opts.M, len(candidates))]
+
+		n.Neighbors[l] = make([]int, 0, len(neighbors))
+		for _, c := range neighbors {
+			n.Neighbors[l] = append(n.Neighbors[l], c.id)
+			h.connect(c.id, id, l)
+		}
+		ep = candidates[0].id
+	}
+
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entryPoint = id
+	}
+}
+
+// connect adds a link from node from to node to on a layer, dropping the
+// farthest link when the node has too many
+func (h *HNSW) connect(from, to, level int) {
+	n := h.nodes[from]
+	n.Neighbors[level] = append(n.Neighbors[level], to)
+
+	maxLinks := h.opts.M
+	if level == 0 {
+		maxLinks = 2 * h.opts.M
+	}
+	if len(n.Neighbors[level]) <= maxLinks {
+		return
+	}
+
+	links := make([]candidate, 0, len(n.Neighbors[level]))
+	for _, id := range n.Neighbors[level] {
+		links = append(links, candidate{id: id, dist: distance(n.Vector, h.nodes[id].Vector)})
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].dist < links[j].dist })
+
+	n.Neighbors[level] = n.Neighbors[level][:0]
+	for _, link := range links[:maxLinks] {
+		n.Neighbors[level] = append(n.Neighbors[level], link.id)
+	}
+}
+
+// greedy walks a layer towards the node closest to q
+func (h *HNSW) greedy(q []float32, ep int, level int) int {
+	best := distance(q, h.nodes[ep].Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, id := range h.nodes[ep].Neighbors[level] {
+			if d := distance(q, h.nodes[id].Vector); d < best {
+				best, ep, changed = d, id, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nodes of a layer closest to q, nearest first
+func (h *HNSW) searchLayer(q []float32, ep int, ef int, level int) []candidate {
+	visited := map[int]bool{ep: true}
+	start := candidate{id: ep, dist: distance(q, h.nodes[ep].Vector)}
+	candidates := &minHeap{start}
+	results := &maxHeap{start}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if c.dist > (*results)[0].dist && results.Len() >= ef {
+			break
+		}
+		for _, id := range h.nodes[c.id].Neighbors[level] {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			d := distance(q, h.nodes[id].Vector)
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(candidates, candidate{id: id, dist: d})
+				heap.Push(results, candidate{id: id, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := make([]candidate, results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(results).(candidate)
+	}
+	return sorted
+}
+
+// compactIfNeeded rebuilds the graph without deleted nodes once they make
+// up half of it. Callers must hold the write lock.
+func (h *HNSW) compactIfNeeded() {
+	if h.deleted < 64 || h.deleted*2 < len(h.nodes) {
+		return
+	}
+
+	nodes := h.nodes
+	h.nodes = make([]*node, 0, len(nodes)-h.deleted)
+	h.entryPoint = -1
+	h.maxLevel = 0
+	h.byDocument = make(map[uint][]int)
+	h.deleted = 0
+	for _, n := range nodes {
+		if !n.Deleted {
+			h.insert(n.Entry)
+		}
+	}
+}
+
+// distance is the cosine distance of two unit vectors
+func distance(a, b []float32) float64 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return 1 - float64(dot)
+}
+
+// Flush writes the index to disk if it changed since the last flush. The
+// file is replaced atomically.
+func (h *HNSW) Flush() error {
+	if h.path == "" {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), hnswFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create vector index file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = gob.NewEncoder(tmp).Encode(&hnswSnapshot{
+		Version:    hnswFormatVersion,
+		Dimensions: h.opts.Dimensions,
+		Nodes:      h.nodes,
+		EntryPoint: h.entryPoint,
+		MaxLevel:   h.maxLevel,
+	})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write vector index: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), h.path); err != nil {
+		return fmt.Errorf("failed to replace vector index file: %w", err)
+	}
+
+	h.dirty = false
+	return nil
+}
+
+func (h *HNSW) load() error {
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open vector index: %w", err)
+	}
+	defer f.Close()
+
+	var snap hnswSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to read vector index: %w", err)
+	}
+	// 格式或维度不兼容时丢弃旧索引，由调用方重建
+	if snap.Version != hnswFormatVersion || snap.Dimensions != h.opts.Dimensions || len(snap.Nodes) == 0 {
+		return nil
+	}
+
+	h.nodes = snap.Nodes
+	h.entryPoint = snap.EntryPoint
+	h.maxLevel = snap.MaxLevel
+	for id, n := range h.nodes {
+		if n.Deleted {
+			h.deleted++
+			continue
+		}
+		h.byDocument[n.DocumentID] = append(h.byDocument[n.DocumentID], id)
+	}
+	return nil
+}
+
+func (h *HNSW) flushLoop(interval time.Duration) {
+	defer close(h.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// 失败时保持 dirty 状态，下一轮重试
+			_ = h.Flush()
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// Close stops background flushing and writes pending changes
+func (h *HNSW) Close() error {
+	if h.stop != nil {
+		close(h.stop)
+		<-h.done
+		h.stop = nil
+	}
+	return h.Flush()
+}
+
+type candidate struct {
+	id   int
+	dist float64
+}
+
+// minHeap pops the nearest candidate first
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// maxHeap keeps the farthest candidate on top so it can be evicted
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
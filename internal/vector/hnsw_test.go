@@ -0,0 +1,171 @@
+package vector
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUnitVector(rng *rand.Rand, dims int) []float32 {
+	v := make([]float32, dims)
+	var sum float64
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+		sum += float64(v[i]) * float64(v[i])
+	}
+	for i := range v {
+		v[i] /= float32(math.Sqrt(sum))
+	}
+	return v
+}
+
+// bruteForce returns the IDs of the k documents nearest to q
+func bruteForce(vectors map[uint][]float32, q []float32, k int) []uint {
+	ids := make([]uint, 0, len(vectors))
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return distance(q, vectors[ids[i]]) < distance(q, vectors[ids[j]])
+	})
+	return ids[:k]
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const dims, count, k = 32, 2000, 10
+	rng := rand.New(rand.NewSource(1))
+	ctx := context.Background()
+
+	h, err := NewHNSW("", Options{Dimensions: dims})
+	require.NoError(t, err)
+
+	vectors := make(map[uint][]float32, count)
+	for id := uint(1); id <= count; id++ {
+		vectors[id] = randomUnitVector(rng, dims)
+		require.NoError(t, h.Upsert(ctx, id, []Entry{{Text: "passage", Vector: vectors[id]}}))
+	}
+	assert.Equal(t, count, h.Len())
+
+	found, total := 0, 0
+	for i := 0; i < 50; i++ {
+		q := randomUnitVector(rng, dims)
+		matches, err := h.Search(ctx, q, k, nil)
+		require.NoError(t, err)
+		require.Len(t, matches, k)
+
+		expected := make(map[uint]bool)
+		for _, id := range bruteForce(vectors, q, k) {
+			expected[id] = true
+		}
+		for j, m := range matches {
+			if expected[m.DocumentID] {
+				found++
+			}
+			if j > 0 {
+				assert.LessOrEqual(t, m.Score, matches[j-1].Score)
+			}
+		}
+		total += k
+	}
+
+	recall := float64(found) / float64(total)
+	assert.Greater(t, recall, 0.9, "recall %.2f", recall)
+}
+
+func TestHNSWUpsertAndDelete(t *testing.T) {
+	ctx := context.Background()
+	h, err := NewHNSW("", Options{Dimensions: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, h.Upsert(ctx, 1, []Entry{
+		{ChunkIndex: 0, Text: "east", Vector: []float32{1, 0}},
+		{ChunkIndex: 1, Text: "north", Vector: []float32{0, 1}},
+	}))
+	require.NoError(t, h.Upsert(ctx, 2, []Entry{{Text: "west", Vector: []float32{-1, 0}}}))
+
+	matches, err := h.Search(ctx, []float32{1, 0}, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, Match{DocumentID: 1, ChunkIndex: 0, Text: "east", Score: 1}, matches[0])
+
+	// 更新会替换文档的全部段落
+	require.NoError(t, h.Upsert(ctx, 1, []Entry{{Text: "south", Vector: []float32{0, -1}}}))
+	assert.Equal(t, 2, h.Len())
+	matches, err = h.Search(ctx, []float32{1, 0}, 3, nil)
+	require.NoError(t, err)
+	texts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		texts = append(texts, m.Text)
+	}
+	assert.ElementsMatch(t, []string{"south", "west"}, texts)
+
+	require.NoError(t, h.Delete(ctx, 2))
+	matches, err = h.Search(ctx, []float32{-1, 0}, 3, nil)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "south", matches[0].Text)
+
+	// 过滤只返回指定文档的段落
+	require.NoError(t, h.Upsert(ctx, 2, []Entry{{Text: "west", Vector: []float32{-1, 0}}}))
+	matches, err = h.Search(ctx, []float32{0, -1}, 3, func(id uint) bool { return id == 2 })
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "west", matches[0].Text)
+
+	_, err = h.Search(ctx, []float32{1, 0, 0}, 1, nil)
+	assert.Error(t, err)
+	assert.Error(t, h.Upsert(ctx, 3, []Entry{{Vector: []float32{1}}}))
+}
+
+func TestHNSWCompaction(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(2))
+	h, err := NewHNSW("", Options{Dimensions: 8})
+	require.NoError(t, err)
+
+	for id := uint(1); id <= 200; id++ {
+		require.NoError(t, h.Upsert(ctx, id, []Entry{{Vector: randomUnitVector(rng, 8)}}))
+	}
+	for id := uint(1); id <= 150; id++ {
+		require.NoError(t, h.Delete(ctx, id))
+	}
+
+	assert.Equal(t, 50, h.Len())
+	assert.Less(t, len(h.nodes), 200, "deleted nodes should have been compacted")
+
+	matches, err := h.Search(ctx, randomUnitVector(rng, 8), 100, nil)
+	require.NoError(t, err)
+	assert.Len(t, matches, 50)
+	for _, m := range matches {
+		assert.Greater(t, m.DocumentID, uint(150))
+	}
+}
+
+func TestHNSWPersistence(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	h, err := NewHNSW(dir, Options{Dimensions: 2})
+	require.NoError(t, err)
+	require.NoError(t, h.Upsert(ctx, 7, []Entry{{Title: "Runbook", Text: "east", Vector: []float32{1, 0}}}))
+	require.NoError(t, h.Upsert(ctx, 8, []Entry{{Text: "gone", Vector: []float32{0, 1}}}))
+	require.NoError(t, h.Delete(ctx, 8))
+	require.NoError(t, h.Close())
+
+	reopened, err := NewHNSW(dir, Options{Dimensions: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 1, reopened.Len())
+	matches, err := reopened.Search(ctx, []float32{1, 0}, 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Match{{DocumentID: 7, Title: "Runbook", Text: "east", Score: 1}}, matches)
+
+	// 维度变化后旧索引被丢弃
+	resized, err := NewHNSW(dir, Options{Dimensions: 3})
+	require.NoError(t, err)
+	assert.Zero(t, resized.Len())
+}
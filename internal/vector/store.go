@@ -0,0 +1,38 @@
+package vector
+
+import "context"
+
+// Entry is a passage of a document with its embedding
+type Entry struct {
+	DocumentID uint
+	ChunkIndex int
+	Title      string
+	Text       string
+	Vector     []float32
+}
+
+// Match is a stored passage similar to a query vector. Score is the cosine
+// similarity, higher is closer.
+type Match struct {
+	DocumentID uint    `json:"document_id"`
+	ChunkIndex int     `json:"chunk_index"`
+	Title      string  `json:"title"`
+	Text       string  `json:"text"`
+	Score      float64 `json:"score"`
+}
+
+// Store holds passage embeddings and finds the nearest ones to a query.
+// Vectors are expected to be unit length.
+type Store interface {
+	// Upsert replaces all passages of a document
+	Upsert(ctx context.Context, documentID uint, entries []Entry) error
+	Delete(ctx context.Context, documentID uint) error
+	// Search returns at most limit passages ordered by descending score.
+	// When filter is not nil only passages of documents it accepts are returned.
+	Search(ctx context.Context, vector []float32, limit int, filter func(documentID uint) bool) ([]Match, error)
+	// Len returns the number of stored passages
+	Len() int
+	// Flush persists pending changes
+	Flush() error
+	Close() error
+}
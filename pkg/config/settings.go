@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// Settings holds the sections of the configuration file that configure the
+// services. Zero values leave the defaults of the services in place.
+type Settings struct {
+	Search SearchSettings `mapstructure:"search"`
+	Render RenderSettings `mapstructure:"render"`
+	Export ExportSettings `mapstructure:"export"`
+	Import ImportSettings `mapstructure:"import"`
+	AI     AISettings     `mapstructure:"ai"`
+}
+
+// Search engines
+const (
+	SearchEngineDatabase = "database" // SQL LIKE
+	SearchEngineEmbedded = "embedded" // in-process BM25 index
+)
+
+type SearchSettings struct {
+	TextConfig    string `mapstructure:"text_config"`
+	Engine        string `mapstructure:"engine"`
+	DataDir       string `mapstructure:"data_dir"`
+	FlushInterval int    `mapstructure:"flush_interval"` // seconds
+	CJKDictionary string `mapstructure:"cjk_dictionary"`
+}
+
+type RenderSettings struct {
+	CacheSize int `mapstructure:"cache_size"`
+}
+
+type ExportSettings struct {
+	PDFFont          string `mapstructure:"pdf_font"`
+	MaxSyncDocuments int    `mapstructure:"max_sync_documents"`
+	MaxSyncBytes     int64  `mapstructure:"max_sync_bytes"`
+}
+
+type ImportSettings struct {
+	MaxArchiveSize int64 `mapstructure:"max_archive_size"`
+}
+
+// Model providers
+const (
+	ProviderHash       = "hash"
+	ProviderOpenAI     = "openai"
+	ProviderFake       = "fake"
+	ProviderExtractive = "extractive"
+	ProviderModel      = "model"
+)
+
+type AISettings struct {
+	Embedding      ModelSettings         `mapstructure:"embedding"`
+	Chat           ModelSettings         `mapstructure:"chat"`
+	AskSources     int                   `mapstructure:"ask_sources"`
+	Summary        SummarySettings       `mapstructure:"summary"`
+	TagSuggestions TagSuggestionSettings `mapstructure:"tag_suggestions"`
+	ChunkSize      int                   `mapstructure:"chunk_size"`
+	ChunkOverlap   int                   `mapstructure:"chunk_overlap"`
+	VectorDir      string                `mapstructure:"vector_dir"`
+}
+
+// ModelSettings selects an embedding or chat model. APIKey may reference
+// environment variables, e.g. "${OPENAI_API_KEY}".
+type ModelSettings struct {
+	Provider   string `mapstructure:"provider"`
+	BaseURL    string `mapstructure:"base_url"`
+	APIKey     string `mapstructure:"api_key"`
+	Model      string `mapstructure:"model"`
+	Dimensions int    `mapstructure:"dimensions"`
+}
+
+type SummarySettings struct {
+	Provider  string `mapstructure:"provider"`
+	Sentences int    `mapstructure:"sentences"`
+	KeyPoints int    `mapstructure:"key_points"`
+}
+
+type TagSuggestionSettings struct {
+	Limit              int     `mapstructure:"limit"`
+	UseModel           bool    `mapstructure:"use_model"`
+	AutoApply          bool    `mapstructure:"auto_apply"`
+	AutoApplyThreshold float64 `mapstructure:"auto_apply_threshold"`
+}
+
+// LoadSettings reads the service settings from the loaded configuration
+func LoadSettings() (*Settings, error) {
+	var settings Settings
+	if err := viper.Unmarshal(&settings); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	// 密钥通常以 ${ENV} 的形式引用环境变量
+	settings.AI.Embedding.APIKey = os.ExpandEnv(settings.AI.Embedding.APIKey)
+	settings.AI.Chat.APIKey = os.ExpandEnv(settings.AI.Chat.APIKey)
+	return &settings, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSettings(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile("../../configs/config.yaml")
+	require.NoError(t, viper.ReadInConfig())
+
+	settings, err := LoadSettings()
+	require.NoError(t, err)
+
+	assert.Equal(t, "english", settings.Search.TextConfig)
+	assert.Equal(t, SearchEngineDatabase, settings.Search.Engine)
+	assert.Equal(t, 30, settings.Search.FlushInterval)
+	assert.Equal(t, 500, settings.Render.CacheSize)
+	assert.Equal(t, 20, settings.Export.MaxSyncDocuments)
+	assert.Equal(t, int64(10485760), settings.Export.MaxSyncBytes)
+	assert.Equal(t, int64(268435456), settings.Import.MaxArchiveSize)
+
+	assert.Equal(t, ProviderHash, settings.AI.Embedding.Provider)
+	assert.Equal(t, 768, settings.AI.Embedding.Dimensions)
+	assert.Equal(t, "sk-test", settings.AI.Chat.APIKey, "API keys expand environment variables")
+	assert.Equal(t, 5, settings.AI.AskSources)
+	assert.Equal(t, ProviderExtractive, settings.AI.Summary.Provider)
+	assert.Equal(t, 0.8, settings.AI.TagSuggestions.AutoApplyThreshold)
+	assert.Equal(t, 150, settings.AI.ChunkOverlap)
+	assert.Equal(t, "./data/vectors", settings.AI.VectorDir)
+}